@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -28,20 +29,50 @@ func main() {
 	}
 	defer db.Close()
 
+	// Загрузка производственного календаря (праздники и переносы выходных)
+	productionCalendar, err := services.LoadProductionCalendar(cfg.Calendar.FilePath)
+	if err != nil {
+		log.Fatalf("Ошибка загрузки производственного календаря: %v", err)
+	}
+
 	// Создание репозиториев
 	userRepo := repositories.NewUserRepository(db)
 	vacationRepo := repositories.NewVacationRepository(db)
 	unitRepo := repositories.NewOrganizationalUnitRepository(db) // Добавлен репозиторий юнитов
 
 	// Создание сервисов
-	// Передаем оба репозитория в NewAuthService
-	authService := services.NewAuthService(userRepo, vacationRepo, cfg.JWT.Secret)
 	// Передаем все три репозитория в NewVacationService
-	vacationService := services.NewVacationService(vacationRepo, userRepo, unitRepo) // Добавлен unitRepo
+	reasonPolicy := services.ReasonPolicy{
+		RejectionRequired:    cfg.Policy.RejectionReasonRequired,
+		CancellationRequired: cfg.Policy.CancellationReasonRequired,
+	}
+	carryOverPolicy := services.CarryOverPolicy{
+		Mode:        cfg.CarryOver.Mode,
+		CapDays:     cfg.CarryOver.CapDays,
+		ExpiryYears: cfg.CarryOver.ExpiryYears,
+	}
+	approvalPolicy := services.ApprovalPolicy{
+		ManagerLevels:  cfg.Policy.ApprovalManagerLevels,
+		HRStep:         cfg.Policy.ApprovalHRStep,
+		AutoDelegation: cfg.Policy.AutoDelegation,
+	}
+	vacationService := services.NewVacationService(vacationRepo, userRepo, unitRepo, productionCalendar, reasonPolicy, carryOverPolicy, approvalPolicy) // Добавлен unitRepo и календарь
+	// Передаем оба репозитория в NewAuthService; начисление при регистрации считает сервис отпусков по политике
+	authService := services.NewAuthService(userRepo, vacationRepo, vacationService, cfg.JWT.Secret)
 	// Создаем UserService
 	userService := services.NewUserService(userRepo, unitRepo)               // Передаем оба репозитория
 	unitService := services.NewOrganizationalUnitService(unitRepo, userRepo) // Добавлен сервис юнитов
 
+	// Фоновый перевод заявок по датам отпуска: "Утверждена" -> "В отпуске" -> "Завершена"
+	go func() {
+		for {
+			if err := vacationService.AdvanceVacationStatuses(time.Now()); err != nil {
+				log.Printf("Ошибка обновления статусов заявок по датам отпуска: %v", err)
+			}
+			time.Sleep(time.Hour)
+		}
+	}()
+
 	// Создание обработчиков
 	authHandler := handlers.NewAuthHandler(authService)
 	// Создаем AppHandler и передаем все три сервиса
@@ -77,10 +108,35 @@ func main() {
 		vacations := api.Group("/vacations")
 		{
 			vacations.GET("/limits/:year", appHandler.GetVacationLimit)
+			vacations.GET("/ledger/:year", appHandler.GetLeaveLedger) // Журнал движения дней (?userId= для руководителя/админа)
+			vacations.GET("/leave-types", appHandler.GetLeaveTypes)   // Справочник видов отпуска
 			vacations.POST("/requests", appHandler.CreateVacationRequest)
+			vacations.POST("/requests/preview", appHandler.PreviewVacationRequest) // Пробная проверка заявки без сохранения (дни, остаток, конфликты)
+			vacations.PUT("/requests/:id", appHandler.UpdateVacationRequest)       // Изменение черновика или заявки на рассмотрении (только автор)
 			vacations.POST("/requests/:id/submit", appHandler.SubmitVacationRequest)
-			vacations.POST("/requests/:id/cancel", appHandler.CancelVacationRequest) // Доступен всем аутентифицированным (проверка прав внутри)
-			vacations.GET("/my", appHandler.GetMyVacations)                          // Получение своих заявок
+			vacations.POST("/requests/:id/cancel", appHandler.CancelVacationRequest)             // Доступен всем аутентифицированным (проверка прав внутри)
+			vacations.GET("/requests/:id/history", appHandler.GetVacationRequestHistory)         // История переходов заявки (проверка прав внутри)
+			vacations.GET("/requests/:id/proposals", appHandler.GetVacationRequestProposals)     // Предложения других дат и ответы сотрудника
+			vacations.POST("/requests/:id/proposal/accept", appHandler.AcceptVacationProposal)   // Принятие предложенных дат (автор)
+			vacations.POST("/requests/:id/proposal/decline", appHandler.DeclineVacationProposal) // Отклонение предложенных дат с пояснением (автор)
+			vacations.GET("/my", appHandler.GetMyVacations)                                      // Получение своих заявок
+			vacations.POST("/transfers", appHandler.CreateVacationTransfer)                      // Заявка на перенос периода утвержденного отпуска
+			vacations.GET("/transfers/my", appHandler.GetMyVacationTransfers)                    // Свои заявки на перенос
+			vacations.GET("/preferences/my/:year", appHandler.GetMyVacationPreference)           // Свое пожелание к графику на год
+			vacations.PUT("/preferences/my/:year", appHandler.SaveMyVacationPreference)          // Варианты периодов по приоритету и ограничения
+			vacations.DELETE("/preferences/my/:year", appHandler.DeleteMyVacationPreference)     // Удаление своего пожелания
+			vacations.POST("/preferences/:id/convert", appHandler.ConvertVacationPreference)     // Заявка по выбранному варианту (автор или руководитель, проверка прав внутри)
+			vacations.POST("/transfers/:id/cancel", appHandler.CancelVacationTransfer)           // Отмена своего переноса на рассмотрении
+			// Рассмотрение заявок доступно руководителям, администраторам и заместителям по делегированию (проверка прав внутри)
+			vacations.GET("/all", appHandler.GetAllVacations)                            // Получение всех заявок (с фильтрами)
+			vacations.POST("/requests/:id/approve", appHandler.ApproveVacationRequest)   // Утверждение заявки (этапа цепочки согласования)
+			vacations.POST("/requests/:id/reject", appHandler.RejectVacationRequest)     // Отклонение заявки
+			vacations.POST("/requests/:id/propose", appHandler.ProposeVacationChanges)   // Предложение других дат сотруднику
+			vacations.POST("/requests/bulk", appHandler.BulkDecideVacationRequests)      // Пакетное утверждение или отклонение (итог по каждой заявке)
+			vacations.GET("/transfers", appHandler.GetVacationTransfers)                 // Переносы сотрудников (?status=)
+			vacations.POST("/periods/:id/recall", appHandler.RecallFromVacation)         // Отзыв сотрудника из отпуска (усечение периода)
+			vacations.POST("/transfers/:id/approve", appHandler.ApproveVacationTransfer) // Утверждение переноса
+			vacations.POST("/transfers/:id/reject", appHandler.RejectVacationTransfer)   // Отклонение переноса
 			// Новый маршрут для получения конфликтов (доступен всем аутентифицированным)
 			vacations.GET("/conflicts", appHandler.GetVacationConflicts)
 
@@ -88,14 +144,39 @@ func main() {
 			vacationsMgmt := vacations.Group("")
 			vacationsMgmt.Use(middleware.ManagerOrAdminOnly()) // Доступ только для менеджеров или админов
 			{
-				vacationsMgmt.GET("/all", appHandler.GetAllVacations)                          // Получение всех заявок (с фильтрами)
-				vacationsMgmt.GET("/unit/:id", appHandler.GetOrganizationalUnitVacations)      // Маршрут обновлен: /department/:id -> /unit/:id, обработчик изменен
-				vacationsMgmt.GET("/intersections", appHandler.GetVacationIntersections)       // Проверка пересечений (доступна менеджерам)
-				vacationsMgmt.POST("/requests/:id/approve", appHandler.ApproveVacationRequest) // Утверждение заявки
-				vacationsMgmt.POST("/requests/:id/reject", appHandler.RejectVacationRequest)   // Отклонение заявки
+				vacationsMgmt.GET("/unit/:id", appHandler.GetOrganizationalUnitVacations)     // Маршрут обновлен: /department/:id -> /unit/:id, обработчик изменен
+				vacationsMgmt.GET("/intersections", appHandler.GetVacationIntersections)      // Проверка пересечений (доступна менеджерам)
+				vacationsMgmt.GET("/preferences", appHandler.GetSubtreeVacationPreferences)   // Пожелания сотрудников поддерева для сравнения (?year=, ?unitId=)
+				vacationsMgmt.POST("/schedule/generate", appHandler.GenerateVacationSchedule) // Предложение графика отпусков юнита на год
+				vacationsMgmt.POST("/schedule/publish", appHandler.PublishVacationSchedule)   // Публикация графика черновиками заявок сотрудников
+				vacationsMgmt.GET("/peak-distribution", appHandler.GetPeakSlotReport)         // Распределение пиковых периодов по юнитам (?unitId=, ?years=)
 			}
 		}
 
+		// Делегирование прав руководителя на время отсутствия (проверка прав внутри)
+		delegations := api.Group("/delegations")
+		{
+			delegations.GET("", appHandler.GetApprovalDelegations)          // Свои делегирования (выданные и полученные)
+			delegations.POST("", appHandler.CreateApprovalDelegation)       // Передать права заместителю на период
+			delegations.DELETE("/:id", appHandler.RevokeApprovalDelegation) // Отозвать делегирование
+		}
+
+		// Периоды запрета отпусков юнитов (наследуются поддеревом; изменяют администратор и руководитель юнита, проверка прав внутри)
+		unitBlackouts := api.Group("/units/:id/blackouts")
+		{
+			unitBlackouts.GET("", appHandler.GetUnitBlackouts)                  // GET /api/units/{id}/blackouts - для календаря
+			unitBlackouts.POST("", appHandler.CreateUnitBlackout)               // POST /api/units/{id}/blackouts
+			unitBlackouts.PUT("/:blackoutId", appHandler.UpdateUnitBlackout)    // PUT /api/units/{id}/blackouts/{blackoutId}
+			unitBlackouts.DELETE("/:blackoutId", appHandler.DeleteUnitBlackout) // DELETE /api/units/{id}/blackouts/{blackoutId}
+		}
+
+		// Кампании планирования графика отпусков: сроки этапов доступны всем, ход кампании - руководителям и администраторам
+		campaigns := api.Group("/campaigns")
+		{
+			campaigns.GET("", appHandler.GetPlanningCampaigns)                                                      // GET /api/campaigns?year=
+			campaigns.GET("/:id/progress", middleware.ManagerOrAdminOnly(), appHandler.GetPlanningCampaignProgress) // Кто подал и не подал заявки
+		}
+
 		// Маршрут для дашборда руководителя
 		dashboard := api.Group("/dashboard")
 		dashboard.Use(middleware.ManagerOrAdminOnly()) // Доступ только для менеджеров или админов
@@ -112,6 +193,46 @@ func main() {
 			// Переименован маршрут для избежания конфликта с GET /api/admin/users
 			admin.GET("/users-with-limits", appHandler.GetAllUsersWithLimits) // GET /api/admin/users-with-limits?year=...
 
+			// Маршруты для справочника видов отпуска
+			admin.POST("/leave-types", appHandler.CreateLeaveType)    // POST /api/admin/leave-types
+			admin.PUT("/leave-types/:id", appHandler.UpdateLeaveType) // PUT /api/admin/leave-types/{id}
+
+			// Маршруты для политик отпуска (правила по юнитам и должностям с наследованием вниз по дереву)
+			policies := admin.Group("/policies")
+			{
+				policies.GET("", appHandler.GetVacationPolicies)                          // GET /api/admin/policies
+				policies.POST("", appHandler.CreateVacationPolicy)                        // POST /api/admin/policies
+				policies.PUT("/:id", appHandler.UpdateVacationPolicy)                     // PUT /api/admin/policies/{id}
+				policies.DELETE("/:id", appHandler.DeleteVacationPolicy)                  // DELETE /api/admin/policies/{id}
+				policies.GET("/effective/:userId", appHandler.GetEffectiveVacationPolicy) // GET /api/admin/policies/effective/{userId}
+			}
+
+			// Маршруты для кампаний планирования графика отпусков (этапы: сбор заявок, руководители, отдел кадров, утвержден)
+			adminCampaigns := admin.Group("/campaigns")
+			{
+				adminCampaigns.POST("", appHandler.CreatePlanningCampaign)              // POST /api/admin/campaigns
+				adminCampaigns.PUT("/:id", appHandler.UpdatePlanningCampaign)           // PUT /api/admin/campaigns/{id} - сроки этапов
+				adminCampaigns.POST("/:id/advance", appHandler.AdvancePlanningCampaign) // POST /api/admin/campaigns/{id}/advance
+			}
+
+			// Маршруты для правил укомплектованности (максимум отсутствующих / минимум на месте по юнитам и группам должностей)
+			coverageRules := admin.Group("/coverage-rules")
+			{
+				coverageRules.GET("", appHandler.GetCoverageRules)          // GET /api/admin/coverage-rules
+				coverageRules.POST("", appHandler.CreateCoverageRule)       // POST /api/admin/coverage-rules
+				coverageRules.PUT("/:id", appHandler.UpdateCoverageRule)    // PUT /api/admin/coverage-rules/{id}
+				coverageRules.DELETE("/:id", appHandler.DeleteCoverageRule) // DELETE /api/admin/coverage-rules/{id}
+			}
+
+			// Маршруты для пиковых периодов отпусков (по отпускам в них за прошлые годы считается очередность выбора пиковых дат)
+			peakWindows := admin.Group("/peak-windows")
+			{
+				peakWindows.GET("", appHandler.GetPeakWindows)          // GET /api/admin/peak-windows
+				peakWindows.POST("", appHandler.CreatePeakWindow)       // POST /api/admin/peak-windows
+				peakWindows.PUT("/:id", appHandler.UpdatePeakWindow)    // PUT /api/admin/peak-windows/{id}
+				peakWindows.DELETE("/:id", appHandler.DeletePeakWindow) // DELETE /api/admin/peak-windows/{id}
+			}
+
 			// Маршруты для управления организационной структурой
 			units := admin.Group("/units")
 			{
@@ -130,7 +251,11 @@ func main() {
 				adminUsers.GET("", appHandler.GetAllUsersHandler)         // GET /api/admin/users - Получить всех пользователей
 				adminUsers.PUT("/:id", appHandler.UpdateUserAdminHandler) // PUT /api/admin/users/{id} - Обновить пользователя админом
 				// Маршрут обновления лимита перенесен сюда и использует :id
-				adminUsers.PUT("/:id/vacation-limit", appHandler.UpdateUserVacationLimitHandler) // PUT /api/admin/users/{id}/vacation-limit
+				adminUsers.PUT("/:id/vacation-limit", appHandler.UpdateUserVacationLimitHandler)                 // PUT /api/admin/users/{id}/vacation-limit
+				adminUsers.POST("/:id/ledger-adjustments", appHandler.CreateLedgerAdjustment)                    // POST /api/admin/users/{id}/ledger-adjustments
+				adminUsers.GET("/:id/priority-categories", appHandler.GetUserPriorityCategories)                 // GET /api/admin/users/{id}/priority-categories
+				adminUsers.POST("/:id/priority-categories", appHandler.AddUserPriorityCategory)                  // POST /api/admin/users/{id}/priority-categories
+				adminUsers.DELETE("/:id/priority-categories/:categoryId", appHandler.DeleteUserPriorityCategory) // DELETE /api/admin/users/{id}/priority-categories/{categoryId}
 				// TODO: Добавить маршруты для создания/удаления пользователей админом, если нужно
 			}
 
@@ -138,6 +263,7 @@ func main() {
 			adminVacations := admin.Group("/vacations")
 			{
 				adminVacations.POST("/export", appHandler.ExportVacationsByUnits) // POST /api/admin/vacations/export
+				adminVacations.POST("/rollover", appHandler.RolloverVacationYear) // POST /api/admin/vacations/rollover - перенос остатков на следующий год
 			}
 		}
 
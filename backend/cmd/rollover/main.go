@@ -0,0 +1,69 @@
+// Команда rollover закрывает год: переносит остатки отпусков сотрудников на следующий год
+// по правилу переноса из конфигурации (CARRY_OVER_MODE, CARRY_OVER_CAP_DAYS, CARRY_OVER_EXPIRY_YEARS).
+//
+// Использование: go run ./cmd/rollover -year 2024
+package main
+
+import (
+	"flag"
+	"log"
+	"time"
+
+	"vacation-scheduler/internal/config"
+	"vacation-scheduler/internal/database"
+	"vacation-scheduler/internal/repositories"
+	"vacation-scheduler/internal/services"
+)
+
+func main() {
+	year := flag.Int("year", time.Now().Year()-1, "Закрываемый год (по умолчанию - прошлый)")
+	flag.Parse()
+
+	// Загрузка конфигурации
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
+	}
+
+	// Инициализация подключения к базе данных
+	db, err := database.NewConnection(cfg.Database)
+	if err != nil {
+		log.Fatalf("Ошибка подключения к базе данных: %v", err)
+	}
+	defer db.Close()
+
+	productionCalendar, err := services.LoadProductionCalendar(cfg.Calendar.FilePath)
+	if err != nil {
+		log.Fatalf("Ошибка загрузки производственного календаря: %v", err)
+	}
+
+	userRepo := repositories.NewUserRepository(db)
+	vacationRepo := repositories.NewVacationRepository(db)
+	unitRepo := repositories.NewOrganizationalUnitRepository(db)
+
+	reasonPolicy := services.ReasonPolicy{
+		RejectionRequired:    cfg.Policy.RejectionReasonRequired,
+		CancellationRequired: cfg.Policy.CancellationReasonRequired,
+	}
+	carryOverPolicy := services.CarryOverPolicy{
+		Mode:        cfg.CarryOver.Mode,
+		CapDays:     cfg.CarryOver.CapDays,
+		ExpiryYears: cfg.CarryOver.ExpiryYears,
+	}
+	approvalPolicy := services.ApprovalPolicy{
+		ManagerLevels:  cfg.Policy.ApprovalManagerLevels,
+		HRStep:         cfg.Policy.ApprovalHRStep,
+		AutoDelegation: cfg.Policy.AutoDelegation,
+	}
+	vacationService := services.NewVacationService(vacationRepo, userRepo, unitRepo, productionCalendar, reasonPolicy, carryOverPolicy, approvalPolicy)
+
+	result, err := vacationService.RolloverYear(*year, nil)
+	if err != nil {
+		log.Fatalf("Ошибка перехода %d года: %v", *year, err)
+	}
+	log.Printf("Переход %d года завершен: обработано %d, пропущено %d, ошибок %d; перенесено %d дн., сгорело %d дн.",
+		result.Year, len(result.Processed), len(result.SkippedUsers), len(result.FailedUsers), result.CarriedDays, result.ExpiredDays)
+	if len(result.FailedUsers) > 0 {
+		log.Fatalf("Не удалось обработать пользователей: %v", result.FailedUsers)
+	}
+}
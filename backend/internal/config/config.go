@@ -3,13 +3,20 @@ package config
 import (
 	// В реальном приложении здесь будут импорты для чтения конфигурации (например, viper)
 	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
 )
 
 // Config - структура для хранения конфигурации приложения
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	JWT       JWTConfig
+	Calendar  CalendarConfig
+	Policy    PolicyConfig
+	CarryOver CarryOverConfig
 }
 
 // ServerConfig - конфигурация сервера
@@ -27,6 +34,27 @@ type JWTConfig struct {
 	Secret string // Секретный ключ для подписи токенов
 }
 
+// CalendarConfig - конфигурация производственного календаря
+type CalendarConfig struct {
+	FilePath string // Путь к JSON-файлу с праздниками и переносами выходных
+}
+
+// PolicyConfig - правила обработки заявок
+type PolicyConfig struct {
+	RejectionReasonRequired    bool // Обязательна ли причина при отклонении заявки
+	CancellationReasonRequired bool // Обязательна ли причина при отмене заявки
+	ApprovalManagerLevels      int  // Сколько руководителей вверх по иерархии юнитов согласуют заявку
+	ApprovalHRStep             bool // Нужен ли завершающий этап согласования отделом кадров
+	AutoDelegation             bool // Передавать права руководителя вышестоящему на время его утвержденного отпуска
+}
+
+// CarryOverConfig - правило переноса неиспользованных дней на следующий год
+type CarryOverConfig struct {
+	Mode        string // FULL - переносится весь остаток, CAP - не более CapDays, EXPIRY - дни сгорают через ExpiryYears лет
+	CapDays     int    // Максимум переносимых дней для режима CAP
+	ExpiryYears int    // Сколько лет после года начисления дни можно использовать (режим EXPIRY)
+}
+
 // Load - функция для загрузки конфигурации (заглушка)
 // В реальном приложении здесь будет логика чтения из файла (e.g., config.yaml) или переменных окружения
 func Load() (*Config, error) {
@@ -42,6 +70,72 @@ func Load() (*Config, error) {
 		JWT: JWTConfig{
 			Secret: "your_very_secret_jwt_key", // ВАЖНО: Замените на ваш секретный ключ! Лучше брать из env.
 		},
+		Calendar: CalendarConfig{
+			FilePath: "data/production_calendar.json", // Относительно рабочей директории backend
+		},
+		Policy: PolicyConfig{
+			RejectionReasonRequired:    true,  // Сотрудник должен знать, почему заявка отклонена
+			CancellationReasonRequired: false, // Сотрудник может отменить свою заявку без объяснений
+			ApprovalManagerLevels:      1,     // Заявку утверждает ближайший руководитель
+			ApprovalHRStep:             false,
+			AutoDelegation:             false, // Делегирования на время отпуска руководитель оформляет сам
+		},
+		CarryOver: CarryOverConfig{
+			Mode:        "FULL", // По ТК РФ неиспользованный отпуск не сгорает
+			CapDays:     0,
+			ExpiryYears: 0,
+		},
+	}
+
+	// Путь к производственному календарю можно переопределить через переменную окружения
+	if path := os.Getenv("PRODUCTION_CALENDAR_PATH"); path != "" {
+		cfg.Calendar.FilePath = path
+	}
+
+	// Требования к причинам отклонения/отмены можно переопределить через переменные окружения
+	if err := overrideBool("REJECTION_REASON_REQUIRED", &cfg.Policy.RejectionReasonRequired); err != nil {
+		return nil, err
+	}
+	if err := overrideBool("CANCELLATION_REASON_REQUIRED", &cfg.Policy.CancellationReasonRequired); err != nil {
+		return nil, err
+	}
+
+	// Цепочка согласования: количество уровней руководителей и этап отдела кадров
+	if err := overrideInt("APPROVAL_MANAGER_LEVELS", &cfg.Policy.ApprovalManagerLevels); err != nil {
+		return nil, err
+	}
+	if err := overrideBool("APPROVAL_HR_STEP", &cfg.Policy.ApprovalHRStep); err != nil {
+		return nil, err
+	}
+	if err := overrideBool("AUTO_DELEGATION", &cfg.Policy.AutoDelegation); err != nil {
+		return nil, err
+	}
+	if cfg.Policy.ApprovalManagerLevels < 0 {
+		return nil, errors.New("APPROVAL_MANAGER_LEVELS не может быть отрицательным")
+	}
+
+	// Правило переноса остатка на следующий год
+	if mode := os.Getenv("CARRY_OVER_MODE"); mode != "" {
+		cfg.CarryOver.Mode = strings.ToUpper(mode)
+	}
+	if err := overrideInt("CARRY_OVER_CAP_DAYS", &cfg.CarryOver.CapDays); err != nil {
+		return nil, err
+	}
+	if err := overrideInt("CARRY_OVER_EXPIRY_YEARS", &cfg.CarryOver.ExpiryYears); err != nil {
+		return nil, err
+	}
+	switch cfg.CarryOver.Mode {
+	case "FULL":
+	case "CAP":
+		if cfg.CarryOver.CapDays < 0 {
+			return nil, errors.New("CARRY_OVER_CAP_DAYS не может быть отрицательным")
+		}
+	case "EXPIRY":
+		if cfg.CarryOver.ExpiryYears <= 0 {
+			return nil, errors.New("для режима EXPIRY необходимо указать CARRY_OVER_EXPIRY_YEARS больше нуля")
+		}
+	default:
+		return nil, fmt.Errorf("неизвестный режим переноса остатка CARRY_OVER_MODE: %s", cfg.CarryOver.Mode)
 	}
 
 	// Простая валидация (пример)
@@ -56,6 +150,37 @@ func Load() (*Config, error) {
 	if cfg.Server.Port == "" {
 		return nil, errors.New("необходимо указать порт сервера")
 	}
+	if cfg.Calendar.FilePath == "" {
+		return nil, errors.New("необходимо указать путь к производственному календарю")
+	}
 
 	return cfg, nil
 }
+
+// overrideBool переопределяет булево значение из переменной окружения, если она задана
+func overrideBool(envName string, target *bool) error {
+	value := os.Getenv(envName)
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("некорректное значение переменной %s: %w", envName, err)
+	}
+	*target = parsed
+	return nil
+}
+
+// overrideInt переопределяет целое значение из переменной окружения, если она задана
+func overrideInt(envName string, target *int) error {
+	value := os.Getenv(envName)
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("некорректное значение переменной %s: %w", envName, err)
+	}
+	*target = parsed
+	return nil
+}
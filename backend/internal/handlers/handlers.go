@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -106,8 +107,10 @@ func (h *AppHandler) SetVacationLimit(c *gin.Context) {
 		return
 	}
 
+	adminID, _ := c.Get("userID")
+
 	// Вызываем сервис для установки лимита
-	err := h.vacationService.SetVacationLimit(input.UserID, input.Year, input.TotalDays)
+	err := h.vacationService.SetVacationLimit(input.UserID, input.Year, input.TotalDays, adminID.(int))
 	if err != nil {
 		// Обрабатываем возможные ошибки сервиса (например, отрицательное количество дней или ошибка репозитория)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка установки лимита: " + err.Error()})
@@ -130,9 +133,10 @@ func (h *AppHandler) CreateVacationRequest(c *gin.Context) {
 	}
 
 	// Логируем распарсенный запрос
+	// DaysCount от клиента не используется: сервис пересчитывает дни по производственному календарю
 	log.Printf("Распарсенный запрос CreateVacationRequest (encoding/json): %+v", request)
 	for i, p := range request.Periods {
-		log.Printf("Период %d: Start=%v, End=%v, Days=%d", i+1, p.StartDate, p.EndDate, p.DaysCount)
+		log.Printf("Период %d: Start=%v, End=%v", i+1, p.StartDate, p.EndDate)
 	}
 
 	// Получаем ID пользователя из контекста
@@ -145,19 +149,45 @@ func (h *AppHandler) CreateVacationRequest(c *gin.Context) {
 
 	// Валидация заявки
 	if err := h.vacationService.ValidateVacationRequest(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, errorBody("", err))
 		return
 	}
 
 	// Сохранение заявки
 	if err := h.vacationService.SaveVacationRequest(&request); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка сохранения заявки: " + err.Error()})
+		c.JSON(transitionErrorStatus(err), errorBody("Ошибка сохранения заявки: ", err))
 		return
 	}
 
 	c.JSON(http.StatusCreated, request)
 }
 
+// PreviewVacationRequest обработчик для пробной проверки заявки без сохранения:
+// нарушения правил, дни по производственному календарю, остаток и конфликты, которые вызовет заявка
+func (h *AppHandler) PreviewVacationRequest(c *gin.Context) {
+	var request models.VacationRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ошибка чтения данных: " + err.Error()})
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+	request.ID = 0
+	request.UserID = userID.(int)
+
+	preview, err := h.vacationService.PreviewVacationRequest(&request)
+	if err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка проверки заявки: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
 // SubmitVacationRequest обработчик для отправки заявки руководителю
 func (h *AppHandler) SubmitVacationRequest(c *gin.Context) {
 	requestIDStr := c.Param("id")
@@ -176,13 +206,42 @@ func (h *AppHandler) SubmitVacationRequest(c *gin.Context) {
 
 	// Отправляем заявку руководителю
 	if err := h.vacationService.SubmitVacationRequest(requestID, userID.(int)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка отправки заявки: " + err.Error()})
+		c.JSON(transitionErrorStatus(err), errorBody("Ошибка отправки заявки: ", err))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Заявка успешно отправлена руководителю"})
 }
 
+// UpdateVacationRequest обработчик для изменения черновика или заявки на рассмотрении
+func (h *AppHandler) UpdateVacationRequest(c *gin.Context) {
+	requestID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID заявки"})
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+
+	var update models.VacationRequestUpdateDTO
+	if err := json.NewDecoder(c.Request.Body).Decode(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ошибка чтения данных: " + err.Error()})
+		return
+	}
+
+	updated, err := h.vacationService.UpdateVacationRequest(requestID, userID.(int), &update)
+	if err != nil {
+		c.JSON(transitionErrorStatus(err), errorBody("Ошибка изменения заявки: ", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
 // GetVacationIntersections обработчик для получения пересечений отпусков
 func (h *AppHandler) GetVacationIntersections(c *gin.Context) {
 	unitIDStr := c.Query("unitId") // departmentId -> unitId
@@ -342,20 +401,20 @@ func (h *AppHandler) CancelVacationRequest(c *gin.Context) {
 		return
 	}
 
+	// Получаем причину отмены из тела запроса (обязательность определяется политикой)
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil && err.Error() != "EOF" { // Игнорируем EOF, если тело пустое
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный формат причины отмены: " + err.Error()})
+		return
+	}
+
 	// Вызываем сервис для отмены заявки
-	err = h.vacationService.CancelVacationRequest(requestID, cancellingUserID.(int))
+	err = h.vacationService.CancelVacationRequest(requestID, cancellingUserID.(int), input.Reason)
 	if err != nil {
 		// Обрабатываем возможные ошибки (заявка не найдена, нет прав, нельзя отменить и т.д.)
-		// Определяем код ошибки
-		statusCode := http.StatusInternalServerError
-		if err.Error() == "заявка не найдена" {
-			statusCode = http.StatusNotFound
-		} else if err.Error() == "нет прав на отмену этой заявки" {
-			statusCode = http.StatusForbidden
-		} else if strings.HasPrefix(err.Error(), "нельзя отменить заявку в статусе") {
-			statusCode = http.StatusBadRequest
-		}
-		c.JSON(statusCode, gin.H{"error": "Ошибка отмены заявки: " + err.Error()})
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка отмены заявки: " + err.Error()})
 		return
 	}
 
@@ -383,31 +442,29 @@ func (h *AppHandler) ApproveVacationRequest(c *gin.Context) {
 	force := forceStr == "true"
 
 	// Вызываем сервис для утверждения заявки с флагом force, получаем конфликты и ошибку
-	conflicts, err := h.vacationService.ApproveVacationRequest(requestID, approverID.(int), force)
+	conflicts, coverage, nextStep, err := h.vacationService.ApproveVacationRequest(requestID, approverID.(int), force)
 	if err != nil {
 		// Обработка ошибок, возникших при попытке утверждения (права, статус, ошибка БД и т.д.)
-		statusCode := http.StatusInternalServerError
-		errMsg := "Ошибка утверждения заявки: " + err.Error()
-		errStr := err.Error()
-		if strings.Contains(errStr, "не найден") {
-			statusCode = http.StatusNotFound
-		} else if strings.Contains(errStr, "не имеет прав") {
-			statusCode = http.StatusForbidden
-		} else if strings.Contains(errStr, "можно утвердить только заявку в статусе") {
-			statusCode = http.StatusBadRequest
-		}
-		c.JSON(statusCode, gin.H{"error": errMsg})
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка утверждения заявки: " + err.Error()})
 		return
 	}
 
 	// Проверяем результат:
-	if len(conflicts) > 0 && !force {
-		// Конфликты найдены, и force был false. Заявка НЕ утверждена.
-		// Возвращаем статус 409 Conflict со списком конфликтов.
+	if (len(conflicts) > 0 || len(coverage) > 0) && !force {
+		// Конфликты или нарушения укомплектованности найдены, и force был false. Заявка НЕ утверждена.
+		// Возвращаем статус 409 Conflict со списком конфликтов и дней с нарушениями.
 		log.Printf("[Handler ApproveVacationRequest] Request %d approval blocked due to conflicts (force=false).", requestID)
+		message := "Обнаружены конфликты с отпусками других сотрудников на той же должности."
+		switch {
+		case len(conflicts) == 0:
+			message = "Утверждение заявки нарушит правила укомплектованности подразделения."
+		case onlyOutOfTurn(conflicts):
+			message = "Заявка пересекается в пиковый период с заявками сотрудников, у которых очередь на пиковые даты раньше."
+		}
 		c.JSON(http.StatusConflict, gin.H{
-			"error":     "Обнаружены конфликты с отпусками других сотрудников на той же должности.",
-			"conflicts": conflicts,
+			"error":               message,
+			"conflicts":           conflicts,
+			"coverage_violations": coverage,
 		})
 		return
 	}
@@ -418,13 +475,30 @@ func (h *AppHandler) ApproveVacationRequest(c *gin.Context) {
 	// В обоих случаях возвращаем HTTP 200 OK.
 	log.Printf("[Handler ApproveVacationRequest] Request %d approved successfully (force=%t, conflicts returned: %d).", requestID, force, len(conflicts))
 	response := gin.H{"message": "Заявка успешно утверждена"}
+	if nextStep != nil {
+		// Согласован промежуточный этап цепочки, заявка остается на рассмотрении
+		response = gin.H{"message": "Этап согласования пройден, заявка передана на следующий этап", "next_step": nextStep}
+	}
 	if len(conflicts) > 0 && force {
 		// Если force был true и были конфликты, возвращаем их как предупреждение.
 		response["warnings"] = conflicts
 	}
+	if len(coverage) > 0 && force {
+		response["coverage_warnings"] = coverage
+	}
 	c.JSON(http.StatusOK, response)
 }
 
+// onlyOutOfTurn проверяет, что все конфликты - пересечения вне очереди на пиковые даты
+func onlyOutOfTurn(conflicts []models.ConflictingPeriod) bool {
+	for _, c := range conflicts {
+		if !c.OutOfTurn {
+			return false
+		}
+	}
+	return true
+}
+
 // RejectVacationRequest обработчик для отклонения заявки (менеджер/админ)
 func (h *AppHandler) RejectVacationRequest(c *gin.Context) {
 	requestIDStr := c.Param("id")
@@ -441,7 +515,7 @@ func (h *AppHandler) RejectVacationRequest(c *gin.Context) {
 		return
 	}
 
-	// Получаем причину отклонения из тела запроса (опционально)
+	// Получаем причину отклонения из тела запроса (обязательность определяется политикой)
 	var input struct {
 		Reason string `json:"reason"`
 	}
@@ -453,256 +527,1098 @@ func (h *AppHandler) RejectVacationRequest(c *gin.Context) {
 	// Вызываем сервис для отклонения заявки
 	err = h.vacationService.RejectVacationRequest(requestID, rejecterID.(int), input.Reason)
 	if err != nil {
-		statusCode := http.StatusInternalServerError
-		if err.Error() == "заявка не найдена" {
-			statusCode = http.StatusNotFound
-		} else if err.Error() == "недостаточно прав для отклонения этой заявки" {
-			statusCode = http.StatusForbidden
-		} else if strings.HasPrefix(err.Error(), "можно отклонить только заявку в статусе") {
-			statusCode = http.StatusBadRequest
-		}
-		c.JSON(statusCode, gin.H{"error": "Ошибка отклонения заявки: " + err.Error()})
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка отклонения заявки: " + err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Заявка успешно отклонена"})
 }
 
-// GetAllUsersWithLimits обработчик для получения списка пользователей с лимитами (для админа)
-func (h *AppHandler) GetAllUsersWithLimits(c *gin.Context) {
-	// Проверяем права администратора
-	isAdmin, exists := c.Get("isAdmin")
-	if !exists || !isAdmin.(bool) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Доступ запрещен. Требуются права администратора"})
+// ProposeVacationChanges обработчик для предложения других дат по заявке на рассмотрении (согласующий/админ)
+func (h *AppHandler) ProposeVacationChanges(c *gin.Context) {
+	requestID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID заявки"})
 		return
 	}
 
-	// Получаем год из query параметра
-	yearStr := c.Query("year")
-	if yearStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Параметр 'year' обязателен"})
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
 		return
 	}
-	year, err := strconv.Atoi(yearStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный формат года"})
+
+	var input models.VacationProposalCreateDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
 		return
 	}
 
-	// Вызываем сервис для получения пользователей с лимитами
-	users, err := h.userService.GetAllUsersWithLimits(year)
+	proposal, err := h.vacationService.ProposeVacationChanges(requestID, userID.(int), &input)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения пользователей: " + err.Error()})
+		c.JSON(transitionErrorStatus(err), errorBody("Ошибка предложения других дат: ", err))
 		return
 	}
 
-	c.JSON(http.StatusOK, users)
-}
-
-// AuthHandler - структура для обработчиков аутентификации (добавлено для main.go)
-type AuthHandler struct {
-	// Здесь должны быть зависимости, например, сервис аутентификации
-	authService *services.AuthService // Предполагаем, что такой сервис существует
+	c.JSON(http.StatusCreated, proposal)
 }
 
-// NewAuthHandler - конструктор для AuthHandler (добавлено для main.go)
-func NewAuthHandler(as *services.AuthService) *AuthHandler {
-	return &AuthHandler{
-		authService: as,
+// AcceptVacationProposal обработчик для принятия сотрудником предложенных дат
+func (h *AppHandler) AcceptVacationProposal(c *gin.Context) {
+	requestID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID заявки"})
+		return
 	}
-}
 
-// Login - обработчик для входа пользователя
-func (h *AuthHandler) Login(c *gin.Context) {
-	var credentials struct {
-		Login    string `json:"login" binding:"required"` // username -> login
-		Password string `json:"password" binding:"required"`
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
 	}
 
-	// Привязываем JSON из тела запроса к структуре credentials
-	if err := c.ShouldBindJSON(&credentials); err != nil {
+	var input models.VacationProposalResponseDTO
+	if err := c.ShouldBindJSON(&input); err != nil && err.Error() != "EOF" { // Комментарий необязателен, тело может быть пустым
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
 		return
 	}
 
-	// Вызываем сервис для проверки логина и пароля
-	token, user, err := h.authService.Login(credentials.Login, credentials.Password) // credentials.Username -> credentials.Login
+	updated, err := h.vacationService.AcceptVacationProposal(requestID, userID.(int), input.Comment)
 	if err != nil {
-		// Если сервис вернул ошибку (неверные данные, ошибка БД и т.д.)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		c.JSON(transitionErrorStatus(err), errorBody("Ошибка принятия предложенных дат: ", err))
 		return
 	}
 
-	// Отправляем токен и данные пользователя в ответе
-	c.JSON(http.StatusOK, gin.H{
-		"token": token,
-		"user":  user, // Убедитесь, что пароль удален в сервисе перед возвратом
-	})
+	c.JSON(http.StatusOK, updated)
 }
 
-// Register - обработчик для регистрации нового пользователя
-func (h *AuthHandler) Register(c *gin.Context) {
-	// Структура для входящих данных (PascalCase как ожидает фронтенд/валидатор)
-	// Username заменено на Login, убрана валидация email для Login
-	var input struct {
-		Login                string `json:"Login" binding:"required"` // username -> Login (PascalCase)
-		Password             string `json:"Password" binding:"required"`
-		ConfirmPassword      string `json:"ConfirmPassword" binding:"required"`
-		FullName             string `json:"FullName" binding:"required"`
-		Email                string `json:"Email" binding:"required"` // Оставляем Email, но без валидации email
-		PositionID           *int   `json:"PositionID"`               // Оставляем PositionID в PascalCase
-		OrganizationalUnitID *int   `json:"OrganizationalUnitID"`     // Добавлено поле для орг. юнита
+// DeclineVacationProposal обработчик для отклонения сотрудником предложенных дат (пояснение обязательно)
+func (h *AppHandler) DeclineVacationProposal(c *gin.Context) {
+	requestID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID заявки"})
+		return
 	}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		// Возвращаем ошибку валидации Gin, которая уже включает детали по полям
-		// Убрали предыдущую ошибку, так как ShouldBindJSON предоставляет лучшую информацию
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные", "details": err.Error()})
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
 		return
 	}
 
-	// Проверка совпадения паролей
-	if input.Password != input.ConfirmPassword {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Пароли не совпадают"})
+	var input models.VacationProposalResponseDTO
+	if err := c.ShouldBindJSON(&input); err != nil && err.Error() != "EOF" { // Пустое тело отклоняется сервисом
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
 		return
 	}
 
-	// Вызов сервиса регистрации - передаем input.Login как login и OrganizationalUnitID
-	user, err := h.authService.Register(input.Login, input.Password, input.FullName, input.PositionID, input.OrganizationalUnitID) // Удален input.Email, Добавлен input.OrganizationalUnitID
-	if err != nil {
-		// Обработка ошибок сервиса (например, пользователь уже существует)
-		c.JSON(http.StatusConflict, gin.H{"error": err.Error()}) // Используем 409 Conflict для дубликата
+	if err := h.vacationService.DeclineVacationProposal(requestID, userID.(int), input.Comment); err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка отклонения предложенных дат: " + err.Error()})
 		return
 	}
 
-	// Возвращаем созданного пользователя (без пароля)
-	c.JSON(http.StatusCreated, user)
+	c.JSON(http.StatusOK, gin.H{"message": "Предложенные даты отклонены, заявка возвращена на рассмотрение"})
 }
 
-// GetPositions обработчик для получения списка должностей (публичный)
-func (h *AppHandler) GetPositions(c *gin.Context) {
-	positions, err := h.userService.GetAllPositions() // Используем userService
+// GetVacationRequestProposals обработчик для получения предложений других дат по заявке и ответов на них
+func (h *AppHandler) GetVacationRequestProposals(c *gin.Context) {
+	requestID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения списка должностей: " + err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID заявки"})
 		return
 	}
 
-	// Раньше возвращались группы, теперь просто список
-	// Если фронтенд ожидает группы, нужно либо изменить фронтенд,
-	// либо сгруппировать здесь (менее предпочтительно).
-	// Пока возвращаем плоский список.
-	c.JSON(http.StatusOK, positions)
-}
-
-// --- Organizational Unit Handlers ---
-
-// CreateOrganizationalUnit обработчик для создания нового орг. юнита (Admin only)
-func (h *AppHandler) CreateOrganizationalUnit(c *gin.Context) {
-	var unit models.OrganizationalUnit
-	if err := c.ShouldBindJSON(&unit); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
 		return
 	}
 
-	createdUnit, err := h.unitService.CreateUnit(&unit)
+	proposals, err := h.vacationService.GetRequestProposals(userID.(int), requestID)
 	if err != nil {
-		// TODO: Различать ошибки валидации (400) и ошибки сервера (500)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания организационного юнита: " + err.Error()})
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка получения предложений по заявке: " + err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, createdUnit)
+	c.JSON(http.StatusOK, proposals)
 }
 
-// GetOrganizationalUnitTree обработчик для получения дерева орг. юнитов
-func (h *AppHandler) GetOrganizationalUnitTree(c *gin.Context) {
-	tree, err := h.unitService.GetUnitTree()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения дерева организационных юнитов: " + err.Error()})
+// BulkDecideVacationRequests обработчик для пакетного утверждения или отклонения заявок.
+// Итог возвращается по каждой заявке; ошибки отдельных заявок не прерывают обработку пакета.
+func (h *AppHandler) BulkDecideVacationRequests(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
 		return
 	}
-	c.JSON(http.StatusOK, tree)
-}
 
-// GetOrganizationalUnitByID обработчик для получения орг. юнита по ID
-func (h *AppHandler) GetOrganizationalUnitByID(c *gin.Context) {
-	unitIDStr := c.Param("id")
-	unitID, err := strconv.Atoi(unitIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID орг. юнита"})
+	var input models.BulkDecisionRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
 		return
 	}
 
-	unit, err := h.unitService.GetUnitByID(unitID)
+	result, err := h.vacationService.BulkDecideVacationRequests(userID.(int), &input)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения организационного юнита: " + err.Error()})
-		return
-	}
-	if unit == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Организационный юнит не найден"})
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка пакетного решения по заявкам: " + err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, unit)
+	c.JSON(http.StatusOK, result)
 }
 
-// UpdateOrganizationalUnit обработчик для обновления орг. юнита (Admin only)
-func (h *AppHandler) UpdateOrganizationalUnit(c *gin.Context) {
-	unitIDStr := c.Param("id")
-	unitID, err := strconv.Atoi(unitIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID орг. юнита"})
+// CreateVacationTransfer обработчик для подачи заявки на перенос периода утвержденного отпуска
+func (h *AppHandler) CreateVacationTransfer(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
 		return
 	}
 
-	var updateData models.OrganizationalUnit
-	if err := c.ShouldBindJSON(&updateData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные для обновления: " + err.Error()})
+	var input models.VacationTransferCreateDTO
+	if err := json.NewDecoder(c.Request.Body).Decode(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ошибка чтения данных: " + err.Error()})
 		return
 	}
 
-	updatedUnit, err := h.unitService.UpdateUnit(unitID, &updateData)
+	transfer, err := h.vacationService.CreateVacationTransfer(userID.(int), &input)
 	if err != nil {
-		// TODO: Различать ошибки (не найдено 404, валидация 400, сервер 500)
-		statusCode := http.StatusInternalServerError
-		if strings.Contains(err.Error(), "не найден") {
-			statusCode = http.StatusNotFound
-		} else if strings.Contains(err.Error(), "не может быть родителем") || strings.Contains(err.Error(), "не найден") { // Уточнить ошибки валидации
-			statusCode = http.StatusBadRequest
-		}
-		c.JSON(statusCode, gin.H{"error": "Ошибка обновления организационного юнита: " + err.Error()})
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка подачи заявки на перенос: " + err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, updatedUnit)
+	c.JSON(http.StatusCreated, transfer)
 }
 
-// DeleteOrganizationalUnit обработчик для удаления орг. юнита (Admin only)
-func (h *AppHandler) DeleteOrganizationalUnit(c *gin.Context) {
-	unitIDStr := c.Param("id")
-	unitID, err := strconv.Atoi(unitIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID орг. юнита"})
+// GetMyVacationTransfers обработчик для получения своих заявок на перенос
+func (h *AppHandler) GetMyVacationTransfers(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
 		return
 	}
 
-	err = h.unitService.DeleteUnit(unitID)
+	transfers, err := h.vacationService.GetMyVacationTransfers(userID.(int))
 	if err != nil {
-		// TODO: Различать ошибки (не найдено 404, конфликт 409/400, сервер 500)
-		statusCode := http.StatusInternalServerError
-		if strings.Contains(err.Error(), "не найден") {
-			statusCode = http.StatusNotFound
-		}
-		// Добавить проверку на конфликт (например, если есть дочерние) -> 409 Conflict или 400 Bad Request
-		c.JSON(statusCode, gin.H{"error": "Ошибка удаления организационного юнита: " + err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения заявок на перенос: " + err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Организационный юнит успешно удален"})
+	c.JSON(http.StatusOK, transfers)
 }
 
-// GetUnitChildrenHandler обработчик для получения дочерних элементов (юнитов и пользователей)
-func (h *AppHandler) GetUnitChildrenHandler(c *gin.Context) {
+// GetVacationTransfers обработчик для получения заявок на перенос сотрудников (руководитель/админ, ?status=)
+func (h *AppHandler) GetVacationTransfers(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+
+	transfers, err := h.vacationService.GetVacationTransfersForApproval(userID.(int), GetIntQueryParam(c, "status"))
+	if err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка получения заявок на перенос: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, transfers)
+}
+
+// ApproveVacationTransfer обработчик для утверждения переноса
+func (h *AppHandler) ApproveVacationTransfer(c *gin.Context) {
+	transferID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID переноса"})
+		return
+	}
+
+	approverID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+
+	// Необязательный параметр ?force=true подтверждает перенос несмотря на конфликты
+	force := c.Query("force") == "true"
+
+	conflicts, coverage, err := h.vacationService.ApproveVacationTransfer(transferID, approverID.(int), force)
+	if err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка утверждения переноса: " + err.Error()})
+		return
+	}
+
+	if (len(conflicts) > 0 || len(coverage) > 0) && !force {
+		// Перенос не утвержден: новые даты конфликтуют с отпусками или нарушают укомплектованность
+		message := "Новые даты конфликтуют с отпусками других сотрудников на той же должности."
+		if len(conflicts) == 0 {
+			message = "Перенос на новые даты нарушит правила укомплектованности подразделения."
+		}
+		c.JSON(http.StatusConflict, gin.H{
+			"error":               message,
+			"conflicts":           conflicts,
+			"coverage_violations": coverage,
+		})
+		return
+	}
+
+	response := gin.H{"message": "Перенос отпуска утвержден"}
+	if len(conflicts) > 0 {
+		response["warnings"] = conflicts
+	}
+	if len(coverage) > 0 {
+		response["coverage_warnings"] = coverage
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RejectVacationTransfer обработчик для отклонения переноса
+func (h *AppHandler) RejectVacationTransfer(c *gin.Context) {
+	transferID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID переноса"})
+		return
+	}
+
+	rejecterID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil && err.Error() != "EOF" { // Игнорируем EOF, если тело пустое
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный формат причины отклонения: " + err.Error()})
+		return
+	}
+
+	if err := h.vacationService.RejectVacationTransfer(transferID, rejecterID.(int), input.Reason); err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка отклонения переноса: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Перенос отпуска отклонен"})
+}
+
+// CancelVacationTransfer обработчик для отмены своего переноса на рассмотрении
+func (h *AppHandler) CancelVacationTransfer(c *gin.Context) {
+	transferID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID переноса"})
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+
+	if err := h.vacationService.CancelVacationTransfer(transferID, userID.(int)); err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка отмены переноса: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Перенос отпуска отменен"})
+}
+
+// RecallFromVacation обработчик для отзыва сотрудника из отпуска по периоду
+func (h *AppHandler) RecallFromVacation(c *gin.Context) {
+	periodID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID периода"})
+		return
+	}
+
+	actorID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+
+	var input models.VacationRecallDTO
+	if err := json.NewDecoder(c.Request.Body).Decode(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ошибка чтения данных: " + err.Error()})
+		return
+	}
+
+	recall, err := h.vacationService.RecallFromVacation(periodID, actorID.(int), &input)
+	if err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка отзыва из отпуска: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, recall)
+}
+
+// RolloverVacationYear обработчик для перехода года: перенос остатков отпусков на следующий год (Admin only)
+func (h *AppHandler) RolloverVacationYear(c *gin.Context) {
+	var input struct {
+		Year int `json:"year" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
+		return
+	}
+
+	adminID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+	actorID := adminID.(int)
+
+	result, err := h.vacationService.RolloverYear(input.Year, &actorID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка перехода года: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetVacationRequestHistory обработчик для получения истории переходов заявки
+func (h *AppHandler) GetVacationRequestHistory(c *gin.Context) {
+	requestID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID заявки"})
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+
+	history, err := h.vacationService.GetRequestHistory(userID.(int), requestID)
+	if err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка получения истории заявки: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// GetLeaveTypes обработчик для получения справочника видов отпуска
+func (h *AppHandler) GetLeaveTypes(c *gin.Context) {
+	leaveTypes, err := h.vacationService.GetLeaveTypes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения видов отпуска: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, leaveTypes)
+}
+
+// CreateLeaveType обработчик для добавления вида отпуска (только админ)
+func (h *AppHandler) CreateLeaveType(c *gin.Context) {
+	var leaveType models.LeaveType
+	if err := c.ShouldBindJSON(&leaveType); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
+		return
+	}
+
+	if err := h.vacationService.CreateLeaveType(&leaveType); err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка создания вида отпуска: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, leaveType)
+}
+
+// UpdateLeaveType обработчик для изменения вида отпуска (только админ)
+func (h *AppHandler) UpdateLeaveType(c *gin.Context) {
+	leaveTypeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID вида отпуска"})
+		return
+	}
+
+	var leaveType models.LeaveType
+	if err := c.ShouldBindJSON(&leaveType); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
+		return
+	}
+	leaveType.ID = leaveTypeID
+
+	if err := h.vacationService.UpdateLeaveType(&leaveType); err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка изменения вида отпуска: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, leaveType)
+}
+
+// GetVacationPolicies обработчик для получения всех политик отпуска (только админ)
+func (h *AppHandler) GetVacationPolicies(c *gin.Context) {
+	policies, err := h.vacationService.GetVacationPolicies()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения политик отпуска: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// CreateVacationPolicy обработчик для создания политики отпуска (только админ)
+func (h *AppHandler) CreateVacationPolicy(c *gin.Context) {
+	var policy models.VacationPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
+		return
+	}
+
+	if err := h.vacationService.CreateVacationPolicy(&policy); err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка создания политики отпуска: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, policy)
+}
+
+// UpdateVacationPolicy обработчик для изменения политики отпуска (только админ)
+func (h *AppHandler) UpdateVacationPolicy(c *gin.Context) {
+	policyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID политики"})
+		return
+	}
+
+	var policy models.VacationPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
+		return
+	}
+	policy.ID = policyID
+
+	if err := h.vacationService.UpdateVacationPolicy(&policy); err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка изменения политики отпуска: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeleteVacationPolicy обработчик для удаления политики отпуска (только админ)
+func (h *AppHandler) DeleteVacationPolicy(c *gin.Context) {
+	policyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID политики"})
+		return
+	}
+
+	if err := h.vacationService.DeleteVacationPolicy(policyID); err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка удаления политики отпуска: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Политика отпуска удалена"})
+}
+
+// GetEffectiveVacationPolicy обработчик для получения итоговых правил отпуска сотрудника (только админ)
+func (h *AppHandler) GetEffectiveVacationPolicy(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID пользователя"})
+		return
+	}
+
+	policy, err := h.vacationService.ResolveVacationPolicy(userID)
+	if err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка получения политики отпуска: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// GetUnitBlackouts обработчик для получения периодов запрета отпусков юнита (включая унаследованные) для календаря
+func (h *AppHandler) GetUnitBlackouts(c *gin.Context) {
+	unitID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID орг. юнита"})
+		return
+	}
+
+	blackouts, err := h.vacationService.GetUnitBlackouts(unitID)
+	if err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка получения периодов запрета отпусков: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, blackouts)
+}
+
+// CreateUnitBlackout обработчик для создания периода запрета отпусков юнита (админ или руководитель юнита)
+func (h *AppHandler) CreateUnitBlackout(c *gin.Context) {
+	unitID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID орг. юнита"})
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+
+	var blackout models.UnitBlackout
+	if err := c.ShouldBindJSON(&blackout); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
+		return
+	}
+	blackout.UnitID = unitID
+
+	if err := h.vacationService.CreateUnitBlackout(userID.(int), &blackout); err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка создания периода запрета отпусков: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, blackout)
+}
+
+// UpdateUnitBlackout обработчик для изменения периода запрета отпусков юнита (админ или руководитель юнита)
+func (h *AppHandler) UpdateUnitBlackout(c *gin.Context) {
+	unitID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID орг. юнита"})
+		return
+	}
+	blackoutID, err := strconv.Atoi(c.Param("blackoutId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID периода запрета"})
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+
+	var blackout models.UnitBlackout
+	if err := c.ShouldBindJSON(&blackout); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
+		return
+	}
+	blackout.ID = blackoutID
+	blackout.UnitID = unitID
+
+	if err := h.vacationService.UpdateUnitBlackout(userID.(int), &blackout); err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка изменения периода запрета отпусков: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, blackout)
+}
+
+// DeleteUnitBlackout обработчик для удаления периода запрета отпусков юнита (админ или руководитель юнита)
+func (h *AppHandler) DeleteUnitBlackout(c *gin.Context) {
+	unitID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID орг. юнита"})
+		return
+	}
+	blackoutID, err := strconv.Atoi(c.Param("blackoutId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID периода запрета"})
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+
+	if err := h.vacationService.DeleteUnitBlackout(userID.(int), unitID, blackoutID); err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка удаления периода запрета отпусков: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Период запрета отпусков удален"})
+}
+
+// GetCoverageRules обработчик для получения всех правил укомплектованности (только админ)
+func (h *AppHandler) GetCoverageRules(c *gin.Context) {
+	rules, err := h.vacationService.GetCoverageRules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения правил укомплектованности: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// CreateCoverageRule обработчик для создания правила укомплектованности (только админ)
+func (h *AppHandler) CreateCoverageRule(c *gin.Context) {
+	var rule models.CoverageRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
+		return
+	}
+
+	if err := h.vacationService.CreateCoverageRule(&rule); err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка создания правила укомплектованности: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateCoverageRule обработчик для изменения правила укомплектованности (только админ)
+func (h *AppHandler) UpdateCoverageRule(c *gin.Context) {
+	ruleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID правила"})
+		return
+	}
+
+	var rule models.CoverageRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
+		return
+	}
+	rule.ID = ruleID
+
+	if err := h.vacationService.UpdateCoverageRule(&rule); err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка изменения правила укомплектованности: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteCoverageRule обработчик для удаления правила укомплектованности (только админ)
+func (h *AppHandler) DeleteCoverageRule(c *gin.Context) {
+	ruleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID правила"})
+		return
+	}
+
+	if err := h.vacationService.DeleteCoverageRule(ruleID); err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка удаления правила укомплектованности: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Правило укомплектованности удалено"})
+}
+
+// GetPeakWindows обработчик для получения пиковых периодов отпусков (только админ)
+func (h *AppHandler) GetPeakWindows(c *gin.Context) {
+	windows, err := h.vacationService.GetPeakWindows()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения пиковых периодов: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, windows)
+}
+
+// CreatePeakWindow обработчик для создания пикового периода отпусков (только админ)
+func (h *AppHandler) CreatePeakWindow(c *gin.Context) {
+	var window models.PeakWindow
+	if err := c.ShouldBindJSON(&window); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
+		return
+	}
+
+	adminID, _ := c.Get("userID")
+	if err := h.vacationService.CreatePeakWindow(adminID.(int), &window); err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка создания пикового периода: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, window)
+}
+
+// UpdatePeakWindow обработчик для изменения пикового периода отпусков (только админ)
+func (h *AppHandler) UpdatePeakWindow(c *gin.Context) {
+	windowID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID пикового периода"})
+		return
+	}
+
+	var window models.PeakWindow
+	if err := c.ShouldBindJSON(&window); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
+		return
+	}
+	window.ID = windowID
+
+	if err := h.vacationService.UpdatePeakWindow(&window); err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка изменения пикового периода: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, window)
+}
+
+// DeletePeakWindow обработчик для удаления пикового периода отпусков (только админ)
+func (h *AppHandler) DeletePeakWindow(c *gin.Context) {
+	windowID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID пикового периода"})
+		return
+	}
+
+	if err := h.vacationService.DeletePeakWindow(windowID); err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка удаления пикового периода: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Пиковый период удален"})
+}
+
+// GetPeakSlotReport обработчик для отчета о распределении пиковых периодов по юнитам поддерева
+// (?unitId= - обязательный, ?years= - за сколько последних лет)
+func (h *AppHandler) GetPeakSlotReport(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+	unitID := GetIntQueryParam(c, "unitId")
+	if unitID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Необходимо указать юнит (unitId)"})
+		return
+	}
+	years := 0
+	if yearsParam := GetIntQueryParam(c, "years"); yearsParam != nil {
+		years = *yearsParam
+	}
+
+	report, err := h.vacationService.GetPeakSlotReport(userID.(int), *unitID, years)
+	if err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка построения отчета по пиковым периодам: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetApprovalDelegations обработчик для получения делегирований пользователя (администратору - всех)
+func (h *AppHandler) GetApprovalDelegations(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+
+	delegations, err := h.vacationService.GetApprovalDelegations(userID.(int))
+	if err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка получения делегирований: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, delegations)
+}
+
+// CreateApprovalDelegation обработчик для передачи прав руководителя заместителю на период
+func (h *AppHandler) CreateApprovalDelegation(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+
+	var input models.ApprovalDelegationCreateDTO
+	if err := json.NewDecoder(c.Request.Body).Decode(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ошибка чтения данных: " + err.Error()})
+		return
+	}
+
+	delegation, err := h.vacationService.CreateApprovalDelegation(userID.(int), &input)
+	if err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка создания делегирования: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, delegation)
+}
+
+// RevokeApprovalDelegation обработчик для отзыва делегирования
+func (h *AppHandler) RevokeApprovalDelegation(c *gin.Context) {
+	delegationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID делегирования"})
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+
+	if err := h.vacationService.RevokeApprovalDelegation(delegationID, userID.(int)); err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка отзыва делегирования: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Делегирование отозвано"})
+}
+
+// errorBody формирует тело ответа с ошибкой; для нарушений правил отпуска добавляет их полный список
+func errorBody(message string, err error) gin.H {
+	body := gin.H{"error": message + err.Error()}
+	var violations *services.PolicyViolationError
+	if errors.As(err, &violations) {
+		body["violations"] = violations.Violations
+	}
+	return body
+}
+
+// transitionErrorStatus определяет HTTP-статус для ошибки действия с заявкой
+func transitionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrTransitionForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrTransitionNotAllowed), errors.Is(err, services.ErrInvalidRequest):
+		return http.StatusBadRequest
+	case strings.Contains(err.Error(), "не найден"):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GetAllUsersWithLimits обработчик для получения списка пользователей с лимитами (для админа)
+func (h *AppHandler) GetAllUsersWithLimits(c *gin.Context) {
+	// Проверяем права администратора
+	isAdmin, exists := c.Get("isAdmin")
+	if !exists || !isAdmin.(bool) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Доступ запрещен. Требуются права администратора"})
+		return
+	}
+
+	// Получаем год из query параметра
+	yearStr := c.Query("year")
+	if yearStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Параметр 'year' обязателен"})
+		return
+	}
+	year, err := strconv.Atoi(yearStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный формат года"})
+		return
+	}
+
+	// Вызываем сервис для получения пользователей с лимитами
+	users, err := h.userService.GetAllUsersWithLimits(year)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения пользователей: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// AuthHandler - структура для обработчиков аутентификации (добавлено для main.go)
+type AuthHandler struct {
+	// Здесь должны быть зависимости, например, сервис аутентификации
+	authService *services.AuthService // Предполагаем, что такой сервис существует
+}
+
+// NewAuthHandler - конструктор для AuthHandler (добавлено для main.go)
+func NewAuthHandler(as *services.AuthService) *AuthHandler {
+	return &AuthHandler{
+		authService: as,
+	}
+}
+
+// Login - обработчик для входа пользователя
+func (h *AuthHandler) Login(c *gin.Context) {
+	var credentials struct {
+		Login    string `json:"login" binding:"required"` // username -> login
+		Password string `json:"password" binding:"required"`
+	}
+
+	// Привязываем JSON из тела запроса к структуре credentials
+	if err := c.ShouldBindJSON(&credentials); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
+		return
+	}
+
+	// Вызываем сервис для проверки логина и пароля
+	token, user, err := h.authService.Login(credentials.Login, credentials.Password) // credentials.Username -> credentials.Login
+	if err != nil {
+		// Если сервис вернул ошибку (неверные данные, ошибка БД и т.д.)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Отправляем токен и данные пользователя в ответе
+	c.JSON(http.StatusOK, gin.H{
+		"token": token,
+		"user":  user, // Убедитесь, что пароль удален в сервисе перед возвратом
+	})
+}
+
+// Register - обработчик для регистрации нового пользователя
+func (h *AuthHandler) Register(c *gin.Context) {
+	// Структура для входящих данных (PascalCase как ожидает фронтенд/валидатор)
+	// Username заменено на Login, убрана валидация email для Login
+	var input struct {
+		Login                string `json:"Login" binding:"required"` // username -> Login (PascalCase)
+		Password             string `json:"Password" binding:"required"`
+		ConfirmPassword      string `json:"ConfirmPassword" binding:"required"`
+		FullName             string `json:"FullName" binding:"required"`
+		Email                string `json:"Email" binding:"required"` // Оставляем Email, но без валидации email
+		PositionID           *int   `json:"PositionID"`               // Оставляем PositionID в PascalCase
+		OrganizationalUnitID *int   `json:"OrganizationalUnitID"`     // Добавлено поле для орг. юнита
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		// Возвращаем ошибку валидации Gin, которая уже включает детали по полям
+		// Убрали предыдущую ошибку, так как ShouldBindJSON предоставляет лучшую информацию
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные", "details": err.Error()})
+		return
+	}
+
+	// Проверка совпадения паролей
+	if input.Password != input.ConfirmPassword {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Пароли не совпадают"})
+		return
+	}
+
+	// Вызов сервиса регистрации - передаем input.Login как login и OrganizationalUnitID
+	user, err := h.authService.Register(input.Login, input.Password, input.FullName, input.PositionID, input.OrganizationalUnitID) // Удален input.Email, Добавлен input.OrganizationalUnitID
+	if err != nil {
+		// Обработка ошибок сервиса (например, пользователь уже существует)
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()}) // Используем 409 Conflict для дубликата
+		return
+	}
+
+	// Возвращаем созданного пользователя (без пароля)
+	c.JSON(http.StatusCreated, user)
+}
+
+// GetPositions обработчик для получения списка должностей (публичный)
+func (h *AppHandler) GetPositions(c *gin.Context) {
+	positions, err := h.userService.GetAllPositions() // Используем userService
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения списка должностей: " + err.Error()})
+		return
+	}
+
+	// Раньше возвращались группы, теперь просто список
+	// Если фронтенд ожидает группы, нужно либо изменить фронтенд,
+	// либо сгруппировать здесь (менее предпочтительно).
+	// Пока возвращаем плоский список.
+	c.JSON(http.StatusOK, positions)
+}
+
+// --- Organizational Unit Handlers ---
+
+// CreateOrganizationalUnit обработчик для создания нового орг. юнита (Admin only)
+func (h *AppHandler) CreateOrganizationalUnit(c *gin.Context) {
+	var unit models.OrganizationalUnit
+	if err := c.ShouldBindJSON(&unit); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
+		return
+	}
+
+	createdUnit, err := h.unitService.CreateUnit(&unit)
+	if err != nil {
+		// TODO: Различать ошибки валидации (400) и ошибки сервера (500)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания организационного юнита: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, createdUnit)
+}
+
+// GetOrganizationalUnitTree обработчик для получения дерева орг. юнитов
+func (h *AppHandler) GetOrganizationalUnitTree(c *gin.Context) {
+	tree, err := h.unitService.GetUnitTree()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения дерева организационных юнитов: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tree)
+}
+
+// GetOrganizationalUnitByID обработчик для получения орг. юнита по ID
+func (h *AppHandler) GetOrganizationalUnitByID(c *gin.Context) {
+	unitIDStr := c.Param("id")
+	unitID, err := strconv.Atoi(unitIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID орг. юнита"})
+		return
+	}
+
+	unit, err := h.unitService.GetUnitByID(unitID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения организационного юнита: " + err.Error()})
+		return
+	}
+	if unit == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Организационный юнит не найден"})
+		return
+	}
+
+	c.JSON(http.StatusOK, unit)
+}
+
+// UpdateOrganizationalUnit обработчик для обновления орг. юнита (Admin only)
+func (h *AppHandler) UpdateOrganizationalUnit(c *gin.Context) {
+	unitIDStr := c.Param("id")
+	unitID, err := strconv.Atoi(unitIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID орг. юнита"})
+		return
+	}
+
+	var updateData models.OrganizationalUnit
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные для обновления: " + err.Error()})
+		return
+	}
+
+	updatedUnit, err := h.unitService.UpdateUnit(unitID, &updateData)
+	if err != nil {
+		// TODO: Различать ошибки (не найдено 404, валидация 400, сервер 500)
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "не найден") {
+			statusCode = http.StatusNotFound
+		} else if strings.Contains(err.Error(), "не может быть родителем") || strings.Contains(err.Error(), "не найден") { // Уточнить ошибки валидации
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, gin.H{"error": "Ошибка обновления организационного юнита: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, updatedUnit)
+}
+
+// DeleteOrganizationalUnit обработчик для удаления орг. юнита (Admin only)
+func (h *AppHandler) DeleteOrganizationalUnit(c *gin.Context) {
+	unitIDStr := c.Param("id")
+	unitID, err := strconv.Atoi(unitIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID орг. юнита"})
+		return
+	}
+
+	err = h.unitService.DeleteUnit(unitID)
+	if err != nil {
+		// TODO: Различать ошибки (не найдено 404, конфликт 409/400, сервер 500)
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "не найден") {
+			statusCode = http.StatusNotFound
+		}
+		// Добавить проверку на конфликт (например, если есть дочерние) -> 409 Conflict или 400 Bad Request
+		c.JSON(statusCode, gin.H{"error": "Ошибка удаления организационного юнита: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Организационный юнит успешно удален"})
+}
+
+// GetUnitChildrenHandler обработчик для получения дочерних элементов (юнитов и пользователей)
+func (h *AppHandler) GetUnitChildrenHandler(c *gin.Context) {
 	parentIDStr := c.Query("parentId") // Получаем parentId из query-параметра
 
 	var parentID *int
@@ -833,97 +1749,233 @@ func (h *AppHandler) GetUnitUsersWithLimitsHandler(c *gin.Context) {
 		return
 	}
 
-	// Получаем год из query параметра
-	yearStr := c.Query("year")
-	if yearStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Параметр 'year' обязателен"})
+	// Получаем год из query параметра
+	yearStr := c.Query("year")
+	if yearStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Параметр 'year' обязателен"})
+		return
+	}
+	year, err := strconv.Atoi(yearStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный формат года"})
+		return
+	}
+
+	// Вызываем сервис для получения пользователей с лимитами
+	usersWithLimits, err := h.unitService.GetUnitUsersWithLimits(unitID, year)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "не найден") { // Проверяем, если юнит не найден
+			statusCode = http.StatusNotFound
+		}
+		c.JSON(statusCode, gin.H{"error": "Ошибка получения пользователей юнита с лимитами: " + err.Error()})
+		return
+	}
+
+	// Если лимиты не найдены для некоторых пользователей, поле TotalDays будет nil.
+	// Здесь можно установить значение по умолчанию, если это требуется для фронтенда.
+	// Например:
+	// for i := range usersWithLimits {
+	// 	if usersWithLimits[i].TotalDays == nil {
+	// 		defaultValue := 28 // Значение по умолчанию
+	// 		usersWithLimits[i].TotalDays = &defaultValue
+	// 	}
+	// }
+
+	c.JSON(http.StatusOK, usersWithLimits)
+}
+
+// UpdateUserVacationLimitHandler обработчик для обновления лимита отпуска пользователя (Admin only)
+func (h *AppHandler) UpdateUserVacationLimitHandler(c *gin.Context) {
+	// Проверяем права администратора
+	isAdmin, exists := c.Get("isAdmin")
+	if !exists || !isAdmin.(bool) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Доступ запрещен. Требуются права администратора"})
+		return
+	}
+
+	// Получаем ID пользователя из URL (исправлено с userId на id)
+	userIDStr := c.Param("id")
+	userID, err := strconv.Atoi(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID пользователя в URL"}) // Уточнено сообщение
+		return
+	}
+
+	// Структура для данных из тела запроса
+	var input struct {
+		Year      int `json:"year" binding:"required"`
+		TotalDays int `json:"total_days"` // Не используем binding:"required", чтобы позволить 0
+	}
+
+	// Привязываем JSON из тела запроса
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
+		return
+	}
+
+	// Проверяем total_days на отрицательное значение (0 разрешен)
+	if input.TotalDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Количество дней отпуска не может быть отрицательным"})
+		return
+	}
+
+	// Проверяем, существует ли пользователь, для которого устанавливается лимит
+	targetUser, errUser := h.userService.FindByID(userID)
+	if errUser != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки пользователя: " + errUser.Error()})
+		return
+	}
+	if targetUser == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь с указанным ID не найден"})
+		return
+	}
+
+	adminID, _ := c.Get("userID")
+
+	// Вызываем сервис VacationService для установки (создания/обновления) лимита
+	err = h.vacationService.SetVacationLimit(userID, input.Year, input.TotalDays, adminID.(int))
+	if err != nil {
+		// Обрабатываем возможные ошибки сервиса (ошибка БД и т.д.)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка установки лимита отпуска: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Лимит отпуска пользователя успешно обновлен"})
+}
+
+// GetLeaveLedger обработчик для получения журнала движения дней отпуска за год.
+// По умолчанию возвращает журнал текущего пользователя, параметр userId позволяет руководителю или админу
+// просмотреть журнал сотрудника.
+func (h *AppHandler) GetLeaveLedger(c *gin.Context) {
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный формат года"})
+		return
+	}
+
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+	requestingUserID := userIDVal.(int)
+
+	targetUserID := requestingUserID
+	if userIDFilter := GetIntQueryParam(c, "userId"); userIDFilter != nil {
+		targetUserID = *userIDFilter
+	}
+
+	ledger, err := h.vacationService.GetLeaveLedger(requestingUserID, targetUserID, year)
+	if err != nil {
+		if strings.Contains(err.Error(), "нет прав") {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		} else if strings.Contains(err.Error(), "не найден") {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения журнала отпусков: " + err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, ledger)
+}
+
+// CreateLedgerAdjustment обработчик для ручной корректировки баланса отпуска сотрудника (Admin only)
+func (h *AppHandler) CreateLedgerAdjustment(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID пользователя в URL"})
+		return
+	}
+
+	var input struct {
+		Year    int    `json:"year" binding:"required"`
+		Days    int    `json:"days" binding:"required"`
+		Comment string `json:"comment" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
+		return
+	}
+
+	targetUser, errUser := h.userService.FindByID(userID)
+	if errUser != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки пользователя: " + errUser.Error()})
 		return
 	}
-	year, err := strconv.Atoi(yearStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный формат года"})
+	if targetUser == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь с указанным ID не найден"})
 		return
 	}
 
-	// Вызываем сервис для получения пользователей с лимитами
-	usersWithLimits, err := h.unitService.GetUnitUsersWithLimits(unitID, year)
-	if err != nil {
-		statusCode := http.StatusInternalServerError
-		if strings.Contains(err.Error(), "не найден") { // Проверяем, если юнит не найден
-			statusCode = http.StatusNotFound
-		}
-		c.JSON(statusCode, gin.H{"error": "Ошибка получения пользователей юнита с лимитами: " + err.Error()})
+	adminID, _ := c.Get("userID")
+	if err := h.vacationService.AdjustVacationBalance(userID, input.Year, input.Days, adminID.(int), input.Comment); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ошибка корректировки баланса: " + err.Error()})
 		return
 	}
 
-	// Если лимиты не найдены для некоторых пользователей, поле TotalDays будет nil.
-	// Здесь можно установить значение по умолчанию, если это требуется для фронтенда.
-	// Например:
-	// for i := range usersWithLimits {
-	// 	if usersWithLimits[i].TotalDays == nil {
-	// 		defaultValue := 28 // Значение по умолчанию
-	// 		usersWithLimits[i].TotalDays = &defaultValue
-	// 	}
-	// }
-
-	c.JSON(http.StatusOK, usersWithLimits)
+	c.JSON(http.StatusCreated, gin.H{"message": "Корректировка баланса отпуска добавлена"})
 }
 
-// UpdateUserVacationLimitHandler обработчик для обновления лимита отпуска пользователя (Admin only)
-func (h *AppHandler) UpdateUserVacationLimitHandler(c *gin.Context) {
-	// Проверяем права администратора
-	isAdmin, exists := c.Get("isAdmin")
-	if !exists || !isAdmin.(bool) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Доступ запрещен. Требуются права администратора"})
+// GetUserPriorityCategories возвращает категории преимущественного права сотрудника (Admin only)
+func (h *AppHandler) GetUserPriorityCategories(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID пользователя в URL"})
 		return
 	}
 
-	// Получаем ID пользователя из URL (исправлено с userId на id)
-	userIDStr := c.Param("id")
-	userID, err := strconv.Atoi(userIDStr)
+	categories, err := h.userService.GetUserPriorityCategories(userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID пользователя в URL"}) // Уточнено сообщение
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения категорий преимущественного права: " + err.Error()})
 		return
 	}
+	c.JSON(http.StatusOK, categories)
+}
 
-	// Структура для данных из тела запроса
-	var input struct {
-		Year      int `json:"year" binding:"required"`
-		TotalDays int `json:"total_days"` // Не используем binding:"required", чтобы позволить 0
+// AddUserPriorityCategory добавляет сотруднику категорию преимущественного права (Admin only)
+func (h *AppHandler) AddUserPriorityCategory(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID пользователя в URL"})
+		return
 	}
 
-	// Привязываем JSON из тела запроса
-	if err := c.ShouldBindJSON(&input); err != nil {
+	var category models.UserPriorityCategory
+	if err := c.ShouldBindJSON(&category); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
 		return
 	}
+	category.ID = 0
+	category.UserID = userID
 
-	// Проверяем total_days на отрицательное значение (0 разрешен)
-	if input.TotalDays < 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Количество дней отпуска не может быть отрицательным"})
+	adminID, _ := c.Get("userID")
+	if err := h.userService.AddUserPriorityCategory(&models.User{ID: adminID.(int), IsAdmin: true}, &category); err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка добавления категории преимущественного права: " + err.Error()})
 		return
 	}
+	c.JSON(http.StatusCreated, category)
+}
 
-	// Проверяем, существует ли пользователь, для которого устанавливается лимит
-	targetUser, errUser := h.userService.FindByID(userID)
-	if errUser != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки пользователя: " + errUser.Error()})
+// DeleteUserPriorityCategory удаляет категорию преимущественного права сотрудника (Admin only)
+func (h *AppHandler) DeleteUserPriorityCategory(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID пользователя в URL"})
 		return
 	}
-	if targetUser == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь с указанным ID не найден"})
+	categoryID, err := strconv.Atoi(c.Param("categoryId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID категории в URL"})
 		return
 	}
 
-	// Вызываем сервис VacationService для установки (создания/обновления) лимита
-	err = h.vacationService.SetVacationLimit(userID, input.Year, input.TotalDays)
-	if err != nil {
-		// Обрабатываем возможные ошибки сервиса (ошибка БД и т.д.)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка установки лимита отпуска: " + err.Error()})
+	if err := h.userService.DeleteUserPriorityCategory(userID, categoryID); err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка удаления категории преимущественного права: " + err.Error()})
 		return
 	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Лимит отпуска пользователя успешно обновлен"})
+	c.JSON(http.StatusOK, gin.H{"message": "Категория преимущественного права удалена"})
 }
 
 // --- Dashboard Handler ---
@@ -1185,3 +2237,274 @@ func (h *AppHandler) ExportVacationsByUnits(c *gin.Context) {
 	// Например, массив объектов, где каждый объект - строка в таблице Т-7
 	c.JSON(http.StatusOK, exportData)
 }
+
+// GenerateVacationSchedule обработчик для составления предложения графика отпусков юнита на год (без сохранения)
+func (h *AppHandler) GenerateVacationSchedule(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+
+	var input models.ScheduleGenerationRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
+		return
+	}
+
+	proposal, err := h.vacationService.GenerateVacationSchedule(userID.(int), &input)
+	if err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка составления графика отпусков: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, proposal)
+}
+
+// PublishVacationSchedule обработчик для публикации графика: создает черновики заявок сотрудников
+func (h *AppHandler) PublishVacationSchedule(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+
+	var input models.SchedulePublishRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
+		return
+	}
+
+	result, err := h.vacationService.PublishVacationSchedule(userID.(int), &input)
+	if err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка публикации графика отпусков: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetPlanningCampaigns обработчик для получения кампаний планирования графика отпусков (?year=)
+func (h *AppHandler) GetPlanningCampaigns(c *gin.Context) {
+	campaigns, err := h.vacationService.GetPlanningCampaigns(GetIntQueryParam(c, "year"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения кампаний планирования: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, campaigns)
+}
+
+// GetPlanningCampaignProgress обработчик для получения хода кампании: кто подал заявки на год, а кто нет
+func (h *AppHandler) GetPlanningCampaignProgress(c *gin.Context) {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID кампании"})
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+
+	progress, err := h.vacationService.GetPlanningCampaignProgress(userID.(int), campaignID)
+	if err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка получения хода кампании планирования: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, progress)
+}
+
+// CreatePlanningCampaign обработчик для открытия кампании планирования на год (только админ)
+func (h *AppHandler) CreatePlanningCampaign(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+
+	var campaign models.PlanningCampaign
+	if err := c.ShouldBindJSON(&campaign); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
+		return
+	}
+
+	if err := h.vacationService.CreatePlanningCampaign(userID.(int), &campaign); err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка создания кампании планирования: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, campaign)
+}
+
+// UpdatePlanningCampaign обработчик для изменения сроков этапов кампании планирования (только админ)
+func (h *AppHandler) UpdatePlanningCampaign(c *gin.Context) {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID кампании"})
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+
+	var campaign models.PlanningCampaign
+	if err := c.ShouldBindJSON(&campaign); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
+		return
+	}
+	campaign.ID = campaignID
+
+	if err := h.vacationService.UpdatePlanningCampaignDeadlines(userID.(int), &campaign); err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка изменения кампании планирования: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+// AdvancePlanningCampaign обработчик для перевода кампании планирования на следующий этап (только админ)
+func (h *AppHandler) AdvancePlanningCampaign(c *gin.Context) {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID кампании"})
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+
+	campaign, err := h.vacationService.AdvancePlanningCampaign(userID.(int), campaignID)
+	if err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка перевода кампании планирования на следующий этап: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+// GetMyVacationPreference обработчик для получения своего пожелания к графику отпусков на год
+func (h *AppHandler) GetMyVacationPreference(c *gin.Context) {
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный формат года"})
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+
+	preference, err := h.vacationService.GetMyVacationPreference(userID.(int), year)
+	if err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка получения пожелания: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, preference)
+}
+
+// SaveMyVacationPreference обработчик для сохранения своего пожелания на год (варианты по приоритету и ограничения)
+func (h *AppHandler) SaveMyVacationPreference(c *gin.Context) {
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный формат года"})
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+
+	var preference models.VacationPreference
+	if err := json.NewDecoder(c.Request.Body).Decode(&preference); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ошибка чтения данных: " + err.Error()})
+		return
+	}
+	preference.Year = year
+
+	if err := h.vacationService.SaveVacationPreference(userID.(int), &preference); err != nil {
+		c.JSON(transitionErrorStatus(err), errorBody("Ошибка сохранения пожелания: ", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, preference)
+}
+
+// DeleteMyVacationPreference обработчик для удаления своего пожелания на год
+func (h *AppHandler) DeleteMyVacationPreference(c *gin.Context) {
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный формат года"})
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+
+	if err := h.vacationService.DeleteVacationPreference(userID.(int), year); err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка удаления пожелания: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Пожелание удалено"})
+}
+
+// GetSubtreeVacationPreferences обработчик для сравнения пожеланий сотрудников поддерева (?year=, ?unitId=)
+func (h *AppHandler) GetSubtreeVacationPreferences(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+	year := time.Now().Year() + 1 // По умолчанию - планируемый год
+	if yearFilter := GetIntQueryParam(c, "year"); yearFilter != nil {
+		year = *yearFilter
+	}
+
+	preferences, err := h.vacationService.GetSubtreeVacationPreferences(userID.(int), year, GetIntQueryParam(c, "unitId"))
+	if err != nil {
+		c.JSON(transitionErrorStatus(err), gin.H{"error": "Ошибка получения пожеланий сотрудников: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, preferences)
+}
+
+// ConvertVacationPreference обработчик для создания заявки по выбранному варианту пожелания
+func (h *AppHandler) ConvertVacationPreference(c *gin.Context) {
+	preferenceID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID пожелания"})
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+
+	var input models.PreferenceConvertDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные: " + err.Error()})
+		return
+	}
+
+	request, err := h.vacationService.ConvertVacationPreference(userID.(int), preferenceID, input.AlternativeID)
+	if err != nil {
+		c.JSON(transitionErrorStatus(err), errorBody("Ошибка создания заявки по пожеланию: ", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, request)
+}
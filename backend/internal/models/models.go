@@ -10,13 +10,51 @@ import (
 )
 
 // --- Vacation Status Constants ---
-// StatusDraft (ID=1) был удален. Остальные статусы НЕ сдвигаются.
+// Допустимые переходы между статусами описаны в машине состояний (services/vacation_state_machine.go).
 const (
-	// StatusDraft     = 1 // Черновик - УДАЛЕНО
-	StatusPending   = 2 // На рассмотрении
-	StatusApproved  = 3 // Утверждена
-	StatusRejected  = 4 // Отклонена
-	StatusCancelled = 5 // Отменена
+	StatusDraft      = 1 // Черновик
+	StatusPending    = 2 // На рассмотрении
+	StatusApproved   = 3 // Утверждена
+	StatusRejected   = 4 // Отклонена
+	StatusCancelled  = 5 // Отменена
+	StatusInProgress = 6 // Сотрудник в отпуске
+	StatusCompleted  = 7 // Отпуск завершен
+	StatusRecalled   = 8 // Сотрудник отозван из отпуска
+	// Руководитель предложил другие даты; заявка ждет ответа сотрудника, дни остаются в резерве
+	StatusChangesProposed = 9
+)
+
+// ApprovedStatuses - статусы утвержденного отпуска: до начала, во время и после него (в том числе прерванного отзывом)
+var ApprovedStatuses = []int{StatusApproved, StatusInProgress, StatusCompleted, StatusRecalled}
+
+// IsApprovedStatus проверяет, относится ли статус к утвержденному отпуску
+func IsApprovedStatus(statusID int) bool {
+	for _, id := range ApprovedStatuses {
+		if id == statusID {
+			return true
+		}
+	}
+	return false
+}
+
+// --- Vacation Request Actions ---
+// Действия, переводящие заявку из одного статуса в другой.
+const (
+	ActionCreate      = "CREATE"       // Создание заявки (черновик или сразу на рассмотрение)
+	ActionSubmit      = "SUBMIT"       // Отправка черновика на рассмотрение
+	ActionApproveStep = "APPROVE_STEP" // Согласование промежуточного этапа цепочки (статус не меняется)
+	ActionApprove     = "APPROVE"      // Утверждение (последний этап цепочки согласования)
+	ActionReject      = "REJECT"       // Отклонение
+	ActionCancel      = "CANCEL"       // Отмена
+	ActionStart       = "START"        // Начало отпуска (системное действие)
+	ActionComplete    = "COMPLETE"     // Окончание отпуска (системное действие)
+	ActionRecall      = "RECALL"       // Отзыв из отпуска
+	ActionEdit        = "EDIT"         // Изменение периодов и комментария (статус не меняется)
+	ActionTransfer    = "TRANSFER"     // Перенос периода утвержденного отпуска (статус не меняется)
+	// Предложение руководителя изменить даты и ответ сотрудника
+	ActionProposeChanges  = "PROPOSE_CHANGES"  // Согласующий предлагает другие периоды
+	ActionAcceptProposal  = "ACCEPT_PROPOSAL"  // Сотрудник принимает предложенные периоды
+	ActionDeclineProposal = "DECLINE_PROPOSAL" // Сотрудник отклоняет предложение, заявка возвращается на рассмотрение
 )
 
 // CustomDate is a wrapper around time.Time to handle specific JSON format and database scanning/valuing
@@ -89,13 +127,64 @@ type User struct {
 	Password string `json:"-" db:"password"`
 	FullName string `json:"full_name" db:"full_name"`
 	// Email                string    `json:"email" db:"email"` // Удалено
-	OrganizationalUnitID *int      `json:"organizational_unit_id,omitempty" db:"organizational_unit_id"` // Переименовано с department_id
-	PositionID           *int      `json:"position_id,omitempty" db:"position_id"`
-	PositionName         *string   `json:"positionName,omitempty" db:"position_name"` // Use pointer for nullable position name
-	IsAdmin              bool      `json:"is_admin" db:"is_admin"`
-	IsManager            bool      `json:"is_manager" db:"is_manager"`
-	CreatedAt            time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt            time.Time `json:"updated_at" db:"updated_at"`
+	OrganizationalUnitID *int        `json:"organizational_unit_id,omitempty" db:"organizational_unit_id"` // Переименовано с department_id
+	PositionID           *int        `json:"position_id,omitempty" db:"position_id"`
+	PositionName         *string     `json:"positionName,omitempty" db:"position_name"` // Use pointer for nullable position name
+	IsAdmin              bool        `json:"is_admin" db:"is_admin"`
+	IsManager            bool        `json:"is_manager" db:"is_manager"`
+	HireDate             *CustomDate `json:"hire_date,omitempty" db:"hire_date"` // Дата приема на работу (nil - работает с начала года или раньше)
+	// Категории преимущественного права выбора времени отпуска; заполняются только там, где они нужны
+	// (карточка сотрудника у администратора, проверка конфликтов)
+	PriorityCategories []UserPriorityCategory `json:"priority_categories,omitempty" db:"-"`
+	CreatedAt          time.Time              `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time              `json:"updated_at" db:"updated_at"`
+}
+
+// Категории преимущественного права выбора времени ежегодного отпуска
+const (
+	PriorityCategoryMinor           = "MINOR"            // Работник моложе 18 лет (ст. 267 ТК РФ)
+	PriorityCategorySpouseMaternity = "SPOUSE_MATERNITY" // Муж в период отпуска жены по беременности и родам (ст. 123 ТК РФ)
+	PriorityCategoryYoungChild      = "YOUNG_CHILD"      // Родитель (усыновитель) малолетнего ребенка в случаях, предусмотренных законом
+	PriorityCategoryLargeFamily     = "LARGE_FAMILY"     // Родитель в многодетной семье (ст. 262.2 ТК РФ)
+	PriorityCategoryDisabledChild   = "DISABLED_CHILD"   // Родитель ребенка-инвалида (ст. 262.1 ТК РФ)
+	PriorityCategoryMilitarySpouse  = "MILITARY_SPOUSE"  // Супруг военнослужащего (ФЗ "О статусе военнослужащих")
+	PriorityCategoryVeteran         = "VETERAN"          // Ветеран боевых действий, инвалид войны
+	PriorityCategoryHonoraryDonor   = "HONORARY_DONOR"   // Почетный донор
+	PriorityCategoryOther           = "OTHER"            // Иное основание по закону (указывается в комментарии)
+)
+
+// PriorityCategoryNames - названия категорий преимущественного права
+var PriorityCategoryNames = map[string]string{
+	PriorityCategoryMinor:           "работник моложе 18 лет",
+	PriorityCategorySpouseMaternity: "муж в период отпуска жены по беременности и родам",
+	PriorityCategoryYoungChild:      "родитель малолетнего ребенка",
+	PriorityCategoryLargeFamily:     "родитель в многодетной семье",
+	PriorityCategoryDisabledChild:   "родитель ребенка-инвалида",
+	PriorityCategoryMilitarySpouse:  "супруг военнослужащего",
+	PriorityCategoryVeteran:         "ветеран боевых действий",
+	PriorityCategoryHonoraryDonor:   "почетный донор",
+	PriorityCategoryOther:           "иное основание по закону",
+}
+
+// UserPriorityCategory - категория преимущественного права сотрудника с периодом действия
+type UserPriorityCategory struct {
+	ID           int         `json:"id" db:"id"`
+	UserID       int         `json:"user_id" db:"user_id"`
+	Category     string      `json:"category" db:"category"`
+	CategoryName string      `json:"category_name" db:"-"`
+	StartDate    CustomDate  `json:"start_date" db:"start_date"`
+	EndDate      *CustomDate `json:"end_date,omitempty" db:"end_date"` // nil - бессрочно
+	Comment      string      `json:"comment" db:"comment"`
+	CreatedBy    *int        `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt    time.Time   `json:"created_at" db:"created_at"`
+}
+
+// ActiveOn проверяет, действует ли категория на дату
+func (c UserPriorityCategory) ActiveOn(date time.Time) bool {
+	if date.Before(c.StartDate.Time) {
+		return false
+	}
+	return c.EndDate == nil || !date.After(c.EndDate.Time)
 }
 
 // UserProfileDTO - DTO для отображения профиля пользователя с иерархией юнитов
@@ -122,12 +211,35 @@ type UserUpdateDTO struct {
 	OrganizationalUnitID *int    `json:"organizational_unit_id"` // Указатель для опционального обновления юнита
 }
 
+// VacationRequestUpdateDTO - структура для изменения черновика или заявки на рассмотрении.
+// Периоды заменяются целиком.
+type VacationRequestUpdateDTO struct {
+	Periods []VacationPeriod `json:"periods"`
+	Comment string           `json:"comment"`
+}
+
+// VacationRequestPreview - результат пробной проверки заявки: нарушения правил, посчитанные сервером дни,
+// остаток после утверждения и конфликты, которые вызовет заявка. Ничего не сохраняется.
+type VacationRequestPreview struct {
+	Valid              bool                `json:"valid"`
+	Violations         []string            `json:"violations"`
+	Warnings           []string            `json:"warnings"`
+	Year               int                 `json:"year"`
+	Periods            []VacationPeriod    `json:"periods"`             // Периоды с днями по производственному календарю
+	DaysRequested      int                 `json:"days_requested"`      // Дней, списываемых с основного баланса
+	AvailableDays      int                 `json:"available_days"`      // Остаток до заявки
+	RemainingDays      int                 `json:"remaining_days"`      // Остаток после утверждения заявки
+	Conflicts          []ConflictingPeriod `json:"conflicts"`           // Пересечения с утвержденными отпусками на той же должности
+	CoverageViolations []CoverageViolation `json:"coverage_violations"` // Дни с нарушением правил укомплектованности
+}
+
 // UserUpdateAdminDTO - структура для обновления данных пользователя администратором
 type UserUpdateAdminDTO struct {
-	PositionID           *int  `json:"position_id"`            // Указатель для опционального обновления должности
-	OrganizationalUnitID *int  `json:"organizational_unit_id"` // Указатель для опционального обновления юнита
-	IsAdmin              *bool `json:"is_admin"`               // Указатель для опционального обновления статуса админа
-	IsManager            *bool `json:"is_manager"`             // Указатель для опционального обновления статуса менеджера
+	PositionID           *int        `json:"position_id"`            // Указатель для опционального обновления должности
+	OrganizationalUnitID *int        `json:"organizational_unit_id"` // Указатель для опционального обновления юнита
+	IsAdmin              *bool       `json:"is_admin"`               // Указатель для опционального обновления статуса админа
+	IsManager            *bool       `json:"is_manager"`             // Указатель для опционального обновления статуса менеджера
+	HireDate             *CustomDate `json:"hire_date"`              // Указатель для опционального обновления даты приема
 }
 
 // Position - модель должности (без GroupID)
@@ -153,37 +265,613 @@ type OrganizationalUnit struct {
 
 // VacationRequest - модель заявки на отпуск
 type VacationRequest struct {
-	ID            int              `json:"id" db:"id"`
-	UserID        int              `json:"user_id" db:"user_id"`
-	Year          int              `json:"year" db:"year"`
-	StatusID      int              `json:"status_id" db:"status_id"`
-	DaysRequested int              `json:"days_requested" db:"days_requested"` // Добавлено поле
-	Comment       string           `json:"comment" db:"comment"`
-	CreatedAt     time.Time        `json:"created_at" db:"created_at"`
-	UpdatedAt     time.Time        `json:"updated_at" db:"updated_at"`
-	Periods       []VacationPeriod `json:"periods"`
+	ID                 int              `json:"id" db:"id"`
+	UserID             int              `json:"user_id" db:"user_id"`
+	Year               int              `json:"year" db:"year"`
+	StatusID           int              `json:"status_id" db:"status_id"`
+	DaysRequested      int              `json:"days_requested" db:"days_requested"` // Добавлено поле
+	Comment            string           `json:"comment" db:"comment"`
+	RejectionReason    string           `json:"rejection_reason,omitempty" db:"rejection_reason"`       // Причина отклонения
+	CancellationReason string           `json:"cancellation_reason,omitempty" db:"cancellation_reason"` // Причина отмены
+	CreatedAt          time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time        `json:"updated_at" db:"updated_at"`
+	Periods            []VacationPeriod `json:"periods"`
+	ApprovalSteps      []ApprovalStep   `json:"approval_steps,omitempty" db:"-"` // Цепочка согласования (заполняется для заявок, отправленных на рассмотрение)
+	Warnings           []string         `json:"warnings,omitempty" db:"-"`       // Предупреждения проверки заявки, не препятствующие ее сохранению
 }
 
 // VacationPeriod - модель периода отпуска
 type VacationPeriod struct {
-	ID        int        `json:"id" db:"id"`
-	RequestID int        `json:"request_id" db:"request_id"`
-	StartDate CustomDate `json:"start_date" db:"start_date"` // Use CustomDate
-	EndDate   CustomDate `json:"end_date" db:"end_date"`     // Use CustomDate
-	DaysCount int        `json:"days_count" db:"days_count"`
-	CreatedAt time.Time  `json:"created_at" db:"created_at"` // Keep time.Time for DB timestamps
-	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"` // Keep time.Time for DB timestamps
+	ID          int        `json:"id" db:"id"`
+	RequestID   int        `json:"request_id" db:"request_id"`
+	LeaveTypeID int        `json:"leave_type_id" db:"leave_type_id"` // Вид отпуска (0 от клиента - основной)
+	StartDate   CustomDate `json:"start_date" db:"start_date"`       // Use CustomDate
+	EndDate     CustomDate `json:"end_date" db:"end_date"`           // Use CustomDate
+	DaysCount   int        `json:"days_count" db:"days_count"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"` // Keep time.Time for DB timestamps
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"` // Keep time.Time for DB timestamps
 }
 
-// VacationLimit - модель лимита отпуска
+// VacationLimit - модель лимита отпуска (баланс, вычисляемый по журналу движения дней)
 type VacationLimit struct {
-	ID        int       `json:"id" db:"id"`
-	UserID    int       `json:"user_id" db:"user_id"`
-	Year      int       `json:"year" db:"year"`
-	TotalDays int       `json:"total_days" db:"total_days"`
-	UsedDays  int       `json:"used_days" db:"used_days"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID            int       `json:"id" db:"limit_id"`
+	UserID        int       `json:"user_id" db:"user_id"`
+	Year          int       `json:"year" db:"year"`
+	TotalDays     int       `json:"total_days" db:"total_days"`               // Начислено за год (начисления и корректировки)
+	CarriedOver   int       `json:"carried_over_days" db:"carried_over_days"` // Перенесено с прошлых лет
+	CarriedOut    int       `json:"carried_out_days" db:"carried_out_days"`   // Перенесено на следующий год
+	ExpiredDays   int       `json:"expired_days" db:"expired_days"`           // Сгорело при переходе года
+	UsedDays      int       `json:"used_days" db:"used_days"`                 // Зарезервировано + использовано
+	ReservedDays  int       `json:"reserved_days" db:"reserved_days"`         // Зарезервировано заявками на рассмотрении
+	ConsumedDays  int       `json:"consumed_days" db:"consumed_days"`         // Использовано по утвержденным заявкам
+	AvailableDays int       `json:"available_days" db:"available_days"`       // Доступный остаток
+	AccruedToDate int       `json:"accrued_to_date" db:"-"`                   // Начислено на текущую дату пропорционально отработанным месяцам
+	FullYearDays  int       `json:"full_year_days" db:"-"`                    // Полагается за весь год (с учетом даты приема)
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// VacationRequestTransition - запись истории переходов заявки между статусами
+type VacationRequestTransition struct {
+	ID             int       `json:"id" db:"id"`
+	RequestID      int       `json:"request_id" db:"request_id"`
+	FromStatusID   *int      `json:"from_status_id,omitempty" db:"from_status_id"` // nil для создания заявки
+	FromStatusName *string   `json:"from_status_name,omitempty" db:"from_status_name"`
+	ToStatusID     int       `json:"to_status_id" db:"to_status_id"`
+	ToStatusName   string    `json:"to_status_name" db:"to_status_name"`
+	Action         string    `json:"action" db:"action"`
+	ActorID        *int      `json:"actor_id,omitempty" db:"actor_id"` // nil для системных переходов
+	ActorFullName  *string   `json:"actor_full_name,omitempty" db:"actor_full_name"`
+	OnBehalfOfID   *int      `json:"on_behalf_of_id,omitempty" db:"on_behalf_of_id"` // Руководитель, за которого действовал заместитель
+	OnBehalfOfName *string   `json:"on_behalf_of_name,omitempty" db:"on_behalf_of_name"`
+	Reason         string    `json:"reason,omitempty" db:"reason"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// VacationTransfer - заявка на перенос периода утвержденного отпуска.
+// Исходные даты сохраняются, чтобы в графике (форма Т-7) оставалась запланированная дата.
+type VacationTransfer struct {
+	ID                int        `json:"id" db:"id"`
+	RequestID         int        `json:"request_id" db:"request_id"`
+	PeriodID          int        `json:"period_id" db:"period_id"`
+	UserID            int        `json:"user_id" db:"user_id"`
+	UserFullName      string     `json:"user_full_name" db:"full_name"`
+	OriginalStartDate CustomDate `json:"original_start_date" db:"original_start_date"` // Даты периода на момент подачи переноса
+	OriginalEndDate   CustomDate `json:"original_end_date" db:"original_end_date"`
+	NewStartDate      CustomDate `json:"new_start_date" db:"new_start_date"` // Предлагаемые даты
+	NewEndDate        CustomDate `json:"new_end_date" db:"new_end_date"`
+	DaysCount         int        `json:"days_count" db:"days_count"`
+	Reason            string     `json:"reason" db:"reason"` // Основание переноса (столбец 11 формы Т-7)
+	StatusID          int        `json:"status_id" db:"status_id"`
+	StatusName        string     `json:"status_name" db:"status_name"`
+	DecidedBy         *int       `json:"decided_by,omitempty" db:"decided_by"`
+	DecisionComment   string     `json:"decision_comment,omitempty" db:"decision_comment"`
+	DecidedAt         *time.Time `json:"decided_at,omitempty" db:"decided_at"`
+	Warnings          []string   `json:"warnings,omitempty" db:"-"` // Предупреждения о нестрогих периодах запрета на новые даты
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// --- Approval Chain ---
+
+// Типы этапов цепочки согласования
+const (
+	ApprovalStepManager = "MANAGER" // Руководитель юнита
+	ApprovalStepHR      = "HR"      // Отдел кадров: согласует любой администратор
+)
+
+// Статусы этапа цепочки согласования
+const (
+	ApprovalStepPending  = "PENDING"
+	ApprovalStepApproved = "APPROVED"
+	ApprovalStepRejected = "REJECTED"
+)
+
+// ApprovalStep - этап цепочки согласования заявки
+type ApprovalStep struct {
+	ID            int        `json:"id" db:"id"`
+	RequestID     int        `json:"request_id" db:"request_id"`
+	StepOrder     int        `json:"step_order" db:"step_order"`
+	StepType      string     `json:"step_type" db:"step_type"`
+	UnitID        *int       `json:"unit_id,omitempty" db:"unit_id"`
+	UnitName      *string    `json:"unit_name,omitempty" db:"unit_name"`
+	ApproverID    *int       `json:"approver_id,omitempty" db:"approver_id"` // nil для этапа отдела кадров
+	ApproverName  *string    `json:"approver_name,omitempty" db:"approver_name"`
+	Status        string     `json:"status" db:"status"`
+	DecidedBy     *int       `json:"decided_by,omitempty" db:"decided_by"`
+	DecidedByName *string    `json:"decided_by_name,omitempty" db:"decided_by_name"`
+	DecidedAt     *time.Time `json:"decided_at,omitempty" db:"decided_at"`
+	Comment       string     `json:"comment,omitempty" db:"comment"`
+}
+
+// CurrentApprovalStep возвращает первый этап цепочки, ожидающий решения, или nil, если таких нет
+func CurrentApprovalStep(steps []ApprovalStep) *ApprovalStep {
+	for i := range steps {
+		if steps[i].Status == ApprovalStepPending {
+			return &steps[i]
+		}
+	}
+	return nil
+}
+
+// --- Approval Delegation ---
+
+// ApprovalDelegation - передача прав руководителя на рассмотрение заявок его поддерева заместителю на период
+type ApprovalDelegation struct {
+	ID              int        `json:"id" db:"id"`
+	DelegatorID     int        `json:"delegator_id" db:"delegator_id"`
+	DelegatorName   string     `json:"delegator_name" db:"delegator_name"`
+	DelegateID      int        `json:"delegate_id" db:"delegate_id"`
+	DelegateName    string     `json:"delegate_name" db:"delegate_name"`
+	StartDate       CustomDate `json:"start_date" db:"start_date"`
+	EndDate         CustomDate `json:"end_date" db:"end_date"`
+	Comment         string     `json:"comment,omitempty" db:"comment"`
+	SourceRequestID *int       `json:"source_request_id,omitempty" db:"source_request_id"` // Заявка на отпуск руководителя (для автоматических делегирований)
+	CreatedBy       *int       `json:"created_by,omitempty" db:"created_by"`
+	RevokedAt       *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+}
+
+// ApprovalDelegationCreateDTO - структура для создания делегирования.
+// DelegatorID указывает администратор; руководитель делегирует свои права.
+type ApprovalDelegationCreateDTO struct {
+	DelegatorID *int       `json:"delegator_id"`
+	DelegateID  int        `json:"delegate_id"`
+	StartDate   CustomDate `json:"start_date"`
+	EndDate     CustomDate `json:"end_date"`
+	Comment     string     `json:"comment"`
+}
+
+// VacationRecall - запись об отзыве сотрудника из отпуска.
+// Период усекается до дня, предшествующего дате выхода, неиспользованные дни возвращаются на баланс.
+type VacationRecall struct {
+	ID              int        `json:"id" db:"id"`
+	RequestID       int        `json:"request_id" db:"request_id"`
+	PeriodID        int        `json:"period_id" db:"period_id"`
+	RecallDate      CustomDate `json:"recall_date" db:"recall_date"`             // Первый рабочий день после отзыва
+	OriginalEndDate CustomDate `json:"original_end_date" db:"original_end_date"` // Дата окончания периода до отзыва
+	UnusedDays      int        `json:"unused_days" db:"unused_days"`             // Возвращено на баланс
+	EmployeeConsent bool       `json:"employee_consent" db:"employee_consent"`   // Согласие сотрудника (ст. 125 ТК РФ)
+	Reason          string     `json:"reason" db:"reason"`
+	RecalledBy      *int       `json:"recalled_by,omitempty" db:"recalled_by"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+}
+
+// VacationRecallDTO - структура для отзыва сотрудника из отпуска
+type VacationRecallDTO struct {
+	RecallDate      CustomDate `json:"recall_date"`
+	EmployeeConsent bool       `json:"employee_consent"`
+	Reason          string     `json:"reason"`
+}
+
+// Статусы предложения изменить даты заявки
+const (
+	ProposalStatusOpen      = "OPEN"      // Ожидает ответа сотрудника
+	ProposalStatusAccepted  = "ACCEPTED"  // Принято, периоды заявки заменены предложенными
+	ProposalStatusDeclined  = "DECLINED"  // Отклонено сотрудником
+	ProposalStatusCancelled = "CANCELLED" // Заявка отменена до ответа сотрудника
+)
+
+// VacationRequestProposal - предложение согласующего изменить даты заявки и ответ сотрудника
+type VacationRequestProposal struct {
+	ID              int              `json:"id" db:"id"`
+	RequestID       int              `json:"request_id" db:"request_id"`
+	ProposedBy      *int             `json:"proposed_by,omitempty" db:"proposed_by"`
+	ProposedByName  string           `json:"proposed_by_name,omitempty" db:"-"`
+	Comment         string           `json:"comment" db:"comment"`
+	Periods         []VacationPeriod `json:"periods" db:"-"`
+	Status          string           `json:"status" db:"status"`
+	ResponseComment string           `json:"response_comment,omitempty" db:"response_comment"`
+	RespondedBy     *int             `json:"responded_by,omitempty" db:"responded_by"`
+	RespondedAt     *time.Time       `json:"responded_at,omitempty" db:"responded_at"`
+	CreatedAt       time.Time        `json:"created_at" db:"created_at"`
+}
+
+// VacationProposalCreateDTO - структура для предложения других дат по заявке
+type VacationProposalCreateDTO struct {
+	Periods []VacationPeriod `json:"periods"`
+	Comment string           `json:"comment"`
+}
+
+// VacationProposalResponseDTO - ответ сотрудника на предложение (пояснение обязательно при отклонении)
+type VacationProposalResponseDTO struct {
+	Comment string `json:"comment"`
+}
+
+// VacationTransferCreateDTO - структура для подачи заявки на перенос периода
+type VacationTransferCreateDTO struct {
+	PeriodID  int        `json:"period_id"`
+	StartDate CustomDate `json:"start_date"`
+	EndDate   CustomDate `json:"end_date"`
+	Reason    string     `json:"reason"`
+}
+
+// --- Leave Types ---
+
+// LeaveTypeMain - ID ежегодного основного оплачиваемого отпуска в справочнике видов отпуска
+const LeaveTypeMain = 1
+
+// Правила подсчета дней отпуска
+const (
+	CountingCalendar = "CALENDAR" // Календарные дни без нерабочих праздничных (ст. 120 ТК РФ)
+	CountingWorking  = "WORKING"  // Рабочие дни по производственному календарю
+)
+
+// LeaveType - вид отпуска из справочника
+type LeaveType struct {
+	ID               int       `json:"id" db:"id"`
+	Code             string    `json:"code" db:"code"`
+	Name             string    `json:"name" db:"name"`
+	CountingRule     string    `json:"counting_rule" db:"counting_rule"`
+	CountsTowardMain bool      `json:"counts_toward_main" db:"counts_toward_main"` // Дни списываются с баланса основного отпуска
+	InSchedule       bool      `json:"in_schedule" db:"in_schedule"`               // Включается в график отпусков (форма Т-7)
+	IsPaid           bool      `json:"is_paid" db:"is_paid"`
+	MaxDaysPerYear   *int      `json:"max_days_per_year,omitempty" db:"max_days_per_year"` // nil - без ограничения
+	IsActive         bool      `json:"is_active" db:"is_active"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// --- Vacation Policies ---
+
+// VacationPolicy - правила отпуска для области действия: вся организация (UnitID и PositionID пусты),
+// организационный юнит (действует на все его поддерево), должность или должность внутри юнита.
+// Незаполненные (nil) правила наследуются от более общей политики.
+type VacationPolicy struct {
+	ID                  int       `json:"id" db:"id"`
+	UnitID              *int      `json:"unit_id,omitempty" db:"unit_id"`
+	PositionID          *int      `json:"position_id,omitempty" db:"position_id"`
+	AnnualDays          *int      `json:"annual_days,omitempty" db:"annual_days"`                     // Дней основного отпуска за полный год работы
+	MinLongPartDays     *int      `json:"min_long_part_days,omitempty" db:"min_long_part_days"`       // Минимальная продолжительность одной из частей (0 - без требования)
+	MaxParts            *int      `json:"max_parts,omitempty" db:"max_parts"`                         // Максимальное количество частей основного отпуска (0 - без ограничения)
+	RequireExactBalance *bool     `json:"require_exact_balance,omitempty" db:"require_exact_balance"` // Заявка должна использовать все доступные дни
+	ForbidOverlap       *bool     `json:"forbid_overlap,omitempty" db:"forbid_overlap"`               // Периоды заявки не должны пересекаться
+	Comment             string    `json:"comment" db:"comment"`
+	CreatedAt           time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// EffectiveVacationPolicy - итоговые правила отпуска сотрудника после наследования политик
+type EffectiveVacationPolicy struct {
+	AnnualDays          int   `json:"annual_days"`
+	MinLongPartDays     int   `json:"min_long_part_days"`
+	MaxParts            int   `json:"max_parts"`
+	RequireExactBalance bool  `json:"require_exact_balance"`
+	ForbidOverlap       bool  `json:"forbid_overlap"`
+	PolicyIDs           []int `json:"policy_ids"` // Примененные политики, от общей к частной
+}
+
+// --- Coverage Rules ---
+
+// CoverageRule - правило укомплектованности юнита: сколько сотрудников может отсутствовать одновременно
+// (MaxAbsent) или сколько должно оставаться на месте (MinPresent) в каждый день.
+// Правило действует на сотрудников юнита (и его поддерева при IncludeSubunits), а если задан список
+// должностей - только на сотрудников этих должностей.
+type CoverageRule struct {
+	ID              int       `json:"id" db:"id"`
+	UnitID          int       `json:"unit_id" db:"unit_id"`
+	UnitName        string    `json:"unit_name,omitempty" db:"-"`
+	IncludeSubunits bool      `json:"include_subunits" db:"include_subunits"`
+	PositionIDs     []int     `json:"position_ids" db:"-"`                    // Группа должностей (пусто - все должности)
+	MaxAbsent       *int      `json:"max_absent,omitempty" db:"max_absent"`   // Максимум одновременно отсутствующих
+	MinPresent      *int      `json:"min_present,omitempty" db:"min_present"` // Минимум сотрудников на месте
+	Comment         string    `json:"comment" db:"comment"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// StaffAbsence - утвержденный период отсутствия сотрудника (для проверки укомплектованности)
+type StaffAbsence struct {
+	UserID       int        `json:"user_id"`
+	UserFullName string     `json:"user_full_name"`
+	RequestID    int        `json:"request_id"`
+	StartDate    CustomDate `json:"start_date"`
+	EndDate      CustomDate `json:"end_date"`
+}
+
+// CoverageViolation - день, в который с учетом заявки нарушается правило укомплектованности
+type CoverageViolation struct {
+	RuleID      int        `json:"rule_id"`
+	UnitID      int        `json:"unit_id"`
+	UnitName    string     `json:"unit_name"`
+	Date        CustomDate `json:"date"`
+	Headcount   int        `json:"headcount"` // Сотрудников, на которых действует правило
+	Absent      int        `json:"absent"`    // Отсутствуют в этот день, включая автора заявки
+	MaxAbsent   *int       `json:"max_absent,omitempty"`
+	MinPresent  *int       `json:"min_present,omitempty"`
+	AbsentUsers []string   `json:"absent_users"`
+	Message     string     `json:"message"`
+}
+
+// --- Blackout Periods ---
+
+// Строгость периода запрета отпусков
+const (
+	BlackoutSeverityBlock = "BLOCK" // Отпуск в период запрещен: заявка не проходит проверку
+	BlackoutSeverityWarn  = "WARN"  // Отпуск нежелателен: заявка проходит проверку с предупреждением
+)
+
+// UnitBlackout - период запрета отпусков юнита. Действует на сотрудников юнита и всего его поддерева,
+// кроме сотрудников должностей из списка исключений.
+type UnitBlackout struct {
+	ID                int        `json:"id" db:"id"`
+	UnitID            int        `json:"unit_id" db:"unit_id"`
+	UnitName          string     `json:"unit_name,omitempty" db:"-"`
+	StartDate         CustomDate `json:"start_date" db:"start_date"`
+	EndDate           CustomDate `json:"end_date" db:"end_date"`
+	Severity          string     `json:"severity" db:"severity"`
+	Reason            string     `json:"reason" db:"reason"`
+	ExemptPositionIDs []int      `json:"exempt_position_ids" db:"-"`
+	CreatedBy         *int       `json:"created_by,omitempty" db:"created_by"`
+	Inherited         bool       `json:"inherited" db:"-"` // Период задан для вышестоящего юнита
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// --- Peak Windows ---
+
+// PeakWindow - пиковый период отпусков, повторяющийся каждый год (например, июль - август).
+// Действует на всю организацию или на поддерево юнита.
+type PeakWindow struct {
+	ID         int       `json:"id" db:"id"`
+	UnitID     *int      `json:"unit_id,omitempty" db:"unit_id"` // nil - для всей организации
+	UnitName   string    `json:"unit_name,omitempty" db:"-"`
+	Name       string    `json:"name" db:"name"`
+	StartMonth int       `json:"start_month" db:"start_month"`
+	StartDay   int       `json:"start_day" db:"start_day"`
+	EndMonth   int       `json:"end_month" db:"end_month"` // Окончание раньше начала - период переходит на следующий год
+	EndDay     int       `json:"end_day" db:"end_day"`
+	Comment    string    `json:"comment" db:"comment"`
+	CreatedBy  *int      `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Occurrence возвращает даты пикового периода, начинающегося в указанном году
+func (w PeakWindow) Occurrence(year int, loc *time.Location) (time.Time, time.Time) {
+	start := time.Date(year, time.Month(w.StartMonth), w.StartDay, 0, 0, 0, 0, loc)
+	end := time.Date(year, time.Month(w.EndMonth), w.EndDay, 0, 0, 0, 0, loc)
+	if end.Before(start) {
+		end = end.AddDate(1, 0, 0)
+	}
+	return start, end
+}
+
+// PeakSlotEmployee - дни сотрудника в пиковых периодах по годам
+type PeakSlotEmployee struct {
+	UserID        int         `json:"user_id"`
+	UserFullName  string      `json:"user_full_name"`
+	DaysByYear    map[int]int `json:"days_by_year"`
+	TotalDays     int         `json:"total_days"`
+	FairnessScore int         `json:"fairness_score"` // Меньше - раньше очередь на пиковый период
+}
+
+// PeakSlotUnit - распределение пиковых периодов среди сотрудников юнита
+type PeakSlotUnit struct {
+	UnitID            int                `json:"unit_id"`
+	UnitName          string             `json:"unit_name"`
+	Employees         []PeakSlotEmployee `json:"employees"`
+	EmployeesWithPeak int                `json:"employees_with_peak"` // Сотрудники, отдыхавшие в пиковый период хотя бы раз
+	DaysByYear        map[int]int        `json:"days_by_year"`
+}
+
+// PeakSlotReport - отчет о распределении пиковых периодов по юнитам поддерева за последние годы
+type PeakSlotReport struct {
+	UnitID  int            `json:"unit_id"`
+	Years   []int          `json:"years"`
+	Windows []PeakWindow   `json:"windows"`
+	Units   []PeakSlotUnit `json:"units"`
+}
+
+// --- Schedule Generator ---
+
+// PreferredWindow - желаемый период отпуска сотрудника для генератора графика
+type PreferredWindow struct {
+	UserID    int        `json:"user_id"`
+	StartDate CustomDate `json:"start_date"`
+	EndDate   CustomDate `json:"end_date"`
+}
+
+// ScheduleGenerationRequest - параметры генерации графика отпусков юнита на год
+type ScheduleGenerationRequest struct {
+	UnitID           int               `json:"unit_id"`
+	Year             int               `json:"year"`
+	IncludeSubunits  bool              `json:"include_subunits"`  // Планировать и сотрудников дочерних юнитов
+	PreferredWindows []PreferredWindow `json:"preferred_windows"` // Желаемые периоды сотрудников (необязательно)
+}
+
+// ScheduleProposalEntry - предложенные периоды отпуска сотрудника
+type ScheduleProposalEntry struct {
+	UserID        int              `json:"user_id"`
+	UserFullName  string           `json:"user_full_name"`
+	AvailableDays int              `json:"available_days"`
+	ScheduledDays int              `json:"scheduled_days"`
+	Periods       []VacationPeriod `json:"periods"`
+	PreferenceMet bool             `json:"preference_met"` // Хотя бы один период целиком в желаемом периоде
+	FairnessScore int              `json:"fairness_score"` // Пиковые дни прошлых лет с учетом давности
+	Notes         []string         `json:"notes"`
+}
+
+// ScheduleProposal - предложенный график отпусков юнита. Не сохраняется: руководитель просматривает
+// его, при необходимости правит и публикует сотрудникам как черновики заявок.
+type ScheduleProposal struct {
+	UnitID    int                     `json:"unit_id"`
+	Year      int                     `json:"year"`
+	Score     int                     `json:"score"` // 0-100: 100 - все дни распределены, желаемые периоды учтены
+	Entries   []ScheduleProposalEntry `json:"entries"`
+	TradeOffs []string                `json:"trade_offs"` // Пояснения к снижению оценки
+}
+
+// SchedulePublishRequest - публикация графика: периоды сотрудников становятся черновиками заявок
+type SchedulePublishRequest struct {
+	Year    int                     `json:"year"`
+	Entries []ScheduleProposalEntry `json:"entries"`
+}
+
+// SchedulePublishResult - результат публикации графика
+type SchedulePublishResult struct {
+	Created []VacationRequest `json:"created"`
+	Errors  []string          `json:"errors"`
+}
+
+// --- Planning Campaigns ---
+
+// Этапы кампании планирования графика отпусков
+const (
+	CampaignPhaseCollecting    = "COLLECTING"     // Сбор заявок сотрудников
+	CampaignPhaseManagerReview = "MANAGER_REVIEW" // Рассмотрение заявок руководителями
+	CampaignPhaseHRReview      = "HR_REVIEW"      // Проверка графика отделом кадров
+	CampaignPhaseLocked        = "LOCKED"         // График утвержден: изменения только через перенос
+)
+
+// CampaignPhases - этапы кампании в порядке прохождения
+var CampaignPhases = []string{CampaignPhaseCollecting, CampaignPhaseManagerReview, CampaignPhaseHRReview, CampaignPhaseLocked}
+
+// PlanningCampaign - кампания планирования графика отпусков на год для всей организации или поддерева юнита.
+// Для сотрудника действует кампания ближайшего юнита его ветки, при ее отсутствии - кампания всей организации.
+type PlanningCampaign struct {
+	ID                    int        `json:"id" db:"id"`
+	Year                  int        `json:"year" db:"year"`
+	UnitID                *int       `json:"unit_id,omitempty" db:"unit_id"` // nil - вся организация
+	UnitName              string     `json:"unit_name,omitempty" db:"-"`
+	Phase                 string     `json:"phase" db:"phase"`
+	CollectingDeadline    CustomDate `json:"collecting_deadline" db:"collecting_deadline"`
+	ManagerReviewDeadline CustomDate `json:"manager_review_deadline" db:"manager_review_deadline"`
+	HRReviewDeadline      CustomDate `json:"hr_review_deadline" db:"hr_review_deadline"`
+	LockedAt              *time.Time `json:"locked_at,omitempty" db:"locked_at"`
+	CreatedBy             *int       `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt             time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// CampaignParticipant - сотрудник в охвате кампании и его заявки на год
+type CampaignParticipant struct {
+	UserID       int    `json:"user_id"`
+	FullName     string `json:"full_name"`
+	UnitID       *int   `json:"unit_id,omitempty"`
+	RequestCount int    `json:"request_count"` // Заявки на рассмотрении и утвержденные
+}
+
+// CampaignProgress - ход кампании: кто из сотрудников подал заявки на год, а кто нет
+type CampaignProgress struct {
+	Campaign     PlanningCampaign      `json:"campaign"`
+	Total        int                   `json:"total"`
+	Submitted    []CampaignParticipant `json:"submitted"`
+	NotSubmitted []CampaignParticipant `json:"not_submitted"`
+}
+
+// --- Vacation Preferences ---
+
+// Вид и строгость ограничения пожелания
+const (
+	PreferenceConstraintInclude = "INCLUDE" // Отпуск должен полностью включать даты
+	PreferenceConstraintExclude = "EXCLUDE" // Отпуск не должен затрагивать даты
+	PreferenceConstraintHard    = "HARD"    // Обязательно для каждого варианта
+	PreferenceConstraintSoft    = "SOFT"    // Желательно: невыполнение показывается руководителю
+)
+
+// PreferenceConstraint - ограничение пожелания сотрудника (например, "отпуск в школьные каникулы")
+type PreferenceConstraint struct {
+	ID          int        `json:"id" db:"id"`
+	Kind        string     `json:"kind" db:"kind"`
+	Strength    string     `json:"strength" db:"strength"`
+	StartDate   CustomDate `json:"start_date" db:"start_date"`
+	EndDate     CustomDate `json:"end_date" db:"end_date"`
+	Description string     `json:"description" db:"description"`
+}
+
+// PreferenceAlternative - вариант пожелания: набор периодов отпуска. Rank 1 - наиболее желательный.
+type PreferenceAlternative struct {
+	ID                   int              `json:"id" db:"id"`
+	Rank                 int              `json:"rank" db:"rank"`
+	Periods              []VacationPeriod `json:"periods" db:"-"`
+	DaysRequested        int              `json:"days_requested" db:"-"`         // Дни с баланса основного отпуска
+	UnmetSoftConstraints []string         `json:"unmet_soft_constraints" db:"-"` // Невыполненные желательные ограничения
+}
+
+// VacationPreference - пожелания сотрудника к графику отпусков на год
+type VacationPreference struct {
+	ID                  int                     `json:"id" db:"id"`
+	UserID              int                     `json:"user_id" db:"user_id"`
+	UserFullName        string                  `json:"user_full_name,omitempty" db:"-"`
+	UnitID              *int                    `json:"unit_id,omitempty" db:"-"`
+	Year                int                     `json:"year" db:"year"`
+	Comment             string                  `json:"comment" db:"comment"`
+	Alternatives        []PreferenceAlternative `json:"alternatives" db:"-"`
+	Constraints         []PreferenceConstraint  `json:"constraints" db:"-"`
+	ChosenAlternativeID *int                    `json:"chosen_alternative_id,omitempty" db:"chosen_alternative_id"`
+	ConvertedRequestID  *int                    `json:"converted_request_id,omitempty" db:"converted_request_id"`
+	CreatedAt           time.Time               `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time               `json:"updated_at" db:"updated_at"`
+}
+
+// PreferenceConvertDTO - выбор варианта пожелания для создания заявки
+type PreferenceConvertDTO struct {
+	AlternativeID int `json:"alternative_id" binding:"required"`
+}
+
+// --- Leave Ledger ---
+
+// Типы записей журнала движения дней отпуска
+const (
+	LedgerEntryAccrual     = "ACCRUAL"     // Начисление дней
+	LedgerEntryReservation = "RESERVATION" // Резервирование дней заявкой (отрицательное) или снятие резерва (положительное)
+	LedgerEntryConsumption = "CONSUMPTION" // Списание дней по утвержденной заявке
+	LedgerEntryRefund      = "REFUND"      // Возврат ранее списанных дней
+	LedgerEntryAdjustment  = "ADJUSTMENT"  // Ручная корректировка администратором
+	LedgerEntryCarryOver   = "CARRY_OVER"  // Остаток, перенесенный с прошлого года (положительное, с годом происхождения)
+	LedgerEntryCarryOut    = "CARRY_OUT"   // Остаток, перенесенный на следующий год (отрицательное)
+	LedgerEntryExpiry      = "EXPIRY"      // Остаток, сгоревший при переходе года (отрицательное)
+)
+
+// LeaveLedgerEntry - запись журнала движения дней отпуска.
+// Записи только добавляются; баланс вычисляется суммированием.
+type LeaveLedgerEntry struct {
+	ID            int       `json:"id" db:"id"`
+	UserID        int       `json:"user_id" db:"user_id"`
+	Year          int       `json:"year" db:"year"`
+	EntryType     string    `json:"entry_type" db:"entry_type"`
+	Days          int       `json:"days" db:"days"`                                 // Положительное - увеличивает остаток, отрицательное - уменьшает
+	RequestID     *int      `json:"request_id,omitempty" db:"request_id"`           // Связанная заявка
+	OriginYear    *int      `json:"origin_year,omitempty" db:"origin_year"`         // Год, за который начислены перенесенные дни (CARRY_OVER)
+	ActorID       *int      `json:"actor_id,omitempty" db:"actor_id"`               // Кто выполнил действие (nil - система)
+	ActorFullName *string   `json:"actor_full_name,omitempty" db:"actor_full_name"` // ФИО выполнившего действие
+	Comment       string    `json:"comment" db:"comment"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// LeaveLedger - журнал движения дней отпуска пользователя за год вместе с итоговым балансом
+type LeaveLedger struct {
+	UserID  int                `json:"user_id"`
+	Year    int                `json:"year"`
+	Balance *VacationLimit     `json:"balance"`
+	Entries []LeaveLedgerEntry `json:"entries"`
+}
+
+// YearRollover - итог перехода года для одного сотрудника
+type YearRollover struct {
+	UserID      int       `json:"user_id" db:"user_id"`
+	Year        int       `json:"year" db:"year"`                 // Закрываемый год
+	CarriedDays int       `json:"carried_days" db:"carried_days"` // Перенесено на следующий год
+	ExpiredDays int       `json:"expired_days" db:"expired_days"` // Сгорело
+	ActorID     *int      `json:"actor_id,omitempty" db:"actor_id"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// YearRolloverResult - итог перехода года по всем сотрудникам
+type YearRolloverResult struct {
+	Year         int            `json:"year"`
+	Processed    []YearRollover `json:"processed"`     // Обработанные сотрудники
+	SkippedUsers []int          `json:"skipped_users"` // Уже обработанные ранее
+	FailedUsers  []int          `json:"failed_users"`  // Ошибки обработки (см. лог)
+	CarriedDays  int            `json:"carried_days"`
+	ExpiredDays  int            `json:"expired_days"`
+}
+
+// RequestLedgerTotals - итоги журнала по одной заявке
+type RequestLedgerTotals struct {
+	ReservedDays int // Текущий резерв по заявке
+	ConsumedDays int // Списано по заявке за вычетом возвратов
 }
 
 // Notification - модель уведомления
@@ -227,18 +915,22 @@ type UserWithLimitDTO struct {
 // --- New DTO for Admin/Manager View ---
 // VacationRequestAdminView includes user and status details for admin/manager displays.
 type VacationRequestAdminView struct {
-	ID            int              `json:"id" db:"id"`
-	UserID        int              `json:"user_id" db:"user_id"`
-	UserFullName  string           `json:"user_full_name" db:"full_name"` // Added user's full name
-	Year          int              `json:"year" db:"year"`
-	StatusID      int              `json:"status_id" db:"status_id"`
-	StatusName    string           `json:"status_name" db:"status_name"`       // Added status name
-	DaysRequested int              `json:"days_requested" db:"days_requested"` // Добавлено поле (уже было в схеме, добавляем сюда для согласованности)
-	Comment       string           `json:"comment" db:"comment"`
-	CreatedAt     time.Time        `json:"created_at" db:"created_at"`
-	UpdatedAt     time.Time        `json:"updated_at" db:"updated_at"`
-	Periods       []VacationPeriod `json:"periods"`    // Populated separately
-	TotalDays     int              `json:"total_days"` // Calculated total days across periods (остается для отображения, но не используется для логики списания)
+	ID                 int              `json:"id" db:"id"`
+	UserID             int              `json:"user_id" db:"user_id"`
+	UserFullName       string           `json:"user_full_name" db:"full_name"` // Added user's full name
+	Year               int              `json:"year" db:"year"`
+	StatusID           int              `json:"status_id" db:"status_id"`
+	StatusName         string           `json:"status_name" db:"status_name"`       // Added status name
+	DaysRequested      int              `json:"days_requested" db:"days_requested"` // Добавлено поле (уже было в схеме, добавляем сюда для согласованности)
+	Comment            string           `json:"comment" db:"comment"`
+	RejectionReason    string           `json:"rejection_reason,omitempty" db:"rejection_reason"`       // Причина отклонения
+	CancellationReason string           `json:"cancellation_reason,omitempty" db:"cancellation_reason"` // Причина отмены
+	CreatedAt          time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time        `json:"updated_at" db:"updated_at"`
+	Periods            []VacationPeriod `json:"periods"`                       // Populated separately
+	TotalDays          int              `json:"total_days"`                    // Calculated total days across periods (остается для отображения, но не используется для логики списания)
+	ApprovalSteps      []ApprovalStep   `json:"approval_steps" db:"-"`         // Цепочка согласования заявки
+	CurrentStep        *ApprovalStep    `json:"current_step,omitempty" db:"-"` // Этап, ожидающий решения (для заявок на рассмотрении)
 }
 
 // --- DTO for Unit/User List ---
@@ -278,21 +970,97 @@ type ConflictingPeriod struct {
 	OriginalEndDate         CustomDate `json:"original_end_date"`          // Конец периода первого пользователя
 	OverlapStartDate        CustomDate `json:"overlap_start_date"`         // Начало пересечения
 	OverlapEndDate          CustomDate `json:"overlap_end_date"`           // Конец пересечения
+	// Категории преимущественного права выбора времени отпуска, действующие на начало пересечения
+	OriginalPriority    []string `json:"original_priority,omitempty"`
+	ConflictingPriority []string `json:"conflicting_priority,omitempty"`
+	// Признак преимущественного права второй стороны; остается, когда названия категорий скрыты от пользователя
+	ConflictingHasPriority bool `json:"conflicting_has_priority,omitempty"`
+	// Пиковый период, в который попадает пересечение, и очередность сторон на пиковые даты
+	// (пиковые дни прошлых лет с учетом давности; меньше - раньше очередь)
+	PeakWindow               string `json:"peak_window,omitempty"`
+	OriginalFairnessScore    *int   `json:"original_fairness_score,omitempty"`
+	ConflictingFairnessScore *int   `json:"conflicting_fairness_score,omitempty"`
+	// Вторая сторона - заявка на рассмотрении, у автора которой очередь на пиковые даты раньше:
+	// утверждение вне очереди, как и при конфликте, требует подтверждения (force)
+	OutOfTurn bool `json:"out_of_turn,omitempty"`
+}
+
+// --- Bulk Decisions ---
+
+// Итог решения по одной заявке из пакета
+const (
+	BulkOutcomeApproved     = "APPROVED"      // Заявка утверждена
+	BulkOutcomeStepApproved = "STEP_APPROVED" // Согласован этап цепочки, заявка передана на следующий этап
+	BulkOutcomeRejected     = "REJECTED"      // Заявка отклонена
+	BulkOutcomeConflict     = "CONFLICT"      // Не утверждена из-за конфликтов, укомплектованности или очередности (без force)
+	BulkOutcomePriority     = "PRIORITY"      // Не утверждена: пересекается с заявкой сотрудника с преимущественным правом
+	BulkOutcomeForbidden    = "FORBIDDEN"     // Нет прав на решение по заявке
+	BulkOutcomeWrongStatus  = "WRONG_STATUS"  // Действие недопустимо для статуса заявки
+	BulkOutcomeNotFound     = "NOT_FOUND"     // Заявка не найдена
+	BulkOutcomeFailed       = "FAILED"        // Иная ошибка (правила отпуска, база данных)
+)
+
+// BulkDecisionRequest - пакетное утверждение или отклонение заявок
+type BulkDecisionRequest struct {
+	RequestIDs []int  `json:"request_ids" binding:"required"`
+	Action     string `json:"action" binding:"required"` // APPROVE или REJECT
+	Reason     string `json:"reason"`                    // Причина отклонения
+	Force      bool   `json:"force"`                     // Утвердить несмотря на конфликты и нарушения укомплектованности
+}
+
+// BulkDecisionItem - итог решения по одной заявке пакета
+type BulkDecisionItem struct {
+	RequestID          int                 `json:"request_id"`
+	Outcome            string              `json:"outcome"`
+	Error              string              `json:"error,omitempty"`
+	Conflicts          []ConflictingPeriod `json:"conflicts,omitempty"`
+	CoverageViolations []CoverageViolation `json:"coverage_violations,omitempty"`
+	NextStep           *ApprovalStep       `json:"next_step,omitempty"`
+}
+
+// BulkDecisionResult - итоги пакетного решения: по каждой заявке и количество по итогам
+type BulkDecisionResult struct {
+	Items   []BulkDecisionItem `json:"items"`
+	Summary map[string]int     `json:"summary"`
 }
 
 // --- Dashboard DTOs ---
 
 // ManagerDashboardData - DTO для дашборда руководителя
 type ManagerDashboardData struct {
-	PendingRequestsCount  int                 `json:"pending_requests_count"`   // Количество заявок "На рассмотрении"
+	PendingRequestsCount  int                 `json:"pending_requests_count"`   // Количество заявок "На рассмотрении", ожидающих решения руководителя
 	ApprovedDaysCountYear int                 `json:"approved_days_count_year"` // Сумма дней в утвержденных заявках за год (или выбранный период)
 	RejectedDaysCountYear int                 `json:"rejected_days_count_year"` // Сумма дней в отклоненных заявках за год (или выбранный период)
 	PendingDaysCountYear  int                 `json:"pending_days_count_year"`  // Сумма дней в заявках "На рассмотрении" за год (или выбранный период)
 	UpcomingConflicts     []ConflictingPeriod `json:"upcoming_conflicts"`       // Список ближайших конфликтов (пересечений утвержденных отпусков)
 	SubordinateUserCount  int                 `json:"subordinate_user_count"`   // Общее количество подчиненных пользователей
+	OwnApprovalStepType   string              `json:"own_approval_step_type"`   // Кто рассматривает заявки самого руководителя: MANAGER - руководитель вышестоящего юнита, HR - администратор
+	OwnApproverID         *int                `json:"own_approver_id,omitempty"`
+	OwnApproverName       string              `json:"own_approver_name,omitempty"`
 	// Можно добавить другие счетчики при необходимости
 }
 
+// --- Production Calendar ---
+
+// Holiday - нерабочий праздничный день (ст. 112 ТК РФ)
+type Holiday struct {
+	Date string `json:"date"` // Дата в формате YYYY-MM-DD
+	Name string `json:"name"` // Название праздника
+}
+
+// DayTransfer - перенос выходного дня: день From становится рабочим, день To - выходным
+type DayTransfer struct {
+	From string `json:"from"` // Исходный выходной день (YYYY-MM-DD)
+	To   string `json:"to"`   // День, на который перенесен выходной (YYYY-MM-DD)
+}
+
+// ProductionCalendarYear - данные производственного календаря на один год
+type ProductionCalendarYear struct {
+	Year      int           `json:"year"`
+	Holidays  []Holiday     `json:"holidays"`
+	Transfers []DayTransfer `json:"transfers"`
+}
+
 // VacationExportRow структура для строки данных в экспорте Т-7
 // Поля соответствуют столбцам формы Т-7 из примера
 type VacationExportRow struct {
@@ -302,11 +1070,11 @@ type VacationExportRow struct {
 	FullName              string      `json:"full_name"`               // 4. Фамилия, имя, отчество
 	EmployeeNumber        string      `json:"employee_number"`         // 5. Табельный номер (используем UserID или Login)
 	PlannedDaysMain       int         `json:"planned_days_main"`       // 6. Дни основного отпуска (из периода)
-	PlannedDaysAdditional int         `json:"planned_days_additional"` // 7. Дни дополнительного отпуска (пока 0)
+	PlannedDaysAdditional int         `json:"planned_days_additional"` // 7. Дни дополнительного отпуска (виды, не списываемые с основного баланса)
 	PlannedDaysTotal      int         `json:"planned_days_total"`      // 8. Итого дней (сумма)
 	PlannedDate           CustomDate  `json:"planned_date"`            // 9. Дата запланированная (StartDate периода)
 	ActualDate            *CustomDate `json:"actual_date,omitempty"`   // 10. Дата фактическая (StartDate, если утвержден?) - Используем указатель
-	TransferReason        string      `json:"transfer_reason"`         // 11. Основание переноса (пока пусто)
-	TransferDate          *CustomDate `json:"transfer_date,omitempty"` // 12. Дата предполагаемого отпуска (пока пусто) - Используем указатель
-	Note                  string      `json:"note"`                    // 13. Примечание (пока пусто)
+	TransferReason        string      `json:"transfer_reason"`         // 11. Основание переноса (из утвержденного переноса)
+	TransferDate          *CustomDate `json:"transfer_date,omitempty"` // 12. Дата предполагаемого отпуска (новая дата после переноса) - Используем указатель
+	Note                  string      `json:"note"`                    // 13. Примечание (отзыв из отпуска)
 }
@@ -0,0 +1,136 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"vacation-scheduler/internal/models"
+)
+
+// --- Делегирование прав руководителя ---
+
+// approvalDelegationSelect - SELECT делегирований с именами руководителя и заместителя
+const approvalDelegationSelect = `
+	SELECT d.id, d.delegator_id, dr.full_name, d.delegate_id, de.full_name, d.start_date, d.end_date,
+		d.comment, d.source_request_id, d.created_by, d.revoked_at, d.created_at
+	FROM approval_delegations d
+	JOIN users dr ON d.delegator_id = dr.id
+	JOIN users de ON d.delegate_id = de.id`
+
+// scanApprovalDelegation сканирует строку, выбранную запросом approvalDelegationSelect
+func scanApprovalDelegation(row rowScanner) (*models.ApprovalDelegation, error) {
+	var d models.ApprovalDelegation
+	var comment sql.NullString
+	var sourceRequestID, createdBy sql.NullInt64
+	var revokedAt sql.NullTime
+	err := row.Scan(&d.ID, &d.DelegatorID, &d.DelegatorName, &d.DelegateID, &d.DelegateName, &d.StartDate, &d.EndDate,
+		&comment, &sourceRequestID, &createdBy, &revokedAt, &d.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	d.Comment = comment.String
+	d.SourceRequestID = nullIntPtr(sourceRequestID)
+	d.CreatedBy = nullIntPtr(createdBy)
+	if revokedAt.Valid {
+		revoked := revokedAt.Time
+		d.RevokedAt = &revoked
+	}
+	return &d, nil
+}
+
+// queryApprovalDelegations выполняет выборку делегирований
+func (r *VacationRepository) queryApprovalDelegations(query string, args ...interface{}) ([]models.ApprovalDelegation, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса делегирований: %w", err)
+	}
+	defer rows.Close()
+
+	delegations := []models.ApprovalDelegation{}
+	for rows.Next() {
+		d, err := scanApprovalDelegation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования делегирования: %w", err)
+		}
+		delegations = append(delegations, *d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по делегированиям: %w", err)
+	}
+	return delegations, nil
+}
+
+// GetApprovalDelegations получает делегирования, где пользователь - руководитель или заместитель.
+// userIDFilter == nil - все делегирования (для администратора).
+func (r *VacationRepository) GetApprovalDelegations(userIDFilter *int) ([]models.ApprovalDelegation, error) {
+	if userIDFilter == nil {
+		return r.queryApprovalDelegations(approvalDelegationSelect + ` ORDER BY d.start_date DESC, d.id DESC`)
+	}
+	return r.queryApprovalDelegations(approvalDelegationSelect+`
+		WHERE d.delegator_id = ? OR d.delegate_id = ?
+		ORDER BY d.start_date DESC, d.id DESC`, *userIDFilter, *userIDFilter)
+}
+
+// GetActiveDelegationsForDelegate получает неотозванные делегирования заместителю, действующие на дату
+func (r *VacationRepository) GetActiveDelegationsForDelegate(delegateID int, date time.Time) ([]models.ApprovalDelegation, error) {
+	day := date.Format("2006-01-02")
+	return r.queryApprovalDelegations(approvalDelegationSelect+`
+		WHERE d.delegate_id = ? AND d.revoked_at IS NULL AND d.start_date <= ? AND d.end_date >= ?
+		ORDER BY d.id`, delegateID, day, day)
+}
+
+// GetApprovalDelegationByID получает делегирование по ID. Возвращает nil, nil, если оно не найдено.
+func (r *VacationRepository) GetApprovalDelegationByID(delegationID int) (*models.ApprovalDelegation, error) {
+	d, err := scanApprovalDelegation(r.db.QueryRow(approvalDelegationSelect+` WHERE d.id = ?`, delegationID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка получения делегирования %d: %w", delegationID, err)
+	}
+	return d, nil
+}
+
+// CreateApprovalDelegation сохраняет делегирование
+func (r *VacationRepository) CreateApprovalDelegation(delegation *models.ApprovalDelegation) error {
+	query := `
+		INSERT INTO approval_delegations (delegator_id, delegate_id, start_date, end_date, comment, source_request_id, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`
+	result, err := r.db.Exec(query, delegation.DelegatorID, delegation.DelegateID, delegation.StartDate, delegation.EndDate,
+		delegation.Comment, delegation.SourceRequestID, delegation.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("ошибка создания делегирования: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("ошибка получения ID делегирования: %w", err)
+	}
+	delegation.ID = int(id)
+	return nil
+}
+
+// RevokeApprovalDelegation отзывает делегирование
+func (r *VacationRepository) RevokeApprovalDelegation(delegationID int) error {
+	result, err := r.db.Exec(`UPDATE approval_delegations SET revoked_at = CURRENT_TIMESTAMP WHERE id = ? AND revoked_at IS NULL`, delegationID)
+	if err != nil {
+		return fmt.Errorf("ошибка отзыва делегирования %d: %w", delegationID, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества обновленных строк при отзыве делегирования: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("делегирование %d не найдено или уже отозвано", delegationID)
+	}
+	return nil
+}
+
+// DeleteRequestDelegations удаляет делегирования, созданные автоматически по заявке на отпуск руководителя
+func (r *VacationRepository) DeleteRequestDelegations(requestID int) error {
+	if _, err := r.db.Exec(`DELETE FROM approval_delegations WHERE source_request_id = ?`, requestID); err != nil {
+		return fmt.Errorf("ошибка удаления делегирований по заявке %d: %w", requestID, err)
+	}
+	return nil
+}
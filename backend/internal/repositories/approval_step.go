@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"vacation-scheduler/internal/models"
+)
+
+// --- Цепочка согласования ---
+
+// approvalStepSelect - SELECT этапов согласования с именами юнита, согласующего и принявшего решение
+const approvalStepSelect = `
+	SELECT s.id, s.request_id, s.step_order, s.step_type, s.unit_id, ou.name, s.approver_id, a.full_name,
+		s.status, s.decided_by, d.full_name, s.decided_at, s.comment
+	FROM vacation_approval_steps s
+	LEFT JOIN organizational_units ou ON s.unit_id = ou.id
+	LEFT JOIN users a ON s.approver_id = a.id
+	LEFT JOIN users d ON s.decided_by = d.id`
+
+// scanApprovalStep сканирует строку, выбранную запросом approvalStepSelect
+func scanApprovalStep(row rowScanner) (*models.ApprovalStep, error) {
+	var step models.ApprovalStep
+	var unitID, approverID, decidedBy sql.NullInt64
+	var unitName, approverName, decidedByName, comment sql.NullString
+	var decidedAt sql.NullTime
+	err := row.Scan(&step.ID, &step.RequestID, &step.StepOrder, &step.StepType, &unitID, &unitName, &approverID, &approverName,
+		&step.Status, &decidedBy, &decidedByName, &decidedAt, &comment)
+	if err != nil {
+		return nil, err
+	}
+	step.UnitID = nullIntPtr(unitID)
+	step.ApproverID = nullIntPtr(approverID)
+	step.DecidedBy = nullIntPtr(decidedBy)
+	if unitName.Valid {
+		step.UnitName = &unitName.String
+	}
+	if approverName.Valid {
+		step.ApproverName = &approverName.String
+	}
+	if decidedByName.Valid {
+		step.DecidedByName = &decidedByName.String
+	}
+	if decidedAt.Valid {
+		decided := decidedAt.Time
+		step.DecidedAt = &decided
+	}
+	step.Comment = comment.String
+	return &step, nil
+}
+
+// queryApprovalSteps выполняет выборку этапов согласования
+func (r *VacationRepository) queryApprovalSteps(query string, args ...interface{}) ([]models.ApprovalStep, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса этапов согласования: %w", err)
+	}
+	defer rows.Close()
+
+	steps := []models.ApprovalStep{}
+	for rows.Next() {
+		step, err := scanApprovalStep(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования этапа согласования: %w", err)
+		}
+		steps = append(steps, *step)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по этапам согласования: %w", err)
+	}
+	return steps, nil
+}
+
+// getApprovalStepsByRequestID получает цепочку согласования заявки по порядку этапов
+func (r *VacationRepository) getApprovalStepsByRequestID(requestID int) ([]models.ApprovalStep, error) {
+	return r.queryApprovalSteps(approvalStepSelect+` WHERE s.request_id = ? ORDER BY s.step_order`, requestID)
+}
+
+// getApprovalStepsByRequestIDs получает цепочки согласования для списка заявок, сгруппированные по ID заявки
+func (r *VacationRepository) getApprovalStepsByRequestIDs(requestIDs []interface{}) (map[int][]models.ApprovalStep, error) {
+	result := make(map[int][]models.ApprovalStep)
+	if len(requestIDs) == 0 {
+		return result, nil
+	}
+	steps, err := r.queryApprovalSteps(approvalStepSelect+` WHERE s.request_id IN (?`+sqlRepeatParams(len(requestIDs)-1)+`) ORDER BY s.request_id, s.step_order`, requestIDs...)
+	if err != nil {
+		return nil, err
+	}
+	for _, step := range steps {
+		result[step.RequestID] = append(result[step.RequestID], step)
+	}
+	return result, nil
+}
+
+// ReplaceApprovalSteps заменяет цепочку согласования заявки новой (все этапы ожидают решения).
+// ID сохраненных этапов записываются в steps.
+func (r *VacationRepository) ReplaceApprovalSteps(requestID int, steps []models.ApprovalStep) error {
+	if _, err := r.db.Exec(`DELETE FROM vacation_approval_steps WHERE request_id = ?`, requestID); err != nil {
+		return fmt.Errorf("ошибка удаления цепочки согласования заявки %d: %w", requestID, err)
+	}
+	query := `
+		INSERT INTO vacation_approval_steps (request_id, step_order, step_type, unit_id, approver_id, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`
+	for i := range steps {
+		step := &steps[i]
+		step.RequestID = requestID
+		step.Status = models.ApprovalStepPending
+		result, err := r.db.Exec(query, requestID, step.StepOrder, step.StepType, step.UnitID, step.ApproverID, step.Status)
+		if err != nil {
+			return fmt.Errorf("ошибка сохранения этапа %d цепочки согласования заявки %d: %w", step.StepOrder, requestID, err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("ошибка получения ID этапа согласования: %w", err)
+		}
+		step.ID = int(id)
+	}
+	return nil
+}
+
+// SetApprovalStepDecision сохраняет решение по этапу согласования
+func (r *VacationRepository) SetApprovalStepDecision(stepID int, status string, decidedBy int, comment string) error {
+	query := `
+		UPDATE vacation_approval_steps
+		SET status = ?, decided_by = ?, decided_at = CURRENT_TIMESTAMP, comment = ?
+		WHERE id = ? AND status = ?`
+	result, err := r.db.Exec(query, status, decidedBy, comment, stepID, models.ApprovalStepPending)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения решения по этапу согласования %d: %w", stepID, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества обновленных строк при решении по этапу: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("этап согласования %d не найден или уже рассмотрен", stepID)
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"vacation-scheduler/internal/models"
+)
+
+// --- Правила укомплектованности ---
+
+// coverageRuleSelect - SELECT правил укомплектованности с названием юнита
+const coverageRuleSelect = `
+	SELECT cr.id, cr.unit_id, ou.name, cr.include_subunits, cr.max_absent, cr.min_present, cr.comment, cr.created_at, cr.updated_at
+	FROM coverage_rules cr
+	JOIN organizational_units ou ON cr.unit_id = ou.id`
+
+// scanCoverageRule сканирует строку, выбранную запросом coverageRuleSelect
+func scanCoverageRule(row rowScanner) (*models.CoverageRule, error) {
+	var rule models.CoverageRule
+	var maxAbsent, minPresent sql.NullInt64
+	var comment sql.NullString
+	err := row.Scan(&rule.ID, &rule.UnitID, &rule.UnitName, &rule.IncludeSubunits, &maxAbsent, &minPresent,
+		&comment, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	rule.MaxAbsent = nullIntPtr(maxAbsent)
+	rule.MinPresent = nullIntPtr(minPresent)
+	rule.Comment = comment.String
+	rule.PositionIDs = []int{}
+	return &rule, nil
+}
+
+// queryCoverageRules выполняет выборку правил укомплектованности вместе с их группами должностей
+func (r *VacationRepository) queryCoverageRules(query string, args ...interface{}) ([]models.CoverageRule, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса правил укомплектованности: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []models.CoverageRule{}
+	for rows.Next() {
+		rule, err := scanCoverageRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования правила укомплектованности: %w", err)
+		}
+		rules = append(rules, *rule)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по правилам укомплектованности: %w", err)
+	}
+	if len(rules) == 0 {
+		return rules, nil
+	}
+
+	ruleIDs := make([]interface{}, len(rules))
+	index := make(map[int]int, len(rules))
+	for i, rule := range rules {
+		ruleIDs[i] = rule.ID
+		index[rule.ID] = i
+	}
+	posRows, err := r.db.Query(`SELECT rule_id, position_id FROM coverage_rule_positions WHERE rule_id IN (?`+sqlRepeatParams(len(ruleIDs)-1)+`) ORDER BY rule_id, position_id`, ruleIDs...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса должностей правил укомплектованности: %w", err)
+	}
+	defer posRows.Close()
+	for posRows.Next() {
+		var ruleID, positionID int
+		if err := posRows.Scan(&ruleID, &positionID); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования должности правила укомплектованности: %w", err)
+		}
+		if i, ok := index[ruleID]; ok {
+			rules[i].PositionIDs = append(rules[i].PositionIDs, positionID)
+		}
+	}
+	if err = posRows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по должностям правил укомплектованности: %w", err)
+	}
+	return rules, nil
+}
+
+// GetCoverageRules получает все правила укомплектованности
+func (r *VacationRepository) GetCoverageRules() ([]models.CoverageRule, error) {
+	return r.queryCoverageRules(coverageRuleSelect + ` ORDER BY cr.unit_id, cr.id`)
+}
+
+// GetCoverageRulesByUnitIDs получает правила, заданные для указанных юнитов
+func (r *VacationRepository) GetCoverageRulesByUnitIDs(unitIDs []int) ([]models.CoverageRule, error) {
+	if len(unitIDs) == 0 {
+		return []models.CoverageRule{}, nil
+	}
+	args := make([]interface{}, len(unitIDs))
+	for i, id := range unitIDs {
+		args[i] = id
+	}
+	return r.queryCoverageRules(coverageRuleSelect+` WHERE cr.unit_id IN (?`+sqlRepeatParams(len(unitIDs)-1)+`) ORDER BY cr.unit_id, cr.id`, args...)
+}
+
+// GetCoverageRuleByID получает правило укомплектованности по ID. Возвращает nil, nil, если правило не найдено.
+func (r *VacationRepository) GetCoverageRuleByID(ruleID int) (*models.CoverageRule, error) {
+	rules, err := r.queryCoverageRules(coverageRuleSelect+` WHERE cr.id = ?`, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	return &rules[0], nil
+}
+
+// CreateCoverageRule сохраняет новое правило укомплектованности и его группу должностей
+func (r *VacationRepository) CreateCoverageRule(rule *models.CoverageRule) error {
+	query := `
+		INSERT INTO coverage_rules (unit_id, include_subunits, max_absent, min_present, comment, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+	result, err := r.db.Exec(query, rule.UnitID, rule.IncludeSubunits, rule.MaxAbsent, rule.MinPresent, rule.Comment)
+	if err != nil {
+		return fmt.Errorf("ошибка создания правила укомплектованности: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("ошибка получения ID правила укомплектованности: %w", err)
+	}
+	rule.ID = int(id)
+	return r.insertCoverageRulePositions(rule)
+}
+
+// UpdateCoverageRule обновляет правило укомплектованности и заменяет его группу должностей
+func (r *VacationRepository) UpdateCoverageRule(rule *models.CoverageRule) error {
+	query := `
+		UPDATE coverage_rules
+		SET unit_id = ?, include_subunits = ?, max_absent = ?, min_present = ?, comment = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`
+	result, err := r.db.Exec(query, rule.UnitID, rule.IncludeSubunits, rule.MaxAbsent, rule.MinPresent, rule.Comment, rule.ID)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления правила укомплектованности %d: %w", rule.ID, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества обновленных строк при обновлении правила укомплектованности: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("правило укомплектованности %d не найдено", rule.ID)
+	}
+	if _, err := r.db.Exec(`DELETE FROM coverage_rule_positions WHERE rule_id = ?`, rule.ID); err != nil {
+		return fmt.Errorf("ошибка удаления должностей правила укомплектованности %d: %w", rule.ID, err)
+	}
+	return r.insertCoverageRulePositions(rule)
+}
+
+// insertCoverageRulePositions сохраняет группу должностей правила
+func (r *VacationRepository) insertCoverageRulePositions(rule *models.CoverageRule) error {
+	for _, positionID := range rule.PositionIDs {
+		if _, err := r.db.Exec(`INSERT INTO coverage_rule_positions (rule_id, position_id) VALUES (?, ?)`, rule.ID, positionID); err != nil {
+			return fmt.Errorf("ошибка сохранения должности %d правила укомплектованности %d: %w", positionID, rule.ID, err)
+		}
+	}
+	return nil
+}
+
+// DeleteCoverageRule удаляет правило укомплектованности
+func (r *VacationRepository) DeleteCoverageRule(ruleID int) error {
+	result, err := r.db.Exec(`DELETE FROM coverage_rules WHERE id = ?`, ruleID)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления правила укомплектованности %d: %w", ruleID, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества удаленных строк при удалении правила укомплектованности: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("правило укомплектованности %d не найдено", ruleID)
+	}
+	return nil
+}
+
+// LockCoverageRules блокирует строки правил до конца транзакции.
+// Используется при утверждении, чтобы проверки одного правила не выполнялись параллельно.
+func (r *VacationRepository) LockCoverageRules(ruleIDs []int) error {
+	if r.tx == nil {
+		return errors.New("блокировка правил укомплектованности возможна только внутри транзакции")
+	}
+	if len(ruleIDs) == 0 {
+		return nil
+	}
+	args := make([]interface{}, len(ruleIDs))
+	for i, id := range ruleIDs {
+		args[i] = id
+	}
+	rows, err := r.db.Query(`SELECT id FROM coverage_rules WHERE id IN (?`+sqlRepeatParams(len(ruleIDs)-1)+`) ORDER BY id FOR UPDATE`, args...)
+	if err != nil {
+		return fmt.Errorf("ошибка блокировки правил укомплектованности: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("ошибка блокировки правил укомплектованности: %w", err)
+		}
+	}
+	return rows.Err()
+}
+
+// GetApprovedAbsences получает утвержденные периоды отсутствия сотрудников, пересекающие диапазон дат.
+// Периоды заявки excludeRequestID не учитываются.
+func (r *VacationRepository) GetApprovedAbsences(userIDs []int, excludeRequestID int, startDate time.Time, endDate time.Time) ([]models.StaffAbsence, error) {
+	if len(userIDs) == 0 {
+		return []models.StaffAbsence{}, nil
+	}
+	args := []interface{}{}
+	for _, id := range userIDs {
+		args = append(args, id)
+	}
+	args = append(args, excludeRequestID)
+	return r.queryAbsences(models.ApprovedStatuses, `vr.user_id IN (?`+sqlRepeatParams(len(userIDs)-1)+`) AND vr.id <> ?`, args, startDate, endDate)
+}
+
+// GetApprovedAbsencesByPosition получает утвержденные периоды отсутствия сотрудников должности, пересекающие диапазон дат
+func (r *VacationRepository) GetApprovedAbsencesByPosition(positionID int, startDate time.Time, endDate time.Time) ([]models.StaffAbsence, error) {
+	return r.queryAbsences(models.ApprovedStatuses, `u.position_id = ?`, []interface{}{positionID}, startDate, endDate)
+}
+
+// GetPendingAbsencesByPosition получает периоды заявок на рассмотрении сотрудников должности, пересекающие диапазон дат
+func (r *VacationRepository) GetPendingAbsencesByPosition(positionID int, startDate time.Time, endDate time.Time) ([]models.StaffAbsence, error) {
+	return r.queryAbsences([]int{models.StatusPending}, `u.position_id = ?`, []interface{}{positionID}, startDate, endDate)
+}
+
+// queryAbsences выбирает периоды заявок в указанных статусах по условию на заявку или сотрудника
+func (r *VacationRepository) queryAbsences(statuses []int, condition string, conditionArgs []interface{}, startDate time.Time, endDate time.Time) ([]models.StaffAbsence, error) {
+	query := `
+		SELECT vr.user_id, u.full_name, vr.id, vp.start_date, vp.end_date
+		FROM vacation_periods vp
+		JOIN vacation_requests vr ON vp.request_id = vr.id
+		JOIN users u ON vr.user_id = u.id
+		WHERE vr.status_id IN (?` + sqlRepeatParams(len(statuses)-1) + `)
+		  AND ` + condition + `
+		  AND vp.start_date <= ?
+		  AND vp.end_date >= ?
+		ORDER BY vp.start_date, vr.user_id`
+	args := []interface{}{}
+	for _, statusID := range statuses {
+		args = append(args, statusID)
+	}
+	args = append(args, conditionArgs...)
+	args = append(args, endDate, startDate)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения отсутствий сотрудников: %w", err)
+	}
+	defer rows.Close()
+
+	absences := []models.StaffAbsence{}
+	for rows.Next() {
+		var a models.StaffAbsence
+		if err := rows.Scan(&a.UserID, &a.UserFullName, &a.RequestID, &a.StartDate, &a.EndDate); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования отсутствия сотрудника: %w", err)
+		}
+		absences = append(absences, a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по отсутствиям сотрудников: %w", err)
+	}
+	return absences, nil
+}
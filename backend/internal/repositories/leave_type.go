@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"vacation-scheduler/internal/models"
+)
+
+// --- Виды отпуска ---
+
+// leaveTypeColumns - общий список полей вида отпуска для SELECT-запросов
+const leaveTypeColumns = `id, code, name, counting_rule, counts_toward_main, in_schedule, is_paid, max_days_per_year, is_active, created_at, updated_at`
+
+// scanLeaveType сканирует строку, выбранную с полями leaveTypeColumns
+func scanLeaveType(row rowScanner) (*models.LeaveType, error) {
+	var lt models.LeaveType
+	var maxDays sql.NullInt64
+	err := row.Scan(&lt.ID, &lt.Code, &lt.Name, &lt.CountingRule, &lt.CountsTowardMain, &lt.InSchedule,
+		&lt.IsPaid, &maxDays, &lt.IsActive, &lt.CreatedAt, &lt.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if maxDays.Valid {
+		days := int(maxDays.Int64)
+		lt.MaxDaysPerYear = &days
+	}
+	return &lt, nil
+}
+
+// GetLeaveTypes получает справочник видов отпуска
+func (r *VacationRepository) GetLeaveTypes() ([]models.LeaveType, error) {
+	rows, err := r.db.Query(`SELECT ` + leaveTypeColumns + ` FROM leave_types ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса видов отпуска: %w", err)
+	}
+	defer rows.Close()
+
+	leaveTypes := []models.LeaveType{}
+	for rows.Next() {
+		lt, err := scanLeaveType(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования вида отпуска: %w", err)
+		}
+		leaveTypes = append(leaveTypes, *lt)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по видам отпуска: %w", err)
+	}
+	return leaveTypes, nil
+}
+
+// GetLeaveTypeByID получает вид отпуска по ID. Возвращает nil, nil, если вид не найден.
+func (r *VacationRepository) GetLeaveTypeByID(leaveTypeID int) (*models.LeaveType, error) {
+	lt, err := scanLeaveType(r.db.QueryRow(`SELECT `+leaveTypeColumns+` FROM leave_types WHERE id = ?`, leaveTypeID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка получения вида отпуска %d: %w", leaveTypeID, err)
+	}
+	return lt, nil
+}
+
+// CreateLeaveType добавляет вид отпуска в справочник
+func (r *VacationRepository) CreateLeaveType(leaveType *models.LeaveType) error {
+	query := `
+		INSERT INTO leave_types (code, name, counting_rule, counts_toward_main, in_schedule, is_paid, max_days_per_year, is_active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+	result, err := r.db.Exec(query, leaveType.Code, leaveType.Name, leaveType.CountingRule, leaveType.CountsTowardMain,
+		leaveType.InSchedule, leaveType.IsPaid, leaveType.MaxDaysPerYear, leaveType.IsActive)
+	if err != nil {
+		return fmt.Errorf("ошибка создания вида отпуска: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("ошибка получения ID вида отпуска: %w", err)
+	}
+	leaveType.ID = int(id)
+	return nil
+}
+
+// UpdateLeaveType обновляет вид отпуска в справочнике
+func (r *VacationRepository) UpdateLeaveType(leaveType *models.LeaveType) error {
+	query := `
+		UPDATE leave_types
+		SET code = ?, name = ?, counting_rule = ?, counts_toward_main = ?, in_schedule = ?, is_paid = ?, max_days_per_year = ?, is_active = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`
+	result, err := r.db.Exec(query, leaveType.Code, leaveType.Name, leaveType.CountingRule, leaveType.CountsTowardMain,
+		leaveType.InSchedule, leaveType.IsPaid, leaveType.MaxDaysPerYear, leaveType.IsActive, leaveType.ID)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления вида отпуска %d: %w", leaveType.ID, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества обновленных строк при обновлении вида отпуска: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("вид отпуска %d не найден", leaveType.ID)
+	}
+	return nil
+}
+
+// GetLeaveTypeDaysUsed суммирует дни по видам отпуска в заявках пользователя за год,
+// которые находятся на рассмотрении (в том числе с предложенными изменениями) или утверждены. Заявка excludeRequestID не учитывается.
+func (r *VacationRepository) GetLeaveTypeDaysUsed(userID int, year int, excludeRequestID int) (map[int]int, error) {
+	statuses := append([]int{models.StatusPending, models.StatusChangesProposed}, models.ApprovedStatuses...)
+	query := `
+		SELECT vp.leave_type_id, SUM(vp.days_count)
+		FROM vacation_periods vp
+		JOIN vacation_requests vr ON vp.request_id = vr.id
+		WHERE vr.user_id = ? AND vr.year = ? AND vr.id != ?
+		  AND vr.status_id IN (?` + sqlRepeatParams(len(statuses)-1) + `)
+		GROUP BY vp.leave_type_id`
+	args := []interface{}{userID, year, excludeRequestID}
+	for _, statusID := range statuses {
+		args = append(args, statusID)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подсчета дней по видам отпуска пользователя %d: %w", userID, err)
+	}
+	defer rows.Close()
+
+	used := map[int]int{}
+	for rows.Next() {
+		var leaveTypeID, days int
+		if err := rows.Scan(&leaveTypeID, &days); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования дней по видам отпуска: %w", err)
+		}
+		used[leaveTypeID] = days
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по дням видов отпуска: %w", err)
+	}
+	return used, nil
+}
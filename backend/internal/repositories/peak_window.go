@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"vacation-scheduler/internal/models"
+)
+
+// --- Пиковые периоды отпусков ---
+
+// peakWindowSelect - SELECT пиковых периодов с названием юнита
+const peakWindowSelect = `
+	SELECT w.id, w.unit_id, ou.name, w.name, w.start_month, w.start_day, w.end_month, w.end_day, w.comment, w.created_by, w.created_at, w.updated_at
+	FROM peak_windows w
+	LEFT JOIN organizational_units ou ON w.unit_id = ou.id`
+
+// scanPeakWindow сканирует строку, выбранную запросом peakWindowSelect
+func scanPeakWindow(row rowScanner) (*models.PeakWindow, error) {
+	var w models.PeakWindow
+	var unitID, createdBy sql.NullInt64
+	var unitName, comment sql.NullString
+	err := row.Scan(&w.ID, &unitID, &unitName, &w.Name, &w.StartMonth, &w.StartDay, &w.EndMonth, &w.EndDay,
+		&comment, &createdBy, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	w.UnitID = nullIntPtr(unitID)
+	w.UnitName = unitName.String
+	w.Comment = comment.String
+	w.CreatedBy = nullIntPtr(createdBy)
+	return &w, nil
+}
+
+// queryPeakWindows выполняет выборку пиковых периодов
+func (r *VacationRepository) queryPeakWindows(query string, args ...interface{}) ([]models.PeakWindow, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса пиковых периодов: %w", err)
+	}
+	defer rows.Close()
+
+	windows := []models.PeakWindow{}
+	for rows.Next() {
+		w, err := scanPeakWindow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования пикового периода: %w", err)
+		}
+		windows = append(windows, *w)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по пиковым периодам: %w", err)
+	}
+	return windows, nil
+}
+
+// GetPeakWindows получает все пиковые периоды
+func (r *VacationRepository) GetPeakWindows() ([]models.PeakWindow, error) {
+	return r.queryPeakWindows(peakWindowSelect + ` ORDER BY w.unit_id, w.start_month, w.start_day, w.id`)
+}
+
+// GetPeakWindowByID получает пиковый период по ID. Возвращает nil, nil, если период не найден.
+func (r *VacationRepository) GetPeakWindowByID(windowID int) (*models.PeakWindow, error) {
+	windows, err := r.queryPeakWindows(peakWindowSelect+` WHERE w.id = ?`, windowID)
+	if err != nil {
+		return nil, err
+	}
+	if len(windows) == 0 {
+		return nil, nil
+	}
+	return &windows[0], nil
+}
+
+// CreatePeakWindow сохраняет новый пиковый период
+func (r *VacationRepository) CreatePeakWindow(window *models.PeakWindow) error {
+	query := `
+		INSERT INTO peak_windows (unit_id, name, start_month, start_day, end_month, end_day, comment, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+	result, err := r.db.Exec(query, window.UnitID, window.Name, window.StartMonth, window.StartDay, window.EndMonth, window.EndDay,
+		window.Comment, window.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("ошибка создания пикового периода: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("ошибка получения ID пикового периода: %w", err)
+	}
+	window.ID = int(id)
+	return nil
+}
+
+// UpdatePeakWindow обновляет пиковый период
+func (r *VacationRepository) UpdatePeakWindow(window *models.PeakWindow) error {
+	query := `
+		UPDATE peak_windows
+		SET unit_id = ?, name = ?, start_month = ?, start_day = ?, end_month = ?, end_day = ?, comment = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`
+	result, err := r.db.Exec(query, window.UnitID, window.Name, window.StartMonth, window.StartDay, window.EndMonth, window.EndDay,
+		window.Comment, window.ID)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления пикового периода %d: %w", window.ID, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества обновленных строк при обновлении пикового периода: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("пиковый период %d не найден", window.ID)
+	}
+	return nil
+}
+
+// DeletePeakWindow удаляет пиковый период
+func (r *VacationRepository) DeletePeakWindow(windowID int) error {
+	result, err := r.db.Exec(`DELETE FROM peak_windows WHERE id = ?`, windowID)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления пикового периода %d: %w", windowID, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества удаленных строк при удалении пикового периода: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("пиковый период %d не найден", windowID)
+	}
+	return nil
+}
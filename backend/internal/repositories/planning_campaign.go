@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"vacation-scheduler/internal/models"
+)
+
+// --- Кампании планирования графика отпусков ---
+
+// planningCampaignSelect - SELECT кампаний с названием юнита
+const planningCampaignSelect = `
+	SELECT c.id, c.year, c.unit_id, ou.name, c.phase, c.collecting_deadline, c.manager_review_deadline, c.hr_review_deadline,
+		c.locked_at, c.created_by, c.created_at, c.updated_at
+	FROM planning_campaigns c
+	LEFT JOIN organizational_units ou ON c.unit_id = ou.id`
+
+// scanPlanningCampaign сканирует строку, выбранную запросом planningCampaignSelect
+func scanPlanningCampaign(row rowScanner) (*models.PlanningCampaign, error) {
+	var c models.PlanningCampaign
+	var unitID, createdBy sql.NullInt64
+	var unitName sql.NullString
+	var lockedAt sql.NullTime
+	err := row.Scan(&c.ID, &c.Year, &unitID, &unitName, &c.Phase, &c.CollectingDeadline, &c.ManagerReviewDeadline, &c.HRReviewDeadline,
+		&lockedAt, &createdBy, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	c.UnitID = nullIntPtr(unitID)
+	c.UnitName = unitName.String
+	c.CreatedBy = nullIntPtr(createdBy)
+	if lockedAt.Valid {
+		locked := lockedAt.Time
+		c.LockedAt = &locked
+	}
+	return &c, nil
+}
+
+// queryPlanningCampaigns выполняет выборку кампаний
+func (r *VacationRepository) queryPlanningCampaigns(query string, args ...interface{}) ([]models.PlanningCampaign, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса кампаний планирования: %w", err)
+	}
+	defer rows.Close()
+
+	campaigns := []models.PlanningCampaign{}
+	for rows.Next() {
+		c, err := scanPlanningCampaign(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования кампании планирования: %w", err)
+		}
+		campaigns = append(campaigns, *c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по кампаниям планирования: %w", err)
+	}
+	return campaigns, nil
+}
+
+// GetPlanningCampaigns получает кампании планирования. yearFilter == nil - кампании всех лет.
+func (r *VacationRepository) GetPlanningCampaigns(yearFilter *int) ([]models.PlanningCampaign, error) {
+	if yearFilter == nil {
+		return r.queryPlanningCampaigns(planningCampaignSelect + ` ORDER BY c.year DESC, c.unit_id, c.id`)
+	}
+	return r.queryPlanningCampaigns(planningCampaignSelect+` WHERE c.year = ? ORDER BY c.unit_id, c.id`, *yearFilter)
+}
+
+// GetPlanningCampaignByID получает кампанию по ID. Возвращает nil, nil, если кампания не найдена.
+func (r *VacationRepository) GetPlanningCampaignByID(campaignID int) (*models.PlanningCampaign, error) {
+	campaigns, err := r.queryPlanningCampaigns(planningCampaignSelect+` WHERE c.id = ?`, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if len(campaigns) == 0 {
+		return nil, nil
+	}
+	return &campaigns[0], nil
+}
+
+// CreatePlanningCampaign сохраняет новую кампанию планирования
+func (r *VacationRepository) CreatePlanningCampaign(campaign *models.PlanningCampaign) error {
+	query := `
+		INSERT INTO planning_campaigns (year, unit_id, phase, collecting_deadline, manager_review_deadline, hr_review_deadline, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+	result, err := r.db.Exec(query, campaign.Year, campaign.UnitID, campaign.Phase, campaign.CollectingDeadline,
+		campaign.ManagerReviewDeadline, campaign.HRReviewDeadline, campaign.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("ошибка создания кампании планирования: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("ошибка получения ID кампании планирования: %w", err)
+	}
+	campaign.ID = int(id)
+	return nil
+}
+
+// UpdatePlanningCampaign обновляет этап и сроки кампании
+func (r *VacationRepository) UpdatePlanningCampaign(campaign *models.PlanningCampaign) error {
+	query := `
+		UPDATE planning_campaigns
+		SET phase = ?, collecting_deadline = ?, manager_review_deadline = ?, hr_review_deadline = ?, locked_at = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`
+	result, err := r.db.Exec(query, campaign.Phase, campaign.CollectingDeadline, campaign.ManagerReviewDeadline, campaign.HRReviewDeadline,
+		campaign.LockedAt, campaign.ID)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления кампании планирования %d: %w", campaign.ID, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества обновленных строк при обновлении кампании: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("кампания планирования %d не найдена", campaign.ID)
+	}
+	return nil
+}
+
+// CountActiveRequestsByUser считает заявки сотрудников на год на рассмотрении (в том числе с предложенными изменениями) и утвержденные.
+// Возвращает количество по ID сотрудника; сотрудники без заявок в результат не попадают.
+func (r *VacationRepository) CountActiveRequestsByUser(userIDs []int, year int) (map[int]int, error) {
+	counts := map[int]int{}
+	if len(userIDs) == 0 {
+		return counts, nil
+	}
+	statuses := append([]int{models.StatusPending, models.StatusChangesProposed}, models.ApprovedStatuses...)
+	args := []interface{}{year}
+	for _, statusID := range statuses {
+		args = append(args, statusID)
+	}
+	for _, id := range userIDs {
+		args = append(args, id)
+	}
+	query := `
+		SELECT user_id, COUNT(*)
+		FROM vacation_requests
+		WHERE year = ?
+		  AND status_id IN (?` + sqlRepeatParams(len(statuses)-1) + `)
+		  AND user_id IN (?` + sqlRepeatParams(len(userIDs)-1) + `)
+		GROUP BY user_id`
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подсчета заявок сотрудников за %d год: %w", year, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID, count int
+		if err := rows.Scan(&userID, &count); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования количества заявок сотрудника: %w", err)
+		}
+		counts[userID] = count
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по количеству заявок сотрудников: %w", err)
+	}
+	return counts, nil
+}
@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"vacation-scheduler/internal/models"
+)
+
+// --- Периоды запрета отпусков ---
+
+// unitBlackoutSelect - SELECT периодов запрета с названием юнита
+const unitBlackoutSelect = `
+	SELECT b.id, b.unit_id, ou.name, b.start_date, b.end_date, b.severity, b.reason, b.created_by, b.created_at, b.updated_at
+	FROM unit_blackouts b
+	JOIN organizational_units ou ON b.unit_id = ou.id`
+
+// scanUnitBlackout сканирует строку, выбранную запросом unitBlackoutSelect
+func scanUnitBlackout(row rowScanner) (*models.UnitBlackout, error) {
+	var b models.UnitBlackout
+	var createdBy sql.NullInt64
+	err := row.Scan(&b.ID, &b.UnitID, &b.UnitName, &b.StartDate, &b.EndDate, &b.Severity, &b.Reason, &createdBy, &b.CreatedAt, &b.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	b.CreatedBy = nullIntPtr(createdBy)
+	b.ExemptPositionIDs = []int{}
+	return &b, nil
+}
+
+// queryUnitBlackouts выполняет выборку периодов запрета вместе с должностями-исключениями
+func (r *VacationRepository) queryUnitBlackouts(query string, args ...interface{}) ([]models.UnitBlackout, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса периодов запрета отпусков: %w", err)
+	}
+	defer rows.Close()
+
+	blackouts := []models.UnitBlackout{}
+	for rows.Next() {
+		b, err := scanUnitBlackout(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования периода запрета отпусков: %w", err)
+		}
+		blackouts = append(blackouts, *b)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по периодам запрета отпусков: %w", err)
+	}
+	if len(blackouts) == 0 {
+		return blackouts, nil
+	}
+
+	blackoutIDs := make([]interface{}, len(blackouts))
+	index := make(map[int]int, len(blackouts))
+	for i, b := range blackouts {
+		blackoutIDs[i] = b.ID
+		index[b.ID] = i
+	}
+	posRows, err := r.db.Query(`SELECT blackout_id, position_id FROM unit_blackout_exempt_positions WHERE blackout_id IN (?`+sqlRepeatParams(len(blackoutIDs)-1)+`) ORDER BY blackout_id, position_id`, blackoutIDs...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса должностей-исключений периодов запрета: %w", err)
+	}
+	defer posRows.Close()
+	for posRows.Next() {
+		var blackoutID, positionID int
+		if err := posRows.Scan(&blackoutID, &positionID); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования должности-исключения периода запрета: %w", err)
+		}
+		if i, ok := index[blackoutID]; ok {
+			blackouts[i].ExemptPositionIDs = append(blackouts[i].ExemptPositionIDs, positionID)
+		}
+	}
+	if err = posRows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по должностям-исключениям периодов запрета: %w", err)
+	}
+	return blackouts, nil
+}
+
+// GetUnitBlackoutsByUnitIDs получает периоды запрета, заданные для указанных юнитов
+func (r *VacationRepository) GetUnitBlackoutsByUnitIDs(unitIDs []int) ([]models.UnitBlackout, error) {
+	if len(unitIDs) == 0 {
+		return []models.UnitBlackout{}, nil
+	}
+	args := make([]interface{}, len(unitIDs))
+	for i, id := range unitIDs {
+		args[i] = id
+	}
+	return r.queryUnitBlackouts(unitBlackoutSelect+` WHERE b.unit_id IN (?`+sqlRepeatParams(len(unitIDs)-1)+`) ORDER BY b.start_date, b.id`, args...)
+}
+
+// GetUnitBlackoutByID получает период запрета по ID. Возвращает nil, nil, если период не найден.
+func (r *VacationRepository) GetUnitBlackoutByID(blackoutID int) (*models.UnitBlackout, error) {
+	blackouts, err := r.queryUnitBlackouts(unitBlackoutSelect+` WHERE b.id = ?`, blackoutID)
+	if err != nil {
+		return nil, err
+	}
+	if len(blackouts) == 0 {
+		return nil, nil
+	}
+	return &blackouts[0], nil
+}
+
+// CreateUnitBlackout сохраняет новый период запрета и его должности-исключения
+func (r *VacationRepository) CreateUnitBlackout(blackout *models.UnitBlackout) error {
+	query := `
+		INSERT INTO unit_blackouts (unit_id, start_date, end_date, severity, reason, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+	result, err := r.db.Exec(query, blackout.UnitID, blackout.StartDate, blackout.EndDate, blackout.Severity, blackout.Reason, blackout.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("ошибка создания периода запрета отпусков: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("ошибка получения ID периода запрета отпусков: %w", err)
+	}
+	blackout.ID = int(id)
+	return r.insertBlackoutExemptPositions(blackout)
+}
+
+// UpdateUnitBlackout обновляет период запрета и заменяет его должности-исключения
+func (r *VacationRepository) UpdateUnitBlackout(blackout *models.UnitBlackout) error {
+	query := `
+		UPDATE unit_blackouts
+		SET start_date = ?, end_date = ?, severity = ?, reason = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`
+	result, err := r.db.Exec(query, blackout.StartDate, blackout.EndDate, blackout.Severity, blackout.Reason, blackout.ID)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления периода запрета отпусков %d: %w", blackout.ID, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества обновленных строк при обновлении периода запрета: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("период запрета отпусков %d не найден", blackout.ID)
+	}
+	if _, err := r.db.Exec(`DELETE FROM unit_blackout_exempt_positions WHERE blackout_id = ?`, blackout.ID); err != nil {
+		return fmt.Errorf("ошибка удаления должностей-исключений периода запрета %d: %w", blackout.ID, err)
+	}
+	return r.insertBlackoutExemptPositions(blackout)
+}
+
+// insertBlackoutExemptPositions сохраняет должности-исключения периода запрета
+func (r *VacationRepository) insertBlackoutExemptPositions(blackout *models.UnitBlackout) error {
+	for _, positionID := range blackout.ExemptPositionIDs {
+		if _, err := r.db.Exec(`INSERT INTO unit_blackout_exempt_positions (blackout_id, position_id) VALUES (?, ?)`, blackout.ID, positionID); err != nil {
+			return fmt.Errorf("ошибка сохранения должности-исключения %d периода запрета %d: %w", positionID, blackout.ID, err)
+		}
+	}
+	return nil
+}
+
+// DeleteUnitBlackout удаляет период запрета отпусков
+func (r *VacationRepository) DeleteUnitBlackout(blackoutID int) error {
+	result, err := r.db.Exec(`DELETE FROM unit_blackouts WHERE id = ?`, blackoutID)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления периода запрета отпусков %d: %w", blackoutID, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества удаленных строк при удалении периода запрета: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("период запрета отпусков %d не найден", blackoutID)
+	}
+	return nil
+}
@@ -27,6 +27,10 @@ type UserRepositoryInterface interface {
 	FindByOrganizationalUnitID(unitID int) ([]*models.User, error)                                       // Найти пользователей по ID орг. юнита
 	GetUsersWithLimitsByOrganizationalUnit(unitID int, year int) ([]models.UserWithLimitAdminDTO, error) // Новый метод
 	GetPositionByID(id int) (*models.Position, error)                                                    // Добавлен метод для получения должности по ID
+	// Категории преимущественного права выбора времени отпуска
+	GetPriorityCategories(userIDs []int) ([]models.UserPriorityCategory, error)
+	CreatePriorityCategory(category *models.UserPriorityCategory) error
+	DeletePriorityCategory(userID int, categoryID int) error
 	// TODO: Добавить интерфейсы для работы с OrganizationalUnit
 }
 
@@ -47,7 +51,7 @@ func (r *UserRepository) FindByLogin(login string) (*models.User, error) { // И
 		SELECT
 			u.id, u.login, u.password, u.full_name, 
 			u.organizational_unit_id, u.position_id, p.name AS position_name, 
-			u.is_admin, u.is_manager, u.created_at, u.updated_at
+			u.is_admin, u.is_manager, u.hire_date, u.created_at, u.updated_at
 		FROM users u
 		LEFT JOIN positions p ON u.position_id = p.id
 		WHERE u.login = ?` // Добавлен LEFT JOIN и выборка p.name
@@ -59,13 +63,14 @@ func (r *UserRepository) FindByLogin(login string) (*models.User, error) { // И
 	var organizationalUnitID sql.NullInt64 // departmentID -> organizationalUnitID
 	var positionID sql.NullInt64
 	var positionName sql.NullString
+	var hireDate sql.NullTime
 
 	err := row.Scan(
 		&user.ID, &user.Login, &user.Password, &user.FullName,
 		&organizationalUnitID, // Сканируем в nullable тип
 		&positionID,
 		&positionName,
-		&user.IsAdmin, &user.IsManager, &user.CreatedAt, &user.UpdatedAt,
+		&user.IsAdmin, &user.IsManager, &hireDate, &user.CreatedAt, &user.UpdatedAt,
 	)
 
 	if err != nil {
@@ -100,6 +105,10 @@ func (r *UserRepository) FindByLogin(login string) (*models.User, error) { // И
 		user.PositionName = nil // Явно устанавливаем nil, если имя должности NULL
 	}
 
+	if hireDate.Valid {
+		user.HireDate = &models.CustomDate{Time: hireDate.Time}
+	}
+
 	return user, nil
 }
 
@@ -110,7 +119,7 @@ func (r *UserRepository) FindByID(id int) (*models.User, error) {
 		SELECT
 			u.id, u.login, u.password, u.full_name, 
 			u.organizational_unit_id, u.position_id, p.name AS position_name, 
-			u.is_admin, u.is_manager, u.created_at, u.updated_at
+			u.is_admin, u.is_manager, u.hire_date, u.created_at, u.updated_at
 		FROM users u
 		LEFT JOIN positions p ON u.position_id = p.id
 		WHERE u.id = ?` // Добавлен LEFT JOIN и выборка p.name
@@ -123,6 +132,7 @@ func (r *UserRepository) FindByID(id int) (*models.User, error) {
 		organizationalUnitID sql.NullInt64 // departmentID -> organizationalUnitID
 		positionID           sql.NullInt64
 		positionName         sql.NullString
+		hireDate             sql.NullTime
 	)
 
 	err := row.Scan(
@@ -130,7 +140,7 @@ func (r *UserRepository) FindByID(id int) (*models.User, error) {
 		&organizationalUnitID, // Сканируем в nullable тип
 		&positionID,
 		&positionName,
-		&user.IsAdmin, &user.IsManager, &user.CreatedAt, &user.UpdatedAt,
+		&user.IsAdmin, &user.IsManager, &hireDate, &user.CreatedAt, &user.UpdatedAt,
 	)
 
 	if err != nil {
@@ -162,6 +172,10 @@ func (r *UserRepository) FindByID(id int) (*models.User, error) {
 		user.PositionName = nil // Явно устанавливаем nil, если имя должности NULL
 	}
 
+	if hireDate.Valid {
+		user.HireDate = &models.CustomDate{Time: hireDate.Time}
+	}
+
 	return user, nil
 }
 
@@ -222,14 +236,15 @@ func (r *UserRepository) CreateUser(user *models.User) error {
 	}
 
 	query := `
-		INSERT INTO users (login, password, full_name, organizational_unit_id, position_id, is_admin, is_manager, created_at, updated_at) 
-		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)` // email уже удален
+		INSERT INTO users (login, password, full_name, organizational_unit_id, position_id, is_admin, is_manager, hire_date, created_at, updated_at) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)` // email уже удален
 
 	result, err := r.db.Exec(query,
 		user.Login, string(hashedPassword), user.FullName,
 		user.OrganizationalUnitID, // Может быть nil
 		user.PositionID,           // Может быть nil
 		user.IsAdmin, user.IsManager,
+		user.HireDate, // Может быть nil
 	)
 	if err != nil {
 		// Обработка специфических ошибок БД (например, дубликат login) может быть добавлена здесь
@@ -328,7 +343,7 @@ func (r *UserRepository) GetAllUsersWithLimits(year int) ([]models.UserWithLimit
 		FROM users u
 		LEFT JOIN organizational_units ou ON u.organizational_unit_id = ou.id -- Добавляем JOIN для юнита
 		LEFT JOIN positions p ON u.position_id = p.id -- Добавляем JOIN для получения должности
-		LEFT JOIN vacation_limits vl ON u.id = vl.user_id AND vl.year = ?
+		LEFT JOIN vacation_balances vl ON u.id = vl.user_id AND vl.year = ?
 		ORDER BY u.full_name` // Сортируем по имени для удобства отображения
 
 	rows, err := r.db.Query(query, year)
@@ -592,7 +607,7 @@ func (r *UserRepository) GetUsersWithLimitsByOrganizationalUnit(unitID int, year
 			vl.total_days
 		FROM users u
 		LEFT JOIN positions p ON u.position_id = p.id
-		LEFT JOIN vacation_limits vl ON u.id = vl.user_id AND vl.year = ?
+		LEFT JOIN vacation_balances vl ON u.id = vl.user_id AND vl.year = ?
 		WHERE u.organizational_unit_id = ?
 		ORDER BY u.full_name ASC`
 
@@ -813,6 +828,10 @@ func (r *UserRepository) UpdateUserAdmin(userID int, updateData *models.UserUpda
 		updates = append(updates, "is_manager = ?")
 		args = append(args, *updateData.IsManager)
 	}
+	if updateData.HireDate != nil {
+		updates = append(updates, "hire_date = ?")
+		args = append(args, *updateData.HireDate)
+	}
 
 	if len(updates) == 0 {
 		return errors.New("нет полей для обновления")
@@ -859,4 +878,80 @@ func (r *UserRepository) GetPositionByID(id int) (*models.Position, error) {
 	return &position, nil
 }
 
+// GetPriorityCategories получает категории преимущественного права сотрудников (все периоды действия)
+func (r *UserRepository) GetPriorityCategories(userIDs []int) ([]models.UserPriorityCategory, error) {
+	categories := []models.UserPriorityCategory{}
+	if len(userIDs) == 0 {
+		return categories, nil
+	}
+	args := make([]interface{}, len(userIDs))
+	for i, id := range userIDs {
+		args[i] = id
+	}
+	query := `
+		SELECT id, user_id, category, start_date, end_date, comment, created_by, created_at
+		FROM user_priority_categories
+		WHERE user_id IN (?` + sqlRepeatParams(len(userIDs)-1) + `)
+		ORDER BY user_id, start_date, id`
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса категорий преимущественного права: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c models.UserPriorityCategory
+		var endDate sql.NullTime
+		var comment sql.NullString
+		var createdBy sql.NullInt64
+		if err := rows.Scan(&c.ID, &c.UserID, &c.Category, &c.StartDate, &endDate, &comment, &createdBy, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования категории преимущественного права: %w", err)
+		}
+		if endDate.Valid {
+			c.EndDate = &models.CustomDate{Time: endDate.Time}
+		}
+		c.Comment = comment.String
+		c.CreatedBy = nullIntPtr(createdBy)
+		c.CategoryName = models.PriorityCategoryNames[c.Category]
+		categories = append(categories, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по категориям преимущественного права: %w", err)
+	}
+	return categories, nil
+}
+
+// CreatePriorityCategory сохраняет категорию преимущественного права сотрудника
+func (r *UserRepository) CreatePriorityCategory(category *models.UserPriorityCategory) error {
+	query := `
+		INSERT INTO user_priority_categories (user_id, category, start_date, end_date, comment, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`
+	result, err := r.db.Exec(query, category.UserID, category.Category, category.StartDate, category.EndDate, category.Comment, category.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения категории преимущественного права: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("ошибка получения ID категории преимущественного права: %w", err)
+	}
+	category.ID = int(id)
+	return nil
+}
+
+// DeletePriorityCategory удаляет категорию преимущественного права сотрудника
+func (r *UserRepository) DeletePriorityCategory(userID int, categoryID int) error {
+	result, err := r.db.Exec(`DELETE FROM user_priority_categories WHERE id = ? AND user_id = ?`, categoryID, userID)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления категории преимущественного права %d: %w", categoryID, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества удаленных строк при удалении категории: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("категория преимущественного права %d сотрудника %d не найдена", categoryID, userID)
+	}
+	return nil
+}
+
 // TODO: Добавить репозиторий и методы для работы с organizational_units (CRUD, получение дерева)
@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"vacation-scheduler/internal/models"
+)
+
+// --- Политики отпуска ---
+
+// vacationPolicyColumns - общий список полей политики для SELECT-запросов
+const vacationPolicyColumns = `id, unit_id, position_id, annual_days, min_long_part_days, max_parts, require_exact_balance, forbid_overlap, comment, created_at, updated_at`
+
+// scanVacationPolicy сканирует строку, выбранную с полями vacationPolicyColumns
+func scanVacationPolicy(row rowScanner) (*models.VacationPolicy, error) {
+	var p models.VacationPolicy
+	var unitID, positionID, annualDays, minLongPartDays, maxParts sql.NullInt64
+	var requireExactBalance, forbidOverlap sql.NullBool
+	var comment sql.NullString
+	err := row.Scan(&p.ID, &unitID, &positionID, &annualDays, &minLongPartDays, &maxParts,
+		&requireExactBalance, &forbidOverlap, &comment, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	p.UnitID = nullIntPtr(unitID)
+	p.PositionID = nullIntPtr(positionID)
+	p.AnnualDays = nullIntPtr(annualDays)
+	p.MinLongPartDays = nullIntPtr(minLongPartDays)
+	p.MaxParts = nullIntPtr(maxParts)
+	if requireExactBalance.Valid {
+		p.RequireExactBalance = &requireExactBalance.Bool
+	}
+	if forbidOverlap.Valid {
+		p.ForbidOverlap = &forbidOverlap.Bool
+	}
+	p.Comment = comment.String
+	return &p, nil
+}
+
+// nullIntPtr преобразует sql.NullInt64 в указатель на int (nil для NULL)
+func nullIntPtr(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	i := int(v.Int64)
+	return &i
+}
+
+// GetVacationPolicies получает все политики отпуска
+func (r *VacationRepository) GetVacationPolicies() ([]models.VacationPolicy, error) {
+	rows, err := r.db.Query(`SELECT ` + vacationPolicyColumns + ` FROM vacation_policies ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса политик отпуска: %w", err)
+	}
+	defer rows.Close()
+
+	policies := []models.VacationPolicy{}
+	for rows.Next() {
+		policy, err := scanVacationPolicy(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования политики отпуска: %w", err)
+		}
+		policies = append(policies, *policy)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по политикам отпуска: %w", err)
+	}
+	return policies, nil
+}
+
+// GetVacationPolicyByID получает политику отпуска по ID. Возвращает nil, nil, если политика не найдена.
+func (r *VacationRepository) GetVacationPolicyByID(policyID int) (*models.VacationPolicy, error) {
+	policy, err := scanVacationPolicy(r.db.QueryRow(`SELECT `+vacationPolicyColumns+` FROM vacation_policies WHERE id = ?`, policyID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка получения политики отпуска %d: %w", policyID, err)
+	}
+	return policy, nil
+}
+
+// CreateVacationPolicy сохраняет новую политику отпуска
+func (r *VacationRepository) CreateVacationPolicy(policy *models.VacationPolicy) error {
+	query := `
+		INSERT INTO vacation_policies (unit_id, position_id, annual_days, min_long_part_days, max_parts, require_exact_balance, forbid_overlap, comment, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+	result, err := r.db.Exec(query, policy.UnitID, policy.PositionID, policy.AnnualDays, policy.MinLongPartDays, policy.MaxParts,
+		policy.RequireExactBalance, policy.ForbidOverlap, policy.Comment)
+	if err != nil {
+		return fmt.Errorf("ошибка создания политики отпуска: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("ошибка получения ID политики отпуска: %w", err)
+	}
+	policy.ID = int(id)
+	return nil
+}
+
+// UpdateVacationPolicy обновляет правила и область действия политики отпуска
+func (r *VacationRepository) UpdateVacationPolicy(policy *models.VacationPolicy) error {
+	query := `
+		UPDATE vacation_policies
+		SET unit_id = ?, position_id = ?, annual_days = ?, min_long_part_days = ?, max_parts = ?,
+			require_exact_balance = ?, forbid_overlap = ?, comment = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`
+	result, err := r.db.Exec(query, policy.UnitID, policy.PositionID, policy.AnnualDays, policy.MinLongPartDays, policy.MaxParts,
+		policy.RequireExactBalance, policy.ForbidOverlap, policy.Comment, policy.ID)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления политики отпуска %d: %w", policy.ID, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества обновленных строк при обновлении политики: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("политика отпуска %d не найдена", policy.ID)
+	}
+	return nil
+}
+
+// DeleteVacationPolicy удаляет политику отпуска
+func (r *VacationRepository) DeleteVacationPolicy(policyID int) error {
+	result, err := r.db.Exec(`DELETE FROM vacation_policies WHERE id = ?`, policyID)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления политики отпуска %d: %w", policyID, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества удаленных строк при удалении политики: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("политика отпуска %d не найдена", policyID)
+	}
+	return nil
+}
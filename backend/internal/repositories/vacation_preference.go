@@ -0,0 +1,315 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"vacation-scheduler/internal/models"
+)
+
+// --- Пожелания сотрудников к графику отпусков ---
+
+// vacationPreferenceSelect - SELECT пожеланий с именем и юнитом сотрудника
+const vacationPreferenceSelect = `
+	SELECT p.id, p.user_id, u.full_name, u.organizational_unit_id, p.year, p.comment, p.chosen_alternative_id, p.converted_request_id,
+		p.created_at, p.updated_at
+	FROM vacation_preferences p
+	JOIN users u ON p.user_id = u.id`
+
+// scanVacationPreference сканирует строку, выбранную запросом vacationPreferenceSelect
+func scanVacationPreference(row rowScanner) (*models.VacationPreference, error) {
+	var p models.VacationPreference
+	var unitID, chosenAlternativeID, convertedRequestID sql.NullInt64
+	var comment sql.NullString
+	err := row.Scan(&p.ID, &p.UserID, &p.UserFullName, &unitID, &p.Year, &comment, &chosenAlternativeID, &convertedRequestID,
+		&p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	p.UnitID = nullIntPtr(unitID)
+	p.Comment = comment.String
+	p.ChosenAlternativeID = nullIntPtr(chosenAlternativeID)
+	p.ConvertedRequestID = nullIntPtr(convertedRequestID)
+	p.Alternatives = []models.PreferenceAlternative{}
+	p.Constraints = []models.PreferenceConstraint{}
+	return &p, nil
+}
+
+// queryVacationPreferences выполняет выборку пожеланий вместе с вариантами, их периодами и ограничениями
+func (r *VacationRepository) queryVacationPreferences(query string, args ...interface{}) ([]models.VacationPreference, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса пожеланий к графику отпусков: %w", err)
+	}
+	defer rows.Close()
+
+	preferences := []models.VacationPreference{}
+	for rows.Next() {
+		p, err := scanVacationPreference(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования пожелания к графику отпусков: %w", err)
+		}
+		preferences = append(preferences, *p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по пожеланиям к графику отпусков: %w", err)
+	}
+	if len(preferences) == 0 {
+		return preferences, nil
+	}
+
+	preferenceIDs := make([]interface{}, len(preferences))
+	index := make(map[int]int, len(preferences))
+	for i, p := range preferences {
+		preferenceIDs[i] = p.ID
+		index[p.ID] = i
+	}
+	if err := r.loadPreferenceAlternatives(preferences, index, preferenceIDs); err != nil {
+		return nil, err
+	}
+	if err := r.loadPreferenceConstraints(preferences, index, preferenceIDs); err != nil {
+		return nil, err
+	}
+	return preferences, nil
+}
+
+// loadPreferenceAlternatives загружает варианты пожеланий (по приоритету) и их периоды
+func (r *VacationRepository) loadPreferenceAlternatives(preferences []models.VacationPreference, index map[int]int, preferenceIDs []interface{}) error {
+	altRows, err := r.db.Query("SELECT id, preference_id, `rank` FROM vacation_preference_alternatives WHERE preference_id IN (?"+sqlRepeatParams(len(preferenceIDs)-1)+") ORDER BY preference_id, `rank`", preferenceIDs...)
+	if err != nil {
+		return fmt.Errorf("ошибка запроса вариантов пожеланий: %w", err)
+	}
+	defer altRows.Close()
+
+	type alternativeRef struct{ preference, alternative int }
+	refs := map[int]alternativeRef{}
+	alternativeIDs := []interface{}{}
+	for altRows.Next() {
+		var alt models.PreferenceAlternative
+		var preferenceID int
+		if err := altRows.Scan(&alt.ID, &preferenceID, &alt.Rank); err != nil {
+			return fmt.Errorf("ошибка сканирования варианта пожелания: %w", err)
+		}
+		i, ok := index[preferenceID]
+		if !ok {
+			continue
+		}
+		alt.Periods = []models.VacationPeriod{}
+		alt.UnmetSoftConstraints = []string{}
+		preferences[i].Alternatives = append(preferences[i].Alternatives, alt)
+		refs[alt.ID] = alternativeRef{preference: i, alternative: len(preferences[i].Alternatives) - 1}
+		alternativeIDs = append(alternativeIDs, alt.ID)
+	}
+	if err = altRows.Err(); err != nil {
+		return fmt.Errorf("ошибка итерации по вариантам пожеланий: %w", err)
+	}
+	if len(alternativeIDs) == 0 {
+		return nil
+	}
+
+	periodRows, err := r.db.Query(`
+		SELECT id, alternative_id, leave_type_id, start_date, end_date
+		FROM vacation_preference_periods
+		WHERE alternative_id IN (?`+sqlRepeatParams(len(alternativeIDs)-1)+`)
+		ORDER BY alternative_id, start_date`, alternativeIDs...)
+	if err != nil {
+		return fmt.Errorf("ошибка запроса периодов вариантов пожеланий: %w", err)
+	}
+	defer periodRows.Close()
+	for periodRows.Next() {
+		var period models.VacationPeriod
+		var alternativeID int
+		if err := periodRows.Scan(&period.ID, &alternativeID, &period.LeaveTypeID, &period.StartDate, &period.EndDate); err != nil {
+			return fmt.Errorf("ошибка сканирования периода варианта пожелания: %w", err)
+		}
+		if ref, ok := refs[alternativeID]; ok {
+			alt := &preferences[ref.preference].Alternatives[ref.alternative]
+			alt.Periods = append(alt.Periods, period)
+		}
+	}
+	if err = periodRows.Err(); err != nil {
+		return fmt.Errorf("ошибка итерации по периодам вариантов пожеланий: %w", err)
+	}
+	return nil
+}
+
+// loadPreferenceConstraints загружает ограничения пожеланий
+func (r *VacationRepository) loadPreferenceConstraints(preferences []models.VacationPreference, index map[int]int, preferenceIDs []interface{}) error {
+	rows, err := r.db.Query(`
+		SELECT id, preference_id, kind, strength, start_date, end_date, description
+		FROM vacation_preference_constraints
+		WHERE preference_id IN (?`+sqlRepeatParams(len(preferenceIDs)-1)+`)
+		ORDER BY preference_id, start_date, id`, preferenceIDs...)
+	if err != nil {
+		return fmt.Errorf("ошибка запроса ограничений пожеланий: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c models.PreferenceConstraint
+		var preferenceID int
+		if err := rows.Scan(&c.ID, &preferenceID, &c.Kind, &c.Strength, &c.StartDate, &c.EndDate, &c.Description); err != nil {
+			return fmt.Errorf("ошибка сканирования ограничения пожелания: %w", err)
+		}
+		if i, ok := index[preferenceID]; ok {
+			preferences[i].Constraints = append(preferences[i].Constraints, c)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("ошибка итерации по ограничениям пожеланий: %w", err)
+	}
+	return nil
+}
+
+// GetVacationPreference получает пожелание сотрудника на год. Возвращает nil, nil, если пожелания нет.
+func (r *VacationRepository) GetVacationPreference(userID int, year int) (*models.VacationPreference, error) {
+	preferences, err := r.queryVacationPreferences(vacationPreferenceSelect+` WHERE p.user_id = ? AND p.year = ?`, userID, year)
+	if err != nil {
+		return nil, err
+	}
+	if len(preferences) == 0 {
+		return nil, nil
+	}
+	return &preferences[0], nil
+}
+
+// GetVacationPreferenceByID получает пожелание по ID. Возвращает nil, nil, если пожелание не найдено.
+func (r *VacationRepository) GetVacationPreferenceByID(preferenceID int) (*models.VacationPreference, error) {
+	preferences, err := r.queryVacationPreferences(vacationPreferenceSelect+` WHERE p.id = ?`, preferenceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(preferences) == 0 {
+		return nil, nil
+	}
+	return &preferences[0], nil
+}
+
+// LockVacationPreference блокирует пожелание (SELECT ... FOR UPDATE) до конца транзакции и возвращает его.
+// Возвращает nil, nil, если пожелание не найдено.
+func (r *VacationRepository) LockVacationPreference(preferenceID int) (*models.VacationPreference, error) {
+	if r.tx == nil {
+		return nil, errors.New("блокировка пожелания возможна только внутри транзакции")
+	}
+	preferences, err := r.queryVacationPreferences(vacationPreferenceSelect+` WHERE p.id = ? FOR UPDATE`, preferenceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(preferences) == 0 {
+		return nil, nil
+	}
+	return &preferences[0], nil
+}
+
+// GetVacationPreferencesByUsers получает пожелания сотрудников на год
+func (r *VacationRepository) GetVacationPreferencesByUsers(userIDs []int, year int) ([]models.VacationPreference, error) {
+	if len(userIDs) == 0 {
+		return []models.VacationPreference{}, nil
+	}
+	args := []interface{}{year}
+	for _, id := range userIDs {
+		args = append(args, id)
+	}
+	return r.queryVacationPreferences(vacationPreferenceSelect+`
+		WHERE p.year = ? AND p.user_id IN (?`+sqlRepeatParams(len(userIDs)-1)+`)
+		ORDER BY u.full_name, p.id`, args...)
+}
+
+// SaveVacationPreference создает пожелание сотрудника на год или заменяет варианты и ограничения существующего.
+// Строка пожелания создается или обновляется одним запросом по уникальному ключу (user_id, year) и остается
+// заблокированной до конца транзакции, поэтому параллельные сохранения не создают второе пожелание.
+// Выбор варианта и созданная по нему заявка сбрасываются.
+func (r *VacationRepository) SaveVacationPreference(preference *models.VacationPreference) error {
+	result, err := r.db.Exec(`
+		INSERT INTO vacation_preferences (user_id, year, comment, created_at, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id), comment = VALUES(comment),
+			chosen_alternative_id = NULL, converted_request_id = NULL, updated_at = CURRENT_TIMESTAMP`,
+		preference.UserID, preference.Year, preference.Comment)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения пожелания к графику отпусков: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("ошибка получения ID пожелания к графику отпусков: %w", err)
+	}
+	preference.ID = int(id)
+	if _, err := r.db.Exec(`DELETE FROM vacation_preference_alternatives WHERE preference_id = ?`, preference.ID); err != nil {
+		return fmt.Errorf("ошибка удаления вариантов пожелания %d: %w", preference.ID, err)
+	}
+	if _, err := r.db.Exec(`DELETE FROM vacation_preference_constraints WHERE preference_id = ?`, preference.ID); err != nil {
+		return fmt.Errorf("ошибка удаления ограничений пожелания %d: %w", preference.ID, err)
+	}
+	preference.ChosenAlternativeID = nil
+	preference.ConvertedRequestID = nil
+
+	for i := range preference.Alternatives {
+		alt := &preference.Alternatives[i]
+		result, err := r.db.Exec("INSERT INTO vacation_preference_alternatives (preference_id, `rank`) VALUES (?, ?)", preference.ID, alt.Rank)
+		if err != nil {
+			return fmt.Errorf("ошибка сохранения варианта %d пожелания %d: %w", alt.Rank, preference.ID, err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("ошибка получения ID варианта пожелания: %w", err)
+		}
+		alt.ID = int(id)
+		for j := range alt.Periods {
+			period := &alt.Periods[j]
+			result, err := r.db.Exec(`
+				INSERT INTO vacation_preference_periods (alternative_id, leave_type_id, start_date, end_date)
+				VALUES (?, ?, ?, ?)`, alt.ID, period.LeaveTypeID, period.StartDate, period.EndDate)
+			if err != nil {
+				return fmt.Errorf("ошибка сохранения периода варианта %d пожелания %d: %w", alt.Rank, preference.ID, err)
+			}
+			id, err := result.LastInsertId()
+			if err != nil {
+				return fmt.Errorf("ошибка получения ID периода варианта пожелания: %w", err)
+			}
+			period.ID = int(id)
+		}
+	}
+	for i := range preference.Constraints {
+		c := &preference.Constraints[i]
+		result, err := r.db.Exec(`
+			INSERT INTO vacation_preference_constraints (preference_id, kind, strength, start_date, end_date, description)
+			VALUES (?, ?, ?, ?, ?, ?)`, preference.ID, c.Kind, c.Strength, c.StartDate, c.EndDate, c.Description)
+		if err != nil {
+			return fmt.Errorf("ошибка сохранения ограничения пожелания %d: %w", preference.ID, err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("ошибка получения ID ограничения пожелания: %w", err)
+		}
+		c.ID = int(id)
+	}
+	return nil
+}
+
+// SetPreferenceConversion отмечает вариант пожелания, по которому создана заявка
+func (r *VacationRepository) SetPreferenceConversion(preferenceID int, alternativeID int, requestID int) error {
+	_, err := r.db.Exec(`
+		UPDATE vacation_preferences
+		SET chosen_alternative_id = ?, converted_request_id = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`, alternativeID, requestID, preferenceID)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения выбранного варианта пожелания %d: %w", preferenceID, err)
+	}
+	return nil
+}
+
+// DeleteVacationPreference удаляет пожелание вместе с вариантами и ограничениями
+func (r *VacationRepository) DeleteVacationPreference(preferenceID int) error {
+	result, err := r.db.Exec(`DELETE FROM vacation_preferences WHERE id = ?`, preferenceID)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления пожелания %d: %w", preferenceID, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества удаленных строк при удалении пожелания: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("пожелание %d не найдено", preferenceID)
+	}
+	return nil
+}
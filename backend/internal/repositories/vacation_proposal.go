@@ -0,0 +1,153 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"vacation-scheduler/internal/models"
+)
+
+// --- Предложения изменить даты заявки ---
+
+// requestProposalSelect - SELECT предложений с ФИО предложившего
+const requestProposalSelect = `
+	SELECT p.id, p.request_id, p.proposed_by, u.full_name, p.comment, p.status, p.response_comment, p.responded_by, p.responded_at, p.created_at
+	FROM vacation_request_proposals p
+	LEFT JOIN users u ON p.proposed_by = u.id`
+
+// scanRequestProposal сканирует строку, выбранную запросом requestProposalSelect
+func scanRequestProposal(row rowScanner) (*models.VacationRequestProposal, error) {
+	var p models.VacationRequestProposal
+	var proposedBy, respondedBy sql.NullInt64
+	var proposedByName, comment, responseComment sql.NullString
+	var respondedAt sql.NullTime
+	err := row.Scan(&p.ID, &p.RequestID, &proposedBy, &proposedByName, &comment, &p.Status, &responseComment, &respondedBy, &respondedAt, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	p.ProposedBy = nullIntPtr(proposedBy)
+	p.ProposedByName = proposedByName.String
+	p.Comment = comment.String
+	p.ResponseComment = responseComment.String
+	p.RespondedBy = nullIntPtr(respondedBy)
+	if respondedAt.Valid {
+		responded := respondedAt.Time
+		p.RespondedAt = &responded
+	}
+	p.Periods = []models.VacationPeriod{}
+	return &p, nil
+}
+
+// queryRequestProposals выполняет выборку предложений вместе с предложенными периодами
+func (r *VacationRepository) queryRequestProposals(query string, args ...interface{}) ([]models.VacationRequestProposal, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса предложений по заявке: %w", err)
+	}
+	defer rows.Close()
+
+	proposals := []models.VacationRequestProposal{}
+	for rows.Next() {
+		p, err := scanRequestProposal(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования предложения по заявке: %w", err)
+		}
+		proposals = append(proposals, *p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по предложениям по заявке: %w", err)
+	}
+	if len(proposals) == 0 {
+		return proposals, nil
+	}
+
+	proposalIDs := make([]interface{}, len(proposals))
+	index := make(map[int]int, len(proposals))
+	for i, p := range proposals {
+		proposalIDs[i] = p.ID
+		index[p.ID] = i
+	}
+	periodRows, err := r.db.Query(`SELECT proposal_id, leave_type_id, start_date, end_date, days_count FROM vacation_request_proposal_periods WHERE proposal_id IN (?`+sqlRepeatParams(len(proposalIDs)-1)+`) ORDER BY proposal_id, start_date`, proposalIDs...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса предложенных периодов: %w", err)
+	}
+	defer periodRows.Close()
+	for periodRows.Next() {
+		var proposalID int
+		var period models.VacationPeriod
+		if err := periodRows.Scan(&proposalID, &period.LeaveTypeID, &period.StartDate, &period.EndDate, &period.DaysCount); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования предложенного периода: %w", err)
+		}
+		if i, ok := index[proposalID]; ok {
+			period.RequestID = proposals[i].RequestID
+			proposals[i].Periods = append(proposals[i].Periods, period)
+		}
+	}
+	if err = periodRows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по предложенным периодам: %w", err)
+	}
+	return proposals, nil
+}
+
+// GetRequestProposals получает все предложения по заявке, от ранних к поздним
+func (r *VacationRepository) GetRequestProposals(requestID int) ([]models.VacationRequestProposal, error) {
+	return r.queryRequestProposals(requestProposalSelect+` WHERE p.request_id = ? ORDER BY p.created_at, p.id`, requestID)
+}
+
+// GetOpenRequestProposal получает предложение по заявке, ожидающее ответа. Возвращает nil, nil, если его нет.
+func (r *VacationRepository) GetOpenRequestProposal(requestID int) (*models.VacationRequestProposal, error) {
+	proposals, err := r.queryRequestProposals(requestProposalSelect+` WHERE p.request_id = ? AND p.status = ? ORDER BY p.id DESC LIMIT 1`,
+		requestID, models.ProposalStatusOpen)
+	if err != nil {
+		return nil, err
+	}
+	if len(proposals) == 0 {
+		return nil, nil
+	}
+	return &proposals[0], nil
+}
+
+// CreateRequestProposal сохраняет предложение и предложенные периоды
+func (r *VacationRepository) CreateRequestProposal(proposal *models.VacationRequestProposal) error {
+	query := `
+		INSERT INTO vacation_request_proposals (request_id, proposed_by, comment, status, created_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)`
+	result, err := r.db.Exec(query, proposal.RequestID, proposal.ProposedBy, proposal.Comment, proposal.Status)
+	if err != nil {
+		return fmt.Errorf("ошибка создания предложения по заявке %d: %w", proposal.RequestID, err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("ошибка получения ID предложения по заявке: %w", err)
+	}
+	proposal.ID = int(id)
+
+	for _, period := range proposal.Periods {
+		_, err := r.db.Exec(`INSERT INTO vacation_request_proposal_periods (proposal_id, leave_type_id, start_date, end_date, days_count) VALUES (?, ?, ?, ?, ?)`,
+			proposal.ID, period.LeaveTypeID, period.StartDate, period.EndDate, period.DaysCount)
+		if err != nil {
+			return fmt.Errorf("ошибка сохранения предложенного периода: %w", err)
+		}
+	}
+	return nil
+}
+
+// SetRequestProposalStatus фиксирует ответ на предложение (или его закрытие при отмене заявки)
+func (r *VacationRepository) SetRequestProposalStatus(proposalID int, status string, respondedBy *int, comment string) error {
+	query := `
+		UPDATE vacation_request_proposals
+		SET status = ?, responded_by = ?, response_comment = ?, responded_at = CURRENT_TIMESTAMP
+		WHERE id = ?`
+	result, err := r.db.Exec(query, status, respondedBy, comment, proposalID)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления предложения %d: %w", proposalID, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества обновленных строк при обновлении предложения: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("предложение %d не найдено", proposalID)
+	}
+	return nil
+}
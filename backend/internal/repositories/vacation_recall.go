@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"vacation-scheduler/internal/models"
+)
+
+// --- Отзыв из отпуска ---
+
+// CreateVacationRecall сохраняет запись об отзыве сотрудника из отпуска
+func (r *VacationRepository) CreateVacationRecall(recall *models.VacationRecall) error {
+	query := `
+		INSERT INTO vacation_recalls (request_id, period_id, recall_date, original_end_date, unused_days, employee_consent, reason, recalled_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`
+	result, err := r.db.Exec(query, recall.RequestID, recall.PeriodID, recall.RecallDate, recall.OriginalEndDate,
+		recall.UnusedDays, recall.EmployeeConsent, recall.Reason, recall.RecalledBy)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения отзыва из отпуска: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("ошибка получения ID отзыва из отпуска: %w", err)
+	}
+	recall.ID = int(id)
+	return nil
+}
+
+// GetVacationRecallsByPeriodIDs получает отзывы из отпуска по списку периодов
+func (r *VacationRepository) GetVacationRecallsByPeriodIDs(periodIDs []int) ([]models.VacationRecall, error) {
+	if len(periodIDs) == 0 {
+		return []models.VacationRecall{}, nil
+	}
+	query := fmt.Sprintf(`
+		SELECT id, request_id, period_id, recall_date, original_end_date, unused_days, employee_consent, reason, recalled_by, created_at
+		FROM vacation_recalls
+		WHERE period_id IN (?%s)
+		ORDER BY created_at ASC, id ASC`, sqlRepeatParams(len(periodIDs)-1))
+	args := make([]interface{}, len(periodIDs))
+	for i, id := range periodIDs {
+		args[i] = id
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса отзывов из отпуска: %w", err)
+	}
+	defer rows.Close()
+
+	recalls := []models.VacationRecall{}
+	for rows.Next() {
+		var recall models.VacationRecall
+		var reason sql.NullString
+		var recalledBy sql.NullInt64
+		if err := rows.Scan(&recall.ID, &recall.RequestID, &recall.PeriodID, &recall.RecallDate, &recall.OriginalEndDate,
+			&recall.UnusedDays, &recall.EmployeeConsent, &reason, &recalledBy, &recall.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования отзыва из отпуска: %w", err)
+		}
+		recall.Reason = reason.String
+		if recalledBy.Valid {
+			id := int(recalledBy.Int64)
+			recall.RecalledBy = &id
+		}
+		recalls = append(recalls, recall)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по отзывам из отпуска: %w", err)
+	}
+	return recalls, nil
+}
+
+// UpdateRequestDaysRequested обновляет количество дней заявки (после изменения ее периодов)
+func (r *VacationRepository) UpdateRequestDaysRequested(requestID int, daysRequested int) error {
+	query := `UPDATE vacation_requests SET days_requested = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	if _, err := r.db.Exec(query, daysRequested, requestID); err != nil {
+		return fmt.Errorf("ошибка обновления количества дней заявки %d: %w", requestID, err)
+	}
+	return nil
+}
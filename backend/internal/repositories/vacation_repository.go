@@ -19,53 +19,173 @@ var ErrLimitNotFound = errors.New("лимит отпуска не найден 
 type VacationRepositoryInterface interface {
 	// --- Лимиты ---
 	GetVacationLimit(userID int, year int) (*models.VacationLimit, error)
-	CreateOrUpdateVacationLimit(userID int, year int, totalDays int) error
-	UpdateVacationLimitUsedDays(userID int, year int, daysDelta int) error // Добавлен метод для изменения использованных дней
+	CreateOrUpdateVacationLimit(userID int, year int, totalDays int, actorID *int, comment string) error
+
+	// --- Журнал движения дней ---
+	AddLedgerEntry(entry *models.LeaveLedgerEntry) error
+	GetLedgerEntries(userID int, year int) ([]models.LeaveLedgerEntry, error)
+	GetRequestLedgerTotals(requestID int) (*models.RequestLedgerTotals, error)
+
+	// --- Переход года ---
+	GetUserIDsWithLimits(year int) ([]int, error)
+	IsYearRolledOver(userID int, year int) (bool, error)
+	SaveYearRollover(rollover *models.YearRollover) error
+
+	// --- Транзакции и блокировки ---
+	RunInTx(fn func(tx VacationRepositoryInterface) error) error
+	LockVacationRequest(requestID int) (*models.VacationRequest, error) // SELECT ... FOR UPDATE, только внутри RunInTx
+	LockVacationLimit(userID int, year int) error                       // SELECT ... FOR UPDATE, только внутри RunInTx
+	LockPosition(positionID int) error                                  // SELECT ... FOR UPDATE, только внутри RunInTx
+	LockCoverageRules(ruleIDs []int) error                              // SELECT ... FOR UPDATE, только внутри RunInTx
 
 	// --- Заявки ---
 	GetVacationRequestByID(requestID int) (*models.VacationRequest, error) // Добавлен метод получения заявки по ID
 	SaveVacationRequest(request *models.VacationRequest) error
-	UpdateVacationRequest(request *models.VacationRequest) error // Для обновления комментария и т.д. пользователем
+	UpdateVacationRequest(request *models.VacationRequest) error // Замена периодов, количества дней и комментария заявки
 	UpdateRequestStatusByID(requestID int, newStatusID int) error
+	SetRequestRejectionReason(requestID int, reason string) error
+	SetRequestCancellationReason(requestID int, reason string) error
 	GetVacationRequestsByUser(userID int, year int, statusFilter *int) ([]models.VacationRequest, error)
 	GetVacationRequestsByOrganizationalUnit(unitID int, year int, statusFilter *int) ([]models.VacationRequest, error)
 	// Изменен тип unitIDsFilter на []int
 	GetAllVacationRequests(yearFilter *int, statusFilter *int, userIDFilter *int, unitIDsFilter []int) ([]models.VacationRequestAdminView, error)
 
+	// --- История переходов ---
+	AddRequestTransition(transition *models.VacationRequestTransition) error
+	GetRequestTransitions(requestID int) ([]models.VacationRequestTransition, error)
+	GetRequestIDsDueForStart(date time.Time) ([]int, error)
+	GetRequestIDsDueForCompletion(date time.Time) ([]int, error)
+
+	// --- Переносы отпуска ---
+	GetVacationPeriodByID(periodID int) (*models.VacationPeriod, error)
+	UpdateVacationPeriodDates(periodID int, startDate models.CustomDate, endDate models.CustomDate, daysCount int) error
+	CreateVacationTransfer(transfer *models.VacationTransfer) error
+	LockVacationTransfer(transferID int) (*models.VacationTransfer, error) // SELECT ... FOR UPDATE, только внутри RunInTx
+	HasPendingVacationTransfer(periodID int) (bool, error)
+	SetVacationTransferDecision(transferID int, statusID int, decidedBy int, comment string) error
+	GetVacationTransfers(userIDFilter *int, statusFilter *int, unitIDsFilter []int) ([]models.VacationTransfer, error)
+	GetApprovedTransfersByPeriodIDs(periodIDs []int) ([]models.VacationTransfer, error)
+
+	// --- Отзыв из отпуска ---
+	CreateVacationRecall(recall *models.VacationRecall) error
+	GetVacationRecallsByPeriodIDs(periodIDs []int) ([]models.VacationRecall, error)
+	UpdateRequestDaysRequested(requestID int, daysRequested int) error
+
+	// --- Виды отпуска ---
+	GetLeaveTypes() ([]models.LeaveType, error)
+	GetLeaveTypeByID(leaveTypeID int) (*models.LeaveType, error)
+	CreateLeaveType(leaveType *models.LeaveType) error
+	UpdateLeaveType(leaveType *models.LeaveType) error
+	GetLeaveTypeDaysUsed(userID int, year int, excludeRequestID int) (map[int]int, error) // Дни по видам отпуска в действующих заявках за год
+
+	// --- Политики отпуска ---
+	GetVacationPolicies() ([]models.VacationPolicy, error)
+	GetVacationPolicyByID(policyID int) (*models.VacationPolicy, error)
+	CreateVacationPolicy(policy *models.VacationPolicy) error
+	UpdateVacationPolicy(policy *models.VacationPolicy) error
+	DeleteVacationPolicy(policyID int) error
+
+	// --- Цепочка согласования ---
+	ReplaceApprovalSteps(requestID int, steps []models.ApprovalStep) error
+	SetApprovalStepDecision(stepID int, status string, decidedBy int, comment string) error
+
+	// --- Делегирование прав руководителя ---
+	GetApprovalDelegations(userIDFilter *int) ([]models.ApprovalDelegation, error)
+	GetActiveDelegationsForDelegate(delegateID int, date time.Time) ([]models.ApprovalDelegation, error)
+	GetApprovalDelegationByID(delegationID int) (*models.ApprovalDelegation, error)
+	CreateApprovalDelegation(delegation *models.ApprovalDelegation) error
+	RevokeApprovalDelegation(delegationID int) error
+	DeleteRequestDelegations(requestID int) error
+
+	// --- Правила укомплектованности ---
+	GetCoverageRules() ([]models.CoverageRule, error)
+	GetCoverageRulesByUnitIDs(unitIDs []int) ([]models.CoverageRule, error)
+	GetCoverageRuleByID(ruleID int) (*models.CoverageRule, error)
+	CreateCoverageRule(rule *models.CoverageRule) error
+	UpdateCoverageRule(rule *models.CoverageRule) error
+	DeleteCoverageRule(ruleID int) error
+	GetApprovedAbsences(userIDs []int, excludeRequestID int, startDate time.Time, endDate time.Time) ([]models.StaffAbsence, error)
+	GetApprovedAbsencesByPosition(positionID int, startDate time.Time, endDate time.Time) ([]models.StaffAbsence, error)
+	GetPendingAbsencesByPosition(positionID int, startDate time.Time, endDate time.Time) ([]models.StaffAbsence, error)
+
+	// --- Периоды запрета отпусков ---
+	GetUnitBlackoutsByUnitIDs(unitIDs []int) ([]models.UnitBlackout, error)
+	GetUnitBlackoutByID(blackoutID int) (*models.UnitBlackout, error)
+	CreateUnitBlackout(blackout *models.UnitBlackout) error
+	UpdateUnitBlackout(blackout *models.UnitBlackout) error
+	DeleteUnitBlackout(blackoutID int) error
+
+	// --- Кампании планирования графика отпусков ---
+	GetPlanningCampaigns(yearFilter *int) ([]models.PlanningCampaign, error)
+	GetPlanningCampaignByID(campaignID int) (*models.PlanningCampaign, error)
+	CreatePlanningCampaign(campaign *models.PlanningCampaign) error
+	UpdatePlanningCampaign(campaign *models.PlanningCampaign) error
+	CountActiveRequestsByUser(userIDs []int, year int) (map[int]int, error)
+
+	// --- Предложения изменить даты заявки ---
+	GetRequestProposals(requestID int) ([]models.VacationRequestProposal, error)
+	GetOpenRequestProposal(requestID int) (*models.VacationRequestProposal, error)
+	CreateRequestProposal(proposal *models.VacationRequestProposal) error
+	SetRequestProposalStatus(proposalID int, status string, respondedBy *int, comment string) error
+
+	// --- Пиковые периоды отпусков ---
+	GetPeakWindows() ([]models.PeakWindow, error)
+	GetPeakWindowByID(windowID int) (*models.PeakWindow, error)
+	CreatePeakWindow(window *models.PeakWindow) error
+	UpdatePeakWindow(window *models.PeakWindow) error
+	DeletePeakWindow(windowID int) error
+
+	// --- Пожелания сотрудников к графику отпусков ---
+	GetVacationPreference(userID int, year int) (*models.VacationPreference, error)
+	GetVacationPreferenceByID(preferenceID int) (*models.VacationPreference, error)
+	LockVacationPreference(preferenceID int) (*models.VacationPreference, error)
+	GetVacationPreferencesByUsers(userIDs []int, year int) ([]models.VacationPreference, error)
+	SaveVacationPreference(preference *models.VacationPreference) error
+	SetPreferenceConversion(preferenceID int, alternativeID int, requestID int) error
+	DeleteVacationPreference(preferenceID int) error
+
 	// --- Уведомления ---
 	CreateNotification(notification *models.Notification) error
 
+	// --- Проверка конфликтов ---
+	GetUserPositionByID(userID int) (*int, error)
+	GetApprovedVacationConflictsByPosition(positionID int, excludeUserID int, periodsToCheck []models.VacationPeriod) ([]models.ConflictingPeriod, error)
+
 	// --- Dashboard Data ---
-	CountPendingRequestsByUnitIDs(unitIDs []int) (int, error)
+	CountPendingRequestsForApprover(approverID int, unitIDs []int) (int, error)
 	SumRequestedDaysByStatusAndUnitIDs(unitIDs []int, statusIDs []int, year int) (int, error) // Новый метод для суммирования дней
 	GetUpcomingApprovedConflictsByUnitIDs(unitIDs []int, startDate time.Time, endDate time.Time) ([]models.ConflictingPeriod, error)
 }
 
 // VacationRepository предоставляет методы для работы с данными отпусков в БД
 type VacationRepository struct {
-	db *sql.DB
+	db   dbExecutor // *sql.DB или *sql.Tx внутри RunInTx
+	conn *sql.DB    // Подключение для открытия транзакций
+	tx   *sql.Tx    // Текущая транзакция (nil вне RunInTx)
 }
 
 // NewVacationRepository создает новый экземпляр VacationRepository
 func NewVacationRepository(db *sql.DB) *VacationRepository {
-	return &VacationRepository{db: db}
+	return &VacationRepository{db: db, conn: db}
 }
 
 // --- Лимиты ---
 
-// GetVacationLimit получает лимит отпуска для пользователя на указанный год
+// GetVacationLimit получает баланс отпуска пользователя на указанный год (вычисляется по журналу)
 func (r *VacationRepository) GetVacationLimit(userID int, year int) (*models.VacationLimit, error) {
 	query := `
-		SELECT id, user_id, year, total_days, used_days, created_at, updated_at
-		FROM vacation_limits
+		SELECT limit_id, user_id, year, total_days, carried_over_days, carried_out_days, expired_days,
+			used_days, reserved_days, consumed_days, available_days, created_at, updated_at
+		FROM vacation_balances
 		WHERE user_id = ? AND year = ?`
 
 	row := r.db.QueryRow(query, userID, year)
 	limit := &models.VacationLimit{}
 
 	err := row.Scan(
-		&limit.ID, &limit.UserID, &limit.Year, &limit.TotalDays,
-		&limit.UsedDays, &limit.CreatedAt, &limit.UpdatedAt,
+		&limit.ID, &limit.UserID, &limit.Year, &limit.TotalDays, &limit.CarriedOver, &limit.CarriedOut, &limit.ExpiredDays, &limit.UsedDays,
+		&limit.ReservedDays, &limit.ConsumedDays, &limit.AvailableDays,
+		&limit.CreatedAt, &limit.UpdatedAt,
 	)
 
 	if err != nil {
@@ -78,148 +198,273 @@ func (r *VacationRepository) GetVacationLimit(userID int, year int) (*models.Vac
 	return limit, nil
 }
 
-// CreateOrUpdateVacationLimit создает или обновляет лимит отпуска для пользователя
-func (r *VacationRepository) CreateOrUpdateVacationLimit(userID int, year int, totalDays int) error {
-	query := `
-		INSERT INTO vacation_limits (user_id, year, total_days, used_days, created_at, updated_at)
-		VALUES (?, ?, ?, 0, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
-		ON DUPLICATE KEY UPDATE
-			total_days = VALUES(total_days),
-			used_days = 0,
-			updated_at = CURRENT_TIMESTAMP`
-
-	_, err := r.db.Exec(query, userID, year, totalDays)
+// CreateOrUpdateVacationLimit устанавливает количество начисленных дней на год.
+// Текущее начисление не перезаписывается: в журнал добавляется начисление (для нового лимита)
+// или корректировка на разницу между новым и текущим значением.
+func (r *VacationRepository) CreateOrUpdateVacationLimit(userID int, year int, totalDays int, actorID *int, comment string) error {
+	return r.inTx(func(tx *sql.Tx) error {
+		if err := ensureVacationLimitTx(tx, userID, year); err != nil {
+			return err
+		}
+
+		var entriesCount, currentTotal int
+		queryTotal := `
+			SELECT COUNT(*), COALESCE(SUM(days), 0)
+			FROM leave_ledger_entries
+			WHERE user_id = ? AND year = ? AND entry_type IN (?, ?)`
+		if err := tx.QueryRow(queryTotal, userID, year, models.LedgerEntryAccrual, models.LedgerEntryAdjustment).Scan(&entriesCount, &currentTotal); err != nil {
+			return fmt.Errorf("ошибка получения текущего начисления (user: %d, year: %d): %w", userID, year, err)
+		}
+
+		entryType := models.LedgerEntryAdjustment
+		if entriesCount == 0 {
+			entryType = models.LedgerEntryAccrual
+		}
+		delta := totalDays - currentTotal
+		if delta == 0 && entryType == models.LedgerEntryAdjustment {
+			log.Printf("[Repo CreateOrUpdateVacationLimit] Limit unchanged. UserID: %d, Year: %d, TotalDays: %d", userID, year, totalDays)
+			return nil
+		}
+
+		entry := &models.LeaveLedgerEntry{
+			UserID: userID, Year: year, EntryType: entryType, Days: delta,
+			ActorID: actorID, Comment: comment,
+		}
+		if err := insertLedgerEntryTx(tx, entry); err != nil {
+			return err
+		}
+		log.Printf("[Repo CreateOrUpdateVacationLimit] %s %+d days. UserID: %d, Year: %d, TotalDays: %d", entryType, delta, userID, year, totalDays)
+		return nil
+	})
+}
+
+// --- Журнал движения дней ---
+
+// AddLedgerEntry добавляет запись в журнал движения дней отпуска (создает счет на год при необходимости)
+func (r *VacationRepository) AddLedgerEntry(entry *models.LeaveLedgerEntry) error {
+	err := r.inTx(func(tx *sql.Tx) error {
+		if err := ensureVacationLimitTx(tx, entry.UserID, entry.Year); err != nil {
+			return err
+		}
+		return insertLedgerEntryTx(tx, entry)
+	})
 	if err != nil {
-		return fmt.Errorf("ошибка создания/обновления лимита отпуска: %w", err)
+		return err
 	}
+	log.Printf("[Repo AddLedgerEntry] %s %+d days. UserID: %d, Year: %d, RequestID: %v", entry.EntryType, entry.Days, entry.UserID, entry.Year, entry.RequestID)
 	return nil
 }
 
-// UpdateVacationLimitUsedDays атомарно обновляет использованные дни для СУЩЕСТВУЮЩЕГО лимита.
-func (r *VacationRepository) UpdateVacationLimitUsedDays(userID int, year int, daysDelta int) error {
-	log.Printf("[Repo UpdateUsedDays] Attempting update. UserID: %d, Year: %d, Delta: %d", userID, year, daysDelta) // LOGGING
+// GetLedgerEntries получает записи журнала пользователя за год в хронологическом порядке
+func (r *VacationRepository) GetLedgerEntries(userID int, year int) ([]models.LeaveLedgerEntry, error) {
 	query := `
-		UPDATE vacation_limits
-		SET
-			used_days = GREATEST(0, used_days + ?), -- Используем параметр (?) для UPDATE
-			updated_at = CURRENT_TIMESTAMP
-		WHERE user_id = ? AND year = ?`
+		SELECT le.id, le.user_id, le.year, le.entry_type, le.days, le.request_id, le.origin_year, le.actor_id, u.full_name, le.comment, le.created_at
+		FROM leave_ledger_entries le
+		LEFT JOIN users u ON le.actor_id = u.id
+		WHERE le.user_id = ? AND le.year = ?
+		ORDER BY le.created_at ASC, le.id ASC`
 
-	result, err := r.db.Exec(query, daysDelta, userID, year)
+	rows, err := r.db.Query(query, userID, year)
 	if err != nil {
-		log.Printf("[Repo UpdateUsedDays] DB Exec Error. UserID: %d, Year: %d, Delta: %d, Error: %v", userID, year, daysDelta, err) // LOGGING
-		return fmt.Errorf("ошибка обновления used_days (user: %d, year: %d, delta: %d): %w", userID, year, daysDelta, err)
+		return nil, fmt.Errorf("ошибка запроса журнала отпусков пользователя %d за %d год: %w", userID, year, err)
 	}
+	defer rows.Close()
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		log.Printf("[Repo UpdateUsedDays] Error getting rows affected. UserID: %d, Year: %d, Delta: %d, Error: %v", userID, year, daysDelta, err) // LOGGING
-		return fmt.Errorf("ошибка получения кол-ва строк при обновлении used_days (user: %d, year: %d, delta: %d): %w", userID, year, daysDelta, err)
+	entries := []models.LeaveLedgerEntry{}
+	for rows.Next() {
+		var entry models.LeaveLedgerEntry
+		var requestID, originYear, actorID sql.NullInt64
+		var actorName, comment sql.NullString
+		if err := rows.Scan(&entry.ID, &entry.UserID, &entry.Year, &entry.EntryType, &entry.Days, &requestID, &originYear, &actorID, &actorName, &comment, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования записи журнала отпусков: %w", err)
+		}
+		if requestID.Valid {
+			id := int(requestID.Int64)
+			entry.RequestID = &id
+		}
+		if originYear.Valid {
+			year := int(originYear.Int64)
+			entry.OriginYear = &year
+		}
+		if actorID.Valid {
+			id := int(actorID.Int64)
+			entry.ActorID = &id
+		}
+		if actorName.Valid {
+			name := actorName.String
+			entry.ActorFullName = &name
+		}
+		if comment.Valid {
+			entry.Comment = comment.String
+		}
+		entries = append(entries, entry)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по журналу отпусков: %w", err)
+	}
+	return entries, nil
+}
 
-	if rowsAffected == 0 {
-		log.Printf("[Repo UpdateUsedDays] Update failed - Limit not found. UserID: %d, Year: %d", userID, year) // LOGGING
-		return errors.New("лимит отпуска не найден для данного пользователя и года")
+// GetRequestLedgerTotals получает текущий резерв и списание по заявке
+func (r *VacationRepository) GetRequestLedgerTotals(requestID int) (*models.RequestLedgerTotals, error) {
+	query := `
+		SELECT
+			COALESCE(-SUM(CASE WHEN entry_type = ? THEN days END), 0),
+			COALESCE(-SUM(CASE WHEN entry_type IN (?, ?) THEN days END), 0)
+		FROM leave_ledger_entries
+		WHERE request_id = ?`
+
+	totals := &models.RequestLedgerTotals{}
+	err := r.db.QueryRow(query, models.LedgerEntryReservation, models.LedgerEntryConsumption, models.LedgerEntryRefund, requestID).
+		Scan(&totals.ReservedDays, &totals.ConsumedDays)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения итогов журнала по заявке %d: %w", requestID, err)
 	}
-	log.Printf("[Repo UpdateUsedDays] DB Exec Success. UserID: %d, Year: %d, Delta: %d", userID, year, daysDelta) // LOGGING
+	return totals, nil
+}
 
-	// READ-AFTER-WRITE CHECK
-	var currentUsedDays int
-	checkQuery := "SELECT used_days FROM vacation_limits WHERE user_id = ? AND year = ?"
-	checkErr := r.db.QueryRow(checkQuery, userID, year).Scan(&currentUsedDays)
-	if checkErr != nil {
-		log.Printf("[Repo UpdateUsedDays] Read-after-write CHECK FAILED. UserID: %d, Year: %d, Error: %v", userID, year, checkErr)
-	} else {
-		log.Printf("[Repo UpdateUsedDays] Read-after-write CHECK PASSED. UserID: %d, Year: %d, CurrentUsedDaysInDB: %d", userID, year, currentUsedDays)
+// ensureVacationLimitTx создает счет (лимит) пользователя на год, если его еще нет
+func ensureVacationLimitTx(tx *sql.Tx, userID int, year int) error {
+	query := `
+		INSERT INTO vacation_limits (user_id, year, created_at, updated_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON DUPLICATE KEY UPDATE updated_at = CURRENT_TIMESTAMP`
+	if _, err := tx.Exec(query, userID, year); err != nil {
+		return fmt.Errorf("ошибка создания лимита отпуска (user: %d, year: %d): %w", userID, year, err)
 	}
+	return nil
+}
 
-	return nil // Успешно
+// insertLedgerEntryTx добавляет запись журнала в рамках транзакции
+func insertLedgerEntryTx(tx *sql.Tx, entry *models.LeaveLedgerEntry) error {
+	query := `
+		INSERT INTO leave_ledger_entries (user_id, year, entry_type, days, request_id, origin_year, actor_id, comment, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`
+	result, err := tx.Exec(query, entry.UserID, entry.Year, entry.EntryType, entry.Days, entry.RequestID, entry.OriginYear, entry.ActorID, entry.Comment)
+	if err != nil {
+		return fmt.Errorf("ошибка добавления записи в журнал (user: %d, year: %d, type: %s, days: %d): %w", entry.UserID, entry.Year, entry.EntryType, entry.Days, err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("ошибка получения ID записи журнала: %w", err)
+	}
+	entry.ID = int(id)
+	return nil
 }
 
-// --- Заявки ---
+// --- Переход года ---
 
-// SaveVacationRequest сохраняет новую заявку на отпуск и ее периоды в транзакции
-func (r *VacationRepository) SaveVacationRequest(request *models.VacationRequest) error {
-	tx, err := r.db.Begin()
+// GetUserIDsWithLimits получает ID пользователей, у которых есть счет (лимит) на указанный год
+func (r *VacationRepository) GetUserIDsWithLimits(year int) ([]int, error) {
+	query := `SELECT user_id FROM vacation_limits WHERE year = ? ORDER BY user_id`
+	rows, err := r.db.Query(query, year)
 	if err != nil {
-		return fmt.Errorf("ошибка начала транзакции: %w", err)
-	}
-	var txErr error
-	defer func() {
-		if p := recover(); p != nil {
-			_ = tx.Rollback()
-			panic(p)
-		}
-		if txErr != nil {
-			log.Printf("Rolling back transaction due to error: %v", txErr)
-			if rbErr := tx.Rollback(); rbErr != nil {
-				log.Printf("Error during transaction rollback: %v", rbErr)
-			}
-		} else {
-			txErr = tx.Commit()
-			if txErr != nil {
-				log.Printf("Ошибка коммита транзакции сохранения заявки: %v", txErr)
-			}
-		}
-	}()
+		return nil, fmt.Errorf("ошибка получения пользователей с лимитами на %d год: %w", year, err)
+	}
+	defer rows.Close()
 
-	if request.DaysRequested == 0 {
-		for _, p := range request.Periods {
-			request.DaysRequested += p.DaysCount
+	userIDs := []int{}
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования ID пользователя: %w", err)
 		}
+		userIDs = append(userIDs, userID)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по пользователям с лимитами: %w", err)
 	}
+	return userIDs, nil
+}
 
-	queryReq := `INSERT INTO vacation_requests (user_id, year, status_id, days_requested, comment, created_at, updated_at) VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
-	result, errExec := tx.Exec(queryReq, request.UserID, request.Year, request.StatusID, request.DaysRequested, request.Comment)
-	if errExec != nil {
-		txErr = fmt.Errorf("ошибка сохранения заявки: %w", errExec)
-		return txErr
+// IsYearRolledOver проверяет, выполнен ли уже переход года для пользователя
+func (r *VacationRepository) IsYearRolledOver(userID int, year int) (bool, error) {
+	query := `SELECT COUNT(*) FROM vacation_year_rollovers WHERE user_id = ? AND year = ?`
+	var count int
+	if err := r.db.QueryRow(query, userID, year).Scan(&count); err != nil {
+		return false, fmt.Errorf("ошибка проверки перехода %d года для пользователя %d: %w", year, userID, err)
 	}
-	requestID, errID := result.LastInsertId()
-	if errID != nil {
-		txErr = fmt.Errorf("ошибка получения ID сохраненной заявки: %w", errID)
-		return txErr
+	return count > 0, nil
+}
+
+// SaveYearRollover фиксирует итог перехода года для пользователя
+func (r *VacationRepository) SaveYearRollover(rollover *models.YearRollover) error {
+	query := `
+		INSERT INTO vacation_year_rollovers (user_id, year, carried_days, expired_days, actor_id, created_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`
+	if _, err := r.db.Exec(query, rollover.UserID, rollover.Year, rollover.CarriedDays, rollover.ExpiredDays, rollover.ActorID); err != nil {
+		return fmt.Errorf("ошибка сохранения перехода %d года для пользователя %d: %w", rollover.Year, rollover.UserID, err)
 	}
-	request.ID = int(requestID)
+	return nil
+}
+
+// --- Заявки ---
 
-	if len(request.Periods) > 0 {
-		queryPeriod := `INSERT INTO vacation_periods (request_id, start_date, end_date, days_count, created_at, updated_at) VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
-		stmt, errPrepare := tx.Prepare(queryPeriod)
-		if errPrepare != nil {
-			txErr = fmt.Errorf("ошибка подготовки запроса для периодов: %w", errPrepare)
-			return txErr
+// SaveVacationRequest сохраняет новую заявку на отпуск и ее периоды в транзакции.
+// DaysRequested (дни, списываемые с баланса основного отпуска) рассчитывается сервисом.
+func (r *VacationRepository) SaveVacationRequest(request *models.VacationRequest) error {
+	return r.inTx(func(tx *sql.Tx) error {
+		queryReq := `INSERT INTO vacation_requests (user_id, year, status_id, days_requested, comment, created_at, updated_at) VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+		result, errExec := tx.Exec(queryReq, request.UserID, request.Year, request.StatusID, request.DaysRequested, request.Comment)
+		if errExec != nil {
+			return fmt.Errorf("ошибка сохранения заявки: %w", errExec)
 		}
-		defer stmt.Close()
-		for i := range request.Periods {
-			if request.Periods[i].StartDate.IsZero() || request.Periods[i].EndDate.IsZero() || request.Periods[i].StartDate.Time.After(request.Periods[i].EndDate.Time) {
-				txErr = fmt.Errorf("некорректные даты в периоде %d", i+1)
-				return txErr
+		requestID, errID := result.LastInsertId()
+		if errID != nil {
+			return fmt.Errorf("ошибка получения ID сохраненной заявки: %w", errID)
+		}
+		request.ID = int(requestID)
+
+		if len(request.Periods) > 0 {
+			queryPeriod := `INSERT INTO vacation_periods (request_id, leave_type_id, start_date, end_date, days_count, created_at, updated_at) VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+			stmt, errPrepare := tx.Prepare(queryPeriod)
+			if errPrepare != nil {
+				return fmt.Errorf("ошибка подготовки запроса для периодов: %w", errPrepare)
 			}
-			_, errStmtExec := stmt.Exec(request.ID, request.Periods[i].StartDate, request.Periods[i].EndDate, request.Periods[i].DaysCount)
-			if errStmtExec != nil {
-				txErr = fmt.Errorf("ошибка сохранения периода %d: %w", i+1, errStmtExec)
-				return txErr
+			defer stmt.Close()
+			for i := range request.Periods {
+				if request.Periods[i].StartDate.IsZero() || request.Periods[i].EndDate.IsZero() || request.Periods[i].StartDate.Time.After(request.Periods[i].EndDate.Time) {
+					return fmt.Errorf("некорректные даты в периоде %d", i+1)
+				}
+				_, errStmtExec := stmt.Exec(request.ID, request.Periods[i].LeaveTypeID, request.Periods[i].StartDate, request.Periods[i].EndDate, request.Periods[i].DaysCount)
+				if errStmtExec != nil {
+					return fmt.Errorf("ошибка сохранения периода %d: %w", i+1, errStmtExec)
+				}
 			}
 		}
-	}
-	return txErr
+		return nil
+	})
 }
 
-// UpdateVacationRequest обновляет существующую заявку (комментарий) пользователем
+// UpdateVacationRequest обновляет существующую заявку пользователя: комментарий, количество дней и периоды.
+// Периоды заменяются целиком в одной транзакции. DaysRequested рассчитывается сервисом.
 func (r *VacationRepository) UpdateVacationRequest(request *models.VacationRequest) error {
-	query := `UPDATE vacation_requests SET comment = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND user_id = ?`
-	result, err := r.db.Exec(query, request.Comment, request.ID, request.UserID)
-	if err != nil {
-		return fmt.Errorf("ошибка обновления комментария заявки: %w", err)
-	}
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("ошибка получения количества обновленных строк при обновлении комментария: %w", err)
-	}
-	if rowsAffected == 0 {
-		return errors.New("заявка для обновления комментария не найдена или не принадлежит пользователю")
-	}
-	return nil
+	return r.inTx(func(tx *sql.Tx) error {
+		query := `UPDATE vacation_requests SET comment = ?, days_requested = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND user_id = ?`
+		result, err := tx.Exec(query, request.Comment, request.DaysRequested, request.ID, request.UserID)
+		if err != nil {
+			return fmt.Errorf("ошибка обновления заявки: %w", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("ошибка получения количества обновленных строк при обновлении заявки: %w", err)
+		}
+		if rowsAffected == 0 {
+			return errors.New("заявка для обновления не найдена или не принадлежит пользователю")
+		}
+
+		if _, err := tx.Exec(`DELETE FROM vacation_periods WHERE request_id = ?`, request.ID); err != nil {
+			return fmt.Errorf("ошибка удаления старых периодов заявки %d: %w", request.ID, err)
+		}
+		queryPeriod := `INSERT INTO vacation_periods (request_id, leave_type_id, start_date, end_date, days_count, created_at, updated_at) VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+		for i := range request.Periods {
+			request.Periods[i].RequestID = request.ID
+			if _, err := tx.Exec(queryPeriod, request.ID, request.Periods[i].LeaveTypeID, request.Periods[i].StartDate, request.Periods[i].EndDate, request.Periods[i].DaysCount); err != nil {
+				return fmt.Errorf("ошибка сохранения периода %d: %w", i+1, err)
+			}
+		}
+		return nil
+	})
 }
 
 // UpdateRequestStatusByID обновляет только статус заявки по ее ID
@@ -239,13 +484,31 @@ func (r *VacationRepository) UpdateRequestStatusByID(requestID int, newStatusID
 	return nil
 }
 
+// SetRequestRejectionReason сохраняет причину отклонения заявки
+func (r *VacationRepository) SetRequestRejectionReason(requestID int, reason string) error {
+	query := `UPDATE vacation_requests SET rejection_reason = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	if _, err := r.db.Exec(query, reason, requestID); err != nil {
+		return fmt.Errorf("ошибка сохранения причины отклонения заявки %d: %w", requestID, err)
+	}
+	return nil
+}
+
+// SetRequestCancellationReason сохраняет причину отмены заявки
+func (r *VacationRepository) SetRequestCancellationReason(requestID int, reason string) error {
+	query := `UPDATE vacation_requests SET cancellation_reason = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	if _, err := r.db.Exec(query, reason, requestID); err != nil {
+		return fmt.Errorf("ошибка сохранения причины отмены заявки %d: %w", requestID, err)
+	}
+	return nil
+}
+
 // GetVacationRequestByID получает одну заявку по ее ID вместе с периодами
 func (r *VacationRepository) GetVacationRequestByID(requestID int) (*models.VacationRequest, error) {
-	queryRequest := `SELECT id, user_id, year, status_id, days_requested, comment, created_at, updated_at FROM vacation_requests WHERE id = ?`
+	queryRequest := `SELECT id, user_id, year, status_id, days_requested, comment, rejection_reason, cancellation_reason, created_at, updated_at FROM vacation_requests WHERE id = ?`
 	row := r.db.QueryRow(queryRequest, requestID)
 	var req models.VacationRequest
-	var comment sql.NullString
-	err := row.Scan(&req.ID, &req.UserID, &req.Year, &req.StatusID, &req.DaysRequested, &comment, &req.CreatedAt, &req.UpdatedAt)
+	var comment, rejectionReason, cancellationReason sql.NullString
+	err := row.Scan(&req.ID, &req.UserID, &req.Year, &req.StatusID, &req.DaysRequested, &comment, &rejectionReason, &cancellationReason, &req.CreatedAt, &req.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -255,16 +518,22 @@ func (r *VacationRepository) GetVacationRequestByID(requestID int) (*models.Vaca
 	if comment.Valid {
 		req.Comment = comment.String
 	}
+	req.RejectionReason = rejectionReason.String
+	req.CancellationReason = cancellationReason.String
 	req.Periods, err = r.getPeriodsByRequestID(req.ID)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка получения периодов для заявки %d: %w", req.ID, err)
 	}
+	req.ApprovalSteps, err = r.getApprovalStepsByRequestID(req.ID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения цепочки согласования заявки %d: %w", req.ID, err)
+	}
 	return &req, nil
 }
 
 // getPeriodsByRequestID - вспомогательный метод для получения периодов заявки
 func (r *VacationRepository) getPeriodsByRequestID(requestID int) ([]models.VacationPeriod, error) {
-	queryPeriods := `SELECT id, request_id, start_date, end_date, days_count, created_at, updated_at FROM vacation_periods WHERE request_id = ?`
+	queryPeriods := `SELECT id, request_id, leave_type_id, start_date, end_date, days_count, created_at, updated_at FROM vacation_periods WHERE request_id = ?`
 	rows, err := r.db.Query(queryPeriods, requestID)
 	if err != nil {
 		return nil, err
@@ -273,7 +542,7 @@ func (r *VacationRepository) getPeriodsByRequestID(requestID int) ([]models.Vaca
 	var periods []models.VacationPeriod
 	for rows.Next() {
 		var period models.VacationPeriod
-		if err := rows.Scan(&period.ID, &period.RequestID, &period.StartDate, &period.EndDate, &period.DaysCount, &period.CreatedAt, &period.UpdatedAt); err != nil {
+		if err := rows.Scan(&period.ID, &period.RequestID, &period.LeaveTypeID, &period.StartDate, &period.EndDate, &period.DaysCount, &period.CreatedAt, &period.UpdatedAt); err != nil {
 			log.Printf("Ошибка сканирования периода для заявки %d: %v\n", requestID, err)
 			continue
 		}
@@ -287,7 +556,7 @@ func (r *VacationRepository) getPeriodsByRequestID(requestID int) ([]models.Vaca
 
 // GetVacationRequestsByUser получает заявки пользователя с фильтрацией по статусу
 func (r *VacationRepository) GetVacationRequestsByUser(userID int, year int, statusFilter *int) ([]models.VacationRequest, error) {
-	baseQuery := `SELECT id, user_id, year, status_id, days_requested, comment, created_at, updated_at FROM vacation_requests WHERE user_id = ? AND year = ?`
+	baseQuery := `SELECT id, user_id, year, status_id, days_requested, comment, rejection_reason, cancellation_reason, created_at, updated_at FROM vacation_requests WHERE user_id = ? AND year = ?`
 	args := []interface{}{userID, year}
 	if statusFilter != nil {
 		baseQuery += " AND status_id = ?"
@@ -303,14 +572,16 @@ func (r *VacationRepository) GetVacationRequestsByUser(userID int, year int, sta
 	var requestIDs []interface{}
 	for rowsReq.Next() {
 		var req models.VacationRequest
-		var comment sql.NullString
-		if err := rowsReq.Scan(&req.ID, &req.UserID, &req.Year, &req.StatusID, &req.DaysRequested, &comment, &req.CreatedAt, &req.UpdatedAt); err != nil {
+		var comment, rejectionReason, cancellationReason sql.NullString
+		if err := rowsReq.Scan(&req.ID, &req.UserID, &req.Year, &req.StatusID, &req.DaysRequested, &comment, &rejectionReason, &cancellationReason, &req.CreatedAt, &req.UpdatedAt); err != nil {
 			log.Printf("Ошибка сканирования заявки пользователя %d: %v\n", userID, err)
 			continue
 		}
 		if comment.Valid {
 			req.Comment = comment.String
 		}
+		req.RejectionReason = rejectionReason.String
+		req.CancellationReason = cancellationReason.String
 		req.Periods = []models.VacationPeriod{}
 		requestsMap[req.ID] = &req
 		requestIDs = append(requestIDs, req.ID)
@@ -339,7 +610,7 @@ func (r *VacationRepository) GetVacationRequestsByUser(userID int, year int, sta
 
 // GetVacationRequestsByOrganizationalUnit получает заявки орг. юнита с фильтрацией по статусу
 func (r *VacationRepository) GetVacationRequestsByOrganizationalUnit(unitID int, year int, statusFilter *int) ([]models.VacationRequest, error) {
-	baseQuery := `SELECT vr.id, vr.user_id, vr.year, vr.status_id, vr.days_requested, vr.comment, vr.created_at, vr.updated_at FROM vacation_requests vr JOIN users u ON vr.user_id = u.id WHERE u.organizational_unit_id = ? AND vr.year = ?`
+	baseQuery := `SELECT vr.id, vr.user_id, vr.year, vr.status_id, vr.days_requested, vr.comment, vr.rejection_reason, vr.cancellation_reason, vr.created_at, vr.updated_at FROM vacation_requests vr JOIN users u ON vr.user_id = u.id WHERE u.organizational_unit_id = ? AND vr.year = ?`
 	args := []interface{}{unitID, year}
 	if statusFilter != nil {
 		baseQuery += " AND vr.status_id = ?"
@@ -355,14 +626,16 @@ func (r *VacationRepository) GetVacationRequestsByOrganizationalUnit(unitID int,
 	var requestIDs []interface{}
 	for rowsReq.Next() {
 		var req models.VacationRequest
-		var comment sql.NullString
-		if err := rowsReq.Scan(&req.ID, &req.UserID, &req.Year, &req.StatusID, &req.DaysRequested, &comment, &req.CreatedAt, &req.UpdatedAt); err != nil {
+		var comment, rejectionReason, cancellationReason sql.NullString
+		if err := rowsReq.Scan(&req.ID, &req.UserID, &req.Year, &req.StatusID, &req.DaysRequested, &comment, &rejectionReason, &cancellationReason, &req.CreatedAt, &req.UpdatedAt); err != nil {
 			log.Printf("Ошибка сканирования заявки орг. юнита %d: %v\n", unitID, err) // Исправлено departmentID -> unitID
 			continue
 		}
 		if comment.Valid {
 			req.Comment = comment.String
 		}
+		req.RejectionReason = rejectionReason.String
+		req.CancellationReason = cancellationReason.String
 		req.Periods = []models.VacationPeriod{}
 		requestsMap[req.ID] = &req
 		requestIDs = append(requestIDs, req.ID)
@@ -394,7 +667,7 @@ func (r *VacationRepository) GetAllVacationRequests(yearFilter *int, statusFilte
 	// Запрос теперь явно соединяется с vacation_status для получения имени статуса
 	queryBase := `
 		SELECT
-			vr.id, vr.user_id, vr.year, vr.status_id, vr.days_requested, vr.comment, vr.created_at, vr.updated_at,
+			vr.id, vr.user_id, vr.year, vr.status_id, vr.days_requested, vr.comment, vr.rejection_reason, vr.cancellation_reason, vr.created_at, vr.updated_at,
 			u.full_name,
 			COALESCE(vs.name, 'Неизвестно') AS status_name
 		FROM vacation_requests vr
@@ -449,11 +722,11 @@ func (r *VacationRepository) GetAllVacationRequests(yearFilter *int, statusFilte
 
 	for rowsReq.Next() {
 		var req models.VacationRequestAdminView
-		var comment sql.NullString
+		var comment, rejectionReason, cancellationReason sql.NullString
 		// Добавляем req.StatusName в Scan
 		err := rowsReq.Scan(
 			&req.ID, &req.UserID, &req.Year, &req.StatusID, &req.DaysRequested, &comment,
-			&req.CreatedAt, &req.UpdatedAt, &req.UserFullName, &req.StatusName,
+			&rejectionReason, &cancellationReason, &req.CreatedAt, &req.UpdatedAt, &req.UserFullName, &req.StatusName,
 		)
 		if err != nil {
 			// Возвращаем ошибку сканирования немедленно
@@ -462,6 +735,8 @@ func (r *VacationRepository) GetAllVacationRequests(yearFilter *int, statusFilte
 		if comment.Valid {
 			req.Comment = comment.String
 		}
+		req.RejectionReason = rejectionReason.String
+		req.CancellationReason = cancellationReason.String
 		// Удаляем логику с statusIDToNameMap, так как имя статуса теперь приходит из БД
 		// if name, ok := statusIDToNameMap[req.StatusID]; ok {
 		// 	req.StatusName = name
@@ -493,8 +768,20 @@ func (r *VacationRepository) GetAllVacationRequests(yearFilter *int, statusFilte
 		}
 	}
 
+	stepsMap, err := r.getApprovalStepsByRequestIDs(requestIDs)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения цепочек согласования для всех заявок: %w", err)
+	}
+
 	result := make([]models.VacationRequestAdminView, 0, len(requestsMap))
 	for _, req := range requestsMap {
+		req.ApprovalSteps = stepsMap[req.ID]
+		if req.ApprovalSteps == nil {
+			req.ApprovalSteps = []models.ApprovalStep{}
+		}
+		if req.StatusID == models.StatusPending {
+			req.CurrentStep = models.CurrentApprovalStep(req.ApprovalSteps)
+		}
 		req.TotalDays = totalDaysMap[req.ID]
 		if req.DaysRequested != req.TotalDays {
 			log.Printf("Warning: DaysRequested (%d) in DB differs from calculated TotalDays (%d) for request ID %d", req.DaysRequested, req.TotalDays, req.ID)
@@ -510,7 +797,7 @@ func (r *VacationRepository) getPeriodsByRequestIDs(requestIDs []interface{}) ([
 	if len(requestIDs) == 0 {
 		return []models.VacationPeriod{}, nil
 	}
-	query := fmt.Sprintf(`SELECT id, request_id, start_date, end_date, days_count, created_at, updated_at FROM vacation_periods WHERE request_id IN (?%s)`, sqlRepeatParams(len(requestIDs)-1))
+	query := fmt.Sprintf(`SELECT id, request_id, leave_type_id, start_date, end_date, days_count, created_at, updated_at FROM vacation_periods WHERE request_id IN (?%s)`, sqlRepeatParams(len(requestIDs)-1))
 	rows, err := r.db.Query(query, requestIDs...)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка запроса периодов по IDs: %w", err)
@@ -519,7 +806,7 @@ func (r *VacationRepository) getPeriodsByRequestIDs(requestIDs []interface{}) ([
 	var periods []models.VacationPeriod
 	for rows.Next() {
 		var period models.VacationPeriod
-		if err := rows.Scan(&period.ID, &period.RequestID, &period.StartDate, &period.EndDate, &period.DaysCount, &period.CreatedAt, &period.UpdatedAt); err != nil {
+		if err := rows.Scan(&period.ID, &period.RequestID, &period.LeaveTypeID, &period.StartDate, &period.EndDate, &period.DaysCount, &period.CreatedAt, &period.UpdatedAt); err != nil {
 			log.Printf("Ошибка сканирования периода (множественный запрос): %v\n", err)
 			continue
 		}
@@ -531,6 +818,129 @@ func (r *VacationRepository) getPeriodsByRequestIDs(requestIDs []interface{}) ([
 	return periods, nil
 }
 
+// --- История переходов ---
+
+// AddRequestTransition добавляет запись в историю переходов заявки
+func (r *VacationRepository) AddRequestTransition(transition *models.VacationRequestTransition) error {
+	query := `
+		INSERT INTO vacation_request_transitions (request_id, from_status_id, to_status_id, action, actor_id, on_behalf_of_id, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`
+	result, err := r.db.Exec(query, transition.RequestID, transition.FromStatusID, transition.ToStatusID, transition.Action, transition.ActorID, transition.OnBehalfOfID, transition.Reason)
+	if err != nil {
+		return fmt.Errorf("ошибка записи перехода заявки %d (%s): %w", transition.RequestID, transition.Action, err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("ошибка получения ID записи перехода: %w", err)
+	}
+	transition.ID = int(id)
+	return nil
+}
+
+// GetRequestTransitions получает историю переходов заявки в хронологическом порядке
+func (r *VacationRepository) GetRequestTransitions(requestID int) ([]models.VacationRequestTransition, error) {
+	query := `
+		SELECT t.id, t.request_id, t.from_status_id, fs.name, t.to_status_id, ts.name, t.action, t.actor_id, u.full_name, t.on_behalf_of_id, ob.full_name, t.reason, t.created_at
+		FROM vacation_request_transitions t
+		LEFT JOIN vacation_status fs ON t.from_status_id = fs.id
+		JOIN vacation_status ts ON t.to_status_id = ts.id
+		LEFT JOIN users u ON t.actor_id = u.id
+		LEFT JOIN users ob ON t.on_behalf_of_id = ob.id
+		WHERE t.request_id = ?
+		ORDER BY t.created_at ASC, t.id ASC`
+
+	rows, err := r.db.Query(query, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса истории заявки %d: %w", requestID, err)
+	}
+	defer rows.Close()
+
+	transitions := []models.VacationRequestTransition{}
+	for rows.Next() {
+		var t models.VacationRequestTransition
+		var fromStatusID, actorID, onBehalfOfID sql.NullInt64
+		var fromStatusName, actorName, onBehalfOfName, reason sql.NullString
+		if err := rows.Scan(&t.ID, &t.RequestID, &fromStatusID, &fromStatusName, &t.ToStatusID, &t.ToStatusName, &t.Action, &actorID, &actorName, &onBehalfOfID, &onBehalfOfName, &reason, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования записи истории заявки: %w", err)
+		}
+		if fromStatusID.Valid {
+			id := int(fromStatusID.Int64)
+			t.FromStatusID = &id
+		}
+		if fromStatusName.Valid {
+			name := fromStatusName.String
+			t.FromStatusName = &name
+		}
+		if actorID.Valid {
+			id := int(actorID.Int64)
+			t.ActorID = &id
+		}
+		if actorName.Valid {
+			name := actorName.String
+			t.ActorFullName = &name
+		}
+		t.OnBehalfOfID = nullIntPtr(onBehalfOfID)
+		if onBehalfOfName.Valid {
+			name := onBehalfOfName.String
+			t.OnBehalfOfName = &name
+		}
+		if reason.Valid {
+			t.Reason = reason.String
+		}
+		transitions = append(transitions, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по истории заявки: %w", err)
+	}
+	return transitions, nil
+}
+
+// GetRequestIDsDueForStart получает ID утвержденных заявок, первый период которых начался не позднее date
+func (r *VacationRepository) GetRequestIDsDueForStart(date time.Time) ([]int, error) {
+	query := `
+		SELECT vr.id
+		FROM vacation_requests vr
+		JOIN vacation_periods vp ON vp.request_id = vr.id
+		WHERE vr.status_id = ?
+		GROUP BY vr.id
+		HAVING MIN(vp.start_date) <= ?`
+	return r.queryRequestIDs(query, models.StatusApproved, date.Format("2006-01-02"))
+}
+
+// GetRequestIDsDueForCompletion получает ID заявок в статусе "В отпуске", последний период которых закончился раньше date
+func (r *VacationRepository) GetRequestIDsDueForCompletion(date time.Time) ([]int, error) {
+	query := `
+		SELECT vr.id
+		FROM vacation_requests vr
+		JOIN vacation_periods vp ON vp.request_id = vr.id
+		WHERE vr.status_id = ?
+		GROUP BY vr.id
+		HAVING MAX(vp.end_date) < ?`
+	return r.queryRequestIDs(query, models.StatusInProgress, date.Format("2006-01-02"))
+}
+
+// queryRequestIDs - вспомогательный метод для запросов, возвращающих список ID заявок
+func (r *VacationRepository) queryRequestIDs(query string, args ...interface{}) ([]int, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса ID заявок: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования ID заявки: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по ID заявок: %w", err)
+	}
+	return ids, nil
+}
+
 // --- Уведомления ---
 
 // CreateNotification создает новое уведомление
@@ -598,14 +1008,18 @@ func (r *VacationRepository) GetApprovedVacationConflictsByPosition(positionID i
 			FROM vacation_periods vp
 			JOIN vacation_requests vr ON vp.request_id = vr.id
 			JOIN users u ON vr.user_id = u.id
-			WHERE vr.status_id = ? -- Только утвержденные
+			WHERE vr.status_id IN (?` + sqlRepeatParams(len(models.ApprovedStatuses)-1) + `) -- Только утвержденные
 			  AND u.position_id = ? -- Та же должность
 			  AND vr.user_id != ?   -- Кроме самого пользователя
 			  AND (` + dateConditionString + `) -- Пересечение дат
 		`
 
-	// Собираем аргументы: StatusApproved, positionID, excludeUserID, затем все start/end даты из dateArgs
-	args := []interface{}{models.StatusApproved, positionID, excludeUserID}
+	// Собираем аргументы: утвержденные статусы, positionID, excludeUserID, затем все start/end даты из dateArgs
+	args := []interface{}{}
+	for _, statusID := range models.ApprovedStatuses {
+		args = append(args, statusID)
+	}
+	args = append(args, positionID, excludeUserID)
 	args = append(args, dateArgs...)
 
 	rows, err := r.db.Query(query, args...)
@@ -658,6 +1072,7 @@ func (r *VacationRepository) GetApprovedVacationConflictsByPosition(positionID i
 
 				// Создаем и добавляем детализированный конфликт
 				detailedConflict := conflictingPeriod                         // Копируем базовую информацию
+				detailedConflict.OriginalUserID = excludeUserID               // Автор проверяемой заявки
 				detailedConflict.OriginalRequestID = originalPeriod.RequestID // Заполняем детали исходного периода
 				detailedConflict.OriginalPeriodID = originalPeriod.ID
 				detailedConflict.OriginalStartDate = originalPeriod.StartDate
@@ -677,28 +1092,37 @@ func (r *VacationRepository) GetApprovedVacationConflictsByPosition(positionID i
 
 // --- Dashboard Data Methods ---
 
-// CountPendingRequestsByUnitIDs подсчитывает заявки в статусе "На рассмотрении" для заданных юнитов
-func (r *VacationRepository) CountPendingRequestsByUnitIDs(unitIDs []int) (int, error) {
-	if len(unitIDs) == 0 {
-		return 0, nil // Нет юнитов для поиска
+// CountPendingRequestsForApprover подсчитывает заявки "На рассмотрении", ожидающие решения руководителя:
+// текущий этап цепочки согласования назначен ему, а заявки без цепочки - из заданных юнитов.
+// Собственные заявки руководителя не учитываются.
+func (r *VacationRepository) CountPendingRequestsForApprover(approverID int, unitIDs []int) (int, error) {
+	unitCondition := "FALSE"
+	args := []interface{}{models.StatusPending, approverID, approverID, models.ApprovalStepPending, models.ApprovalStepPending}
+	if len(unitIDs) > 0 {
+		unitCondition = "u.organizational_unit_id IN (?" + sqlRepeatParams(len(unitIDs)-1) + ")"
+		for _, id := range unitIDs {
+			args = append(args, id)
+		}
 	}
 
 	query := `
 		SELECT COUNT(vr.id)
 		FROM vacation_requests vr
 		JOIN users u ON vr.user_id = u.id
-		WHERE vr.status_id = ?
-		  AND u.organizational_unit_id IN (?` + sqlRepeatParams(len(unitIDs)-1) + `)`
-
-	args := []interface{}{models.StatusPending}
-	for _, id := range unitIDs {
-		args = append(args, id)
-	}
+		WHERE vr.status_id = ? AND vr.user_id != ?
+		  AND (
+			EXISTS (
+				SELECT 1 FROM vacation_approval_steps s
+				WHERE s.request_id = vr.id AND s.approver_id = ? AND s.status = ?
+				  AND s.step_order = (SELECT MIN(s2.step_order) FROM vacation_approval_steps s2 WHERE s2.request_id = vr.id AND s2.status = ?)
+			)
+			OR (NOT EXISTS (SELECT 1 FROM vacation_approval_steps s3 WHERE s3.request_id = vr.id) AND ` + unitCondition + `)
+		  )`
 
 	var count int
 	err := r.db.QueryRow(query, args...).Scan(&count)
 	if err != nil {
-		return 0, fmt.Errorf("ошибка подсчета ожидающих заявок по юнитам %v: %w", unitIDs, err)
+		return 0, fmt.Errorf("ошибка подсчета заявок, ожидающих решения руководителя %d: %w", approverID, err)
 	}
 	return count, nil
 }
@@ -717,13 +1141,16 @@ func (r *VacationRepository) GetUpcomingApprovedConflictsByUnitIDs(unitIDs []int
 		FROM vacation_periods vp
 		JOIN vacation_requests vr ON vp.request_id = vr.id
 		JOIN users u ON vr.user_id = u.id
-		WHERE vr.status_id = ? -- Только утвержденные
+		WHERE vr.status_id IN (?` + sqlRepeatParams(len(models.ApprovedStatuses)-1) + `) -- Только утвержденные
 		  AND u.organizational_unit_id IN (?` + sqlRepeatParams(len(unitIDs)-1) + `)
 		  AND vp.start_date <= ? -- Периоды, которые начинаются до конца диапазона
 		  AND vp.end_date >= ?   -- Периоды, которые заканчиваются после начала диапазона
 		ORDER BY u.position_id, vp.start_date
 	`
-	args := []interface{}{models.StatusApproved}
+	args := []interface{}{}
+	for _, statusID := range models.ApprovedStatuses {
+		args = append(args, statusID)
+	}
 	for _, id := range unitIDs {
 		args = append(args, id)
 	}
@@ -0,0 +1,207 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"vacation-scheduler/internal/models"
+)
+
+// --- Переносы отпуска ---
+
+// vacationTransferColumns - общий список полей переноса для SELECT-запросов (таблица vt, статус vs, сотрудник u)
+const vacationTransferColumns = `
+	vt.id, vt.request_id, vt.period_id, vt.user_id, u.full_name,
+	vt.original_start_date, vt.original_end_date, vt.new_start_date, vt.new_end_date, vt.days_count,
+	vt.reason, vt.status_id, vs.name, vt.decided_by, vt.decision_comment, vt.decided_at, vt.created_at, vt.updated_at`
+
+// vacationTransferFrom - FROM/JOIN для SELECT-запросов переносов
+const vacationTransferFrom = `
+	FROM vacation_transfers vt
+	JOIN users u ON vt.user_id = u.id
+	JOIN vacation_status vs ON vt.status_id = vs.id`
+
+// rowScanner - общий интерфейс *sql.Row и *sql.Rows для сканирования одной строки
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanVacationTransfer сканирует строку, выбранную с полями vacationTransferColumns
+func scanVacationTransfer(row rowScanner) (*models.VacationTransfer, error) {
+	var t models.VacationTransfer
+	var decidedBy sql.NullInt64
+	var decisionComment sql.NullString
+	var decidedAt sql.NullTime
+	err := row.Scan(&t.ID, &t.RequestID, &t.PeriodID, &t.UserID, &t.UserFullName,
+		&t.OriginalStartDate, &t.OriginalEndDate, &t.NewStartDate, &t.NewEndDate, &t.DaysCount,
+		&t.Reason, &t.StatusID, &t.StatusName, &decidedBy, &decisionComment, &decidedAt, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if decidedBy.Valid {
+		id := int(decidedBy.Int64)
+		t.DecidedBy = &id
+	}
+	t.DecisionComment = decisionComment.String
+	if decidedAt.Valid {
+		decided := decidedAt.Time
+		t.DecidedAt = &decided
+	}
+	return &t, nil
+}
+
+// GetVacationPeriodByID получает период отпуска по ID. Возвращает nil, nil, если период не найден.
+func (r *VacationRepository) GetVacationPeriodByID(periodID int) (*models.VacationPeriod, error) {
+	query := `SELECT id, request_id, leave_type_id, start_date, end_date, days_count, created_at, updated_at FROM vacation_periods WHERE id = ?`
+	var period models.VacationPeriod
+	err := r.db.QueryRow(query, periodID).Scan(&period.ID, &period.RequestID, &period.LeaveTypeID, &period.StartDate, &period.EndDate, &period.DaysCount, &period.CreatedAt, &period.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка получения периода отпуска %d: %w", periodID, err)
+	}
+	return &period, nil
+}
+
+// UpdateVacationPeriodDates переносит период отпуска на новые даты
+func (r *VacationRepository) UpdateVacationPeriodDates(periodID int, startDate models.CustomDate, endDate models.CustomDate, daysCount int) error {
+	query := `UPDATE vacation_periods SET start_date = ?, end_date = ?, days_count = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	result, err := r.db.Exec(query, startDate, endDate, daysCount, periodID)
+	if err != nil {
+		return fmt.Errorf("ошибка переноса периода отпуска %d: %w", periodID, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества обновленных строк при переносе периода: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("период отпуска %d для переноса не найден", periodID)
+	}
+	return nil
+}
+
+// CreateVacationTransfer сохраняет заявку на перенос периода отпуска
+func (r *VacationRepository) CreateVacationTransfer(transfer *models.VacationTransfer) error {
+	query := `
+		INSERT INTO vacation_transfers (request_id, period_id, user_id, original_start_date, original_end_date,
+			new_start_date, new_end_date, days_count, reason, status_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+	result, err := r.db.Exec(query, transfer.RequestID, transfer.PeriodID, transfer.UserID, transfer.OriginalStartDate, transfer.OriginalEndDate,
+		transfer.NewStartDate, transfer.NewEndDate, transfer.DaysCount, transfer.Reason, transfer.StatusID)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения заявки на перенос: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("ошибка получения ID заявки на перенос: %w", err)
+	}
+	transfer.ID = int(id)
+	return nil
+}
+
+// LockVacationTransfer получает заявку на перенос и блокирует ее строку до конца транзакции.
+// Возвращает nil, nil, если перенос не найден.
+func (r *VacationRepository) LockVacationTransfer(transferID int) (*models.VacationTransfer, error) {
+	if r.tx == nil {
+		return nil, errors.New("блокировка переноса возможна только внутри транзакции")
+	}
+	query := `SELECT ` + vacationTransferColumns + vacationTransferFrom + ` WHERE vt.id = ? FOR UPDATE`
+	transfer, err := scanVacationTransfer(r.db.QueryRow(query, transferID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка блокировки заявки на перенос %d: %w", transferID, err)
+	}
+	return transfer, nil
+}
+
+// HasPendingVacationTransfer проверяет, есть ли у периода перенос на рассмотрении
+func (r *VacationRepository) HasPendingVacationTransfer(periodID int) (bool, error) {
+	query := `SELECT COUNT(*) FROM vacation_transfers WHERE period_id = ? AND status_id = ?`
+	var count int
+	if err := r.db.QueryRow(query, periodID, models.StatusPending).Scan(&count); err != nil {
+		return false, fmt.Errorf("ошибка проверки переносов периода %d: %w", periodID, err)
+	}
+	return count > 0, nil
+}
+
+// SetVacationTransferDecision фиксирует решение по переносу: новый статус, кто и когда принял решение
+func (r *VacationRepository) SetVacationTransferDecision(transferID int, statusID int, decidedBy int, comment string) error {
+	query := `UPDATE vacation_transfers SET status_id = ?, decided_by = ?, decision_comment = ?, decided_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	result, err := r.db.Exec(query, statusID, decidedBy, comment, transferID)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления статуса переноса %d: %w", transferID, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества обновленных строк при обновлении переноса: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("перенос %d не найден", transferID)
+	}
+	return nil
+}
+
+// GetVacationTransfers получает заявки на перенос с фильтрами по сотруднику, статусу и юнитам сотрудников
+func (r *VacationRepository) GetVacationTransfers(userIDFilter *int, statusFilter *int, unitIDsFilter []int) ([]models.VacationTransfer, error) {
+	query := `SELECT ` + vacationTransferColumns + vacationTransferFrom + ` WHERE 1=1`
+	args := []interface{}{}
+	if userIDFilter != nil {
+		query += ` AND vt.user_id = ?`
+		args = append(args, *userIDFilter)
+	}
+	if statusFilter != nil {
+		query += ` AND vt.status_id = ?`
+		args = append(args, *statusFilter)
+	}
+	if unitIDsFilter != nil {
+		if len(unitIDsFilter) == 0 {
+			return []models.VacationTransfer{}, nil
+		}
+		query += fmt.Sprintf(` AND u.organizational_unit_id IN (?%s)`, sqlRepeatParams(len(unitIDsFilter)-1))
+		for _, id := range unitIDsFilter {
+			args = append(args, id)
+		}
+	}
+	query += ` ORDER BY vt.created_at DESC, vt.id DESC`
+	return r.queryVacationTransfers(query, args...)
+}
+
+// GetApprovedTransfersByPeriodIDs получает утвержденные переносы периодов в порядке принятия решений
+func (r *VacationRepository) GetApprovedTransfersByPeriodIDs(periodIDs []int) ([]models.VacationTransfer, error) {
+	if len(periodIDs) == 0 {
+		return []models.VacationTransfer{}, nil
+	}
+	query := `SELECT ` + vacationTransferColumns + vacationTransferFrom +
+		fmt.Sprintf(` WHERE vt.status_id = ? AND vt.period_id IN (?%s) ORDER BY vt.decided_at ASC, vt.id ASC`, sqlRepeatParams(len(periodIDs)-1))
+	args := []interface{}{models.StatusApproved}
+	for _, id := range periodIDs {
+		args = append(args, id)
+	}
+	return r.queryVacationTransfers(query, args...)
+}
+
+// queryVacationTransfers выполняет запрос, выбирающий поля vacationTransferColumns
+func (r *VacationRepository) queryVacationTransfers(query string, args ...interface{}) ([]models.VacationTransfer, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса заявок на перенос: %w", err)
+	}
+	defer rows.Close()
+
+	transfers := []models.VacationTransfer{}
+	for rows.Next() {
+		transfer, err := scanVacationTransfer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования заявки на перенос: %w", err)
+		}
+		transfers = append(transfers, *transfer)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по заявкам на перенос: %w", err)
+	}
+	return transfers, nil
+}
@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"vacation-scheduler/internal/models"
+)
+
+// dbExecutor - общий набор методов *sql.DB и *sql.Tx, через который репозиторий выполняет запросы
+type dbExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Prepare(query string) (*sql.Stmt, error)
+}
+
+// RunInTx выполняет fn в одной транзакции (unit of work).
+// Репозиторий, переданный в fn, выполняет все запросы в рамках этой транзакции.
+// Если fn вернула ошибку или запаниковала, транзакция откатывается, иначе фиксируется.
+// Вызов внутри уже открытой транзакции переиспользует ее.
+func (r *VacationRepository) RunInTx(fn func(tx VacationRepositoryInterface) error) error {
+	return r.inTx(func(tx *sql.Tx) error {
+		return fn(&VacationRepository{db: tx, conn: r.conn, tx: tx})
+	})
+}
+
+// inTx выполняет fn в текущей транзакции репозитория или открывает новую
+func (r *VacationRepository) inTx(fn func(tx *sql.Tx) error) (err error) {
+	if r.tx != nil {
+		return fn(r.tx)
+	}
+
+	tx, err := r.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("[Repo Tx] Error during transaction rollback: %v", rbErr)
+			}
+			return
+		}
+		if err = tx.Commit(); err != nil {
+			err = fmt.Errorf("ошибка коммита транзакции: %w", err)
+		}
+	}()
+
+	return fn(tx)
+}
+
+// LockVacationRequest получает заявку вместе с периодами и блокирует ее строку до конца транзакции (SELECT ... FOR UPDATE).
+// Возвращает nil, nil, если заявка не найдена. Вне транзакции блокировка не имеет смысла, поэтому возвращается ошибка.
+func (r *VacationRepository) LockVacationRequest(requestID int) (*models.VacationRequest, error) {
+	if r.tx == nil {
+		return nil, errors.New("блокировка заявки возможна только внутри транзакции")
+	}
+	queryRequest := `SELECT id, user_id, year, status_id, days_requested, comment, rejection_reason, cancellation_reason, created_at, updated_at FROM vacation_requests WHERE id = ? FOR UPDATE`
+	var req models.VacationRequest
+	var comment, rejectionReason, cancellationReason sql.NullString
+	err := r.db.QueryRow(queryRequest, requestID).Scan(&req.ID, &req.UserID, &req.Year, &req.StatusID, &req.DaysRequested, &comment, &rejectionReason, &cancellationReason, &req.CreatedAt, &req.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка блокировки заявки %d: %w", requestID, err)
+	}
+	if comment.Valid {
+		req.Comment = comment.String
+	}
+	req.RejectionReason = rejectionReason.String
+	req.CancellationReason = cancellationReason.String
+	req.Periods, err = r.getPeriodsByRequestID(req.ID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения периодов для заявки %d: %w", req.ID, err)
+	}
+	req.ApprovalSteps, err = r.getApprovalStepsByRequestID(req.ID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения цепочки согласования заявки %d: %w", req.ID, err)
+	}
+	return &req, nil
+}
+
+// LockVacationLimit блокирует счет (лимит) пользователя на год до конца транзакции.
+// Все изменения журнала по этому счету проходят через эту строку, поэтому параллельные операции выполняются последовательно.
+// Возвращает ErrLimitNotFound, если лимит на год еще не создан.
+func (r *VacationRepository) LockVacationLimit(userID int, year int) error {
+	if r.tx == nil {
+		return errors.New("блокировка лимита возможна только внутри транзакции")
+	}
+	var limitID int
+	if err := r.db.QueryRow(`SELECT id FROM vacation_limits WHERE user_id = ? AND year = ? FOR UPDATE`, userID, year).Scan(&limitID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrLimitNotFound
+		}
+		return fmt.Errorf("ошибка блокировки лимита (user: %d, year: %d): %w", userID, year, err)
+	}
+	return nil
+}
+
+// LockPosition блокирует строку должности до конца транзакции.
+// Используется при утверждении, чтобы проверки конфликтов по одной должности не выполнялись параллельно.
+func (r *VacationRepository) LockPosition(positionID int) error {
+	if r.tx == nil {
+		return errors.New("блокировка должности возможна только внутри транзакции")
+	}
+	var id int
+	if err := r.db.QueryRow(`SELECT id FROM positions WHERE id = ? FOR UPDATE`, positionID).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("должность %d не найдена", positionID)
+		}
+		return fmt.Errorf("ошибка блокировки должности %d: %w", positionID, err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"time"
+
+	"vacation-scheduler/internal/models"
+)
+
+// halfMonthDays - излишек дней, начиная с которого неполный месяц округляется до полного
+// (п. 35 Правил об очередных и дополнительных отпусках: менее половины месяца исключается,
+// не менее половины - округляется до полного месяца)
+const halfMonthDays = 15
+
+// addMonthsClamped прибавляет месяцы к дате, не перескакивая через конец месяца (31.01 + 1 мес. = 28/29.02)
+func addMonthsClamped(t time.Time, months int) time.Time {
+	firstOfMonth := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()).AddDate(0, months, 0)
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(firstOfMonth.Year(), firstOfMonth.Month(), day, 0, 0, 0, 0, t.Location())
+}
+
+// accrualMonths считает месяцы работы с from по to включительно с округлением по правилам ТК РФ.
+// Месяцы отсчитываются от даты from, неполный последний месяц округляется по halfMonthDays.
+func accrualMonths(from time.Time, to time.Time) int {
+	from, to = truncateToDate(from), truncateToDate(to)
+	if to.Before(from) {
+		return 0
+	}
+	months := 0
+	for {
+		next := addMonthsClamped(from, months+1)
+		if next.After(to.AddDate(0, 0, 1)) {
+			break
+		}
+		months++
+	}
+	remainderStart := addMonthsClamped(from, months)
+	remainderDays := int(to.Sub(remainderStart).Hours()/24) + 1
+	if remainderDays >= halfMonthDays {
+		months++
+	}
+	if months > 12 {
+		months = 12
+	}
+	return months
+}
+
+// proRataDays считает дни отпуска за отработанные месяцы; дробная часть округляется в пользу сотрудника
+func proRataDays(annualDays int, months int) int {
+	if months <= 0 {
+		return 0
+	}
+	if months >= 12 {
+		return annualDays
+	}
+	return (annualDays*months + 11) / 12
+}
+
+// accrualPeriodStart возвращает дату, с которой сотрудник накапливает отпуск в указанном году,
+// и false, если в этом году он еще не работает
+func accrualPeriodStart(hireDate *models.CustomDate, year int) (time.Time, bool) {
+	yearStart := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if hireDate == nil || hireDate.IsZero() {
+		return yearStart, true
+	}
+	hired := time.Date(hireDate.Year(), hireDate.Month(), hireDate.Day(), 0, 0, 0, 0, time.UTC)
+	if hired.Year() > year {
+		return time.Time{}, false
+	}
+	if hired.Before(yearStart) {
+		return yearStart, true
+	}
+	return hired, true
+}
+
+// annualEntitlement считает полагающиеся сотруднику дни отпуска за календарный год из annualDays за полный год
+// с учетом даты приема (для принятых в течение года - пропорционально отработанным месяцам)
+func annualEntitlement(hireDate *models.CustomDate, year int, annualDays int) int {
+	start, ok := accrualPeriodStart(hireDate, year)
+	if !ok {
+		return 0
+	}
+	yearEnd := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC)
+	return proRataDays(annualDays, accrualMonths(start, yearEnd))
+}
+
+// applyAccrual заполняет в балансе дни, начисленные на дату asOf, и полагающиеся за весь год.
+// Начисленное на дату пропорционально отработанным в году месяцам от начисления за год (с учетом корректировок).
+func applyAccrual(limit *models.VacationLimit, hireDate *models.CustomDate, asOf time.Time) {
+	limit.FullYearDays = limit.TotalDays
+	limit.AccruedToDate = 0
+
+	start, ok := accrualPeriodStart(hireDate, limit.Year)
+	if !ok {
+		return
+	}
+	yearEnd := time.Date(limit.Year, time.December, 31, 0, 0, 0, 0, time.UTC)
+	asOfDate := time.Date(asOf.Year(), asOf.Month(), asOf.Day(), 0, 0, 0, 0, time.UTC)
+	if asOfDate.After(yearEnd) {
+		limit.AccruedToDate = limit.TotalDays
+		return
+	}
+	fullMonths := accrualMonths(start, yearEnd)
+	if fullMonths == 0 || limit.TotalDays <= 0 {
+		return
+	}
+	monthsToDate := accrualMonths(start, asOfDate)
+	limit.AccruedToDate = (limit.TotalDays*monthsToDate + fullMonths - 1) / fullMonths
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"vacation-scheduler/internal/models"
+)
+
+func ymd(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestAddMonthsClamped(t *testing.T) {
+	tests := []struct {
+		name   string
+		from   time.Time
+		months int
+		want   time.Time
+	}{
+		{"обычный день", ymd(2025, time.March, 10), 1, ymd(2025, time.April, 10)},
+		{"31 января в невисокосном году", ymd(2025, time.January, 31), 1, ymd(2025, time.February, 28)},
+		{"31 января в високосном году", ymd(2024, time.January, 31), 1, ymd(2024, time.February, 29)},
+		{"переход через год", ymd(2025, time.November, 30), 3, ymd(2026, time.February, 28)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := addMonthsClamped(tt.from, tt.months); !got.Equal(tt.want) {
+				t.Errorf("addMonthsClamped(%s, %d) = %s, want %s", tt.from.Format("2006-01-02"), tt.months, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestAccrualMonths(t *testing.T) {
+	tests := []struct {
+		name string
+		from time.Time
+		to   time.Time
+		want int
+	}{
+		{"полный год", ymd(2025, time.January, 1), ymd(2025, time.December, 31), 12},
+		{"конец раньше начала", ymd(2025, time.March, 1), ymd(2025, time.February, 28), 0},
+		{"14 дней - меньше половины месяца", ymd(2025, time.January, 1), ymd(2025, time.January, 14), 0},
+		{"15 дней - половина месяца", ymd(2025, time.January, 1), ymd(2025, time.January, 15), 1},
+		{"ровно один месяц", ymd(2025, time.January, 1), ymd(2025, time.January, 31), 1},
+		{"месяц и 14 дней", ymd(2025, time.January, 1), ymd(2025, time.February, 14), 1},
+		{"месяц и 15 дней", ymd(2025, time.January, 1), ymd(2025, time.February, 15), 2},
+		{"прием 16.06: остаток 16 дней", ymd(2025, time.June, 16), ymd(2025, time.December, 31), 7},
+		{"прием 17.06: остаток 15 дней", ymd(2025, time.June, 17), ymd(2025, time.December, 31), 7},
+		{"прием 18.06: остаток 14 дней", ymd(2025, time.June, 18), ymd(2025, time.December, 31), 6},
+		{"с 31 января по 27 февраля", ymd(2025, time.January, 31), ymd(2025, time.February, 27), 1},
+		{"больше года ограничено 12", ymd(2024, time.January, 1), ymd(2025, time.December, 31), 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := accrualMonths(tt.from, tt.to); got != tt.want {
+				t.Errorf("accrualMonths(%s, %s) = %d, want %d", tt.from.Format("2006-01-02"), tt.to.Format("2006-01-02"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProRataDays(t *testing.T) {
+	tests := []struct {
+		months int
+		want   int
+	}{
+		{-1, 0},
+		{0, 0},
+		{1, 3},  // 2,33 округляется вверх
+		{6, 14}, // ровно половина
+		{7, 17}, // 16,33 округляется вверх
+		{11, 26},
+		{12, 28},
+		{13, 28},
+	}
+	for _, tt := range tests {
+		if got := proRataDays(28, tt.months); got != tt.want {
+			t.Errorf("proRataDays(28, %d) = %d, want %d", tt.months, got, tt.want)
+		}
+	}
+}
+
+func TestAnnualEntitlement(t *testing.T) {
+	hired := func(year int, month time.Month, day int) *models.CustomDate {
+		return &models.CustomDate{Time: ymd(year, month, day)}
+	}
+	tests := []struct {
+		name     string
+		hireDate *models.CustomDate
+		want     int
+	}{
+		{"дата приема не указана", nil, 28},
+		{"принят до начала года", hired(2020, time.March, 5), 28},
+		{"принят 1 января", hired(2025, time.January, 1), 28},
+		{"принят 17 июня", hired(2025, time.June, 17), 17},
+		{"принят 18 июня", hired(2025, time.June, 18), 14},
+		{"принят 17 декабря", hired(2025, time.December, 17), 3},
+		{"принят 18 декабря", hired(2025, time.December, 18), 0},
+		{"принят в следующем году", hired(2026, time.February, 1), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := annualEntitlement(tt.hireDate, 2025, 28); got != tt.want {
+				t.Errorf("annualEntitlement(%s) = %d, want %d", tt.name, got, tt.want)
+			}
+		})
+	}
+}
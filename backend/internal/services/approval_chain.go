@@ -0,0 +1,127 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"vacation-scheduler/internal/models"
+	"vacation-scheduler/internal/repositories"
+)
+
+// ApprovalPolicy определяет состав цепочки согласования заявки
+type ApprovalPolicy struct {
+	ManagerLevels  int  // Сколько руководителей юнитов вверх по иерархии согласуют заявку
+	HRStep         bool // Завершающий этап отдела кадров (согласует любой администратор)
+	AutoDelegation bool // На время утвержденного отпуска руководителя его права переходят вышестоящему руководителю
+}
+
+// buildApprovalChain строит цепочку согласования заявки сотрудника: руководители юнитов от юнита
+// сотрудника вверх по иерархии (сам сотрудник и повторяющиеся руководители пропускаются),
+// затем этап отдела кадров. Если руководителей не нашлось, заявку согласует отдел кадров.
+func (s *VacationService) buildApprovalChain(employee *models.User) ([]models.ApprovalStep, error) {
+	steps := []models.ApprovalStep{}
+	seen := map[int]bool{employee.ID: true}
+	unitID := employee.OrganizationalUnitID
+	for unitID != nil && len(steps) < s.approval.ManagerLevels {
+		unit, err := s.unitRepo.GetByID(*unitID)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения юнита %d: %w", *unitID, err)
+		}
+		if unit == nil {
+			break
+		}
+		if unit.ManagerID != nil && !seen[*unit.ManagerID] {
+			seen[*unit.ManagerID] = true
+			unitRef, managerID := unit.ID, *unit.ManagerID
+			steps = append(steps, models.ApprovalStep{
+				StepType: models.ApprovalStepManager, UnitID: &unitRef, ApproverID: &managerID,
+			})
+		}
+		unitID = unit.ParentID
+	}
+	if s.approval.HRStep || len(steps) == 0 {
+		steps = append(steps, models.ApprovalStep{StepType: models.ApprovalStepHR})
+	}
+	for i := range steps {
+		steps[i].StepOrder = i + 1
+	}
+	return steps, nil
+}
+
+// startApprovalChain строит и сохраняет новую цепочку согласования заявки, отправленной на рассмотрение.
+// Прежняя цепочка (и принятые по ней решения) заменяется.
+func (s *VacationService) startApprovalChain(tx repositories.VacationRepositoryInterface, req *models.VacationRequest, employee *models.User) error {
+	if employee == nil || employee.ID != req.UserID {
+		found, err := s.findActor(req.UserID)
+		if err != nil {
+			return err
+		}
+		employee = found
+	}
+	steps, err := s.buildApprovalChain(employee)
+	if err != nil {
+		return fmt.Errorf("ошибка построения цепочки согласования заявки %d: %w", req.ID, err)
+	}
+	if err := tx.ReplaceApprovalSteps(req.ID, steps); err != nil {
+		return err
+	}
+	req.ApprovalSteps = steps
+	log.Printf("[Service ApprovalChain] Request %d: approval chain of %d step(s) started", req.ID, len(steps))
+	return nil
+}
+
+// decideCurrentStep сохраняет решение по текущему этапу цепочки и возвращает этот этап.
+// Для заявок без цепочки возвращает nil.
+func (s *VacationService) decideCurrentStep(tx repositories.VacationRepositoryInterface, req *models.VacationRequest, status string, actorID int, comment string) (*models.ApprovalStep, error) {
+	step := models.CurrentApprovalStep(req.ApprovalSteps)
+	if step == nil {
+		return nil, nil
+	}
+	if err := tx.SetApprovalStepDecision(step.ID, status, actorID, comment); err != nil {
+		return nil, err
+	}
+	step.Status = status
+	step.DecidedBy = &actorID
+	step.Comment = comment
+	return step, nil
+}
+
+// isStepApprover проверяет, что пользователь - согласующий текущего этапа цепочки
+// или его заместитель по действующему делегированию (кроме собственных заявок заместителя)
+func (s *VacationService) isStepApprover(actor *models.User, req *models.VacationRequest) (bool, error) {
+	step := models.CurrentApprovalStep(req.ApprovalSteps)
+	if step == nil || step.ApproverID == nil {
+		return false, nil
+	}
+	if *step.ApproverID == actor.ID {
+		return true, nil
+	}
+	if actor.ID == req.UserID {
+		return false, nil
+	}
+	delegations, err := s.vacationRepo.GetActiveDelegationsForDelegate(actor.ID, time.Now())
+	if err != nil {
+		return false, err
+	}
+	for _, d := range delegations {
+		if d.DelegatorID == *step.ApproverID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// notifyStepApprover уведомляет согласующего текущего этапа цепочки.
+// Этап отдела кадров адресован всем администраторам, они видят его в списке заявок.
+func (s *VacationService) notifyStepApprover(req *models.VacationRequest, title string, message string) {
+	step := models.CurrentApprovalStep(req.ApprovalSteps)
+	if step == nil {
+		return
+	}
+	if step.ApproverID == nil {
+		log.Printf("[Service Notify] Request %d is waiting for HR approval (step %d)", req.ID, step.StepOrder)
+		return
+	}
+	s.notifyUser(*step.ApproverID, title, message)
+}
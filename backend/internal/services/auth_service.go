@@ -12,20 +12,27 @@ import (
 	"golang.org/x/crypto/bcrypt"   // Раскомментирован для проверки пароля
 )
 
+// EntitlementProvider рассчитывает начисление отпуска сотруднику за год по действующей политике
+type EntitlementProvider interface {
+	AnnualEntitlement(user *models.User, year int) (int, error)
+}
+
 // AuthService предоставляет методы для аутентификации пользователей
 type AuthService struct {
 	userRepo repositories.UserRepositoryInterface // Используем интерфейс пользователя
 	// Используем интерфейс, определенный в repositories/vacation_repository.go (или где он должен быть)
 	vacationRepo repositories.VacationRepositoryInterface
-	jwtSecret    string // Секрет для JWT
+	entitlement  EntitlementProvider // Начисление отпуска при регистрации
+	jwtSecret    string              // Секрет для JWT
 }
 
 // NewAuthService создает новый экземпляр AuthService
 // Принимаем интерфейсы репозиториев
-func NewAuthService(userRepo repositories.UserRepositoryInterface, vacationRepo repositories.VacationRepositoryInterface, jwtSecret string) *AuthService {
+func NewAuthService(userRepo repositories.UserRepositoryInterface, vacationRepo repositories.VacationRepositoryInterface, entitlement EntitlementProvider, jwtSecret string) *AuthService {
 	return &AuthService{
 		userRepo:     userRepo,
 		vacationRepo: vacationRepo,
+		entitlement:  entitlement,
 		jwtSecret:    jwtSecret,
 	}
 }
@@ -106,6 +113,8 @@ func (s *AuthService) ValidateToken(tokenString string) (*models.User, error) {
 }
 
 // Register создает нового пользователя
+// Сотрудник считается принятым в день регистрации: дата приема влияет на начисление отпуска,
+// поэтому изменить ее может только администратор (UpdateUserAdmin).
 func (s *AuthService) Register(login, password, fullName string, positionID *int, organizationalUnitID *int) (*models.User, error) { // Удален параметр email, Добавлен organizationalUnitID
 	existingUser, err := s.userRepo.FindByLogin(login)
 	if err != nil {
@@ -124,6 +133,7 @@ func (s *AuthService) Register(login, password, fullName string, positionID *int
 		OrganizationalUnitID: organizationalUnitID, // Добавлено присваивание
 		IsAdmin:              false,
 		IsManager:            false,
+		HireDate:             &models.CustomDate{Time: truncateToDate(time.Now())},
 	}
 
 	err = s.userRepo.CreateUser(newUser)
@@ -131,12 +141,15 @@ func (s *AuthService) Register(login, password, fullName string, positionID *int
 		return nil, fmt.Errorf("ошибка создания пользователя в репозитории: %w", err)
 	}
 
-	const defaultVacationLimit = 28
+	// Начисление за текущий год по политике отпуска, пропорционально месяцам с даты приема
 	currentYear := time.Now().Year()
-	// Используем интерфейс repositories.VacationRepositoryInterface, переданный в конструкторе
-	errLimit := s.vacationRepo.CreateOrUpdateVacationLimit(newUser.ID, currentYear, defaultVacationLimit)
+	vacationLimit, errLimit := s.entitlement.AnnualEntitlement(newUser, currentYear)
+	if errLimit == nil {
+		// Используем интерфейс repositories.VacationRepositoryInterface, переданный в конструкторе
+		errLimit = s.vacationRepo.CreateOrUpdateVacationLimit(newUser.ID, currentYear, vacationLimit, nil, "Начисление при регистрации")
+	}
 	if errLimit != nil {
-		fmt.Printf("ВНИМАНИЕ: Пользователь %d создан, но не удалось установить начальный лимит отпуска (%d дней на %d год): %v\n", newUser.ID, defaultVacationLimit, currentYear, errLimit)
+		fmt.Printf("ВНИМАНИЕ: Пользователь %d создан, но не удалось установить начальный лимит отпуска (%d дней на %d год): %v\n", newUser.ID, vacationLimit, currentYear, errLimit)
 	}
 
 	return newUser, nil
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"vacation-scheduler/internal/models"
+)
+
+// maxBulkDecisionItems - наибольшее количество заявок в одном пакетном решении
+const maxBulkDecisionItems = 100
+
+// BulkDecideVacationRequests утверждает или отклоняет пакет заявок. Каждая заявка обрабатывается отдельно
+// теми же методами, что и одиночное решение (права, статус, конфликты, укомплектованность), в своей транзакции;
+// ошибка по одной заявке не отменяет решения по остальным и возвращается в итоге по этой заявке.
+func (s *VacationService) BulkDecideVacationRequests(actorID int, input *models.BulkDecisionRequest) (*models.BulkDecisionResult, error) {
+	action := strings.ToUpper(strings.TrimSpace(input.Action))
+	if action != models.ActionApprove && action != models.ActionReject {
+		return nil, fmt.Errorf("%w: неизвестное действие %q (допустимо %s или %s)", ErrInvalidRequest, input.Action, models.ActionApprove, models.ActionReject)
+	}
+	reason := strings.TrimSpace(input.Reason)
+	if action == models.ActionReject && s.reasonPolicy.RejectionRequired && reason == "" {
+		return nil, fmt.Errorf("%w: необходимо указать причину отклонения", ErrInvalidRequest)
+	}
+	requestIDs := []int{}
+	for _, id := range input.RequestIDs {
+		if !containsInt(requestIDs, id) {
+			requestIDs = append(requestIDs, id)
+		}
+	}
+	if len(requestIDs) == 0 {
+		return nil, fmt.Errorf("%w: не указаны заявки", ErrInvalidRequest)
+	}
+	if len(requestIDs) > maxBulkDecisionItems {
+		return nil, fmt.Errorf("%w: в одном пакете не более %d заявок", ErrInvalidRequest, maxBulkDecisionItems)
+	}
+	if _, err := s.findActor(actorID); err != nil {
+		return nil, err
+	}
+
+	result := &models.BulkDecisionResult{Items: []models.BulkDecisionItem{}, Summary: map[string]int{}}
+	for _, requestID := range requestIDs {
+		item := models.BulkDecisionItem{RequestID: requestID}
+		if action == models.ActionApprove {
+			conflicts, coverage, nextStep, err := s.ApproveVacationRequest(requestID, actorID, input.Force)
+			item.Conflicts, item.CoverageViolations = conflicts, coverage
+			switch {
+			case err != nil:
+				item.Outcome, item.Error = bulkFailureOutcome(err), err.Error()
+			case (len(conflicts) > 0 || len(coverage) > 0) && !input.Force:
+				item.Outcome = models.BulkOutcomeConflict
+			case nextStep != nil:
+				item.Outcome, item.NextStep = models.BulkOutcomeStepApproved, nextStep
+			default:
+				item.Outcome = models.BulkOutcomeApproved
+			}
+		} else if err := s.RejectVacationRequest(requestID, actorID, reason); err != nil {
+			item.Outcome, item.Error = bulkFailureOutcome(err), err.Error()
+		} else {
+			item.Outcome = models.BulkOutcomeRejected
+		}
+		result.Items = append(result.Items, item)
+		result.Summary[item.Outcome]++
+	}
+	log.Printf("[Service BulkDecideVacationRequests] %s of %d request(s) by user %d (force=%t): %v", action, len(requestIDs), actorID, input.Force, result.Summary)
+	return result, nil
+}
+
+// bulkFailureOutcome определяет итог по заявке, решение по которой завершилось ошибкой
+func bulkFailureOutcome(err error) string {
+	switch {
+	case errors.Is(err, ErrTransitionForbidden):
+		return models.BulkOutcomeForbidden
+	case errors.Is(err, ErrWrongStatus):
+		return models.BulkOutcomeWrongStatus
+	case errors.Is(err, ErrPriorityDisplacement):
+		return models.BulkOutcomePriority
+	case errors.Is(err, ErrRequestNotFound):
+		return models.BulkOutcomeNotFound
+	default:
+		return models.BulkOutcomeFailed
+	}
+}
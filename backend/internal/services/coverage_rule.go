@@ -0,0 +1,261 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"vacation-scheduler/internal/models"
+	"vacation-scheduler/internal/repositories"
+)
+
+// GetCoverageRules возвращает все правила укомплектованности
+func (s *VacationService) GetCoverageRules() ([]models.CoverageRule, error) {
+	return s.vacationRepo.GetCoverageRules()
+}
+
+// CreateCoverageRule создает правило укомплектованности юнита
+func (s *VacationService) CreateCoverageRule(rule *models.CoverageRule) error {
+	if err := s.checkCoverageRule(rule); err != nil {
+		return err
+	}
+	err := s.vacationRepo.RunInTx(func(tx repositories.VacationRepositoryInterface) error {
+		return tx.CreateCoverageRule(rule)
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("[Service CreateCoverageRule] Coverage rule %d created (unit %d, positions %v)", rule.ID, rule.UnitID, rule.PositionIDs)
+	return nil
+}
+
+// UpdateCoverageRule изменяет правило укомплектованности
+func (s *VacationService) UpdateCoverageRule(rule *models.CoverageRule) error {
+	existing, err := s.vacationRepo.GetCoverageRuleByID(rule.ID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return fmt.Errorf("правило укомплектованности %d не найдено", rule.ID)
+	}
+	if err := s.checkCoverageRule(rule); err != nil {
+		return err
+	}
+	err = s.vacationRepo.RunInTx(func(tx repositories.VacationRepositoryInterface) error {
+		return tx.UpdateCoverageRule(rule)
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("[Service UpdateCoverageRule] Coverage rule %d updated", rule.ID)
+	return nil
+}
+
+// DeleteCoverageRule удаляет правило укомплектованности
+func (s *VacationService) DeleteCoverageRule(ruleID int) error {
+	if err := s.vacationRepo.DeleteCoverageRule(ruleID); err != nil {
+		return err
+	}
+	log.Printf("[Service DeleteCoverageRule] Coverage rule %d deleted", ruleID)
+	return nil
+}
+
+// checkCoverageRule проверяет ограничения правила, существование юнита и должностей группы
+func (s *VacationService) checkCoverageRule(rule *models.CoverageRule) error {
+	rule.Comment = strings.TrimSpace(rule.Comment)
+	if rule.MaxAbsent == nil && rule.MinPresent == nil {
+		return fmt.Errorf("%w: необходимо указать максимум отсутствующих или минимум сотрудников на месте", ErrInvalidRequest)
+	}
+	if (rule.MaxAbsent != nil && *rule.MaxAbsent < 0) || (rule.MinPresent != nil && *rule.MinPresent < 0) {
+		return fmt.Errorf("%w: ограничения правила укомплектованности не могут быть отрицательными", ErrInvalidRequest)
+	}
+	unit, err := s.unitRepo.GetByID(rule.UnitID)
+	if err != nil {
+		return fmt.Errorf("ошибка получения юнита %d: %w", rule.UnitID, err)
+	}
+	if unit == nil {
+		return fmt.Errorf("юнит %d не найден", rule.UnitID)
+	}
+	seen := map[int]bool{}
+	positionIDs := []int{}
+	for _, positionID := range rule.PositionIDs {
+		if seen[positionID] {
+			continue
+		}
+		seen[positionID] = true
+		position, err := s.userRepo.GetPositionByID(positionID)
+		if err != nil {
+			return err
+		}
+		if position == nil {
+			return fmt.Errorf("должность %d не найдена", positionID)
+		}
+		positionIDs = append(positionIDs, positionID)
+	}
+	rule.PositionIDs = positionIDs
+	return nil
+}
+
+// coverageRuleCoversPosition проверяет, входит ли должность в группу должностей правила
+func coverageRuleCoversPosition(rule models.CoverageRule, positionID *int) bool {
+	if len(rule.PositionIDs) == 0 {
+		return true
+	}
+	return positionID != nil && containsInt(rule.PositionIDs, *positionID)
+}
+
+// coverageRuleMembers возвращает сотрудников, на которых действует правило: юнит (или его поддерево)
+// с учетом группы должностей
+func (s *VacationService) coverageRuleMembers(rule models.CoverageRule) ([]models.User, error) {
+	unitIDs := []int{rule.UnitID}
+	if rule.IncludeSubunits {
+		subtree, err := s.unitRepo.GetSubtreeIDs(rule.UnitID)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения поддерева юнита %d: %w", rule.UnitID, err)
+		}
+		unitIDs = subtree
+	}
+	users, err := s.userRepo.GetUsersByUnitIDs(unitIDs)
+	if err != nil {
+		return nil, err
+	}
+	members := []models.User{}
+	for _, u := range users {
+		if coverageRuleCoversPosition(rule, u.PositionID) {
+			members = append(members, u)
+		}
+	}
+	return members, nil
+}
+
+// applicableCoverageRules находит правила укомплектованности, действующие на сотрудника:
+// правила его юнита и правила вышестоящих юнитов, распространяющиеся на поддерево.
+func (s *VacationService) applicableCoverageRules(repo repositories.VacationRepositoryInterface, employee *models.User) ([]models.CoverageRule, error) {
+	if employee.OrganizationalUnitID == nil {
+		return nil, nil
+	}
+	unitIDs, err := s.unitAncestry(*employee.OrganizationalUnitID)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := repo.GetCoverageRulesByUnitIDs(unitIDs)
+	if err != nil {
+		return nil, err
+	}
+	applicable := []models.CoverageRule{}
+	for _, rule := range rules {
+		if rule.UnitID != *employee.OrganizationalUnitID && !rule.IncludeSubunits {
+			continue
+		}
+		if coverageRuleCoversPosition(rule, employee.PositionID) {
+			applicable = append(applicable, rule)
+		}
+	}
+	return applicable, nil
+}
+
+// checkCoverage проверяет по дням периодов заявки правила укомплектованности, действующие на автора:
+// отсутствие автора добавляется к утвержденным отпускам сотрудников, на которых действует правило.
+// lock блокирует проверяемые правила до конца транзакции (при утверждении).
+func (s *VacationService) checkCoverage(repo repositories.VacationRepositoryInterface, req *models.VacationRequest, lock bool) ([]models.CoverageViolation, error) {
+	violations := []models.CoverageViolation{}
+	if len(req.Periods) == 0 {
+		return violations, nil
+	}
+	employee, err := s.findActor(req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := s.applicableCoverageRules(repo, employee)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return violations, nil
+	}
+	if lock {
+		ruleIDs := make([]int, len(rules))
+		for i, rule := range rules {
+			ruleIDs[i] = rule.ID
+		}
+		if err := repo.LockCoverageRules(ruleIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	// Дни отсутствия автора по заявке
+	requestDays := map[time.Time]bool{}
+	from, to := req.Periods[0].StartDate.Time, req.Periods[0].EndDate.Time
+	for _, period := range req.Periods {
+		start, end := truncateToDate(period.StartDate.Time), truncateToDate(period.EndDate.Time)
+		for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+			requestDays[day] = true
+		}
+		if period.StartDate.Time.Before(from) {
+			from = period.StartDate.Time
+		}
+		if period.EndDate.Time.After(to) {
+			to = period.EndDate.Time
+		}
+	}
+	days := make([]time.Time, 0, len(requestDays))
+	for day := range requestDays {
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+
+	for _, rule := range rules {
+		users, err := s.coverageRuleMembers(rule)
+		if err != nil {
+			return nil, err
+		}
+		memberIDs := []int{}
+		headcount := 1 // Автор заявки
+		for _, u := range users {
+			if u.ID != employee.ID {
+				memberIDs = append(memberIDs, u.ID)
+				headcount++
+			}
+		}
+		absences, err := repo.GetApprovedAbsences(memberIDs, req.ID, from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, day := range days {
+			absentNames := map[int]string{employee.ID: employee.FullName}
+			for _, a := range absences {
+				if !truncateToDate(a.StartDate.Time).After(day) && !truncateToDate(a.EndDate.Time).Before(day) {
+					absentNames[a.UserID] = a.UserFullName
+				}
+			}
+			absent := len(absentNames)
+			tooManyAbsent := rule.MaxAbsent != nil && absent > *rule.MaxAbsent
+			tooFewPresent := rule.MinPresent != nil && headcount-absent < *rule.MinPresent
+			if !tooManyAbsent && !tooFewPresent {
+				continue
+			}
+			names := make([]string, 0, len(absentNames))
+			for _, name := range absentNames {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			message := fmt.Sprintf("%s, %s: отсутствуют %d из %d", day.Format("02.01.2006"), rule.UnitName, absent, headcount)
+			if tooManyAbsent {
+				message += fmt.Sprintf(", допускается не более %d", *rule.MaxAbsent)
+			}
+			if tooFewPresent {
+				message += fmt.Sprintf(", на месте должно оставаться не менее %d", *rule.MinPresent)
+			}
+			violations = append(violations, models.CoverageViolation{
+				RuleID: rule.ID, UnitID: rule.UnitID, UnitName: rule.UnitName,
+				Date: models.CustomDate{Time: day}, Headcount: headcount, Absent: absent,
+				MaxAbsent: rule.MaxAbsent, MinPresent: rule.MinPresent, AbsentUsers: names, Message: message,
+			})
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].Date.Time.Before(violations[j].Date.Time) })
+	return violations, nil
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"vacation-scheduler/internal/models"
+	"vacation-scheduler/internal/repositories"
+)
+
+// GetApprovalDelegations возвращает делегирования пользователя (выданные и полученные), администратору - все
+func (s *VacationService) GetApprovalDelegations(requestingUserID int) ([]models.ApprovalDelegation, error) {
+	user, err := s.findActor(requestingUserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsAdmin {
+		return s.vacationRepo.GetApprovalDelegations(nil)
+	}
+	return s.vacationRepo.GetApprovalDelegations(&user.ID)
+}
+
+// CreateApprovalDelegation передает права руководителя на рассмотрение заявок его поддерева заместителю на период.
+// Руководитель делегирует свои права, администратор - права любого руководителя.
+func (s *VacationService) CreateApprovalDelegation(actorID int, input *models.ApprovalDelegationCreateDTO) (*models.ApprovalDelegation, error) {
+	actor, err := s.findActor(actorID)
+	if err != nil {
+		return nil, err
+	}
+	delegatorID := actor.ID
+	if input.DelegatorID != nil && *input.DelegatorID != actor.ID {
+		if !actor.IsAdmin {
+			return nil, fmt.Errorf("%w: делегировать права другого руководителя может только администратор", ErrTransitionForbidden)
+		}
+		delegatorID = *input.DelegatorID
+	}
+	delegator, err := s.findActor(delegatorID)
+	if err != nil {
+		return nil, err
+	}
+	if !delegator.IsManager || delegator.OrganizationalUnitID == nil {
+		return nil, fmt.Errorf("%w: делегировать можно только права руководителя юнита", ErrInvalidRequest)
+	}
+	if input.DelegateID == delegator.ID {
+		return nil, fmt.Errorf("%w: нельзя делегировать права самому себе", ErrInvalidRequest)
+	}
+	if _, err := s.findActor(input.DelegateID); err != nil {
+		return nil, err
+	}
+	if input.StartDate.IsZero() || input.EndDate.IsZero() || input.EndDate.Time.Before(input.StartDate.Time) {
+		return nil, fmt.Errorf("%w: некорректный период делегирования", ErrInvalidRequest)
+	}
+	if input.EndDate.Time.Before(truncateToDate(time.Now())) {
+		return nil, fmt.Errorf("%w: период делегирования уже закончился", ErrInvalidRequest)
+	}
+
+	delegation := &models.ApprovalDelegation{
+		DelegatorID: delegator.ID, DelegateID: input.DelegateID,
+		StartDate: input.StartDate, EndDate: input.EndDate,
+		Comment: strings.TrimSpace(input.Comment), CreatedBy: &actor.ID,
+	}
+	if err := s.vacationRepo.CreateApprovalDelegation(delegation); err != nil {
+		return nil, err
+	}
+	log.Printf("[Service CreateApprovalDelegation] Delegation %d: manager %d -> user %d (%s - %s), created by %d",
+		delegation.ID, delegation.DelegatorID, delegation.DelegateID, delegation.StartDate.Format("2006-01-02"), delegation.EndDate.Format("2006-01-02"), actorID)
+
+	s.notifyUser(delegation.DelegateID, "Вам переданы права руководителя",
+		fmt.Sprintf("%s передал вам права на рассмотрение заявок своих сотрудников с %s по %s.", delegator.FullName,
+			delegation.StartDate.Format("02.01.2006"), delegation.EndDate.Format("02.01.2006")))
+	return delegation, nil
+}
+
+// RevokeApprovalDelegation отзывает делегирование (руководитель, передавший права, или администратор)
+func (s *VacationService) RevokeApprovalDelegation(delegationID int, actorID int) error {
+	actor, err := s.findActor(actorID)
+	if err != nil {
+		return err
+	}
+	delegation, err := s.vacationRepo.GetApprovalDelegationByID(delegationID)
+	if err != nil {
+		return err
+	}
+	if delegation == nil {
+		return fmt.Errorf("делегирование %d не найдено", delegationID)
+	}
+	if !actor.IsAdmin && delegation.DelegatorID != actor.ID {
+		return fmt.Errorf("%w: отозвать делегирование может только передавший права руководитель", ErrTransitionForbidden)
+	}
+	if err := s.vacationRepo.RevokeApprovalDelegation(delegationID); err != nil {
+		return err
+	}
+	log.Printf("[Service RevokeApprovalDelegation] Delegation %d revoked by user %d", delegationID, actorID)
+	return nil
+}
+
+// delegationCovering ищет среди действующих делегирований пользователя то, по которому он получает
+// права руководителя над сотрудником. Делегирование не дает прав на собственные заявки заместителя.
+func (s *VacationService) delegationCovering(delegate *models.User, target *models.User) (*models.ApprovalDelegation, error) {
+	if delegate.ID == target.ID {
+		return nil, nil
+	}
+	delegations, err := s.vacationRepo.GetActiveDelegationsForDelegate(delegate.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for i := range delegations {
+		delegator, err := s.userRepo.FindByID(delegations[i].DelegatorID)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения руководителя %d: %w", delegations[i].DelegatorID, err)
+		}
+		if delegator == nil || delegator.ID == target.ID {
+			continue
+		}
+		granted, err := s.directUnitAccess(delegator, target)
+		if err != nil {
+			return nil, err
+		}
+		if granted {
+			return &delegations[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// actingDelegation определяет руководителя, за которого пользователь действует с заявкой по делегированию.
+// Возвращает nil, если у пользователя есть собственные права (автор, администратор, согласующий этапа или руководитель).
+func (s *VacationService) actingDelegation(actor *models.User, req *models.VacationRequest) (*int, error) {
+	if actor == nil || actor.ID == req.UserID || actor.IsAdmin {
+		return nil, nil
+	}
+	if step := models.CurrentApprovalStep(req.ApprovalSteps); step != nil && req.StatusID == models.StatusPending {
+		if step.ApproverID == nil || *step.ApproverID == actor.ID {
+			return nil, nil
+		}
+		delegations, err := s.vacationRepo.GetActiveDelegationsForDelegate(actor.ID, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range delegations {
+			if d.DelegatorID == *step.ApproverID {
+				return &d.DelegatorID, nil
+			}
+		}
+		return nil, nil
+	}
+	employee, err := s.findActor(req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	direct, err := s.directUnitAccess(actor, employee)
+	if err != nil || direct {
+		return nil, err
+	}
+	delegation, err := s.delegationCovering(actor, employee)
+	if err != nil || delegation == nil {
+		return nil, err
+	}
+	return &delegation.DelegatorID, nil
+}
+
+// managedUnitIDs возвращает юниты, заявки сотрудников которых доступны руководителю:
+// собственное поддерево и поддеревья руководителей, передавших ему права.
+func (s *VacationService) managedUnitIDs(user *models.User) ([]int, error) {
+	seen := map[int]bool{}
+	unitIDs := []int{}
+	addSubtree := func(unitID int) error {
+		subtree, err := s.unitRepo.GetSubtreeIDs(unitID)
+		if err != nil {
+			return fmt.Errorf("ошибка получения поддерева юнитов руководителя: %w", err)
+		}
+		for _, id := range subtree {
+			if !seen[id] {
+				seen[id] = true
+				unitIDs = append(unitIDs, id)
+			}
+		}
+		return nil
+	}
+	if user.IsManager && user.OrganizationalUnitID != nil {
+		if err := addSubtree(*user.OrganizationalUnitID); err != nil {
+			return nil, err
+		}
+	}
+	delegations, err := s.vacationRepo.GetActiveDelegationsForDelegate(user.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range delegations {
+		delegator, err := s.userRepo.FindByID(d.DelegatorID)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения руководителя %d: %w", d.DelegatorID, err)
+		}
+		if delegator == nil || !delegator.IsManager || delegator.OrganizationalUnitID == nil {
+			continue
+		}
+		if err := addSubtree(*delegator.OrganizationalUnitID); err != nil {
+			return nil, err
+		}
+	}
+	return unitIDs, nil
+}
+
+// syncRequestDelegations пересоздает делегирования, созданные по отпуску руководителя:
+// на каждый еще не закончившийся период утвержденного отпуска права передаются вышестоящему руководителю.
+// Вызывается при утверждении, отмене, переносе и отзыве; для неутвержденной заявки делегирования удаляются.
+func (s *VacationService) syncRequestDelegations(tx repositories.VacationRepositoryInterface, requestID int) error {
+	if !s.approval.AutoDelegation {
+		return nil
+	}
+	if err := tx.DeleteRequestDelegations(requestID); err != nil {
+		return err
+	}
+	req, err := tx.GetVacationRequestByID(requestID)
+	if err != nil {
+		return err
+	}
+	if req == nil || !models.IsApprovedStatus(req.StatusID) {
+		return nil
+	}
+	employee, err := s.findActor(req.UserID)
+	if err != nil {
+		return err
+	}
+	if !employee.IsManager {
+		return nil
+	}
+	delegateID, err := s.findApproverID(employee)
+	if err != nil {
+		return err
+	}
+	if delegateID == nil {
+		log.Printf("[Service Delegation] No upper manager to delegate to for manager %d (request %d)", employee.ID, requestID)
+		return nil
+	}
+	today := truncateToDate(time.Now())
+	for _, period := range req.Periods {
+		if period.EndDate.Time.Before(today) {
+			continue
+		}
+		sourceID := req.ID
+		delegation := &models.ApprovalDelegation{
+			DelegatorID: employee.ID, DelegateID: *delegateID,
+			StartDate: period.StartDate, EndDate: period.EndDate,
+			Comment: fmt.Sprintf("На время отпуска по заявке №%d", req.ID), SourceRequestID: &sourceID,
+		}
+		if err := tx.CreateApprovalDelegation(delegation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
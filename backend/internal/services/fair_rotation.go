@@ -0,0 +1,508 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"vacation-scheduler/internal/models"
+	"vacation-scheduler/internal/repositories"
+)
+
+const (
+	// fairnessHistoryYears - сколько прошлых лет учитывается в очередности выбора пиковых дат
+	fairnessHistoryYears = 3
+	// maxPeakReportYears - наибольшая глубина отчета о распределении пиковых периодов
+	maxPeakReportYears = 10
+)
+
+// GetPeakWindows возвращает все пиковые периоды
+func (s *VacationService) GetPeakWindows() ([]models.PeakWindow, error) {
+	return s.vacationRepo.GetPeakWindows()
+}
+
+// CreatePeakWindow создает пиковый период (для организации или поддерева юнита)
+func (s *VacationService) CreatePeakWindow(actorID int, window *models.PeakWindow) error {
+	if err := s.checkPeakWindow(window); err != nil {
+		return err
+	}
+	window.CreatedBy = &actorID
+	if err := s.vacationRepo.CreatePeakWindow(window); err != nil {
+		return err
+	}
+	log.Printf("[Service CreatePeakWindow] Peak window %d (%s, %02d.%02d - %02d.%02d) created by user %d", window.ID, window.Name,
+		window.StartDay, window.StartMonth, window.EndDay, window.EndMonth, actorID)
+	return nil
+}
+
+// UpdatePeakWindow изменяет пиковый период
+func (s *VacationService) UpdatePeakWindow(window *models.PeakWindow) error {
+	existing, err := s.vacationRepo.GetPeakWindowByID(window.ID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return fmt.Errorf("пиковый период %d не найден", window.ID)
+	}
+	if err := s.checkPeakWindow(window); err != nil {
+		return err
+	}
+	window.CreatedBy = existing.CreatedBy
+	if err := s.vacationRepo.UpdatePeakWindow(window); err != nil {
+		return err
+	}
+	log.Printf("[Service UpdatePeakWindow] Peak window %d updated", window.ID)
+	return nil
+}
+
+// DeletePeakWindow удаляет пиковый период
+func (s *VacationService) DeletePeakWindow(windowID int) error {
+	if err := s.vacationRepo.DeletePeakWindow(windowID); err != nil {
+		return err
+	}
+	log.Printf("[Service DeletePeakWindow] Peak window %d deleted", windowID)
+	return nil
+}
+
+// checkPeakWindow проверяет название, даты и юнит пикового периода
+func (s *VacationService) checkPeakWindow(window *models.PeakWindow) error {
+	window.Name = strings.TrimSpace(window.Name)
+	window.Comment = strings.TrimSpace(window.Comment)
+	if window.Name == "" {
+		return fmt.Errorf("%w: необходимо указать название пикового периода", ErrInvalidRequest)
+	}
+	if !validMonthDay(window.StartMonth, window.StartDay) || !validMonthDay(window.EndMonth, window.EndDay) {
+		return fmt.Errorf("%w: некорректная дата начала или окончания пикового периода", ErrInvalidRequest)
+	}
+	if window.UnitID != nil {
+		unit, err := s.unitRepo.GetByID(*window.UnitID)
+		if err != nil {
+			return fmt.Errorf("ошибка получения юнита %d: %w", *window.UnitID, err)
+		}
+		if unit == nil {
+			return fmt.Errorf("юнит %d не найден", *window.UnitID)
+		}
+	}
+	return nil
+}
+
+// validMonthDay проверяет, что день существует в месяце невисокосного года
+func validMonthDay(month, day int) bool {
+	if month < 1 || month > 12 || day < 1 {
+		return false
+	}
+	return time.Date(2001, time.Month(month), day, 0, 0, 0, 0, time.UTC).Month() == time.Month(month)
+}
+
+// dayKey - ключ календарного дня, не зависящий от часового пояса даты
+func dayKey(t time.Time) int {
+	return t.Year()*10000 + int(t.Month())*100 + t.Day()
+}
+
+// fairnessScore - очередность сотрудника на пиковые даты года: пиковые дни прошлых лет с весом по давности
+// (прошлый год - наибольший вес). Меньше - раньше очередь.
+func fairnessScore(daysByYear map[int]int, targetYear int) int {
+	score := 0
+	for age := 1; age <= fairnessHistoryYears; age++ {
+		score += daysByYear[targetYear-age] * (fairnessHistoryYears - age + 1)
+	}
+	return score
+}
+
+// fairRotation - пиковые периоды, действующие на сотрудников, и история их отпусков в пиковые периоды
+type fairRotation struct {
+	s        *VacationService
+	repo     repositories.VacationRepositoryInterface
+	all      []models.PeakWindow
+	windows  map[int][]models.PeakWindow // Пиковые периоды, действующие на сотрудника
+	ancestry map[int][]int               // Вышестоящие юниты по ID юнита
+}
+
+// newFairRotation загружает пиковые периоды
+func (s *VacationService) newFairRotation(repo repositories.VacationRepositoryInterface) (*fairRotation, error) {
+	all, err := repo.GetPeakWindows()
+	if err != nil {
+		return nil, err
+	}
+	return &fairRotation{s: s, repo: repo, all: all, windows: map[int][]models.PeakWindow{}, ancestry: map[int][]int{}}, nil
+}
+
+// addEmployee определяет пиковые периоды сотрудника: общие для организации и заданные для его юнита или вышестоящих юнитов
+func (f *fairRotation) addEmployee(employee *models.User) error {
+	if _, ok := f.windows[employee.ID]; ok {
+		return nil
+	}
+	var unitIDs []int
+	if employee.OrganizationalUnitID != nil {
+		ancestry, ok := f.ancestry[*employee.OrganizationalUnitID]
+		if !ok {
+			var err error
+			ancestry, err = f.s.unitAncestry(*employee.OrganizationalUnitID)
+			if err != nil {
+				return err
+			}
+			f.ancestry[*employee.OrganizationalUnitID] = ancestry
+		}
+		unitIDs = ancestry
+	}
+	windows := []models.PeakWindow{}
+	for _, w := range f.all {
+		if w.UnitID == nil || containsInt(unitIDs, *w.UnitID) {
+			windows = append(windows, w)
+		}
+	}
+	f.windows[employee.ID] = windows
+	return nil
+}
+
+// addEmployeeByID определяет пиковые периоды сотрудника по его ID
+func (f *fairRotation) addEmployeeByID(userID int) error {
+	if _, ok := f.windows[userID]; ok {
+		return nil
+	}
+	employee, err := f.s.findActor(userID)
+	if err != nil {
+		return err
+	}
+	return f.addEmployee(employee)
+}
+
+// peakWindowIn возвращает пиковый период сотрудника, пересекающийся с диапазоном дат, или nil
+func (f *fairRotation) peakWindowIn(userID int, start, end time.Time) *models.PeakWindow {
+	for i, w := range f.windows[userID] {
+		for year := start.Year() - 1; year <= end.Year(); year++ {
+			from, to := w.Occurrence(year, start.Location())
+			if !start.After(to) && !from.After(end) {
+				return &f.windows[userID][i]
+			}
+		}
+	}
+	return nil
+}
+
+// daysByYear считает по утвержденным периодам прошлых заявок, сколько дней сотрудники провели
+// в своих пиковых периодах, начинающихся в годы fromYear..toYear. Возвращает дни по ID сотрудника и году.
+func (f *fairRotation) daysByYear(userIDs []int, fromYear, toYear int) (map[int]map[int]int, error) {
+	result := map[int]map[int]int{}
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+	absences, err := f.repo.GetApprovedAbsences(userIDs, 0,
+		time.Date(fromYear, time.January, 1, 0, 0, 0, 0, time.UTC), time.Date(toYear+1, time.December, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения отпусков прошлых лет: %w", err)
+	}
+	byUser := map[int][]models.StaffAbsence{}
+	for _, a := range absences {
+		byUser[a.UserID] = append(byUser[a.UserID], a)
+	}
+	for _, userID := range userIDs {
+		result[userID] = map[int]int{}
+		// День пикового периода -> год, в котором начинается этот пиковый период
+		peakYear := map[int]int{}
+		for _, w := range f.windows[userID] {
+			for year := fromYear; year <= toYear; year++ {
+				from, to := w.Occurrence(year, time.UTC)
+				for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+					if _, ok := peakYear[dayKey(day)]; !ok {
+						peakYear[dayKey(day)] = year
+					}
+				}
+			}
+		}
+		if len(peakYear) == 0 {
+			continue
+		}
+		counted := map[int]bool{}
+		for _, a := range byUser[userID] {
+			for day := truncateToDate(a.StartDate.Time); !day.After(a.EndDate.Time); day = day.AddDate(0, 0, 1) {
+				key := dayKey(day)
+				if year, ok := peakYear[key]; ok && !counted[key] {
+					counted[key] = true
+					result[userID][year]++
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// scores возвращает очередность сотрудников на пиковые даты указанного года
+func (f *fairRotation) scores(userIDs []int, targetYear int) (map[int]int, error) {
+	days, err := f.daysByYear(userIDs, targetYear-fairnessHistoryYears, targetYear-1)
+	if err != nil {
+		return nil, err
+	}
+	scores := make(map[int]int, len(userIDs))
+	for _, userID := range userIDs {
+		scores[userID] = fairnessScore(days[userID], targetYear)
+	}
+	return scores, nil
+}
+
+// annotateConflictFairness отмечает в конфликтах пиковый период, в который попадает пересечение,
+// и очередность обеих сторон на пиковые даты года пересечения. История отпусков читается через repo
+// (внутри транзакции утверждения - через транзакцию).
+func (s *VacationService) annotateConflictFairness(repo repositories.VacationRepositoryInterface, conflicts []models.ConflictingPeriod) error {
+	if len(conflicts) == 0 {
+		return nil
+	}
+	f, err := s.newFairRotation(repo)
+	if err != nil {
+		return err
+	}
+	usersByYear := map[int][]int{}
+	for _, c := range conflicts {
+		year := c.OverlapStartDate.Year()
+		for _, id := range []int{c.OriginalUserID, c.ConflictingUserID} {
+			if id == 0 {
+				continue
+			}
+			if err := f.addEmployeeByID(id); err != nil {
+				return err
+			}
+			if !containsInt(usersByYear[year], id) {
+				usersByYear[year] = append(usersByYear[year], id)
+			}
+		}
+	}
+	scoresByYear := map[int]map[int]int{}
+	for year, userIDs := range usersByYear {
+		scores, err := f.scores(userIDs, year)
+		if err != nil {
+			return err
+		}
+		scoresByYear[year] = scores
+	}
+	for i := range conflicts {
+		c := &conflicts[i]
+		scores := scoresByYear[c.OverlapStartDate.Year()]
+		if score, ok := scores[c.OriginalUserID]; ok {
+			c.OriginalFairnessScore = &score
+		}
+		if score, ok := scores[c.ConflictingUserID]; ok {
+			c.ConflictingFairnessScore = &score
+		}
+		w := f.peakWindowIn(c.OriginalUserID, c.OverlapStartDate.Time, c.OverlapEndDate.Time)
+		if w == nil {
+			w = f.peakWindowIn(c.ConflictingUserID, c.OverlapStartDate.Time, c.OverlapEndDate.Time)
+		}
+		if w != nil {
+			c.PeakWindow = w.Name
+		}
+	}
+	return nil
+}
+
+// checkFairRotation находит заявки на рассмотрении сотрудников той же должности, пересекающиеся с заявкой
+// в пиковый период, если очередь на пиковые даты у их авторов раньше. Каждое такое пересечение возвращается
+// как конфликт с признаком OutOfTurn и очередностью сторон. Сотрудник с преимущественным правом выбора
+// времени отпуска очереди не ждет.
+func (s *VacationService) checkFairRotation(repo repositories.VacationRepositoryInterface, req *models.VacationRequest, positionID int) ([]models.ConflictingPeriod, error) {
+	if len(req.Periods) == 0 {
+		return nil, nil
+	}
+	f, err := s.newFairRotation(repo)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.all) == 0 {
+		return nil, nil
+	}
+	employee, err := s.findActor(req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.addEmployee(employee); err != nil {
+		return nil, err
+	}
+	from, to := req.Periods[0].StartDate.Time, req.Periods[0].EndDate.Time
+	for _, period := range req.Periods {
+		if period.StartDate.Time.Before(from) {
+			from = period.StartDate.Time
+		}
+		if period.EndDate.Time.After(to) {
+			to = period.EndDate.Time
+		}
+	}
+	pending, err := repo.GetPendingAbsencesByPosition(positionID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.priorityCategoriesByUser([]int{req.UserID})
+	if err != nil {
+		return nil, err
+	}
+
+	outOfTurn := []models.ConflictingPeriod{}
+	scoresByYear := map[int]map[int]int{}
+	for _, a := range pending {
+		if a.UserID == req.UserID || a.RequestID == req.ID {
+			continue
+		}
+		for _, period := range req.Periods {
+			overlapStart := max(truncateToDate(period.StartDate.Time), truncateToDate(a.StartDate.Time))
+			overlapEnd := min(truncateToDate(period.EndDate.Time), truncateToDate(a.EndDate.Time))
+			if overlapStart.After(overlapEnd) {
+				continue
+			}
+			w := f.peakWindowIn(req.UserID, overlapStart, overlapEnd)
+			if w == nil || len(activePriorityNames(categories[req.UserID], overlapStart)) > 0 {
+				continue
+			}
+			if err := f.addEmployeeByID(a.UserID); err != nil {
+				return nil, err
+			}
+			year := overlapStart.Year()
+			if scoresByYear[year] == nil {
+				scoresByYear[year] = map[int]int{}
+			}
+			for _, id := range []int{req.UserID, a.UserID} {
+				if _, ok := scoresByYear[year][id]; ok {
+					continue
+				}
+				scores, err := f.scores([]int{id}, year)
+				if err != nil {
+					return nil, err
+				}
+				scoresByYear[year][id] = scores[id]
+			}
+			own, other := scoresByYear[year][req.UserID], scoresByYear[year][a.UserID]
+			if other >= own {
+				continue
+			}
+			outOfTurn = append(outOfTurn, models.ConflictingPeriod{
+				ConflictingUserID: a.UserID, ConflictingUserFullName: a.UserFullName, ConflictingRequestID: a.RequestID,
+				ConflictingStartDate: a.StartDate, ConflictingEndDate: a.EndDate,
+				OriginalUserID: req.UserID, OriginalUserFullName: employee.FullName, OriginalRequestID: req.ID, OriginalPeriodID: period.ID,
+				OriginalStartDate: period.StartDate, OriginalEndDate: period.EndDate,
+				OverlapStartDate: models.CustomDate{Time: overlapStart}, OverlapEndDate: models.CustomDate{Time: overlapEnd},
+				PeakWindow: w.Name, OriginalFairnessScore: &own, ConflictingFairnessScore: &other, OutOfTurn: true,
+			})
+		}
+	}
+	return outOfTurn, nil
+}
+
+// outOfTurnText описывает пересечения вне очереди для истории заявки
+func outOfTurnText(outOfTurn []models.ConflictingPeriod) string {
+	parts := make([]string, 0, len(outOfTurn))
+	for _, c := range outOfTurn {
+		parts = append(parts, fmt.Sprintf("с %s по %s («%s») раньше очередь у %s, заявка №%d (%d против %d)",
+			c.OverlapStartDate.Format("02.01.2006"), c.OverlapEndDate.Format("02.01.2006"), c.PeakWindow,
+			c.ConflictingUserFullName, c.ConflictingRequestID, *c.ConflictingFairnessScore, *c.OriginalFairnessScore))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// GetPeakSlotReport строит отчет о распределении пиковых периодов по юнитам поддерева за последние годы
+// (включая текущий). Очередность сотрудников рассчитывается на следующий год.
+func (s *VacationService) GetPeakSlotReport(requestingUserID int, unitID int, years int) (*models.PeakSlotReport, error) {
+	actor, err := s.findActor(requestingUserID)
+	if err != nil {
+		return nil, err
+	}
+	manages, err := s.managesUnit(actor, unitID)
+	if err != nil {
+		return nil, err
+	}
+	if !manages {
+		return nil, fmt.Errorf("%w: юнит %d не входит в ваше поддерево", ErrTransitionForbidden, unitID)
+	}
+	if years <= 0 {
+		years = fairnessHistoryYears
+	}
+	if years > maxPeakReportYears {
+		return nil, fmt.Errorf("%w: отчет строится не более чем за %d лет", ErrInvalidRequest, maxPeakReportYears)
+	}
+
+	subtreeIDs, err := s.unitRepo.GetSubtreeIDs(unitID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения поддерева юнита %d: %w", unitID, err)
+	}
+	allUnits, err := s.unitRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения юнитов: %w", err)
+	}
+	unitNames := map[int]string{}
+	for _, unit := range allUnits {
+		unitNames[unit.ID] = unit.Name
+	}
+	users, err := s.userRepo.GetUsersByUnitIDs(subtreeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := s.newFairRotation(s.vacationRepo)
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]int, len(users))
+	for i := range users {
+		if err := f.addEmployee(&users[i]); err != nil {
+			return nil, err
+		}
+		userIDs[i] = users[i].ID
+	}
+	currentYear := time.Now().Year()
+	fromYear := currentYear - years + 1
+	if scoreFrom := currentYear + 1 - fairnessHistoryYears; scoreFrom < fromYear {
+		fromYear = scoreFrom
+	}
+	days, err := f.daysByYear(userIDs, fromYear, currentYear)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.PeakSlotReport{UnitID: unitID, Years: []int{}, Windows: []models.PeakWindow{}, Units: []models.PeakSlotUnit{}}
+	for year := currentYear - years + 1; year <= currentYear; year++ {
+		report.Years = append(report.Years, year)
+	}
+	// Периоды вышестоящих юнитов тоже действуют на поддерево
+	ancestry, err := s.unitAncestry(unitID)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range f.all {
+		if w.UnitID == nil || containsInt(subtreeIDs, *w.UnitID) || containsInt(ancestry, *w.UnitID) {
+			report.Windows = append(report.Windows, w)
+		}
+	}
+
+	units := map[int]*models.PeakSlotUnit{}
+	for _, id := range subtreeIDs {
+		units[id] = &models.PeakSlotUnit{UnitID: id, UnitName: unitNames[id], Employees: []models.PeakSlotEmployee{}, DaysByYear: map[int]int{}}
+	}
+	for _, u := range users {
+		if u.OrganizationalUnitID == nil || units[*u.OrganizationalUnitID] == nil {
+			continue
+		}
+		unit := units[*u.OrganizationalUnitID]
+		employee := models.PeakSlotEmployee{UserID: u.ID, UserFullName: u.FullName, DaysByYear: map[int]int{},
+			FairnessScore: fairnessScore(days[u.ID], currentYear+1)}
+		for _, year := range report.Years {
+			employee.DaysByYear[year] = days[u.ID][year]
+			employee.TotalDays += days[u.ID][year]
+			unit.DaysByYear[year] += days[u.ID][year]
+		}
+		if employee.TotalDays > 0 {
+			unit.EmployeesWithPeak++
+		}
+		unit.Employees = append(unit.Employees, employee)
+	}
+	for _, id := range subtreeIDs {
+		unit := units[id]
+		// Сначала сотрудники, дольше всех не отдыхавшие в пиковый период
+		sort.SliceStable(unit.Employees, func(i, j int) bool {
+			if unit.Employees[i].FairnessScore != unit.Employees[j].FairnessScore {
+				return unit.Employees[i].FairnessScore < unit.Employees[j].FairnessScore
+			}
+			return unit.Employees[i].UserFullName < unit.Employees[j].UserFullName
+		})
+		report.Units = append(report.Units, *unit)
+	}
+	log.Printf("[Service GetPeakSlotReport] Unit %d, %d year(s): %d unit(s), %d employee(s) (by user %d)", unitID, years, len(report.Units), len(users), requestingUserID)
+	return report, nil
+}
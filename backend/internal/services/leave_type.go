@@ -0,0 +1,121 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"vacation-scheduler/internal/models"
+	"vacation-scheduler/internal/repositories"
+)
+
+// GetLeaveTypes возвращает справочник видов отпуска
+func (s *VacationService) GetLeaveTypes() ([]models.LeaveType, error) {
+	return s.vacationRepo.GetLeaveTypes()
+}
+
+// CreateLeaveType добавляет вид отпуска в справочник. Новый вид создается активным.
+func (s *VacationService) CreateLeaveType(leaveType *models.LeaveType) error {
+	if err := normalizeLeaveType(leaveType); err != nil {
+		return err
+	}
+	leaveType.IsActive = true
+	if err := s.vacationRepo.CreateLeaveType(leaveType); err != nil {
+		return err
+	}
+	log.Printf("[Service CreateLeaveType] Leave type %d (%s) created", leaveType.ID, leaveType.Code)
+	return nil
+}
+
+// UpdateLeaveType изменяет вид отпуска.
+// Основной отпуск нельзя отключить или исключить из баланса: на нем построены начисление и журнал дней.
+func (s *VacationService) UpdateLeaveType(leaveType *models.LeaveType) error {
+	if err := normalizeLeaveType(leaveType); err != nil {
+		return err
+	}
+	existing, err := s.vacationRepo.GetLeaveTypeByID(leaveType.ID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return fmt.Errorf("вид отпуска %d не найден", leaveType.ID)
+	}
+	if leaveType.ID == models.LeaveTypeMain && (!leaveType.CountsTowardMain || !leaveType.IsActive) {
+		return fmt.Errorf("%w: основной отпуск нельзя отключить или исключить из баланса", ErrInvalidRequest)
+	}
+	if err := s.vacationRepo.UpdateLeaveType(leaveType); err != nil {
+		return err
+	}
+	log.Printf("[Service UpdateLeaveType] Leave type %d (%s) updated", leaveType.ID, leaveType.Code)
+	return nil
+}
+
+// normalizeLeaveType проверяет поля вида отпуска перед сохранением
+func normalizeLeaveType(leaveType *models.LeaveType) error {
+	leaveType.Code = strings.ToUpper(strings.TrimSpace(leaveType.Code))
+	leaveType.Name = strings.TrimSpace(leaveType.Name)
+	if leaveType.Code == "" || leaveType.Name == "" {
+		return fmt.Errorf("%w: необходимо указать код и название вида отпуска", ErrInvalidRequest)
+	}
+	if leaveType.CountingRule == "" {
+		leaveType.CountingRule = models.CountingCalendar
+	}
+	if leaveType.CountingRule != models.CountingCalendar && leaveType.CountingRule != models.CountingWorking {
+		return fmt.Errorf("%w: неизвестное правило подсчета дней %q", ErrInvalidRequest, leaveType.CountingRule)
+	}
+	if leaveType.MaxDaysPerYear != nil && *leaveType.MaxDaysPerYear < 0 {
+		return fmt.Errorf("%w: ограничение дней в году не может быть отрицательным", ErrInvalidRequest)
+	}
+	return nil
+}
+
+// leaveTypesByID загружает справочник видов отпуска в виде карты по ID
+func (s *VacationService) leaveTypesByID(repo repositories.VacationRepositoryInterface) (map[int]models.LeaveType, error) {
+	leaveTypes, err := repo.GetLeaveTypes()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения справочника видов отпуска: %w", err)
+	}
+	result := make(map[int]models.LeaveType, len(leaveTypes))
+	for _, lt := range leaveTypes {
+		result[lt.ID] = lt
+	}
+	return result, nil
+}
+
+// countLeaveDays считает дни периода по правилу подсчета вида отпуска
+func (s *VacationService) countLeaveDays(leaveType models.LeaveType, startDate time.Time, endDate time.Time) int {
+	if leaveType.CountingRule == models.CountingWorking {
+		return s.calendar.CountWorkingDays(startDate, endDate)
+	}
+	return s.calendar.CountVacationDays(startDate, endDate)
+}
+
+// validateLeaveTypeLimits проверяет ограничения дней в году по видам отпуска с учетом
+// других заявок сотрудника на рассмотрении и утвержденных. Возвращает список нарушений.
+func (s *VacationService) validateLeaveTypeLimits(repo repositories.VacationRepositoryInterface, request *models.VacationRequest, leaveTypes map[int]models.LeaveType) ([]string, error) {
+	requested := map[int]int{}
+	for _, period := range request.Periods {
+		if lt := leaveTypes[period.LeaveTypeID]; lt.MaxDaysPerYear != nil {
+			requested[period.LeaveTypeID] += period.DaysCount
+		}
+	}
+	if len(requested) == 0 {
+		return nil, nil
+	}
+	used, err := repo.GetLeaveTypeDaysUsed(request.UserID, request.Year, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	violations := []string{}
+	for leaveTypeID, days := range requested {
+		lt := leaveTypes[leaveTypeID]
+		if used[leaveTypeID]+days > *lt.MaxDaysPerYear {
+			violations = append(violations, fmt.Sprintf("превышено ограничение для вида отпуска \"%s\": не более %d дн. в году, уже запланировано %d, запрошено %d",
+				lt.Name, *lt.MaxDaysPerYear, used[leaveTypeID], days))
+		}
+	}
+	sort.Strings(violations)
+	return violations, nil
+}
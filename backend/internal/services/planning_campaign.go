@@ -0,0 +1,334 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"vacation-scheduler/internal/models"
+	"vacation-scheduler/internal/repositories"
+)
+
+// campaignPhaseNames - названия этапов кампании для сообщений
+var campaignPhaseNames = map[string]string{
+	models.CampaignPhaseCollecting:    "сбор заявок",
+	models.CampaignPhaseManagerReview: "рассмотрение руководителями",
+	models.CampaignPhaseHRReview:      "проверка отделом кадров",
+	models.CampaignPhaseLocked:        "график утвержден",
+}
+
+// GetPlanningCampaigns возвращает кампании планирования графика отпусков (yearFilter == nil - всех лет)
+func (s *VacationService) GetPlanningCampaigns(yearFilter *int) ([]models.PlanningCampaign, error) {
+	return s.vacationRepo.GetPlanningCampaigns(yearFilter)
+}
+
+// CreatePlanningCampaign открывает кампанию планирования на год для всей организации или поддерева юнита.
+// Кампания начинается с этапа сбора заявок; на год и юнит допускается одна кампания.
+func (s *VacationService) CreatePlanningCampaign(actorID int, campaign *models.PlanningCampaign) error {
+	if err := s.checkCampaignDeadlines(campaign); err != nil {
+		return err
+	}
+	if campaign.UnitID != nil {
+		unit, err := s.unitRepo.GetByID(*campaign.UnitID)
+		if err != nil {
+			return fmt.Errorf("ошибка получения юнита %d: %w", *campaign.UnitID, err)
+		}
+		if unit == nil {
+			return fmt.Errorf("юнит %d не найден", *campaign.UnitID)
+		}
+	}
+	existing, err := s.vacationRepo.GetPlanningCampaigns(&campaign.Year)
+	if err != nil {
+		return err
+	}
+	for _, c := range existing {
+		if (c.UnitID == nil && campaign.UnitID == nil) || (c.UnitID != nil && campaign.UnitID != nil && *c.UnitID == *campaign.UnitID) {
+			return fmt.Errorf("%w: кампания планирования на %d год для этого охвата уже существует (ID %d)", ErrInvalidRequest, campaign.Year, c.ID)
+		}
+	}
+	campaign.Phase = models.CampaignPhaseCollecting
+	campaign.LockedAt = nil
+	campaign.CreatedBy = &actorID
+	if err := s.vacationRepo.CreatePlanningCampaign(campaign); err != nil {
+		return err
+	}
+	log.Printf("[Service CreatePlanningCampaign] Campaign %d for year %d (unit %v) created by user %d, collecting until %s",
+		campaign.ID, campaign.Year, campaign.UnitID, actorID, campaign.CollectingDeadline.Format("2006-01-02"))
+	return nil
+}
+
+// UpdatePlanningCampaignDeadlines изменяет сроки этапов кампании; этап и охват кампании не меняются
+func (s *VacationService) UpdatePlanningCampaignDeadlines(actorID int, campaign *models.PlanningCampaign) error {
+	existing, err := s.vacationRepo.GetPlanningCampaignByID(campaign.ID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return fmt.Errorf("кампания планирования %d не найдена", campaign.ID)
+	}
+	if existing.Phase == models.CampaignPhaseLocked {
+		return fmt.Errorf("%w: график отпусков на %d год утвержден, сроки кампании не изменяются", ErrTransitionNotAllowed, existing.Year)
+	}
+	existing.CollectingDeadline = campaign.CollectingDeadline
+	existing.ManagerReviewDeadline = campaign.ManagerReviewDeadline
+	existing.HRReviewDeadline = campaign.HRReviewDeadline
+	if err := s.checkCampaignDeadlines(existing); err != nil {
+		return err
+	}
+	if err := s.vacationRepo.UpdatePlanningCampaign(existing); err != nil {
+		return err
+	}
+	*campaign = *existing
+	log.Printf("[Service UpdatePlanningCampaignDeadlines] Campaign %d deadlines updated by user %d", campaign.ID, actorID)
+	return nil
+}
+
+// AdvancePlanningCampaign переводит кампанию на следующий этап: сбор заявок -> рассмотрение руководителями ->
+// проверка отделом кадров -> график утвержден. Утвержденный график меняется только через перенос.
+func (s *VacationService) AdvancePlanningCampaign(actorID int, campaignID int) (*models.PlanningCampaign, error) {
+	campaign, err := s.vacationRepo.GetPlanningCampaignByID(campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil {
+		return nil, fmt.Errorf("кампания планирования %d не найдена", campaignID)
+	}
+	next := ""
+	for i, phase := range models.CampaignPhases {
+		if phase == campaign.Phase && i+1 < len(models.CampaignPhases) {
+			next = models.CampaignPhases[i+1]
+		}
+	}
+	if next == "" {
+		return nil, fmt.Errorf("%w: график отпусков на %d год уже утвержден", ErrTransitionNotAllowed, campaign.Year)
+	}
+	previous := campaign.Phase
+	campaign.Phase = next
+	if next == models.CampaignPhaseLocked {
+		now := time.Now()
+		campaign.LockedAt = &now
+	}
+	if err := s.vacationRepo.UpdatePlanningCampaign(campaign); err != nil {
+		return nil, err
+	}
+	log.Printf("[Service AdvancePlanningCampaign] Campaign %d (year %d) moved from %s to %s by user %d", campaignID, campaign.Year, previous, next, actorID)
+	return campaign, nil
+}
+
+// GetPlanningCampaignProgress показывает, кто из сотрудников в охвате кампании подал заявки на год, а кто нет.
+// Сотрудники юнитов со своей кампанией на этот год относятся к ней. Руководитель видит только свое поддерево.
+func (s *VacationService) GetPlanningCampaignProgress(requestingUserID int, campaignID int) (*models.CampaignProgress, error) {
+	actor, err := s.findActor(requestingUserID)
+	if err != nil {
+		return nil, err
+	}
+	campaign, err := s.vacationRepo.GetPlanningCampaignByID(campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil {
+		return nil, fmt.Errorf("кампания планирования %d не найдена", campaignID)
+	}
+
+	var unitIDs []int
+	if campaign.UnitID != nil {
+		unitIDs, err = s.unitRepo.GetSubtreeIDs(*campaign.UnitID)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения поддерева юнита %d: %w", *campaign.UnitID, err)
+		}
+	} else {
+		units, err := s.unitRepo.GetAll()
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения юнитов: %w", err)
+		}
+		for _, unit := range units {
+			unitIDs = append(unitIDs, unit.ID)
+		}
+	}
+	if !actor.IsAdmin {
+		managed, err := s.managedUnitIDs(actor)
+		if err != nil {
+			return nil, err
+		}
+		visible := []int{}
+		for _, unitID := range unitIDs {
+			if containsInt(managed, unitID) {
+				visible = append(visible, unitID)
+			}
+		}
+		if len(visible) == 0 {
+			return nil, fmt.Errorf("%w: нет доступа к ходу кампании планирования %d", ErrTransitionForbidden, campaignID)
+		}
+		unitIDs = visible
+	}
+
+	campaigns, err := s.vacationRepo.GetPlanningCampaigns(&campaign.Year)
+	if err != nil {
+		return nil, err
+	}
+	users, err := s.userRepo.GetUsersByUnitIDs(unitIDs)
+	if err != nil {
+		return nil, err
+	}
+	ownCampaign := map[int]bool{} // Юнит относится к этой кампании (а не к более частной)
+	participants := []models.User{}
+	userIDs := []int{}
+	for _, u := range users {
+		if u.OrganizationalUnitID == nil {
+			continue
+		}
+		belongs, ok := ownCampaign[*u.OrganizationalUnitID]
+		if !ok {
+			ancestry, err := s.unitAncestry(*u.OrganizationalUnitID)
+			if err != nil {
+				return nil, err
+			}
+			nearest := selectCampaign(campaigns, ancestry)
+			belongs = nearest != nil && nearest.ID == campaign.ID
+			ownCampaign[*u.OrganizationalUnitID] = belongs
+		}
+		if belongs {
+			participants = append(participants, u)
+			userIDs = append(userIDs, u.ID)
+		}
+	}
+	counts, err := s.vacationRepo.CountActiveRequestsByUser(userIDs, campaign.Year)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := &models.CampaignProgress{
+		Campaign:     *campaign,
+		Total:        len(participants),
+		Submitted:    []models.CampaignParticipant{},
+		NotSubmitted: []models.CampaignParticipant{},
+	}
+	for _, u := range participants {
+		participant := models.CampaignParticipant{UserID: u.ID, FullName: u.FullName, UnitID: u.OrganizationalUnitID, RequestCount: counts[u.ID]}
+		if participant.RequestCount > 0 {
+			progress.Submitted = append(progress.Submitted, participant)
+		} else {
+			progress.NotSubmitted = append(progress.NotSubmitted, participant)
+		}
+	}
+	return progress, nil
+}
+
+// checkCampaignDeadlines проверяет год и сроки этапов: этапы идут по порядку,
+// а график утверждается не позднее чем за две недели до наступления календарного года
+func (s *VacationService) checkCampaignDeadlines(campaign *models.PlanningCampaign) error {
+	if campaign.Year <= 0 {
+		return fmt.Errorf("%w: необходимо указать год кампании", ErrInvalidRequest)
+	}
+	if campaign.CollectingDeadline.IsZero() || campaign.ManagerReviewDeadline.IsZero() || campaign.HRReviewDeadline.IsZero() {
+		return fmt.Errorf("%w: необходимо указать сроки всех этапов кампании", ErrInvalidRequest)
+	}
+	if campaign.ManagerReviewDeadline.Time.Before(campaign.CollectingDeadline.Time) || campaign.HRReviewDeadline.Time.Before(campaign.ManagerReviewDeadline.Time) {
+		return fmt.Errorf("%w: сроки этапов кампании должны идти по порядку: сбор заявок, рассмотрение руководителями, проверка отделом кадров", ErrInvalidRequest)
+	}
+	latest := time.Date(campaign.Year, time.January, 1, 0, 0, 0, 0, campaign.HRReviewDeadline.Location()).AddDate(0, 0, -14)
+	if campaign.HRReviewDeadline.Time.After(latest) {
+		return fmt.Errorf("%w: график отпусков на %d год утверждается не позднее %s (за две недели до начала года)",
+			ErrInvalidRequest, campaign.Year, latest.Format("02.01.2006"))
+	}
+	return nil
+}
+
+// selectCampaign выбирает кампанию ближайшего юнита ветки (ancestry - от юнита к корню),
+// при ее отсутствии - кампанию всей организации
+func selectCampaign(campaigns []models.PlanningCampaign, ancestry []int) *models.PlanningCampaign {
+	for _, unitID := range ancestry {
+		for i := range campaigns {
+			if campaigns[i].UnitID != nil && *campaigns[i].UnitID == unitID {
+				return &campaigns[i]
+			}
+		}
+	}
+	for i := range campaigns {
+		if campaigns[i].UnitID == nil {
+			return &campaigns[i]
+		}
+	}
+	return nil
+}
+
+// employeeCampaign возвращает кампанию планирования, действующую для сотрудника на год (nil - кампании нет)
+func (s *VacationService) employeeCampaign(repo repositories.VacationRepositoryInterface, employee *models.User, year int) (*models.PlanningCampaign, error) {
+	campaigns, err := repo.GetPlanningCampaigns(&year)
+	if err != nil {
+		return nil, err
+	}
+	if len(campaigns) == 0 {
+		return nil, nil
+	}
+	var ancestry []int
+	if employee.OrganizationalUnitID != nil {
+		ancestry, err = s.unitAncestry(*employee.OrganizationalUnitID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return selectCampaign(campaigns, ancestry), nil
+}
+
+// hasSchedulePeriods проверяет, есть ли в заявке периоды видов отпуска, включаемых в график
+func hasSchedulePeriods(request *models.VacationRequest, leaveTypes map[int]models.LeaveType) bool {
+	for _, period := range request.Periods {
+		leaveTypeID := period.LeaveTypeID
+		if leaveTypeID == 0 {
+			leaveTypeID = models.LeaveTypeMain
+		}
+		if leaveTypes[leaveTypeID].InSchedule {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPlanningCampaign проверяет, принимаются ли заявки на отпуска графика: при кампании на год заявку
+// можно создать, изменить или отправить только на этапе сбора до его срока. Возвращает нарушение или пустую строку.
+func (s *VacationService) checkPlanningCampaign(repo repositories.VacationRepositoryInterface, employee *models.User, request *models.VacationRequest, leaveTypes map[int]models.LeaveType) (string, error) {
+	if !hasSchedulePeriods(request, leaveTypes) {
+		return "", nil
+	}
+	campaign, err := s.employeeCampaign(repo, employee, request.Year)
+	if err != nil || campaign == nil {
+		return "", err
+	}
+	switch {
+	case campaign.Phase == models.CampaignPhaseLocked:
+		return fmt.Sprintf("график отпусков на %d год утвержден: изменить отпуск можно только заявкой на перенос", request.Year), nil
+	case campaign.Phase != models.CampaignPhaseCollecting:
+		return fmt.Sprintf("сбор заявок на %d год завершен, график на этапе \"%s\"", request.Year, campaignPhaseNames[campaign.Phase]), nil
+	case truncateToDate(time.Now()).After(truncateToDate(campaign.CollectingDeadline.Time)):
+		return fmt.Sprintf("сбор заявок на %d год завершен %s", request.Year, campaign.CollectingDeadline.Format("02.01.2006")), nil
+	}
+	return "", nil
+}
+
+// ensureScheduleNotLocked запрещает отменять утвержденный отпуск графика после утверждения графика:
+// изменить его можно только переносом. Администратор (отдел кадров) может отменить такой отпуск.
+func (s *VacationService) ensureScheduleNotLocked(repo repositories.VacationRepositoryInterface, actor *models.User, req *models.VacationRequest) error {
+	if actor.IsAdmin || !models.IsApprovedStatus(req.StatusID) {
+		return nil
+	}
+	leaveTypes, err := s.leaveTypesByID(repo)
+	if err != nil {
+		return err
+	}
+	if !hasSchedulePeriods(req, leaveTypes) {
+		return nil
+	}
+	employee, err := s.findActor(req.UserID)
+	if err != nil {
+		return err
+	}
+	campaign, err := s.employeeCampaign(repo, employee, req.Year)
+	if err != nil {
+		return err
+	}
+	if campaign != nil && campaign.Phase == models.CampaignPhaseLocked {
+		return fmt.Errorf("%w: график отпусков на %d год утвержден, изменить отпуск можно только заявкой на перенос", ErrTransitionNotAllowed, req.Year)
+	}
+	return nil
+}
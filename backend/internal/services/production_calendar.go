@@ -0,0 +1,169 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"vacation-scheduler/internal/models"
+)
+
+const calendarDateFormat = "2006-01-02"
+
+// ProductionCalendarInterface определяет методы производственного календаря
+type ProductionCalendarInterface interface {
+	HasYear(year int) bool
+	IsHoliday(date time.Time) bool
+	IsWorkingDay(date time.Time) bool
+	CountVacationDays(startDate time.Time, endDate time.Time) int // Календарные дни без нерабочих праздничных (ст. 120 ТК РФ)
+	CountWorkingDays(startDate time.Time, endDate time.Time) int  // Рабочие дни с учетом выходных, праздников и переносов
+	VacationEndDate(startDate time.Time, days int) time.Time      // Дата окончания отпуска с продлением на праздники
+}
+
+// productionCalendarFile - формат файла производственного календаря
+type productionCalendarFile struct {
+	Years []models.ProductionCalendarYear `json:"years"`
+}
+
+// calendarYear - внутреннее представление года производственного календаря
+type calendarYear struct {
+	holidays     map[string]string   // Дата -> название праздника
+	transferFrom map[string]struct{} // Выходные дни, ставшие рабочими
+	transferTo   map[string]struct{} // Рабочие дни, ставшие выходными
+}
+
+// ProductionCalendar реализует ProductionCalendarInterface
+// Хранит нерабочие праздничные дни и переносы выходных по годам.
+type ProductionCalendar struct {
+	years map[int]*calendarYear
+}
+
+// NewProductionCalendar создает производственный календарь из данных по годам
+func NewProductionCalendar(years []models.ProductionCalendarYear) (*ProductionCalendar, error) {
+	calendar := &ProductionCalendar{years: make(map[int]*calendarYear)}
+	for _, y := range years {
+		cy := &calendarYear{
+			holidays:     make(map[string]string),
+			transferFrom: make(map[string]struct{}),
+			transferTo:   make(map[string]struct{}),
+		}
+		for _, h := range y.Holidays {
+			if _, err := time.Parse(calendarDateFormat, h.Date); err != nil {
+				return nil, fmt.Errorf("некорректная дата праздника '%s' (год %d): %w", h.Date, y.Year, err)
+			}
+			cy.holidays[h.Date] = h.Name
+		}
+		for _, t := range y.Transfers {
+			if _, err := time.Parse(calendarDateFormat, t.From); err != nil {
+				return nil, fmt.Errorf("некорректная дата переноса '%s' (год %d): %w", t.From, y.Year, err)
+			}
+			if _, err := time.Parse(calendarDateFormat, t.To); err != nil {
+				return nil, fmt.Errorf("некорректная дата переноса '%s' (год %d): %w", t.To, y.Year, err)
+			}
+			cy.transferFrom[t.From] = struct{}{}
+			cy.transferTo[t.To] = struct{}{}
+		}
+		calendar.years[y.Year] = cy
+	}
+	return calendar, nil
+}
+
+// LoadProductionCalendar загружает производственный календарь из JSON-файла
+func LoadProductionCalendar(path string) (*ProductionCalendar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения файла производственного календаря %s: %w", path, err)
+	}
+	var file productionCalendarFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("ошибка разбора файла производственного календаря %s: %w", path, err)
+	}
+	calendar, err := NewProductionCalendar(file.Years)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[ProductionCalendar] Loaded %d years from %s", len(file.Years), path)
+	return calendar, nil
+}
+
+// HasYear проверяет, есть ли в календаре данные на указанный год
+func (c *ProductionCalendar) HasYear(year int) bool {
+	_, ok := c.years[year]
+	return ok
+}
+
+// IsHoliday проверяет, является ли дата нерабочим праздничным днем
+func (c *ProductionCalendar) IsHoliday(date time.Time) bool {
+	cy, ok := c.years[date.Year()]
+	if !ok {
+		return false
+	}
+	_, isHoliday := cy.holidays[date.Format(calendarDateFormat)]
+	return isHoliday
+}
+
+// IsWorkingDay проверяет, является ли дата рабочим днем с учетом праздников и переносов
+func (c *ProductionCalendar) IsWorkingDay(date time.Time) bool {
+	if c.IsHoliday(date) {
+		return false
+	}
+	key := date.Format(calendarDateFormat)
+	if cy, ok := c.years[date.Year()]; ok {
+		if _, movedOff := cy.transferTo[key]; movedOff {
+			return false
+		}
+		if _, movedOn := cy.transferFrom[key]; movedOn {
+			return true
+		}
+	}
+	return date.Weekday() != time.Saturday && date.Weekday() != time.Sunday
+}
+
+// CountVacationDays считает продолжительность отпуска в календарных днях.
+// Нерабочие праздничные дни, приходящиеся на период отпуска, в число дней отпуска не включаются (ст. 120 ТК РФ).
+func (c *ProductionCalendar) CountVacationDays(startDate time.Time, endDate time.Time) int {
+	days := 0
+	for d := truncateToDate(startDate); !d.After(truncateToDate(endDate)); d = d.AddDate(0, 0, 1) {
+		if !c.IsHoliday(d) {
+			days++
+		}
+	}
+	return days
+}
+
+// CountWorkingDays считает количество рабочих дней в периоде (включительно)
+func (c *ProductionCalendar) CountWorkingDays(startDate time.Time, endDate time.Time) int {
+	days := 0
+	for d := truncateToDate(startDate); !d.After(truncateToDate(endDate)); d = d.AddDate(0, 0, 1) {
+		if c.IsWorkingDay(d) {
+			days++
+		}
+	}
+	return days
+}
+
+// VacationEndDate вычисляет дату окончания отпуска продолжительностью days календарных дней,
+// продлевая его на нерабочие праздничные дни внутри периода.
+func (c *ProductionCalendar) VacationEndDate(startDate time.Time, days int) time.Time {
+	d := truncateToDate(startDate)
+	if days <= 0 {
+		return d
+	}
+	counted := 0
+	for {
+		if !c.IsHoliday(d) {
+			counted++
+			if counted == days {
+				return d
+			}
+		}
+		d = d.AddDate(0, 0, 1)
+	}
+}
+
+// truncateToDate отбрасывает время, оставляя только дату (в исходной временной зоне)
+func truncateToDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"vacation-scheduler/internal/models"
+	"vacation-scheduler/internal/repositories"
+)
+
+// priorityCategoriesByUser возвращает категории преимущественного права сотрудников по ID сотрудника
+func (s *VacationService) priorityCategoriesByUser(userIDs []int) (map[int][]models.UserPriorityCategory, error) {
+	categories, err := s.userRepo.GetPriorityCategories(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	byUser := map[int][]models.UserPriorityCategory{}
+	for _, c := range categories {
+		byUser[c.UserID] = append(byUser[c.UserID], c)
+	}
+	return byUser, nil
+}
+
+// activePriorityNames возвращает названия категорий преимущественного права, действующих на дату
+func activePriorityNames(categories []models.UserPriorityCategory, date time.Time) []string {
+	var names []string
+	day := truncateToDate(date)
+	for _, c := range categories {
+		if c.ActiveOn(day) {
+			names = append(names, c.CategoryName)
+		}
+	}
+	return names
+}
+
+// annotateConflictPriority отмечает в конфликтах категории преимущественного права обеих сторон на начало пересечения
+func (s *VacationService) annotateConflictPriority(conflicts []models.ConflictingPeriod) error {
+	if len(conflicts) == 0 {
+		return nil
+	}
+	userIDs := []int{}
+	for _, c := range conflicts {
+		for _, id := range []int{c.OriginalUserID, c.ConflictingUserID} {
+			if id != 0 && !containsInt(userIDs, id) {
+				userIDs = append(userIDs, id)
+			}
+		}
+	}
+	categories, err := s.priorityCategoriesByUser(userIDs)
+	if err != nil {
+		return err
+	}
+	for i := range conflicts {
+		c := &conflicts[i]
+		c.OriginalPriority = activePriorityNames(categories[c.OriginalUserID], c.OverlapStartDate.Time)
+		c.ConflictingPriority = activePriorityNames(categories[c.ConflictingUserID], c.OverlapStartDate.Time)
+		c.ConflictingHasPriority = len(c.ConflictingPriority) > 0
+	}
+	return nil
+}
+
+// redactConflictPriority скрывает названия категорий преимущественного права второй стороны конфликта
+// (инвалидность, статус пострадавшего от радиации и т.п. - специальные персональные данные) от пользователя,
+// который не является администратором или руководителем этого сотрудника. Остается только признак ConflictingHasPriority.
+func (s *VacationService) redactConflictPriority(viewer *models.User, conflicts []models.ConflictingPeriod) error {
+	if viewer.IsAdmin {
+		return nil
+	}
+	access := map[int]bool{}
+	for i := range conflicts {
+		c := &conflicts[i]
+		if len(c.ConflictingPriority) == 0 {
+			continue
+		}
+		granted, checked := access[c.ConflictingUserID]
+		if !checked {
+			other, err := s.findActor(c.ConflictingUserID)
+			if err != nil {
+				return err
+			}
+			granted, err = s.checkUserUnitAccess(viewer, other)
+			if err != nil {
+				return err
+			}
+			access[c.ConflictingUserID] = granted
+		}
+		if !granted {
+			c.ConflictingPriority = nil
+		}
+	}
+	return nil
+}
+
+// checkPriorityDisplacement не дает утвердить заявку сотрудника без преимущественного права, если она пересекается
+// с заявкой на рассмотрении сотрудника той же должности, имеющего право выбора времени отпуска на эти даты:
+// утверждение вытеснило бы его заявку. Сначала рассматривается заявка сотрудника с преимущественным правом.
+// Подтверждение конфликтов (force) эту проверку не отменяет.
+func (s *VacationService) checkPriorityDisplacement(repo repositories.VacationRepositoryInterface, req *models.VacationRequest, positionID int) error {
+	if len(req.Periods) == 0 {
+		return nil
+	}
+	from, to := req.Periods[0].StartDate.Time, req.Periods[0].EndDate.Time
+	for _, period := range req.Periods {
+		if period.StartDate.Time.Before(from) {
+			from = period.StartDate.Time
+		}
+		if period.EndDate.Time.After(to) {
+			to = period.EndDate.Time
+		}
+	}
+	pending, err := repo.GetPendingAbsencesByPosition(positionID, from, to)
+	if err != nil {
+		return err
+	}
+	userIDs := []int{req.UserID}
+	competing := []models.StaffAbsence{}
+	for _, a := range pending {
+		if a.UserID == req.UserID || a.RequestID == req.ID {
+			continue
+		}
+		competing = append(competing, a)
+		if !containsInt(userIDs, a.UserID) {
+			userIDs = append(userIDs, a.UserID)
+		}
+	}
+	if len(competing) == 0 {
+		return nil
+	}
+	categories, err := s.priorityCategoriesByUser(userIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range competing {
+		for _, period := range req.Periods {
+			overlapStart := max(truncateToDate(period.StartDate.Time), truncateToDate(a.StartDate.Time))
+			overlapEnd := min(truncateToDate(period.EndDate.Time), truncateToDate(a.EndDate.Time))
+			if overlapStart.After(overlapEnd) {
+				continue
+			}
+			holder := activePriorityNames(categories[a.UserID], overlapStart)
+			if len(holder) == 0 || len(activePriorityNames(categories[req.UserID], overlapStart)) > 0 {
+				continue
+			}
+			return fmt.Errorf("%w: период с %s по %s пересекается с заявкой №%d сотрудника %s (%s); сначала рассмотрите ее",
+				ErrPriorityDisplacement, overlapStart.Format("02.01.2006"), overlapEnd.Format("02.01.2006"), a.RequestID, a.UserFullName, strings.Join(holder, ", "))
+		}
+	}
+	return nil
+}
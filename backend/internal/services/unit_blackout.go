@@ -0,0 +1,255 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"vacation-scheduler/internal/models"
+	"vacation-scheduler/internal/repositories"
+)
+
+// unitAncestry возвращает ID юнита и всех его вышестоящих юнитов, от юнита к корню
+func (s *VacationService) unitAncestry(unitID int) ([]int, error) {
+	unitIDs := []int{}
+	seen := map[int]bool{}
+	for current := &unitID; current != nil && !seen[*current]; {
+		seen[*current] = true
+		unit, err := s.unitRepo.GetByID(*current)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения юнита %d: %w", *current, err)
+		}
+		if unit == nil {
+			break
+		}
+		unitIDs = append(unitIDs, unit.ID)
+		current = unit.ParentID
+	}
+	return unitIDs, nil
+}
+
+// GetUnitBlackouts возвращает периоды запрета отпусков, действующие в юните:
+// заданные для самого юнита и унаследованные от вышестоящих юнитов
+func (s *VacationService) GetUnitBlackouts(unitID int) ([]models.UnitBlackout, error) {
+	unitIDs, err := s.unitAncestry(unitID)
+	if err != nil {
+		return nil, err
+	}
+	if len(unitIDs) == 0 {
+		return nil, fmt.Errorf("юнит %d не найден", unitID)
+	}
+	blackouts, err := s.vacationRepo.GetUnitBlackoutsByUnitIDs(unitIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range blackouts {
+		blackouts[i].Inherited = blackouts[i].UnitID != unitID
+	}
+	return blackouts, nil
+}
+
+// CreateUnitBlackout создает период запрета отпусков юнита (администратор или руководитель юнита)
+func (s *VacationService) CreateUnitBlackout(actorID int, blackout *models.UnitBlackout) error {
+	if err := s.authorizeBlackoutChange(actorID, blackout.UnitID); err != nil {
+		return err
+	}
+	if err := s.checkUnitBlackout(blackout); err != nil {
+		return err
+	}
+	blackout.CreatedBy = &actorID
+	err := s.vacationRepo.RunInTx(func(tx repositories.VacationRepositoryInterface) error {
+		return tx.CreateUnitBlackout(blackout)
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("[Service CreateUnitBlackout] Blackout %d created for unit %d (%s - %s, %s) by user %d", blackout.ID, blackout.UnitID,
+		blackout.StartDate.Format("2006-01-02"), blackout.EndDate.Format("2006-01-02"), blackout.Severity, actorID)
+	return nil
+}
+
+// UpdateUnitBlackout изменяет период запрета отпусков юнита
+func (s *VacationService) UpdateUnitBlackout(actorID int, blackout *models.UnitBlackout) error {
+	existing, err := s.unitBlackoutOfUnit(blackout.UnitID, blackout.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.authorizeBlackoutChange(actorID, existing.UnitID); err != nil {
+		return err
+	}
+	if err := s.checkUnitBlackout(blackout); err != nil {
+		return err
+	}
+	blackout.CreatedBy = existing.CreatedBy
+	err = s.vacationRepo.RunInTx(func(tx repositories.VacationRepositoryInterface) error {
+		return tx.UpdateUnitBlackout(blackout)
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("[Service UpdateUnitBlackout] Blackout %d of unit %d updated by user %d", blackout.ID, blackout.UnitID, actorID)
+	return nil
+}
+
+// DeleteUnitBlackout удаляет период запрета отпусков юнита
+func (s *VacationService) DeleteUnitBlackout(actorID int, unitID int, blackoutID int) error {
+	existing, err := s.unitBlackoutOfUnit(unitID, blackoutID)
+	if err != nil {
+		return err
+	}
+	if err := s.authorizeBlackoutChange(actorID, existing.UnitID); err != nil {
+		return err
+	}
+	if err := s.vacationRepo.DeleteUnitBlackout(blackoutID); err != nil {
+		return err
+	}
+	log.Printf("[Service DeleteUnitBlackout] Blackout %d of unit %d deleted by user %d", blackoutID, unitID, actorID)
+	return nil
+}
+
+// unitBlackoutOfUnit получает период запрета, заданный именно для указанного юнита
+func (s *VacationService) unitBlackoutOfUnit(unitID int, blackoutID int) (*models.UnitBlackout, error) {
+	existing, err := s.vacationRepo.GetUnitBlackoutByID(blackoutID)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil || existing.UnitID != unitID {
+		return nil, fmt.Errorf("период запрета отпусков %d для юнита %d не найден", blackoutID, unitID)
+	}
+	return existing, nil
+}
+
+// authorizeBlackoutChange проверяет право изменять периоды запрета юнита:
+// администратор - для любого юнита, руководитель - для юнитов своего поддерева
+func (s *VacationService) authorizeBlackoutChange(actorID int, unitID int) error {
+	actor, err := s.findActor(actorID)
+	if err != nil {
+		return err
+	}
+	manages, err := s.managesUnit(actor, unitID)
+	if err != nil {
+		return err
+	}
+	if !manages {
+		return fmt.Errorf("%w: периоды запрета отпусков юнита задает администратор или руководитель юнита", ErrTransitionForbidden)
+	}
+	return nil
+}
+
+// managesUnit проверяет, что юнит входит в поддерево руководителя (администратору доступны все юниты)
+func (s *VacationService) managesUnit(actor *models.User, unitID int) (bool, error) {
+	if actor.IsAdmin {
+		return true, nil
+	}
+	if !actor.IsManager || actor.OrganizationalUnitID == nil {
+		return false, nil
+	}
+	subtreeIDs, err := s.unitRepo.GetSubtreeIDs(*actor.OrganizationalUnitID)
+	if err != nil {
+		return false, fmt.Errorf("ошибка получения поддерева юнитов руководителя: %w", err)
+	}
+	return containsInt(subtreeIDs, unitID), nil
+}
+
+// checkUnitBlackout проверяет даты, строгость, существование юнита и должностей-исключений
+func (s *VacationService) checkUnitBlackout(blackout *models.UnitBlackout) error {
+	blackout.Reason = strings.TrimSpace(blackout.Reason)
+	blackout.Severity = strings.ToUpper(strings.TrimSpace(blackout.Severity))
+	if blackout.Severity == "" {
+		blackout.Severity = models.BlackoutSeverityBlock
+	}
+	if blackout.Severity != models.BlackoutSeverityBlock && blackout.Severity != models.BlackoutSeverityWarn {
+		return fmt.Errorf("%w: неизвестная строгость периода запрета %q", ErrInvalidRequest, blackout.Severity)
+	}
+	if blackout.Reason == "" {
+		return fmt.Errorf("%w: необходимо указать причину запрета отпусков", ErrInvalidRequest)
+	}
+	if blackout.StartDate.IsZero() || blackout.EndDate.IsZero() || blackout.EndDate.Time.Before(blackout.StartDate.Time) {
+		return fmt.Errorf("%w: некорректный период запрета отпусков", ErrInvalidRequest)
+	}
+	unit, err := s.unitRepo.GetByID(blackout.UnitID)
+	if err != nil {
+		return fmt.Errorf("ошибка получения юнита %d: %w", blackout.UnitID, err)
+	}
+	if unit == nil {
+		return fmt.Errorf("юнит %d не найден", blackout.UnitID)
+	}
+	seen := map[int]bool{}
+	positionIDs := []int{}
+	for _, positionID := range blackout.ExemptPositionIDs {
+		if seen[positionID] {
+			continue
+		}
+		seen[positionID] = true
+		position, err := s.userRepo.GetPositionByID(positionID)
+		if err != nil {
+			return err
+		}
+		if position == nil {
+			return fmt.Errorf("должность %d не найдена", positionID)
+		}
+		positionIDs = append(positionIDs, positionID)
+	}
+	blackout.ExemptPositionIDs = positionIDs
+	return nil
+}
+
+// checkBlackouts сверяет периоды заявки с периодами запрета юнита сотрудника и вышестоящих юнитов.
+// Пересечение со строгим запретом - нарушение, с нестрогим - предупреждение.
+// Запрет не действует на сотрудника, чья должность в списке исключений.
+func (s *VacationService) checkBlackouts(repo repositories.VacationRepositoryInterface, employee *models.User, request *models.VacationRequest) (violations []string, warnings []string, err error) {
+	blackouts, err := s.employeeBlackouts(repo, employee)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, blackout := range blackouts {
+		window := models.VacationPeriod{StartDate: blackout.StartDate, EndDate: blackout.EndDate}
+		for i, period := range request.Periods {
+			if period.StartDate.IsZero() || period.EndDate.IsZero() || !doPeriodIntersect(period, window) {
+				continue
+			}
+			message := fmt.Sprintf("период %d пересекается с периодом запрета отпусков в юните \"%s\" с %s по %s: %s", i+1, blackout.UnitName,
+				blackout.StartDate.Format("02.01.2006"), blackout.EndDate.Format("02.01.2006"), blackout.Reason)
+			if blackout.Severity == models.BlackoutSeverityWarn {
+				warnings = append(warnings, message)
+			} else {
+				violations = append(violations, message)
+			}
+		}
+	}
+	return violations, warnings, nil
+}
+
+// employeeBlackouts возвращает периоды запрета юнита сотрудника и вышестоящих юнитов,
+// кроме тех, в исключениях которых указана должность сотрудника
+func (s *VacationService) employeeBlackouts(repo repositories.VacationRepositoryInterface, employee *models.User) ([]models.UnitBlackout, error) {
+	if employee.OrganizationalUnitID == nil {
+		return nil, nil
+	}
+	unitIDs, err := s.unitAncestry(*employee.OrganizationalUnitID)
+	if err != nil {
+		return nil, err
+	}
+	blackouts, err := repo.GetUnitBlackoutsByUnitIDs(unitIDs)
+	if err != nil {
+		return nil, err
+	}
+	applicable := []models.UnitBlackout{}
+	for _, blackout := range blackouts {
+		if employee.PositionID != nil && containsInt(blackout.ExemptPositionIDs, *employee.PositionID) {
+			continue
+		}
+		applicable = append(applicable, blackout)
+	}
+	return applicable, nil
+}
+
+// containsInt проверяет наличие значения в срезе
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
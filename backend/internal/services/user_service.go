@@ -2,6 +2,9 @@ package services
 
 import (
 	"fmt"
+	"log"
+	"strings"
+
 	"vacation-scheduler/internal/models"
 	"vacation-scheduler/internal/repositories"
 )
@@ -18,6 +21,10 @@ type UserServiceInterface interface {
 	GetAllUsers() ([]models.UserProfileDTO, error)                                                              // Новый метод для получения всех пользователей (админ)
 	UpdateUserAdmin(requestingUser *models.User, targetUserID int, updateData *models.UserUpdateAdminDTO) error // Новый метод для обновления админом
 	FindByID(id int) (*models.User, error)                                                                      // Добавлен метод для поиска по ID
+	// Категории преимущественного права выбора времени отпуска (админ)
+	GetUserPriorityCategories(userID int) ([]models.UserPriorityCategory, error)
+	AddUserPriorityCategory(requestingUser *models.User, category *models.UserPriorityCategory) error
+	DeleteUserPriorityCategory(userID int, categoryID int) error
 	// TODO: Добавить другие методы сервиса пользователей по мере необходимости
 }
 
@@ -162,7 +169,7 @@ func (s *UserService) UpdateUserAdmin(requestingUser *models.User, targetUserID
 	}
 
 	// Проверяем, есть ли что обновлять (хотя бы одно поле не nil)
-	hasUpdate := updateData.PositionID != nil || updateData.OrganizationalUnitID != nil || updateData.IsAdmin != nil || updateData.IsManager != nil
+	hasUpdate := updateData.PositionID != nil || updateData.OrganizationalUnitID != nil || updateData.IsAdmin != nil || updateData.IsManager != nil || updateData.HireDate != nil
 	if !hasUpdate {
 		return fmt.Errorf("нет полей для обновления")
 	}
@@ -218,10 +225,58 @@ func (s *UserService) FindByID(id int) (*models.User, error) {
 	if err != nil {
 		return nil, fmt.Errorf("ошибка поиска пользователя ID %d в репозитории: %w", id, err)
 	}
-	// Репозиторий должен вернуть nil, nil если пользователь не найден,
-	// поэтому дополнительная проверка на nil здесь не обязательна, если репозиторий следует этому контракту.
+	if user == nil {
+		return nil, nil
+	}
+	user.PriorityCategories, err = s.userRepo.GetPriorityCategories([]int{id})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения категорий преимущественного права пользователя ID %d: %w", id, err)
+	}
 	return user, nil
 }
 
+// GetUserPriorityCategories возвращает категории преимущественного права сотрудника со всеми периодами действия
+func (s *UserService) GetUserPriorityCategories(userID int) ([]models.UserPriorityCategory, error) {
+	return s.userRepo.GetPriorityCategories([]int{userID})
+}
+
+// AddUserPriorityCategory добавляет сотруднику категорию преимущественного права на период
+func (s *UserService) AddUserPriorityCategory(requestingUser *models.User, category *models.UserPriorityCategory) error {
+	category.Category = strings.ToUpper(strings.TrimSpace(category.Category))
+	category.Comment = strings.TrimSpace(category.Comment)
+	name, ok := models.PriorityCategoryNames[category.Category]
+	if !ok {
+		return fmt.Errorf("%w: неизвестная категория преимущественного права %q", ErrInvalidRequest, category.Category)
+	}
+	if category.StartDate.IsZero() {
+		return fmt.Errorf("%w: необходимо указать дату начала действия категории", ErrInvalidRequest)
+	}
+	if category.EndDate != nil && category.EndDate.Time.Before(category.StartDate.Time) {
+		return fmt.Errorf("%w: дата окончания действия категории раньше даты начала", ErrInvalidRequest)
+	}
+	if category.Category == models.PriorityCategoryOther && category.Comment == "" {
+		return fmt.Errorf("%w: для иного основания необходимо указать комментарий", ErrInvalidRequest)
+	}
+	user, err := s.userRepo.FindByID(category.UserID)
+	if err != nil {
+		return fmt.Errorf("ошибка поиска пользователя ID %d в репозитории: %w", category.UserID, err)
+	}
+	if user == nil {
+		return fmt.Errorf("пользователь %d не найден", category.UserID)
+	}
+	category.CategoryName = name
+	category.CreatedBy = &requestingUser.ID
+	if err := s.userRepo.CreatePriorityCategory(category); err != nil {
+		return err
+	}
+	log.Printf("[Service AddUserPriorityCategory] Category %s added to user %d by user %d", category.Category, category.UserID, requestingUser.ID)
+	return nil
+}
+
+// DeleteUserPriorityCategory удаляет категорию преимущественного права сотрудника
+func (s *UserService) DeleteUserPriorityCategory(userID int, categoryID int) error {
+	return s.userRepo.DeletePriorityCategory(userID, categoryID)
+}
+
 // TODO: Реализовать другие методы бизнес-логики для пользователей
 // Например: CreateUser, ChangePassword и т.д.
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"vacation-scheduler/internal/models"
+)
+
+// defaultVacationPolicy - встроенные правила, действующие, пока их не переопределит политика:
+// 28 дней основного отпуска (ст. 115 ТК РФ), одна из частей не менее 14 дней (ст. 125 ТК РФ),
+// заявка использует все доступные дни, периоды не пересекаются.
+func defaultVacationPolicy() models.EffectiveVacationPolicy {
+	return models.EffectiveVacationPolicy{
+		AnnualDays:          28,
+		MinLongPartDays:     14,
+		MaxParts:            0,
+		RequireExactBalance: true,
+		ForbidOverlap:       true,
+		PolicyIDs:           []int{},
+	}
+}
+
+// PolicyViolationError - заявка нарушает одно или несколько правил отпуска.
+// Содержит все найденные нарушения, а не только первое.
+type PolicyViolationError struct {
+	Violations []string
+}
+
+func (e *PolicyViolationError) Error() string {
+	return strings.Join(e.Violations, "; ")
+}
+
+// Unwrap позволяет проверять нарушение правил через errors.Is(err, ErrInvalidRequest)
+func (e *PolicyViolationError) Unwrap() error {
+	return ErrInvalidRequest
+}
+
+// GetVacationPolicies возвращает все политики отпуска
+func (s *VacationService) GetVacationPolicies() ([]models.VacationPolicy, error) {
+	return s.vacationRepo.GetVacationPolicies()
+}
+
+// CreateVacationPolicy создает политику отпуска. Для каждой области действия допускается одна политика.
+func (s *VacationService) CreateVacationPolicy(policy *models.VacationPolicy) error {
+	if err := s.checkVacationPolicy(policy); err != nil {
+		return err
+	}
+	if err := s.vacationRepo.CreateVacationPolicy(policy); err != nil {
+		return err
+	}
+	log.Printf("[Service CreateVacationPolicy] Policy %d created (unit %v, position %v)", policy.ID, policy.UnitID, policy.PositionID)
+	return nil
+}
+
+// UpdateVacationPolicy изменяет политику отпуска
+func (s *VacationService) UpdateVacationPolicy(policy *models.VacationPolicy) error {
+	existing, err := s.vacationRepo.GetVacationPolicyByID(policy.ID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return fmt.Errorf("политика отпуска %d не найдена", policy.ID)
+	}
+	if err := s.checkVacationPolicy(policy); err != nil {
+		return err
+	}
+	if err := s.vacationRepo.UpdateVacationPolicy(policy); err != nil {
+		return err
+	}
+	log.Printf("[Service UpdateVacationPolicy] Policy %d updated", policy.ID)
+	return nil
+}
+
+// DeleteVacationPolicy удаляет политику отпуска; ее правила снова наследуются от более общей политики
+func (s *VacationService) DeleteVacationPolicy(policyID int) error {
+	if err := s.vacationRepo.DeleteVacationPolicy(policyID); err != nil {
+		return err
+	}
+	log.Printf("[Service DeleteVacationPolicy] Policy %d deleted", policyID)
+	return nil
+}
+
+// checkVacationPolicy проверяет значения правил, существование юнита и должности и уникальность области действия
+func (s *VacationService) checkVacationPolicy(policy *models.VacationPolicy) error {
+	for _, rule := range []struct {
+		value *int
+		name  string
+	}{
+		{policy.AnnualDays, "количество дней отпуска"},
+		{policy.MinLongPartDays, "минимальная продолжительность части"},
+		{policy.MaxParts, "максимальное количество частей"},
+	} {
+		if rule.value != nil && *rule.value < 0 {
+			return fmt.Errorf("%w: %s не может быть отрицательным", ErrInvalidRequest, rule.name)
+		}
+	}
+	if policy.UnitID != nil {
+		unit, err := s.unitRepo.GetByID(*policy.UnitID)
+		if err != nil {
+			return fmt.Errorf("ошибка получения юнита %d: %w", *policy.UnitID, err)
+		}
+		if unit == nil {
+			return fmt.Errorf("юнит %d не найден", *policy.UnitID)
+		}
+	}
+	if policy.PositionID != nil {
+		position, err := s.userRepo.GetPositionByID(*policy.PositionID)
+		if err != nil {
+			return err
+		}
+		if position == nil {
+			return fmt.Errorf("должность %d не найдена", *policy.PositionID)
+		}
+	}
+
+	policies, err := s.vacationRepo.GetVacationPolicies()
+	if err != nil {
+		return err
+	}
+	for _, other := range policies {
+		if other.ID != policy.ID && sameIntPtr(other.UnitID, policy.UnitID) && sameIntPtr(other.PositionID, policy.PositionID) {
+			return fmt.Errorf("%w: для этой области действия уже есть политика %d", ErrInvalidRequest, other.ID)
+		}
+	}
+	return nil
+}
+
+// ResolveVacationPolicy возвращает итоговые правила отпуска сотрудника
+func (s *VacationService) ResolveVacationPolicy(userID int) (*models.EffectiveVacationPolicy, error) {
+	user, err := s.findActor(userID)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := s.resolvePolicy(user)
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// AnnualEntitlement считает начисление основного отпуска сотруднику за год
+// по действующей политике и пропорционально месяцам с даты приема
+func (s *VacationService) AnnualEntitlement(user *models.User, year int) (int, error) {
+	policy, err := s.resolvePolicy(user)
+	if err != nil {
+		return 0, err
+	}
+	return annualEntitlement(user.HireDate, year, policy.AnnualDays), nil
+}
+
+// resolvePolicy собирает итоговые правила сотрудника. Политики применяются от общей к частной:
+// политика организации, политика должности, затем для каждого юнита от корня до юнита сотрудника -
+// политика юнита и политика должности в этом юните. Заполненные правила более частной политики
+// перекрывают общие.
+func (s *VacationService) resolvePolicy(user *models.User) (models.EffectiveVacationPolicy, error) {
+	result := defaultVacationPolicy()
+	policies, err := s.vacationRepo.GetVacationPolicies()
+	if err != nil {
+		return result, err
+	}
+	if len(policies) == 0 {
+		return result, nil
+	}
+
+	unitPath, err := s.unitPath(user.OrganizationalUnitID)
+	if err != nil {
+		return result, err
+	}
+	find := func(unitID *int, positionID *int) *models.VacationPolicy {
+		for i := range policies {
+			if sameIntPtr(policies[i].UnitID, unitID) && sameIntPtr(policies[i].PositionID, positionID) {
+				return &policies[i]
+			}
+		}
+		return nil
+	}
+
+	applyVacationPolicy(&result, find(nil, nil))
+	if user.PositionID != nil {
+		applyVacationPolicy(&result, find(nil, user.PositionID))
+	}
+	for i := range unitPath {
+		unitID := unitPath[i]
+		applyVacationPolicy(&result, find(&unitID, nil))
+		if user.PositionID != nil {
+			applyVacationPolicy(&result, find(&unitID, user.PositionID))
+		}
+	}
+	return result, nil
+}
+
+// unitPath возвращает ID юнитов от корня дерева до указанного юнита
+func (s *VacationService) unitPath(unitID *int) ([]int, error) {
+	path := []int{}
+	for unitID != nil {
+		unit, err := s.unitRepo.GetByID(*unitID)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения юнита %d: %w", *unitID, err)
+		}
+		if unit == nil {
+			break
+		}
+		path = append([]int{unit.ID}, path...)
+		unitID = unit.ParentID
+	}
+	return path, nil
+}
+
+// applyVacationPolicy переносит заполненные правила политики в итоговые
+func applyVacationPolicy(result *models.EffectiveVacationPolicy, policy *models.VacationPolicy) {
+	if policy == nil {
+		return
+	}
+	if policy.AnnualDays != nil {
+		result.AnnualDays = *policy.AnnualDays
+	}
+	if policy.MinLongPartDays != nil {
+		result.MinLongPartDays = *policy.MinLongPartDays
+	}
+	if policy.MaxParts != nil {
+		result.MaxParts = *policy.MaxParts
+	}
+	if policy.RequireExactBalance != nil {
+		result.RequireExactBalance = *policy.RequireExactBalance
+	}
+	if policy.ForbidOverlap != nil {
+		result.ForbidOverlap = *policy.ForbidOverlap
+	}
+	result.PolicyIDs = append(result.PolicyIDs, policy.ID)
+}
+
+// sameIntPtr сравнивает необязательные ID (оба nil или равные значения)
+func sameIntPtr(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
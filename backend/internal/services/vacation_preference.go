@@ -0,0 +1,351 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"vacation-scheduler/internal/models"
+	"vacation-scheduler/internal/repositories"
+)
+
+// maxPreferenceAlternatives - максимальное количество вариантов в пожелании сотрудника
+const maxPreferenceAlternatives = 3
+
+// GetMyVacationPreference возвращает пожелание сотрудника к графику отпусков на год
+func (s *VacationService) GetMyVacationPreference(userID int, year int) (*models.VacationPreference, error) {
+	preference, err := s.vacationRepo.GetVacationPreference(userID, year)
+	if err != nil {
+		return nil, err
+	}
+	if preference == nil {
+		return nil, fmt.Errorf("пожелание к графику отпусков на %d год не найдено", year)
+	}
+	if err := s.describePreferences([]*models.VacationPreference{preference}); err != nil {
+		return nil, err
+	}
+	return preference, nil
+}
+
+// SaveVacationPreference сохраняет пожелание сотрудника на год, заменяя прежнее.
+// Каждый вариант проверяется по правилам отпуска, как заявка, и должен выполнять обязательные ограничения.
+// Варианты нумеруются по приоритету начиная с 1.
+func (s *VacationService) SaveVacationPreference(userID int, preference *models.VacationPreference) error {
+	preference.UserID = userID
+	preference.Comment = strings.TrimSpace(preference.Comment)
+	if preference.Year <= 0 {
+		return fmt.Errorf("%w: необходимо указать год пожелания", ErrInvalidRequest)
+	}
+	if len(preference.Alternatives) == 0 {
+		return fmt.Errorf("%w: необходимо указать хотя бы один вариант периодов отпуска", ErrInvalidRequest)
+	}
+	if len(preference.Alternatives) > maxPreferenceAlternatives {
+		return fmt.Errorf("%w: можно указать не более %d вариантов", ErrInvalidRequest, maxPreferenceAlternatives)
+	}
+	if err := checkPreferenceConstraints(preference.Constraints); err != nil {
+		return err
+	}
+
+	// Приоритет задается rank; варианты без него идут в порядке перечисления
+	sort.SliceStable(preference.Alternatives, func(i, j int) bool {
+		ri, rj := preference.Alternatives[i].Rank, preference.Alternatives[j].Rank
+		return ri > 0 && (rj == 0 || ri < rj)
+	})
+	violations := []string{}
+	for i := range preference.Alternatives {
+		alt := &preference.Alternatives[i]
+		alt.ID = 0
+		alt.Rank = i + 1
+		request := &models.VacationRequest{UserID: userID, Year: preference.Year, Periods: alt.Periods}
+		if err := s.validateVacationRequest(s.vacationRepo, request); err != nil {
+			var policyErr *PolicyViolationError
+			if !errors.As(err, &policyErr) {
+				return err
+			}
+			for _, v := range policyErr.Violations {
+				violations = append(violations, fmt.Sprintf("вариант %d: %s", alt.Rank, v))
+			}
+		}
+		for _, c := range preference.Constraints {
+			if c.Strength == models.PreferenceConstraintHard && !preferenceConstraintMet(c, request.Periods) {
+				violations = append(violations, fmt.Sprintf("вариант %d не выполняет обязательное ограничение \"%s\"", alt.Rank, c.Description))
+			}
+		}
+		alt.Periods = request.Periods
+		alt.DaysRequested = request.DaysRequested
+	}
+	if len(violations) > 0 {
+		return &PolicyViolationError{Violations: violations}
+	}
+
+	// Прежнее пожелание на год находится по уникальному ключу при сохранении, а не отдельным запросом:
+	// иначе параллельные сохранения могли бы создать два пожелания
+	err := s.vacationRepo.RunInTx(func(tx repositories.VacationRepositoryInterface) error {
+		return tx.SaveVacationPreference(preference)
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.describePreferences([]*models.VacationPreference{preference}); err != nil {
+		return err
+	}
+	log.Printf("[Service SaveVacationPreference] Preference %d saved for user %d, year %d: %d alternative(s), %d constraint(s)",
+		preference.ID, userID, preference.Year, len(preference.Alternatives), len(preference.Constraints))
+	return nil
+}
+
+// DeleteVacationPreference удаляет пожелание сотрудника на год
+func (s *VacationService) DeleteVacationPreference(userID int, year int) error {
+	preference, err := s.vacationRepo.GetVacationPreference(userID, year)
+	if err != nil {
+		return err
+	}
+	if preference == nil {
+		return fmt.Errorf("пожелание к графику отпусков на %d год не найдено", year)
+	}
+	if err := s.vacationRepo.DeleteVacationPreference(preference.ID); err != nil {
+		return err
+	}
+	log.Printf("[Service DeleteVacationPreference] Preference %d of user %d for year %d deleted", preference.ID, userID, year)
+	return nil
+}
+
+// GetSubtreeVacationPreferences возвращает пожелания сотрудников поддерева руководителя (администратору - всех)
+// для сравнения вариантов. unitIDFilter ограничивает выборку поддеревом указанного юнита.
+func (s *VacationService) GetSubtreeVacationPreferences(requestingUserID int, year int, unitIDFilter *int) ([]models.VacationPreference, error) {
+	actor, err := s.findActor(requestingUserID)
+	if err != nil {
+		return nil, err
+	}
+	var unitIDs []int
+	if actor.IsAdmin {
+		if unitIDFilter != nil {
+			unitIDs, err = s.unitRepo.GetSubtreeIDs(*unitIDFilter)
+			if err != nil {
+				return nil, fmt.Errorf("ошибка получения поддерева юнита %d: %w", *unitIDFilter, err)
+			}
+		} else {
+			units, err := s.unitRepo.GetAll()
+			if err != nil {
+				return nil, fmt.Errorf("ошибка получения юнитов: %w", err)
+			}
+			for _, unit := range units {
+				unitIDs = append(unitIDs, unit.ID)
+			}
+		}
+	} else {
+		managed, err := s.managedUnitIDs(actor)
+		if err != nil {
+			return nil, err
+		}
+		unitIDs = managed
+		if unitIDFilter != nil {
+			if !containsInt(managed, *unitIDFilter) {
+				return nil, fmt.Errorf("%w: юнит %d не входит в ваше поддерево", ErrTransitionForbidden, *unitIDFilter)
+			}
+			unitIDs, err = s.unitRepo.GetSubtreeIDs(*unitIDFilter)
+			if err != nil {
+				return nil, fmt.Errorf("ошибка получения поддерева юнита %d: %w", *unitIDFilter, err)
+			}
+		}
+	}
+
+	users, err := s.userRepo.GetUsersByUnitIDs(unitIDs)
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]int, len(users))
+	for i, u := range users {
+		userIDs[i] = u.ID
+	}
+	preferences, err := s.vacationRepo.GetVacationPreferencesByUsers(userIDs, year)
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]*models.VacationPreference, len(preferences))
+	for i := range preferences {
+		refs[i] = &preferences[i]
+	}
+	if err := s.describePreferences(refs); err != nil {
+		return nil, err
+	}
+	return preferences, nil
+}
+
+// ConvertVacationPreference создает заявку по выбранному варианту пожелания обычным путем сохранения заявки.
+// Сотрудник создает заявку на рассмотрении; руководитель (или администратор) - черновик, который сотрудник
+// проверяет и отправляет сам. Повторно заявка создается, только если прежняя отклонена или отменена.
+// Проверка прежней заявки, создание новой и отметка выбранного варианта выполняются в одной транзакции
+// под блокировкой пожелания, поэтому параллельный выбор варианта не создает вторую заявку.
+func (s *VacationService) ConvertVacationPreference(actorID int, preferenceID int, alternativeID int) (*models.VacationRequest, error) {
+	actor, err := s.findActor(actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	var request *models.VacationRequest
+	var employee *models.User
+	var alternative *models.PreferenceAlternative
+	err = s.vacationRepo.RunInTx(func(tx repositories.VacationRepositoryInterface) error {
+		preference, err := tx.LockVacationPreference(preferenceID)
+		if err != nil {
+			return err
+		}
+		if preference == nil {
+			return fmt.Errorf("пожелание %d не найдено", preferenceID)
+		}
+		employee = actor
+		if preference.UserID != actor.ID {
+			employee, err = s.findActor(preference.UserID)
+			if err != nil {
+				return err
+			}
+			granted, err := s.checkUserUnitAccess(actor, employee)
+			if err != nil {
+				return err
+			}
+			if !granted {
+				return fmt.Errorf("%w: нет доступа к пожеланию сотрудника", ErrTransitionForbidden)
+			}
+		}
+		if preference.ConvertedRequestID != nil {
+			existing, err := tx.GetVacationRequestByID(*preference.ConvertedRequestID)
+			if err != nil {
+				return err
+			}
+			if existing != nil && existing.StatusID != models.StatusRejected && existing.StatusID != models.StatusCancelled {
+				return fmt.Errorf("%w: по пожеланию уже создана заявка №%d", ErrTransitionNotAllowed, existing.ID)
+			}
+		}
+		for i := range preference.Alternatives {
+			if preference.Alternatives[i].ID == alternativeID {
+				alternative = &preference.Alternatives[i]
+			}
+		}
+		if alternative == nil {
+			return fmt.Errorf("вариант %d пожелания %d не найден", alternativeID, preferenceID)
+		}
+
+		request = &models.VacationRequest{
+			UserID:  employee.ID,
+			Year:    preference.Year,
+			Comment: fmt.Sprintf("По пожеланию к графику отпусков, вариант %d", alternative.Rank),
+			Periods: make([]models.VacationPeriod, len(alternative.Periods)),
+		}
+		if preference.Comment != "" {
+			request.Comment += ": " + preference.Comment
+		}
+		for i, period := range alternative.Periods {
+			request.Periods[i] = models.VacationPeriod{LeaveTypeID: period.LeaveTypeID, StartDate: period.StartDate, EndDate: period.EndDate}
+		}
+		if employee.ID != actor.ID {
+			request.StatusID = models.StatusDraft
+		}
+		if err := s.validateVacationRequest(tx, request); err != nil {
+			return err
+		}
+		if err := s.createVacationRequest(tx, request); err != nil {
+			return err
+		}
+		return tx.SetPreferenceConversion(preference.ID, alternative.ID, request.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[Service ConvertVacationPreference] Request %d (status %d) created from preference %d, alternative %d by user %d",
+		request.ID, request.StatusID, preferenceID, alternative.Rank, actorID)
+
+	if employee.ID != actor.ID {
+		s.notifyUser(employee.ID, "Выбран вариант отпуска",
+			fmt.Sprintf("%s выбрал вариант %d вашего пожелания и подготовил черновик заявки на отпуск №%d. Проверьте даты и отправьте заявку на согласование.",
+				actor.FullName, alternative.Rank, request.ID))
+	}
+	return request, nil
+}
+
+// checkPreferenceConstraints нормализует и проверяет ограничения пожелания
+func checkPreferenceConstraints(constraints []models.PreferenceConstraint) error {
+	for i := range constraints {
+		c := &constraints[i]
+		c.ID = 0
+		c.Description = strings.TrimSpace(c.Description)
+		c.Kind = strings.ToUpper(strings.TrimSpace(c.Kind))
+		c.Strength = strings.ToUpper(strings.TrimSpace(c.Strength))
+		if c.Kind == "" {
+			c.Kind = models.PreferenceConstraintInclude
+		}
+		if c.Strength == "" {
+			c.Strength = models.PreferenceConstraintSoft
+		}
+		if c.Kind != models.PreferenceConstraintInclude && c.Kind != models.PreferenceConstraintExclude {
+			return fmt.Errorf("%w: неизвестный вид ограничения %q", ErrInvalidRequest, c.Kind)
+		}
+		if c.Strength != models.PreferenceConstraintHard && c.Strength != models.PreferenceConstraintSoft {
+			return fmt.Errorf("%w: неизвестная строгость ограничения %q", ErrInvalidRequest, c.Strength)
+		}
+		if c.Description == "" {
+			return fmt.Errorf("%w: необходимо указать описание ограничения %d", ErrInvalidRequest, i+1)
+		}
+		if c.StartDate.IsZero() || c.EndDate.IsZero() || c.EndDate.Time.Before(c.StartDate.Time) {
+			return fmt.Errorf("%w: некорректные даты ограничения \"%s\"", ErrInvalidRequest, c.Description)
+		}
+	}
+	return nil
+}
+
+// preferenceConstraintMet проверяет ограничение по дням: INCLUDE - каждый день диапазона приходится на отпуск,
+// EXCLUDE - ни один день диапазона не приходится на отпуск
+func preferenceConstraintMet(c models.PreferenceConstraint, periods []models.VacationPeriod) bool {
+	start, end := truncateToDate(c.StartDate.Time), truncateToDate(c.EndDate.Time)
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if vacationCoversDay(periods, day) != (c.Kind == models.PreferenceConstraintInclude) {
+			return false
+		}
+	}
+	return true
+}
+
+// vacationCoversDay проверяет, приходится ли день на один из периодов
+func vacationCoversDay(periods []models.VacationPeriod, day time.Time) bool {
+	for _, p := range periods {
+		if p.StartDate.IsZero() || p.EndDate.IsZero() {
+			continue
+		}
+		if !truncateToDate(p.StartDate.Time).After(day) && !truncateToDate(p.EndDate.Time).Before(day) {
+			return true
+		}
+	}
+	return false
+}
+
+// describePreferences пересчитывает дни вариантов по производственному календарю
+// и отмечает невыполненные желательные ограничения
+func (s *VacationService) describePreferences(preferences []*models.VacationPreference) error {
+	if len(preferences) == 0 {
+		return nil
+	}
+	leaveTypes, err := s.leaveTypesByID(s.vacationRepo)
+	if err != nil {
+		return err
+	}
+	for _, preference := range preferences {
+		for i := range preference.Alternatives {
+			alt := &preference.Alternatives[i]
+			request := &models.VacationRequest{Periods: alt.Periods}
+			if err := s.recalculateDays(leaveTypes, request); err != nil {
+				log.Printf("[describePreferences] Warning: preference %d, alternative %d: %v", preference.ID, alt.Rank, err)
+			}
+			alt.DaysRequested = request.DaysRequested
+			alt.UnmetSoftConstraints = []string{}
+			for _, c := range preference.Constraints {
+				if c.Strength == models.PreferenceConstraintSoft && !preferenceConstraintMet(c, alt.Periods) {
+					alt.UnmetSoftConstraints = append(alt.UnmetSoftConstraints, c.Description)
+				}
+			}
+		}
+	}
+	return nil
+}
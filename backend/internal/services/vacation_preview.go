@@ -0,0 +1,105 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"vacation-scheduler/internal/models"
+	"vacation-scheduler/internal/repositories"
+)
+
+// errPreviewRollback откатывает транзакцию пробной проверки: лимит, созданный по умолчанию при чтении баланса,
+// не должен сохраняться
+var errPreviewRollback = errors.New("пробная проверка заявки: откат транзакции")
+
+// PreviewVacationRequest выполняет пробную проверку заявки без сохранения: проверяет правила отпуска,
+// считает дни по производственному календарю, показывает остаток после утверждения и конфликты,
+// которые найдет руководитель при утверждении (по должности и правилам укомплектованности).
+func (s *VacationService) PreviewVacationRequest(request *models.VacationRequest) (*models.VacationRequestPreview, error) {
+	if request.Year == 0 && len(request.Periods) > 0 {
+		request.Year = request.Periods[0].StartDate.Year()
+	}
+	preview := &models.VacationRequestPreview{
+		Valid:              true,
+		Violations:         []string{},
+		Warnings:           []string{},
+		Year:               request.Year,
+		Conflicts:          []models.ConflictingPeriod{},
+		CoverageViolations: []models.CoverageViolation{},
+	}
+
+	err := s.vacationRepo.RunInTx(func(tx repositories.VacationRepositoryInterface) error {
+		if err := s.validateVacationRequest(tx, request); err != nil {
+			var violations *PolicyViolationError
+			if !errors.As(err, &violations) {
+				return err
+			}
+			preview.Valid = false
+			preview.Violations = violations.Violations
+		}
+		if request.Warnings != nil {
+			preview.Warnings = request.Warnings
+		}
+		preview.Periods = request.Periods
+		preview.DaysRequested = request.DaysRequested
+
+		limit, err := s.getVacationLimit(tx, request.UserID, request.Year)
+		if err != nil {
+			return fmt.Errorf("ошибка получения остатка отпуска: %w", err)
+		}
+		preview.AvailableDays = limit.AvailableDays
+		preview.RemainingDays = limit.AvailableDays - request.DaysRequested
+
+		// Конфликты проверяются только по периодам с корректными датами
+		checked := *request
+		checked.Periods = []models.VacationPeriod{}
+		for _, period := range request.Periods {
+			if !period.StartDate.IsZero() && !period.EndDate.IsZero() && !period.EndDate.Time.Before(period.StartDate.Time) {
+				checked.Periods = append(checked.Periods, period)
+			}
+		}
+		if len(checked.Periods) == 0 {
+			return errPreviewRollback
+		}
+		positionID, err := tx.GetUserPositionByID(request.UserID)
+		if err != nil {
+			log.Printf("[PreviewVacationRequest] Warning: could not get position for user %d: %v", request.UserID, err)
+		} else if positionID != nil {
+			conflicts, err := tx.GetApprovedVacationConflictsByPosition(*positionID, request.UserID, checked.Periods)
+			if err != nil {
+				return fmt.Errorf("ошибка проверки конфликтов отпусков: %w", err)
+			}
+			if err := s.annotateConflictPriority(conflicts); err != nil {
+				return fmt.Errorf("ошибка проверки преимущественного права: %w", err)
+			}
+			// Пробная проверка доступна любому сотруднику: категории коллег видят только их руководители
+			requester, err := s.findActor(request.UserID)
+			if err != nil {
+				return err
+			}
+			if err := s.redactConflictPriority(requester, conflicts); err != nil {
+				return fmt.Errorf("ошибка проверки доступа к данным о преимущественном праве: %w", err)
+			}
+			if err := s.annotateConflictFairness(tx, conflicts); err != nil {
+				return fmt.Errorf("ошибка расчета очередности пиковых периодов: %w", err)
+			}
+			if conflicts != nil {
+				preview.Conflicts = conflicts
+			}
+		}
+		coverage, err := s.checkCoverage(tx, &checked, false)
+		if err != nil {
+			return fmt.Errorf("ошибка проверки правил укомплектованности: %w", err)
+		}
+		preview.CoverageViolations = coverage
+		return errPreviewRollback
+	})
+	if err != nil && !errors.Is(err, errPreviewRollback) {
+		return nil, err
+	}
+
+	log.Printf("[PreviewVacationRequest] UserID: %d, Year: %d - valid=%t, days=%d, remaining=%d, conflicts=%d, coverage violations=%d",
+		request.UserID, request.Year, preview.Valid, preview.DaysRequested, preview.RemainingDays, len(preview.Conflicts), len(preview.CoverageViolations))
+	return preview, nil
+}
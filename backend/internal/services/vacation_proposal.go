@@ -0,0 +1,265 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"vacation-scheduler/internal/models"
+	"vacation-scheduler/internal/repositories"
+)
+
+// proposalPeriodsText перечисляет периоды предложения для истории заявки и уведомлений
+func proposalPeriodsText(periods []models.VacationPeriod) string {
+	parts := make([]string, 0, len(periods))
+	for _, period := range periods {
+		parts = append(parts, fmt.Sprintf("%s–%s", period.StartDate.Format("02.01.2006"), period.EndDate.Format("02.01.2006")))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// withComment добавляет к тексту перехода пояснение участника, если оно есть
+func withComment(text string, comment string) string {
+	if comment == "" {
+		return text
+	}
+	return text + ". " + comment
+}
+
+// closeOpenProposal закрывает предложение по заявке, ожидающее ответа, если оно есть
+func (s *VacationService) closeOpenProposal(tx repositories.VacationRepositoryInterface, requestID int, status string, respondedBy *int, comment string) error {
+	proposal, err := tx.GetOpenRequestProposal(requestID)
+	if err != nil {
+		return err
+	}
+	if proposal == nil {
+		return nil
+	}
+	return tx.SetRequestProposalStatus(proposal.ID, status, respondedBy, comment)
+}
+
+// lockProposalRequest блокирует заявку внутри транзакции
+func lockProposalRequest(tx repositories.VacationRepositoryInterface, requestID int) (*models.VacationRequest, error) {
+	req, err := tx.LockVacationRequest(requestID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения заявки ID %d: %w", requestID, err)
+	}
+	if req == nil {
+		return nil, fmt.Errorf("%w: ID %d", ErrRequestNotFound, requestID)
+	}
+	return req, nil
+}
+
+// ProposeVacationChanges возвращает заявку на рассмотрении сотруднику с предложением других дат.
+// Предложенные периоды проверяются по правилам политики отпуска так, будто ими заменены периоды заявки
+// (этап кампании планирования не проверяется); резерв дней по заявке сохраняется до ответа сотрудника.
+// Проверка, переход в статус "Предложены изменения" и сохранение предложения выполняются в одной транзакции
+// под блокировкой заявки и лимита.
+func (s *VacationService) ProposeVacationChanges(requestID int, actorID int, input *models.VacationProposalCreateDTO) (*models.VacationRequestProposal, error) {
+	if len(input.Periods) == 0 {
+		return nil, fmt.Errorf("%w: необходимо указать хотя бы один предложенный период", ErrInvalidRequest)
+	}
+	comment := strings.TrimSpace(input.Comment)
+	actor, err := s.findActor(actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	var proposal *models.VacationRequestProposal
+	var req *models.VacationRequest
+	err = s.vacationRepo.RunInTx(func(tx repositories.VacationRepositoryInterface) error {
+		req, err = lockProposalRequest(tx, requestID)
+		if err != nil {
+			return err
+		}
+		if _, err := s.authorizeTransition(actor, req, models.ActionProposeChanges); err != nil {
+			return err
+		}
+		if err := s.lockVacationLimit(tx, req.UserID, req.Year); err != nil {
+			return err
+		}
+		// Резерв заявки снимается на время проверки, чтобы ее дни считались доступными, и восстанавливается
+		totals, err := tx.GetRequestLedgerTotals(req.ID)
+		if err != nil {
+			return err
+		}
+		if err := s.releaseRequestReservation(tx, req, actorID, "Снятие резерва при проверке предложенных дат"); err != nil {
+			return err
+		}
+		proposed := *req
+		proposed.Periods = append([]models.VacationPeriod(nil), input.Periods...)
+		if err := s.validateVacationRequestWith(tx, &proposed, validationOptions{renegotiation: true}); err != nil {
+			return err
+		}
+		if totals.ReservedDays != 0 {
+			if err := s.addRequestLedgerEntry(tx, req, models.LedgerEntryReservation, -totals.ReservedDays, actorID, "Восстановление резерва до ответа на предложение"); err != nil {
+				return err
+			}
+		}
+
+		reason := withComment("Предложены другие даты: "+proposalPeriodsText(proposed.Periods), comment)
+		if err := s.applyTransition(tx, actor, req, models.ActionProposeChanges, reason); err != nil {
+			return err
+		}
+		proposal = &models.VacationRequestProposal{
+			RequestID: requestID, ProposedBy: &actorID, ProposedByName: actor.FullName,
+			Comment: comment, Periods: proposed.Periods, Status: models.ProposalStatusOpen,
+		}
+		return tx.CreateRequestProposal(proposal)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[Service ProposeVacationChanges] Proposal %d for request %d by user %d (%d period(s))", proposal.ID, requestID, actorID, len(proposal.Periods))
+
+	s.notifyUser(req.UserID, "Предложены другие даты отпуска",
+		fmt.Sprintf("%s предлагает изменить даты по заявке на отпуск №%d: %s. Примите или отклоните предложение.",
+			actor.FullName, req.ID, proposalPeriodsText(proposal.Periods)))
+	return proposal, nil
+}
+
+// AcceptVacationProposal принимает предложение согласующего: периоды заявки заменяются предложенными и проверяются заново
+// (без проверки этапа кампании планирования), резерв дней пересчитывается, заявка возвращается на рассмотрение
+// и проходит согласование с начала.
+func (s *VacationService) AcceptVacationProposal(requestID int, userID int, comment string) (*models.VacationRequest, error) {
+	comment = strings.TrimSpace(comment)
+	actor, err := s.findActor(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated *models.VacationRequest
+	err = s.vacationRepo.RunInTx(func(tx repositories.VacationRepositoryInterface) error {
+		req, err := lockProposalRequest(tx, requestID)
+		if err != nil {
+			return err
+		}
+		toStatus, err := s.authorizeTransition(actor, req, models.ActionAcceptProposal)
+		if err != nil {
+			return err
+		}
+		if err := s.lockVacationLimit(tx, req.UserID, req.Year); err != nil {
+			return err
+		}
+		proposal, err := tx.GetOpenRequestProposal(requestID)
+		if err != nil {
+			return err
+		}
+		if proposal == nil {
+			return fmt.Errorf("предложение по заявке ID %d не найдено", requestID)
+		}
+
+		if err := s.releaseRequestReservation(tx, req, userID, "Снятие резерва при принятии предложенных дат"); err != nil {
+			return err
+		}
+		req.Periods = make([]models.VacationPeriod, len(proposal.Periods))
+		for i, period := range proposal.Periods {
+			period.ID = 0
+			req.Periods[i] = period
+		}
+		if err := s.validateVacationRequestWith(tx, req, validationOptions{renegotiation: true}); err != nil {
+			return err
+		}
+		if err := tx.UpdateVacationRequest(req); err != nil {
+			return err
+		}
+		if err := s.recordTransition(tx, actor, req, models.ActionAcceptProposal, toStatus, withComment("Сотрудник принял предложенные даты", comment)); err != nil {
+			return err
+		}
+		if err := tx.SetRequestProposalStatus(proposal.ID, models.ProposalStatusAccepted, &userID, comment); err != nil {
+			return err
+		}
+		// Заявка с новыми датами проходит согласование заново
+		if err := s.startApprovalChain(tx, req, actor); err != nil {
+			return err
+		}
+		if req.DaysRequested > 0 {
+			if err := s.addRequestLedgerEntry(tx, req, models.LedgerEntryReservation, -req.DaysRequested, userID, "Резерв по принятым предложенным датам"); err != nil {
+				return err
+			}
+		}
+		updated = req
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[Service AcceptVacationProposal] Request %d: proposal accepted by user %d, days: %d", requestID, userID, updated.DaysRequested)
+
+	s.notifyApprover(updated, actor, "Предложенные даты приняты",
+		fmt.Sprintf("Сотрудник %s принял предложенные даты по заявке на отпуск №%d (%d дн.). Требуется повторное рассмотрение.", actor.FullName, updated.ID, updated.DaysRequested))
+	return updated, nil
+}
+
+// DeclineVacationProposal отклоняет предложение согласующего: заявка с прежними датами возвращается на рассмотрение.
+// Сотрудник обязан пояснить отказ; пояснение сохраняется в предложении и в истории заявки.
+func (s *VacationService) DeclineVacationProposal(requestID int, userID int, comment string) error {
+	comment = strings.TrimSpace(comment)
+	if comment == "" {
+		return fmt.Errorf("%w: необходимо пояснить отказ от предложенных дат", ErrInvalidRequest)
+	}
+	actor, err := s.findActor(userID)
+	if err != nil {
+		return err
+	}
+
+	var req *models.VacationRequest
+	var proposedBy *int
+	err = s.vacationRepo.RunInTx(func(tx repositories.VacationRepositoryInterface) error {
+		req, err = lockProposalRequest(tx, requestID)
+		if err != nil {
+			return err
+		}
+		if err := s.applyTransition(tx, actor, req, models.ActionDeclineProposal, "Сотрудник отклонил предложенные даты. "+comment); err != nil {
+			return err
+		}
+		proposal, err := tx.GetOpenRequestProposal(requestID)
+		if err != nil {
+			return err
+		}
+		if proposal == nil {
+			return nil
+		}
+		proposedBy = proposal.ProposedBy
+		return tx.SetRequestProposalStatus(proposal.ID, models.ProposalStatusDeclined, &userID, comment)
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("[Service DeclineVacationProposal] Request %d: proposal declined by user %d", requestID, userID)
+
+	title := "Предложенные даты отклонены"
+	message := fmt.Sprintf("Сотрудник %s отклонил предложенные даты по заявке на отпуск №%d: %s", actor.FullName, req.ID, comment)
+	if proposedBy != nil {
+		s.notifyUser(*proposedBy, title, message)
+	} else {
+		s.notifyApprover(req, actor, title, message)
+	}
+	return nil
+}
+
+// GetRequestProposals возвращает предложения по заявке вместе с ответами сотрудника.
+// Доступно автору заявки, администратору и руководителю сотрудника.
+func (s *VacationService) GetRequestProposals(requestingUserID int, requestID int) ([]models.VacationRequestProposal, error) {
+	req, err := s.vacationRepo.GetVacationRequestByID(requestID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения заявки ID %d: %w", requestID, err)
+	}
+	if req == nil {
+		return nil, fmt.Errorf("%w: ID %d", ErrRequestNotFound, requestID)
+	}
+	if req.UserID != requestingUserID {
+		requestingUser, err := s.findActor(requestingUserID)
+		if err != nil {
+			return nil, err
+		}
+		roles, err := s.actorRoles(requestingUser, req)
+		if err != nil {
+			return nil, err
+		}
+		if !roles[roleAdmin] && !roles[roleManager] && !roles[roleApprover] {
+			return nil, fmt.Errorf("%w: просмотр предложений по заявке ID %d", ErrTransitionForbidden, requestID)
+		}
+	}
+	return s.vacationRepo.GetRequestProposals(requestID)
+}
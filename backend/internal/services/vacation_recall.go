@@ -0,0 +1,147 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"vacation-scheduler/internal/models"
+	"vacation-scheduler/internal/repositories"
+)
+
+// RecallFromVacation отзывает сотрудника из отпуска по одному периоду.
+// Период усекается до дня, предшествующего дате выхода, неиспользованные дни основного отпуска возвращаются
+// на баланс записью REFUND и могут быть использованы новой заявкой. Заявка переходит в статус "Отозван из отпуска",
+// если после отзываемого периода не осталось других периодов.
+func (s *VacationService) RecallFromVacation(periodID int, actorID int, input *models.VacationRecallDTO) (*models.VacationRecall, error) {
+	if !input.EmployeeConsent {
+		return nil, fmt.Errorf("%w: отзыв из отпуска допускается только с согласия сотрудника", ErrInvalidRequest)
+	}
+	if input.RecallDate.IsZero() {
+		return nil, fmt.Errorf("%w: необходимо указать дату выхода на работу", ErrInvalidRequest)
+	}
+	actor, err := s.findActor(actorID)
+	if err != nil {
+		return nil, err
+	}
+	reason := strings.TrimSpace(input.Reason)
+	recallDate := truncateToDate(input.RecallDate.Time)
+
+	var recall *models.VacationRecall
+	var employeeID int
+	var refunded bool
+	err = s.vacationRepo.RunInTx(func(tx repositories.VacationRepositoryInterface) error {
+		period, err := tx.GetVacationPeriodByID(periodID)
+		if err != nil {
+			return err
+		}
+		if period == nil {
+			return fmt.Errorf("период отпуска %d не найден", periodID)
+		}
+		req, err := tx.LockVacationRequest(period.RequestID)
+		if err != nil {
+			return fmt.Errorf("ошибка получения заявки для отзыва: %w", err)
+		}
+		if req == nil {
+			return errors.New("заявка не найдена")
+		}
+		toStatus, err := s.authorizeTransition(actor, req, models.ActionRecall)
+		if err != nil {
+			return err
+		}
+		if period.StartDate.Time.After(truncateToDate(time.Now())) {
+			return fmt.Errorf("%w: период отпуска еще не начался", ErrTransitionNotAllowed)
+		}
+		if !recallDate.After(period.StartDate.Time) || recallDate.After(period.EndDate.Time) {
+			return fmt.Errorf("%w: дата выхода должна быть позже начала периода (%s) и не позже его окончания (%s)",
+				ErrInvalidRequest, period.StartDate.Format("02.01.2006"), period.EndDate.Format("02.01.2006"))
+		}
+
+		leaveType, err := tx.GetLeaveTypeByID(period.LeaveTypeID)
+		if err != nil {
+			return err
+		}
+		if leaveType == nil {
+			return fmt.Errorf("вид отпуска %d не найден", period.LeaveTypeID)
+		}
+
+		newEnd := recallDate.AddDate(0, 0, -1)
+		usedDays := s.countLeaveDays(*leaveType, period.StartDate.Time, newEnd)
+		unusedDays := period.DaysCount - usedDays
+		if unusedDays <= 0 {
+			return fmt.Errorf("%w: после даты выхода в периоде не остается неиспользованных дней", ErrInvalidRequest)
+		}
+
+		if err := s.lockVacationLimit(tx, req.UserID, req.Year); err != nil {
+			return err
+		}
+		if err := tx.UpdateVacationPeriodDates(period.ID, period.StartDate, models.CustomDate{Time: newEnd}, usedDays); err != nil {
+			return err
+		}
+		// Дни видов отпуска, не списываемых с основного баланса, не возвращаются: период только усекается
+		if leaveType.CountsTowardMain {
+			if err := tx.UpdateRequestDaysRequested(req.ID, req.DaysRequested-unusedDays); err != nil {
+				return err
+			}
+			if err := s.addRequestLedgerEntry(tx, req, models.LedgerEntryRefund, unusedDays, actorID, "Возврат неиспользованных дней при отзыве из отпуска"); err != nil {
+				return err
+			}
+		}
+
+		// Если впереди есть другие периоды заявки, отпуск продолжается и статус не меняется
+		for _, other := range req.Periods {
+			if other.ID != period.ID && other.StartDate.Time.After(period.EndDate.Time) {
+				toStatus = req.StatusID
+				break
+			}
+		}
+		note := fmt.Sprintf("Отзыв из отпуска с %s, возвращено %d дн.", recallDate.Format("02.01.2006"), unusedDays)
+		if !leaveType.CountsTowardMain {
+			note = fmt.Sprintf("Отзыв из отпуска (%s) с %s, неиспользовано %d дн.", leaveType.Name, recallDate.Format("02.01.2006"), unusedDays)
+		}
+		refunded = leaveType.CountsTowardMain
+		if reason != "" {
+			note += " Причина: " + reason
+		}
+		if err := s.recordTransition(tx, actor, req, models.ActionRecall, toStatus, note); err != nil {
+			return err
+		}
+		if err := s.syncRequestDelegations(tx, req.ID); err != nil {
+			return err
+		}
+
+		recall = &models.VacationRecall{
+			RequestID: req.ID, PeriodID: period.ID, RecallDate: models.CustomDate{Time: recallDate},
+			OriginalEndDate: period.EndDate, UnusedDays: unusedDays, EmployeeConsent: true,
+			Reason: reason, RecalledBy: &actorID,
+		}
+		employeeID = req.UserID
+		return tx.CreateVacationRecall(recall)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[Service RecallFromVacation] Period %d (request %d) recalled from %s by user %d, refunded %d days",
+		periodID, recall.RequestID, recallDate.Format("2006-01-02"), actorID, recall.UnusedDays)
+
+	message := fmt.Sprintf("Вы отозваны из отпуска с %s. Неиспользованные дни (%d) возвращены на баланс и могут быть запланированы новой заявкой.",
+		recallDate.Format("02.01.2006"), recall.UnusedDays)
+	if !refunded {
+		message = fmt.Sprintf("Вы отозваны из отпуска с %s. Неиспользовано дней: %d.", recallDate.Format("02.01.2006"), recall.UnusedDays)
+	}
+	s.notifyUser(employeeID, "Отзыв из отпуска", message)
+	return recall, nil
+}
+
+// applyRecallsToExportRow заполняет примечание формы Т-7 (столбец 13) по отзывам из отпуска
+func applyRecallsToExportRow(row *models.VacationExportRow, recalls []models.VacationRecall) {
+	notes := make([]string, 0, len(recalls))
+	for _, recall := range recalls {
+		notes = append(notes, fmt.Sprintf("Отозван из отпуска с %s, неиспользовано %d дн.", recall.RecallDate.Format("02.01.2006"), recall.UnusedDays))
+	}
+	if len(notes) > 0 {
+		row.Note = strings.Join(notes, "; ")
+	}
+}
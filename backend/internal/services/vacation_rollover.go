@@ -0,0 +1,209 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"vacation-scheduler/internal/models"
+	"vacation-scheduler/internal/repositories"
+)
+
+// Режимы переноса остатка на следующий год
+const (
+	CarryOverFull   = "FULL"   // Переносится весь остаток
+	CarryOverCap    = "CAP"    // Переносится не более CapDays
+	CarryOverExpiry = "EXPIRY" // Дни сгорают через ExpiryYears лет после года начисления
+)
+
+// CarryOverPolicy определяет, какая часть остатка закрываемого года переносится на следующий
+type CarryOverPolicy struct {
+	Mode        string
+	CapDays     int
+	ExpiryYears int
+}
+
+// carryBucket - часть остатка, начисленная за определенный год
+type carryBucket struct {
+	OriginYear int
+	Days       int
+}
+
+// RolloverYear закрывает год: остаток каждого сотрудника переносится на следующий год
+// по правилу переноса, а не перенесенная часть сгорает. Каждый сотрудник обрабатывается
+// в отдельной транзакции и только один раз, поэтому повторный запуск безопасен.
+// actorID == nil означает запуск из командной строки.
+func (s *VacationService) RolloverYear(year int, actorID *int) (*models.YearRolloverResult, error) {
+	userIDs, err := s.vacationRepo.GetUserIDsWithLimits(year)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[Service RolloverYear] Rolling over year %d for %d users (policy: %+v)", year, len(userIDs), s.carryOver)
+
+	result := &models.YearRolloverResult{Year: year, Processed: []models.YearRollover{}, SkippedUsers: []int{}, FailedUsers: []int{}}
+	for _, userID := range userIDs {
+		rollover, err := s.rolloverUserYear(userID, year, actorID)
+		if err != nil {
+			log.Printf("[Service RolloverYear] Failed to roll over year %d for user %d: %v", year, userID, err)
+			result.FailedUsers = append(result.FailedUsers, userID)
+			continue
+		}
+		if rollover == nil {
+			result.SkippedUsers = append(result.SkippedUsers, userID)
+			continue
+		}
+		result.Processed = append(result.Processed, *rollover)
+		result.CarriedDays += rollover.CarriedDays
+		result.ExpiredDays += rollover.ExpiredDays
+	}
+	log.Printf("[Service RolloverYear] Year %d: processed %d, skipped %d, failed %d, carried %d days, expired %d days",
+		year, len(result.Processed), len(result.SkippedUsers), len(result.FailedUsers), result.CarriedDays, result.ExpiredDays)
+	return result, nil
+}
+
+// rolloverUserYear переносит остаток одного сотрудника. Возвращает nil, nil, если год уже закрыт.
+func (s *VacationService) rolloverUserYear(userID int, year int, actorID *int) (*models.YearRollover, error) {
+	var rollover *models.YearRollover
+	err := s.vacationRepo.RunInTx(func(tx repositories.VacationRepositoryInterface) error {
+		// Блокируем оба счета в порядке возрастания года; счет следующего года создается с начислением по умолчанию
+		if err := s.lockVacationLimit(tx, userID, year); err != nil {
+			return err
+		}
+		if err := s.lockVacationLimit(tx, userID, year+1); err != nil {
+			return err
+		}
+		done, err := tx.IsYearRolledOver(userID, year)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		balance, err := s.getVacationLimit(tx, userID, year)
+		if err != nil {
+			return err
+		}
+		entries, err := tx.GetLedgerEntries(userID, year)
+		if err != nil {
+			return err
+		}
+		carried, expired := s.carryOver.split(year, entries, balance.AvailableDays)
+
+		carriedDays := 0
+		for _, bucket := range carried {
+			originYear := bucket.OriginYear
+			entry := &models.LeaveLedgerEntry{
+				UserID: userID, Year: year + 1, EntryType: models.LedgerEntryCarryOver, Days: bucket.Days,
+				OriginYear: &originYear, ActorID: actorID, Comment: fmt.Sprintf("Перенос остатка за %d год", originYear),
+			}
+			if err := tx.AddLedgerEntry(entry); err != nil {
+				return err
+			}
+			carriedDays += bucket.Days
+		}
+		if carriedDays > 0 {
+			entry := &models.LeaveLedgerEntry{
+				UserID: userID, Year: year, EntryType: models.LedgerEntryCarryOut, Days: -carriedDays,
+				ActorID: actorID, Comment: fmt.Sprintf("Перенос остатка на %d год", year+1),
+			}
+			if err := tx.AddLedgerEntry(entry); err != nil {
+				return err
+			}
+		}
+		if expired > 0 {
+			entry := &models.LeaveLedgerEntry{
+				UserID: userID, Year: year, EntryType: models.LedgerEntryExpiry, Days: -expired,
+				ActorID: actorID, Comment: "Остаток не переносится по правилу переноса",
+			}
+			if err := tx.AddLedgerEntry(entry); err != nil {
+				return err
+			}
+		}
+
+		rollover = &models.YearRollover{UserID: userID, Year: year, CarriedDays: carriedDays, ExpiredDays: expired, ActorID: actorID}
+		return tx.SaveYearRollover(rollover)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rollover, nil
+}
+
+// split делит остаток закрываемого года на переносимые части (по годам начисления) и сгорающие дни.
+// Дни расходуются в порядке начисления (сначала самые старые), поэтому в остатке остаются самые новые.
+func (p CarryOverPolicy) split(year int, entries []models.LeaveLedgerEntry, available int) ([]carryBucket, int) {
+	if available <= 0 {
+		return nil, 0
+	}
+
+	// Начисления закрываемого года и перенесенные с прошлых лет, сгруппированные по году начисления
+	byOrigin := make(map[int]int)
+	for _, entry := range entries {
+		switch entry.EntryType {
+		case models.LedgerEntryAccrual, models.LedgerEntryAdjustment:
+			byOrigin[year] += entry.Days
+		case models.LedgerEntryCarryOver:
+			origin := year - 1
+			if entry.OriginYear != nil {
+				origin = *entry.OriginYear
+			}
+			byOrigin[origin] += entry.Days
+		}
+	}
+	origins := make([]int, 0, len(byOrigin))
+	for origin := range byOrigin {
+		origins = append(origins, origin)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(origins)))
+
+	// Остаток распределяется от самого нового года начисления к самому старому
+	remaining := available
+	kept := []carryBucket{}
+	for _, origin := range origins {
+		days := byOrigin[origin]
+		if days > remaining {
+			days = remaining
+		}
+		if days > 0 {
+			kept = append(kept, carryBucket{OriginYear: origin, Days: days})
+			remaining -= days
+		}
+	}
+	if remaining > 0 { // Остаток сверх начислений (например, возвраты) относим к закрываемому году
+		if len(kept) > 0 && kept[0].OriginYear == year {
+			kept[0].Days += remaining
+		} else {
+			kept = append([]carryBucket{{OriginYear: year, Days: remaining}}, kept...)
+		}
+	}
+
+	// Применяем правило переноса; kept упорядочен от новых к старым
+	carried := []carryBucket{}
+	limit := -1
+	if p.Mode == CarryOverCap {
+		limit = p.CapDays
+	}
+	for _, bucket := range kept {
+		if p.Mode == CarryOverExpiry && year+1-bucket.OriginYear > p.ExpiryYears {
+			continue
+		}
+		if limit >= 0 {
+			if bucket.Days > limit {
+				bucket.Days = limit
+			}
+			limit -= bucket.Days
+		}
+		if bucket.Days > 0 {
+			carried = append(carried, bucket)
+		}
+	}
+
+	carriedDays := 0
+	for _, bucket := range carried {
+		carriedDays += bucket.Days
+	}
+	// В журнал переносы пишутся от старых к новым
+	sort.Slice(carried, func(i, j int) bool { return carried[i].OriginYear < carried[j].OriginYear })
+	return carried, available - carriedDays
+}
@@ -0,0 +1,589 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"vacation-scheduler/internal/models"
+	"vacation-scheduler/internal/repositories"
+)
+
+// schedulePlanner - состояние генератора графика: отсутствия по дням (утвержденные и уже предложенные)
+// и их учет по правилам укомплектованности, должностям и юниту
+type schedulePlanner struct {
+	s            *VacationService
+	mainType     models.LeaveType
+	from, to     time.Time
+	absentDays   map[int]map[time.Time]bool    // Дни отсутствия сотрудника
+	rules        map[int]*plannerRule          // Правила укомплектованности по ID
+	userRules    map[int][]int                 // ID правил, действующих на сотрудника
+	positionOf   map[int]*int                  // Должность сотрудника
+	positionLoad map[int]map[time.Time]int     // Отсутствующих по должности в день
+	unitLoad     map[time.Time]int             // Отсутствующих среди планируемых сотрудников в день
+	blackouts    map[int][]models.UnitBlackout // Периоды запрета, действующие на сотрудника
+	rotation     *fairRotation                 // Пиковые периоды сотрудников
+	avoidPeak    map[int]bool                  // Сотрудники, чья очередь на пиковые даты позже средней по юниту
+}
+
+// plannerRule - правило укомплектованности с количеством отсутствующих по дням
+type plannerRule struct {
+	rule      models.CoverageRule
+	headcount int
+	absent    map[time.Time]int
+}
+
+// scheduleCandidate - проверенный вариант периода отпуска
+type scheduleCandidate struct {
+	start, end time.Time
+	load       int  // Суммарная загрузка дней периода (для равномерного распределения)
+	warned     bool // Период пересекается с нестрогим запретом
+	preferred  bool // Период целиком в желаемом периоде сотрудника
+	peak       bool // Период пересекается с пиковым периодом
+}
+
+// GenerateVacationSchedule предлагает график отпусков юнита на год: для каждого сотрудника без заявок на этот год
+// остаток дней делится на части по правилам его политики отпуска (одна часть не короче минимальной) и
+// размещается без пересечений по должности, без нарушения правил укомплектованности и строгих запретов.
+// Желаемые периоды сотрудников учитываются в первую очередь (раньше - у тех, чья очередь на пиковые даты раньше),
+// остальные дни распределяются по году равномерно; сотрудникам, недавно отдыхавшим в пиковые периоды, пиковые
+// даты без их пожелания не предлагаются, если есть другие варианты.
+// Ничего не сохраняется; оценка и список компромиссов объясняют, чем пришлось поступиться.
+func (s *VacationService) GenerateVacationSchedule(actorID int, input *models.ScheduleGenerationRequest) (*models.ScheduleProposal, error) {
+	actor, err := s.findActor(actorID)
+	if err != nil {
+		return nil, err
+	}
+	manages, err := s.managesUnit(actor, input.UnitID)
+	if err != nil {
+		return nil, err
+	}
+	if !manages {
+		return nil, fmt.Errorf("%w: график отпусков юнита составляет администратор или руководитель юнита", ErrTransitionForbidden)
+	}
+	today := truncateToDate(time.Now())
+	if input.Year < today.Year() {
+		return nil, fmt.Errorf("%w: нельзя составить график на прошедший год", ErrInvalidRequest)
+	}
+
+	unitIDs := []int{input.UnitID}
+	if input.IncludeSubunits {
+		unitIDs, err = s.unitRepo.GetSubtreeIDs(input.UnitID)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения поддерева юнита %d: %w", input.UnitID, err)
+		}
+	}
+	users, err := s.userRepo.GetUsersByUnitIDs(unitIDs)
+	if err != nil {
+		return nil, err
+	}
+	leaveTypes, err := s.leaveTypesByID(s.vacationRepo)
+	if err != nil {
+		return nil, err
+	}
+	rotation, err := s.newFairRotation(s.vacationRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &schedulePlanner{
+		s:            s,
+		mainType:     leaveTypes[models.LeaveTypeMain],
+		from:         time.Date(input.Year, time.January, 1, 0, 0, 0, 0, today.Location()),
+		to:           time.Date(input.Year, time.December, 31, 0, 0, 0, 0, today.Location()),
+		absentDays:   map[int]map[time.Time]bool{},
+		rules:        map[int]*plannerRule{},
+		userRules:    map[int][]int{},
+		positionOf:   map[int]*int{},
+		positionLoad: map[int]map[time.Time]int{},
+		unitLoad:     map[time.Time]int{},
+		blackouts:    map[int][]models.UnitBlackout{},
+		rotation:     rotation,
+		avoidPeak:    map[int]bool{},
+	}
+	if input.Year == today.Year() {
+		p.from = today.AddDate(0, 0, 1)
+	}
+
+	windows := map[int][]models.PreferredWindow{}
+	for _, w := range input.PreferredWindows {
+		if !w.StartDate.IsZero() && !w.EndDate.IsZero() && !w.EndDate.Time.Before(w.StartDate.Time) {
+			windows[w.UserID] = append(windows[w.UserID], w)
+		}
+	}
+
+	proposal := &models.ScheduleProposal{UnitID: input.UnitID, Year: input.Year, Entries: []models.ScheduleProposalEntry{}, TradeOffs: []string{}}
+	type plannedEmployee struct {
+		user   models.User
+		policy models.EffectiveVacationPolicy
+		entry  int // Индекс записи в proposal.Entries
+	}
+	planned := []plannedEmployee{}
+	for i := range users {
+		u := users[i]
+		entry := models.ScheduleProposalEntry{UserID: u.ID, UserFullName: u.FullName, Periods: []models.VacationPeriod{}, Notes: []string{}}
+		existing, err := s.vacationRepo.GetVacationRequestsByUser(u.ID, input.Year, nil)
+		if err != nil {
+			return nil, err
+		}
+		if hasActiveRequests(existing) {
+			entry.Notes = append(entry.Notes, "у сотрудника уже есть заявки на этот год, график не составлялся")
+			proposal.Entries = append(proposal.Entries, entry)
+			continue
+		}
+		limit, err := s.getVacationLimit(s.vacationRepo, u.ID, input.Year)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения остатка отпуска сотрудника %d: %w", u.ID, err)
+		}
+		entry.AvailableDays = limit.AvailableDays
+		if limit.AvailableDays <= 0 {
+			entry.Notes = append(entry.Notes, "нет доступных дней отпуска")
+			proposal.Entries = append(proposal.Entries, entry)
+			continue
+		}
+		full, err := s.findActor(u.ID)
+		if err != nil {
+			return nil, err
+		}
+		policy, err := s.resolvePolicy(full)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения политики отпуска сотрудника %d: %w", u.ID, err)
+		}
+		if err := p.addEmployee(full); err != nil {
+			return nil, err
+		}
+		proposal.Entries = append(proposal.Entries, entry)
+		planned = append(planned, plannedEmployee{user: *full, policy: policy, entry: len(proposal.Entries) - 1})
+	}
+	if err := p.loadApprovedAbsences(); err != nil {
+		return nil, err
+	}
+	plannedIDs := make([]int, len(planned))
+	for i, pe := range planned {
+		plannedIDs[i] = pe.user.ID
+	}
+	scores, err := p.loadFairness(plannedIDs, input.Year)
+	if err != nil {
+		return nil, err
+	}
+	for _, pe := range planned {
+		proposal.Entries[pe.entry].FairnessScore = scores[pe.user.ID]
+	}
+
+	// Сначала сотрудники с желаемыми периодами (раньше - с более ранней очередью на пиковые даты), затем с большим остатком
+	sort.SliceStable(planned, func(i, j int) bool {
+		wi, wj := len(windows[planned[i].user.ID]) > 0, len(windows[planned[j].user.ID]) > 0
+		if wi != wj {
+			return wi
+		}
+		fi, fj := proposal.Entries[planned[i].entry].FairnessScore, proposal.Entries[planned[j].entry].FairnessScore
+		if fi != fj {
+			return fi < fj
+		}
+		ai, aj := proposal.Entries[planned[i].entry].AvailableDays, proposal.Entries[planned[j].entry].AvailableDays
+		if ai != aj {
+			return ai > aj
+		}
+		return planned[i].user.ID < planned[j].user.ID
+	})
+
+	totalDays, scheduledDays, withWindows, windowsMet, warnedPeriods := 0, 0, 0, 0, 0
+	for _, pe := range planned {
+		entry := &proposal.Entries[pe.entry]
+		userWindows := windows[pe.user.ID]
+		totalDays += entry.AvailableDays
+		if len(userWindows) > 0 {
+			withWindows++
+		}
+		for _, days := range splitVacationDays(entry.AvailableDays, pe.policy) {
+			candidate := p.bestCandidate(pe.user.ID, days, userWindows)
+			if candidate == nil {
+				entry.Notes = append(entry.Notes, fmt.Sprintf("не удалось разместить часть отпуска %d дн. без конфликтов", days))
+				proposal.TradeOffs = append(proposal.TradeOffs, fmt.Sprintf("%s: часть отпуска %d дн. не размещена - нет дат без конфликтов и нарушений правил укомплектованности", pe.user.FullName, days))
+				continue
+			}
+			p.markAbsent(pe.user.ID, candidate.start, candidate.end, true)
+			entry.Periods = append(entry.Periods, models.VacationPeriod{
+				LeaveTypeID: models.LeaveTypeMain,
+				StartDate:   models.CustomDate{Time: candidate.start},
+				EndDate:     models.CustomDate{Time: candidate.end},
+				DaysCount:   days,
+			})
+			entry.ScheduledDays += days
+			if candidate.preferred {
+				entry.PreferenceMet = true
+			}
+			if candidate.warned {
+				warnedPeriods++
+				entry.Notes = append(entry.Notes, fmt.Sprintf("период с %s по %s пересекается с нежелательным для отпусков периодом",
+					candidate.start.Format("02.01.2006"), candidate.end.Format("02.01.2006")))
+				proposal.TradeOffs = append(proposal.TradeOffs, fmt.Sprintf("%s: период с %s по %s пересекается с нежелательным для отпусков периодом юнита",
+					pe.user.FullName, candidate.start.Format("02.01.2006"), candidate.end.Format("02.01.2006")))
+			}
+		}
+		sort.Slice(entry.Periods, func(i, j int) bool { return entry.Periods[i].StartDate.Time.Before(entry.Periods[j].StartDate.Time) })
+		scheduledDays += entry.ScheduledDays
+		if len(userWindows) > 0 {
+			if entry.PreferenceMet {
+				windowsMet++
+			} else {
+				proposal.TradeOffs = append(proposal.TradeOffs, fmt.Sprintf("%s: желаемый период недоступен (конфликты по должности, правила укомплектованности или запрет отпусков)", pe.user.FullName))
+			}
+		}
+	}
+
+	proposal.Score = scheduleScore(totalDays, scheduledDays, withWindows, windowsMet, warnedPeriods)
+	log.Printf("[Service GenerateVacationSchedule] Unit %d, year %d: %d employee(s) planned, %d of %d days scheduled, score %d (by user %d)",
+		input.UnitID, input.Year, len(planned), scheduledDays, totalDays, proposal.Score, actorID)
+	return proposal, nil
+}
+
+// PublishVacationSchedule публикует график: периоды каждого сотрудника сохраняются черновиком заявки
+// от его имени, сотрудник получает уведомление и может изменить черновик перед отправкой.
+// Черновик, не прошедший проверку правил отпуска, не создается; ошибка возвращается в результате.
+func (s *VacationService) PublishVacationSchedule(actorID int, input *models.SchedulePublishRequest) (*models.SchedulePublishResult, error) {
+	actor, err := s.findActor(actorID)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.SchedulePublishResult{Created: []models.VacationRequest{}, Errors: []string{}}
+	for _, entry := range input.Entries {
+		if len(entry.Periods) == 0 {
+			continue
+		}
+		employee, err := s.findActor(entry.UserID)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("сотрудник %d: %v", entry.UserID, err))
+			continue
+		}
+		granted, err := s.checkUserUnitAccess(actor, employee)
+		if err != nil {
+			return nil, err
+		}
+		if !granted {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: нет прав на составление графика сотрудника", employee.FullName))
+			continue
+		}
+		request := &models.VacationRequest{
+			UserID:   employee.ID,
+			Year:     input.Year,
+			StatusID: models.StatusDraft,
+			Comment:  "Черновик по графику отпусков",
+			Periods:  append([]models.VacationPeriod{}, entry.Periods...),
+		}
+		for i := range request.Periods {
+			request.Periods[i].ID = 0
+		}
+		if err := s.validateVacationRequest(s.vacationRepo, request); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", employee.FullName, err))
+			continue
+		}
+		err = s.vacationRepo.RunInTx(func(tx repositories.VacationRepositoryInterface) error {
+			if err := tx.SaveVacationRequest(request); err != nil {
+				return err
+			}
+			transition := &models.VacationRequestTransition{
+				RequestID: request.ID, ToStatusID: request.StatusID, Action: models.ActionCreate, ActorID: &actor.ID,
+				Reason: "Черновик создан по графику отпусков",
+			}
+			return tx.AddRequestTransition(transition)
+		})
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", employee.FullName, err))
+			continue
+		}
+		result.Created = append(result.Created, *request)
+		s.notifyUser(employee.ID, "Предложен график отпуска",
+			fmt.Sprintf("%s подготовил черновик заявки на отпуск №%d на %d год. Проверьте даты и отправьте заявку на согласование.", actor.FullName, request.ID, input.Year))
+	}
+	log.Printf("[Service PublishVacationSchedule] %d draft(s) created, %d error(s) (by user %d)", len(result.Created), len(result.Errors), actorID)
+	return result, nil
+}
+
+// hasActiveRequests проверяет, есть ли среди заявок черновики, заявки на рассмотрении (в том числе с предложенными изменениями) или утвержденные
+func hasActiveRequests(requests []models.VacationRequest) bool {
+	for _, req := range requests {
+		if req.StatusID == models.StatusDraft || req.StatusID == models.StatusPending || req.StatusID == models.StatusChangesProposed ||
+			models.IsApprovedStatus(req.StatusID) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitVacationDays делит остаток на части: одна часть не короче минимальной по политике,
+// остаток - вторая часть. Если политика допускает одну часть или дней мало, отпуск не делится.
+func splitVacationDays(available int, policy models.EffectiveVacationPolicy) []int {
+	if policy.MinLongPartDays <= 0 || policy.MaxParts == 1 || available <= policy.MinLongPartDays {
+		return []int{available}
+	}
+	long := (available + 1) / 2
+	if long < policy.MinLongPartDays {
+		long = policy.MinLongPartDays
+	}
+	if available-long == 0 {
+		return []int{long}
+	}
+	return []int{long, available - long}
+}
+
+// scheduleScore оценивает график: 70 баллов - доля распределенных дней, 30 - доля учтенных желаемых периодов,
+// минус 5 баллов за каждый период в нежелательное время
+func scheduleScore(totalDays, scheduledDays, withWindows, windowsMet, warnedPeriods int) int {
+	completion := 1.0
+	if totalDays > 0 {
+		completion = float64(scheduledDays) / float64(totalDays)
+	}
+	preference := 1.0
+	if withWindows > 0 {
+		preference = float64(windowsMet) / float64(withWindows)
+	}
+	score := int(70*completion+30*preference+0.5) - 5*warnedPeriods
+	if score < 0 {
+		return 0
+	}
+	return score
+}
+
+// addEmployee подготавливает данные планировщика по сотруднику: правила укомплектованности, должность, запреты
+func (p *schedulePlanner) addEmployee(employee *models.User) error {
+	p.positionOf[employee.ID] = employee.PositionID
+	rules, err := p.s.applicableCoverageRules(p.s.vacationRepo, employee)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if _, ok := p.rules[rule.ID]; ok {
+			continue
+		}
+		members, err := p.s.coverageRuleMembers(rule)
+		if err != nil {
+			return err
+		}
+		pr := &plannerRule{rule: rule, headcount: len(members), absent: map[time.Time]int{}}
+		if !containsMember(members, employee.ID) {
+			pr.headcount++
+		}
+		p.rules[rule.ID] = pr
+		for _, m := range members {
+			if !containsInt(p.userRules[m.ID], rule.ID) {
+				p.userRules[m.ID] = append(p.userRules[m.ID], rule.ID)
+			}
+			if _, ok := p.positionOf[m.ID]; !ok {
+				p.positionOf[m.ID] = m.PositionID
+			}
+		}
+		if !containsInt(p.userRules[employee.ID], rule.ID) {
+			p.userRules[employee.ID] = append(p.userRules[employee.ID], rule.ID)
+		}
+	}
+	blackouts, err := p.s.employeeBlackouts(p.s.vacationRepo, employee)
+	if err != nil {
+		return err
+	}
+	p.blackouts[employee.ID] = blackouts
+	return p.rotation.addEmployee(employee)
+}
+
+// loadFairness рассчитывает очередность планируемых сотрудников на пиковые даты года и отмечает тех,
+// чья очередь позже средней: им пиковые даты предлагаются в последнюю очередь
+func (p *schedulePlanner) loadFairness(userIDs []int, year int) (map[int]int, error) {
+	scores, err := p.rotation.scores(userIDs, year)
+	if err != nil {
+		return nil, err
+	}
+	if len(userIDs) == 0 {
+		return scores, nil
+	}
+	total := 0
+	for _, id := range userIDs {
+		total += scores[id]
+	}
+	for _, id := range userIDs {
+		p.avoidPeak[id] = scores[id]*len(userIDs) > total
+	}
+	return scores, nil
+}
+
+// loadApprovedAbsences учитывает утвержденные отпуска участников правил и сотрудников тех же должностей
+func (p *schedulePlanner) loadApprovedAbsences() error {
+	memberIDs := make([]int, 0, len(p.userRules))
+	for userID := range p.userRules {
+		memberIDs = append(memberIDs, userID)
+	}
+	sort.Ints(memberIDs)
+	absences, err := p.s.vacationRepo.GetApprovedAbsences(memberIDs, 0, p.from, p.to)
+	if err != nil {
+		return err
+	}
+	positions := map[int]bool{}
+	for _, positionID := range p.positionOf {
+		if positionID != nil {
+			positions[*positionID] = true
+		}
+	}
+	for positionID := range positions {
+		byPosition, err := p.s.vacationRepo.GetApprovedAbsencesByPosition(positionID, p.from, p.to)
+		if err != nil {
+			return err
+		}
+		for _, a := range byPosition {
+			if _, ok := p.positionOf[a.UserID]; !ok {
+				id := positionID
+				p.positionOf[a.UserID] = &id
+			}
+		}
+		absences = append(absences, byPosition...)
+	}
+	for _, a := range absences {
+		p.markAbsent(a.UserID, truncateToDate(a.StartDate.Time), truncateToDate(a.EndDate.Time), false)
+	}
+	return nil
+}
+
+// markAbsent отмечает дни отсутствия сотрудника; planned - отсутствие из предлагаемого графика
+func (p *schedulePlanner) markAbsent(userID int, start, end time.Time, planned bool) {
+	days := p.absentDays[userID]
+	if days == nil {
+		days = map[time.Time]bool{}
+		p.absentDays[userID] = days
+	}
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if days[day] {
+			continue
+		}
+		days[day] = true
+		for _, ruleID := range p.userRules[userID] {
+			p.rules[ruleID].absent[day]++
+		}
+		if positionID := p.positionOf[userID]; positionID != nil {
+			if p.positionLoad[*positionID] == nil {
+				p.positionLoad[*positionID] = map[time.Time]int{}
+			}
+			p.positionLoad[*positionID][day]++
+		}
+		if planned {
+			p.unitLoad[day]++
+		}
+	}
+}
+
+// periodEnd находит дату окончания части отпуска заданной продолжительности (с продлением на праздники)
+func (p *schedulePlanner) periodEnd(start time.Time, days int) (time.Time, bool) {
+	counted := 0
+	for day := start; !day.After(p.to); day = day.AddDate(0, 0, 1) {
+		counted += p.s.countLeaveDays(p.mainType, day, day)
+		if counted == days {
+			return day, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// evaluate проверяет вариант периода: без пересечения с другими частями отпуска сотрудника и строгими запретами,
+// без конфликтов по должности и нарушений правил укомплектованности. Возвращает nil для недопустимого варианта.
+func (p *schedulePlanner) evaluate(userID int, start time.Time, days int) *scheduleCandidate {
+	end, ok := p.periodEnd(start, days)
+	if !ok {
+		return nil
+	}
+	own := p.absentDays[userID]
+	if own[start.AddDate(0, 0, -1)] || own[end.AddDate(0, 0, 1)] {
+		return nil // Части отпуска не должны примыкать друг к другу
+	}
+	candidate := &scheduleCandidate{start: start, end: end, peak: p.rotation.peakWindowIn(userID, start, end) != nil}
+	window := models.VacationPeriod{StartDate: models.CustomDate{Time: start}, EndDate: models.CustomDate{Time: end}}
+	for _, blackout := range p.blackouts[userID] {
+		if !doPeriodIntersect(window, models.VacationPeriod{StartDate: blackout.StartDate, EndDate: blackout.EndDate}) {
+			continue
+		}
+		if blackout.Severity != models.BlackoutSeverityWarn {
+			return nil
+		}
+		candidate.warned = true
+	}
+	positionID := p.positionOf[userID]
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if own[day] {
+			return nil
+		}
+		if positionID != nil && p.positionLoad[*positionID][day] > 0 {
+			return nil
+		}
+		for _, ruleID := range p.userRules[userID] {
+			rule := p.rules[ruleID]
+			absent := rule.absent[day] + 1
+			if rule.rule.MaxAbsent != nil && absent > *rule.rule.MaxAbsent {
+				return nil
+			}
+			if rule.rule.MinPresent != nil && rule.headcount-absent < *rule.rule.MinPresent {
+				return nil
+			}
+			candidate.load += rule.absent[day]
+		}
+		candidate.load += p.unitLoad[day]
+	}
+	return candidate
+}
+
+// bestCandidate выбирает период для части отпуска: сначала внутри желаемых периодов сотрудника,
+// затем по всему году с наименьшей загрузкой (при равной - более ранний). Сотруднику с поздней очередью
+// на пиковые даты период вне пиковых периодов предпочтительнее. Период начинается в рабочий день.
+func (p *schedulePlanner) bestCandidate(userID int, days int, windows []models.PreferredWindow) *scheduleCandidate {
+	better := func(best, c *scheduleCandidate) bool {
+		if best == nil {
+			return true
+		}
+		if best.warned != c.warned {
+			return !c.warned
+		}
+		if p.avoidPeak[userID] && best.peak != c.peak {
+			return !c.peak
+		}
+		return c.load < best.load
+	}
+
+	var best *scheduleCandidate
+	for _, w := range windows {
+		from, to := truncateToDate(w.StartDate.Time), truncateToDate(w.EndDate.Time)
+		if from.Before(p.from) {
+			from = p.from
+		}
+		for start := from; !start.After(to); start = start.AddDate(0, 0, 1) {
+			if !p.s.calendar.IsWorkingDay(start) {
+				continue
+			}
+			c := p.evaluate(userID, start, days)
+			if c == nil || c.end.After(to) {
+				continue
+			}
+			c.preferred = true
+			if better(best, c) {
+				best = c
+			}
+		}
+	}
+	if best != nil {
+		return best
+	}
+	for start := p.from; !start.After(p.to); start = start.AddDate(0, 0, 1) {
+		if !p.s.calendar.IsWorkingDay(start) {
+			continue
+		}
+		if c := p.evaluate(userID, start, days); c != nil && better(best, c) {
+			best = c
+		}
+	}
+	return best
+}
+
+// containsMember проверяет, есть ли сотрудник в списке
+func containsMember(users []models.User, userID int) bool {
+	for _, u := range users {
+		if u.ID == userID {
+			return true
+		}
+	}
+	return false
+}
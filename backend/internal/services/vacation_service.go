@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt" // Добавлен импорт fmt
 	"log" // Добавляем импорт log
+	"strings"
 	"time"
 
 	"vacation-scheduler/internal/models"
@@ -13,43 +14,224 @@ import (
 // VacationServiceInterface определяет методы для сервиса отпусков
 type VacationServiceInterface interface {
 	GetVacationLimit(userID int, year int) (*models.VacationLimit, error)
-	SetVacationLimit(userID int, year int, totalDays int) error
+	SetVacationLimit(userID int, year int, totalDays int, actorID int) error
+	AdjustVacationBalance(userID int, year int, days int, actorID int, comment string) error
+	GetLeaveLedger(requestingUserID int, targetUserID int, year int) (*models.LeaveLedger, error)
+	RolloverYear(year int, actorID *int) (*models.YearRolloverResult, error)
 	ValidateVacationRequest(request *models.VacationRequest) error
+	PreviewVacationRequest(request *models.VacationRequest) (*models.VacationRequestPreview, error)
 	SaveVacationRequest(request *models.VacationRequest) error
 	SubmitVacationRequest(requestID int, userID int) error
+	UpdateVacationRequest(requestID int, userID int, update *models.VacationRequestUpdateDTO) (*models.VacationRequest, error)
 	CheckIntersections(unitID int, year int) ([]models.Intersection, error) // departmentID -> unitID
 	NotifyManager(managerID int, intersections []models.Intersection) error
 	GetUserVacations(userID int, year int, statusFilter *int) ([]models.VacationRequest, error)
 	GetOrganizationalUnitVacations(unitID int, year int, statusFilter *int) ([]models.VacationRequest, error)                                                      // GetDepartmentVacations -> GetOrganizationalUnitVacations, departmentID -> unitID
 	GetAllUserVacations(requestingUserID int, yearFilter *int, statusFilter *int, userIDFilter *int, unitIDFilter *int) ([]models.VacationRequestAdminView, error) // departmentIDFilter -> unitIDFilter
-	CancelVacationRequest(requestID int, cancellingUserID int) error
-	// Изменена сигнатура: добавлен флаг force, возвращает список конфликтов и ошибку
-	ApproveVacationRequest(requestID int, approverID int, force bool) ([]models.ConflictingPeriod, error)
+	CancelVacationRequest(requestID int, cancellingUserID int, reason string) error
+	// Изменена сигнатура: добавлен флаг force, возвращает список конфликтов, следующий этап согласования (nil, если заявка утверждена) и ошибку
+	ApproveVacationRequest(requestID int, approverID int, force bool) ([]models.ConflictingPeriod, []models.CoverageViolation, *models.ApprovalStep, error)
 	RejectVacationRequest(requestID int, rejecterID int, reason string) error
+	BulkDecideVacationRequests(actorID int, input *models.BulkDecisionRequest) (*models.BulkDecisionResult, error)
 	// Добавлен метод для дашборда
 	GetManagerDashboardData(managerID int) (*models.ManagerDashboardData, error)
 	// Добавлен метод для получения конфликтов для календаря/списка
 	GetVacationConflicts(requestingUserID int, startDate time.Time, endDate time.Time) ([]models.ConflictingPeriod, error)
 	// Добавлен метод для получения данных для экспорта
 	GetVacationDataForExport(unitIDs []int, year int) ([]models.VacationExportRow, error)
+	// Переносы утвержденного отпуска
+	CreateVacationTransfer(userID int, input *models.VacationTransferCreateDTO) (*models.VacationTransfer, error)
+	ApproveVacationTransfer(transferID int, approverID int, force bool) ([]models.ConflictingPeriod, []models.CoverageViolation, error)
+	RejectVacationTransfer(transferID int, rejecterID int, reason string) error
+	CancelVacationTransfer(transferID int, userID int) error
+	GetMyVacationTransfers(userID int) ([]models.VacationTransfer, error)
+	GetVacationTransfersForApproval(requestingUserID int, statusFilter *int) ([]models.VacationTransfer, error)
+	// Отзыв из отпуска
+	RecallFromVacation(periodID int, actorID int, input *models.VacationRecallDTO) (*models.VacationRecall, error)
+	// Справочник видов отпуска
+	GetLeaveTypes() ([]models.LeaveType, error)
+	CreateLeaveType(leaveType *models.LeaveType) error
+	UpdateLeaveType(leaveType *models.LeaveType) error
+	// Политики отпуска
+	GetVacationPolicies() ([]models.VacationPolicy, error)
+	CreateVacationPolicy(policy *models.VacationPolicy) error
+	UpdateVacationPolicy(policy *models.VacationPolicy) error
+	DeleteVacationPolicy(policyID int) error
+	ResolveVacationPolicy(userID int) (*models.EffectiveVacationPolicy, error)
+	AnnualEntitlement(user *models.User, year int) (int, error)
+	// Делегирование прав руководителя
+	GetApprovalDelegations(requestingUserID int) ([]models.ApprovalDelegation, error)
+	CreateApprovalDelegation(actorID int, input *models.ApprovalDelegationCreateDTO) (*models.ApprovalDelegation, error)
+	RevokeApprovalDelegation(delegationID int, actorID int) error
+	// Правила укомплектованности
+	GetCoverageRules() ([]models.CoverageRule, error)
+	CreateCoverageRule(rule *models.CoverageRule) error
+	UpdateCoverageRule(rule *models.CoverageRule) error
+	DeleteCoverageRule(ruleID int) error
+	// Периоды запрета отпусков юнитов
+	GetUnitBlackouts(unitID int) ([]models.UnitBlackout, error)
+	CreateUnitBlackout(actorID int, blackout *models.UnitBlackout) error
+	UpdateUnitBlackout(actorID int, blackout *models.UnitBlackout) error
+	DeleteUnitBlackout(actorID int, unitID int, blackoutID int) error
+	// График отпусков юнита
+	GenerateVacationSchedule(actorID int, input *models.ScheduleGenerationRequest) (*models.ScheduleProposal, error)
+	PublishVacationSchedule(actorID int, input *models.SchedulePublishRequest) (*models.SchedulePublishResult, error)
+	// Кампании планирования графика отпусков
+	GetPlanningCampaigns(yearFilter *int) ([]models.PlanningCampaign, error)
+	CreatePlanningCampaign(actorID int, campaign *models.PlanningCampaign) error
+	UpdatePlanningCampaignDeadlines(actorID int, campaign *models.PlanningCampaign) error
+	AdvancePlanningCampaign(actorID int, campaignID int) (*models.PlanningCampaign, error)
+	GetPlanningCampaignProgress(requestingUserID int, campaignID int) (*models.CampaignProgress, error)
+	// Пожелания сотрудников к графику отпусков
+	GetMyVacationPreference(userID int, year int) (*models.VacationPreference, error)
+	SaveVacationPreference(userID int, preference *models.VacationPreference) error
+	DeleteVacationPreference(userID int, year int) error
+	GetSubtreeVacationPreferences(requestingUserID int, year int, unitIDFilter *int) ([]models.VacationPreference, error)
+	ConvertVacationPreference(actorID int, preferenceID int, alternativeID int) (*models.VacationRequest, error)
+	// Пиковые периоды и очередность выбора пиковых дат
+	GetPeakWindows() ([]models.PeakWindow, error)
+	CreatePeakWindow(actorID int, window *models.PeakWindow) error
+	UpdatePeakWindow(window *models.PeakWindow) error
+	DeletePeakWindow(windowID int) error
+	GetPeakSlotReport(requestingUserID int, unitID int, years int) (*models.PeakSlotReport, error)
+	// Предложение других дат по заявке и ответ сотрудника
+	ProposeVacationChanges(requestID int, actorID int, input *models.VacationProposalCreateDTO) (*models.VacationRequestProposal, error)
+	AcceptVacationProposal(requestID int, userID int, comment string) (*models.VacationRequest, error)
+	DeclineVacationProposal(requestID int, userID int, comment string) error
+	GetRequestProposals(requestingUserID int, requestID int) ([]models.VacationRequestProposal, error)
+	// История переходов заявки и системные переходы по датам отпуска
+	GetRequestHistory(requestingUserID int, requestID int) ([]models.VacationRequestTransition, error)
+	AdvanceVacationStatuses(today time.Time) error
 }
 
 // VacationRepositoryInterface определяет методы для работы с данными отпусков.
 type VacationRepositoryInterface interface {
 	// --- Лимиты ---
 	GetVacationLimit(userID int, year int) (*models.VacationLimit, error)
-	CreateOrUpdateVacationLimit(userID int, year int, totalDays int) error
-	UpdateVacationLimitUsedDays(userID int, year int, daysDelta int) error
+	CreateOrUpdateVacationLimit(userID int, year int, totalDays int, actorID *int, comment string) error
+
+	// --- Журнал движения дней ---
+	AddLedgerEntry(entry *models.LeaveLedgerEntry) error
+	GetLedgerEntries(userID int, year int) ([]models.LeaveLedgerEntry, error)
+	GetRequestLedgerTotals(requestID int) (*models.RequestLedgerTotals, error)
+
+	// --- Переход года ---
+	GetUserIDsWithLimits(year int) ([]int, error)
+	IsYearRolledOver(userID int, year int) (bool, error)
+	SaveYearRollover(rollover *models.YearRollover) error
+
+	// --- Транзакции и блокировки ---
+	RunInTx(fn func(tx repositories.VacationRepositoryInterface) error) error
+	LockVacationRequest(requestID int) (*models.VacationRequest, error)
+	LockVacationLimit(userID int, year int) error
+	LockPosition(positionID int) error
+	LockCoverageRules(ruleIDs []int) error
 
 	// --- Заявки ---
 	GetVacationRequestByID(requestID int) (*models.VacationRequest, error)
 	SaveVacationRequest(request *models.VacationRequest) error
 	UpdateVacationRequest(request *models.VacationRequest) error
 	UpdateRequestStatusByID(requestID int, newStatusID int) error
+	SetRequestRejectionReason(requestID int, reason string) error
+	SetRequestCancellationReason(requestID int, reason string) error
 	GetVacationRequestsByUser(userID int, year int, statusFilter *int) ([]models.VacationRequest, error)
 	GetVacationRequestsByOrganizationalUnit(unitID int, year int, statusFilter *int) ([]models.VacationRequest, error)
 	GetAllVacationRequests(yearFilter *int, statusFilter *int, userIDFilter *int, unitIDsFilter []int) ([]models.VacationRequestAdminView, error) // Изменен тип unitIDsFilter на []int
 
+	// --- История переходов ---
+	AddRequestTransition(transition *models.VacationRequestTransition) error
+	GetRequestTransitions(requestID int) ([]models.VacationRequestTransition, error)
+	GetRequestIDsDueForStart(date time.Time) ([]int, error)
+	GetRequestIDsDueForCompletion(date time.Time) ([]int, error)
+
+	// --- Переносы отпуска ---
+	GetVacationPeriodByID(periodID int) (*models.VacationPeriod, error)
+	UpdateVacationPeriodDates(periodID int, startDate models.CustomDate, endDate models.CustomDate, daysCount int) error
+	CreateVacationTransfer(transfer *models.VacationTransfer) error
+	LockVacationTransfer(transferID int) (*models.VacationTransfer, error)
+	HasPendingVacationTransfer(periodID int) (bool, error)
+	SetVacationTransferDecision(transferID int, statusID int, decidedBy int, comment string) error
+	GetVacationTransfers(userIDFilter *int, statusFilter *int, unitIDsFilter []int) ([]models.VacationTransfer, error)
+	GetApprovedTransfersByPeriodIDs(periodIDs []int) ([]models.VacationTransfer, error)
+
+	// --- Отзыв из отпуска ---
+	CreateVacationRecall(recall *models.VacationRecall) error
+	GetVacationRecallsByPeriodIDs(periodIDs []int) ([]models.VacationRecall, error)
+	UpdateRequestDaysRequested(requestID int, daysRequested int) error
+
+	// --- Виды отпуска ---
+	GetLeaveTypes() ([]models.LeaveType, error)
+	GetLeaveTypeByID(leaveTypeID int) (*models.LeaveType, error)
+	CreateLeaveType(leaveType *models.LeaveType) error
+	UpdateLeaveType(leaveType *models.LeaveType) error
+	GetLeaveTypeDaysUsed(userID int, year int, excludeRequestID int) (map[int]int, error)
+
+	// --- Политики отпуска ---
+	GetVacationPolicies() ([]models.VacationPolicy, error)
+	GetVacationPolicyByID(policyID int) (*models.VacationPolicy, error)
+	CreateVacationPolicy(policy *models.VacationPolicy) error
+	UpdateVacationPolicy(policy *models.VacationPolicy) error
+	DeleteVacationPolicy(policyID int) error
+
+	// --- Цепочка согласования ---
+	ReplaceApprovalSteps(requestID int, steps []models.ApprovalStep) error
+	SetApprovalStepDecision(stepID int, status string, decidedBy int, comment string) error
+
+	// --- Делегирование прав руководителя ---
+	GetApprovalDelegations(userIDFilter *int) ([]models.ApprovalDelegation, error)
+	GetActiveDelegationsForDelegate(delegateID int, date time.Time) ([]models.ApprovalDelegation, error)
+	GetApprovalDelegationByID(delegationID int) (*models.ApprovalDelegation, error)
+	CreateApprovalDelegation(delegation *models.ApprovalDelegation) error
+	RevokeApprovalDelegation(delegationID int) error
+	DeleteRequestDelegations(requestID int) error
+
+	// --- Правила укомплектованности ---
+	GetCoverageRules() ([]models.CoverageRule, error)
+	GetCoverageRulesByUnitIDs(unitIDs []int) ([]models.CoverageRule, error)
+	GetCoverageRuleByID(ruleID int) (*models.CoverageRule, error)
+	CreateCoverageRule(rule *models.CoverageRule) error
+	UpdateCoverageRule(rule *models.CoverageRule) error
+	DeleteCoverageRule(ruleID int) error
+	GetApprovedAbsences(userIDs []int, excludeRequestID int, startDate time.Time, endDate time.Time) ([]models.StaffAbsence, error)
+	GetApprovedAbsencesByPosition(positionID int, startDate time.Time, endDate time.Time) ([]models.StaffAbsence, error)
+	GetPendingAbsencesByPosition(positionID int, startDate time.Time, endDate time.Time) ([]models.StaffAbsence, error)
+
+	// --- Периоды запрета отпусков ---
+	GetUnitBlackoutsByUnitIDs(unitIDs []int) ([]models.UnitBlackout, error)
+	GetUnitBlackoutByID(blackoutID int) (*models.UnitBlackout, error)
+	CreateUnitBlackout(blackout *models.UnitBlackout) error
+	UpdateUnitBlackout(blackout *models.UnitBlackout) error
+	DeleteUnitBlackout(blackoutID int) error
+
+	// --- Кампании планирования графика отпусков ---
+	GetPlanningCampaigns(yearFilter *int) ([]models.PlanningCampaign, error)
+	GetPlanningCampaignByID(campaignID int) (*models.PlanningCampaign, error)
+	CreatePlanningCampaign(campaign *models.PlanningCampaign) error
+	UpdatePlanningCampaign(campaign *models.PlanningCampaign) error
+	CountActiveRequestsByUser(userIDs []int, year int) (map[int]int, error)
+
+	// --- Предложения изменить даты заявки ---
+	GetRequestProposals(requestID int) ([]models.VacationRequestProposal, error)
+	GetOpenRequestProposal(requestID int) (*models.VacationRequestProposal, error)
+	CreateRequestProposal(proposal *models.VacationRequestProposal) error
+	SetRequestProposalStatus(proposalID int, status string, respondedBy *int, comment string) error
+
+	// --- Пиковые периоды отпусков ---
+	GetPeakWindows() ([]models.PeakWindow, error)
+	GetPeakWindowByID(windowID int) (*models.PeakWindow, error)
+	CreatePeakWindow(window *models.PeakWindow) error
+	UpdatePeakWindow(window *models.PeakWindow) error
+	DeletePeakWindow(windowID int) error
+
+	// --- Пожелания сотрудников к графику отпусков ---
+	GetVacationPreference(userID int, year int) (*models.VacationPreference, error)
+	GetVacationPreferenceByID(preferenceID int) (*models.VacationPreference, error)
+	LockVacationPreference(preferenceID int) (*models.VacationPreference, error)
+	GetVacationPreferencesByUsers(userIDs []int, year int) ([]models.VacationPreference, error)
+	SaveVacationPreference(preference *models.VacationPreference) error
+	SetPreferenceConversion(preferenceID int, alternativeID int, requestID int) error
+	DeleteVacationPreference(preferenceID int) error
+
 	// --- Уведомления ---
 	CreateNotification(notification *models.Notification) error
 
@@ -57,38 +239,107 @@ type VacationRepositoryInterface interface {
 	GetUserPositionByID(userID int) (*int, error)                                                                                                         // Добавлен метод получения должности
 	GetApprovedVacationConflictsByPosition(positionID int, excludeUserID int, periodsToCheck []models.VacationPeriod) ([]models.ConflictingPeriod, error) // Добавлен метод поиска конфликтов
 	// --- Dashboard Data ---
-	CountPendingRequestsByUnitIDs(unitIDs []int) (int, error)                                                                        // Добавлен метод подсчета ожидающих заявок
+	CountPendingRequestsForApprover(approverID int, unitIDs []int) (int, error)                                                      // Подсчет заявок, ожидающих решения руководителя
 	SumRequestedDaysByStatusAndUnitIDs(unitIDs []int, statusIDs []int, year int) (int, error)                                        // Добавлен метод суммирования дней
 	GetUpcomingApprovedConflictsByUnitIDs(unitIDs []int, startDate time.Time, endDate time.Time) ([]models.ConflictingPeriod, error) // Добавлен метод получения предстоящих конфликтов
 }
 
+// ErrInvalidRequest - заявка не прошла проверку условий отпуска
+var ErrInvalidRequest = errors.New("заявка не соответствует условиям отпуска")
+
+// ReasonPolicy определяет, обязательна ли причина при отклонении и отмене заявки
+type ReasonPolicy struct {
+	RejectionRequired    bool
+	CancellationRequired bool
+}
+
 // VacationService реализует VacationServiceInterface
 type VacationService struct {
 	vacationRepo VacationRepositoryInterface                        // Используем интерфейс репозитория отпусков
 	userRepo     repositories.UserRepositoryInterface               // Используем интерфейс репозитория пользователей
 	unitRepo     repositories.OrganizationalUnitRepositoryInterface // Используем полный интерфейс из repositories
+	calendar     ProductionCalendarInterface                        // Производственный календарь для подсчета дней
+	reasonPolicy ReasonPolicy                                       // Обязательность причин отклонения/отмены
+	carryOver    CarryOverPolicy                                    // Правило переноса остатка на следующий год
+	approval     ApprovalPolicy                                     // Состав цепочки согласования заявок
 }
 
 // Обновляем конструктор, чтобы принимать интерфейсы
-func NewVacationService(vacationRepo VacationRepositoryInterface, userRepo repositories.UserRepositoryInterface, unitRepo repositories.OrganizationalUnitRepositoryInterface) *VacationService { // Используем полный интерфейс
+func NewVacationService(vacationRepo VacationRepositoryInterface, userRepo repositories.UserRepositoryInterface, unitRepo repositories.OrganizationalUnitRepositoryInterface, calendar ProductionCalendarInterface, reasonPolicy ReasonPolicy, carryOver CarryOverPolicy, approval ApprovalPolicy) *VacationService { // Используем полный интерфейс
 	return &VacationService{
 		vacationRepo: vacationRepo,
 		userRepo:     userRepo,
 		unitRepo:     unitRepo, // Сохраняем unitRepo
+		calendar:     calendar,
+		reasonPolicy: reasonPolicy,
+		carryOver:    carryOver,
+		approval:     approval,
 	}
 }
 
-// GetVacationLimit получает лимит отпуска для пользователя.
-// Если лимит не найден, пытается создать лимит по умолчанию (28 дней) и возвращает его.
+// recalculateDays пересчитывает количество дней в периодах заявки по производственному календарю
+// и правилу подсчета вида отпуска. Значение DaysCount, пришедшее от клиента, игнорируется.
+// В DaysRequested попадают только дни видов отпуска, списываемых с баланса основного отпуска.
+func (s *VacationService) recalculateDays(leaveTypes map[int]models.LeaveType, request *models.VacationRequest) error {
+	request.DaysRequested = 0
+	for i := range request.Periods {
+		period := &request.Periods[i]
+		if period.LeaveTypeID == 0 {
+			period.LeaveTypeID = models.LeaveTypeMain
+		}
+		leaveType, ok := leaveTypes[period.LeaveTypeID]
+		if !ok || !leaveType.IsActive {
+			return fmt.Errorf("недопустимый вид отпуска %d в периоде %d", period.LeaveTypeID, i+1)
+		}
+		if period.StartDate.IsZero() || period.EndDate.IsZero() || period.EndDate.Time.Before(period.StartDate.Time) {
+			period.DaysCount = 0
+			continue
+		}
+		for _, year := range []int{period.StartDate.Year(), period.EndDate.Year()} {
+			if !s.calendar.HasYear(year) {
+				log.Printf("[recalculateDays] Warning: production calendar has no data for year %d, holidays are not taken into account", year)
+			}
+		}
+		period.DaysCount = s.countLeaveDays(leaveType, period.StartDate.Time, period.EndDate.Time)
+		if leaveType.CountsTowardMain {
+			request.DaysRequested += period.DaysCount
+		}
+	}
+	return nil
+}
+
+// GetVacationLimit получает лимит отпуска для пользователя вместе с днями, начисленными на текущую дату.
+// Если лимит не найден, пытается создать лимит по умолчанию (пропорционально месяцам с даты приема) и возвращает его.
 func (s *VacationService) GetVacationLimit(userID int, year int) (*models.VacationLimit, error) {
-	limit, err := s.vacationRepo.GetVacationLimit(userID, year)
+	limit, err := s.getVacationLimit(s.vacationRepo, userID, year)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения данных пользователя %d: %w", userID, err)
+	}
+	var hireDate *models.CustomDate
+	if user != nil {
+		hireDate = user.HireDate
+	}
+	applyAccrual(limit, hireDate, time.Now())
+	return limit, nil
+}
+
+// getVacationLimit получает лимит через переданный репозиторий (в том числе внутри транзакции)
+func (s *VacationService) getVacationLimit(repo repositories.VacationRepositoryInterface, userID int, year int) (*models.VacationLimit, error) {
+	limit, err := repo.GetVacationLimit(userID, year)
 	if err != nil {
 		// Проверяем, является ли ошибка "не найдено" с помощью errors.Is и экспортированной ошибки
 		if errors.Is(err, repositories.ErrLimitNotFound) {
 			log.Printf("[GetVacationLimit] Limit not found for UserID: %d, Year: %d. Attempting to create default limit.", userID, year)
 			// Пытаемся создать лимит по умолчанию
-			defaultTotalDays := 28 // Лимит по умолчанию
-			createErr := s.vacationRepo.CreateOrUpdateVacationLimit(userID, year, defaultTotalDays)
+			defaultTotalDays, entErr := s.defaultEntitlement(userID, year)
+			if entErr != nil {
+				return nil, entErr
+			}
+			createErr := repo.CreateOrUpdateVacationLimit(userID, year, defaultTotalDays, nil, "Начисление по умолчанию")
 			if createErr != nil {
 				log.Printf("[GetVacationLimit] Failed to create default limit for UserID: %d, Year: %d. Error: %v", userID, year, createErr)
 				// Возвращаем исходную ошибку "не найдено", т.к. создать не удалось
@@ -96,7 +347,7 @@ func (s *VacationService) GetVacationLimit(userID int, year int) (*models.Vacati
 			}
 			log.Printf("[GetVacationLimit] Default limit created successfully for UserID: %d, Year: %d.", userID, year)
 			// Повторно пытаемся получить только что созданный лимит
-			limit, err = s.vacationRepo.GetVacationLimit(userID, year)
+			limit, err = repo.GetVacationLimit(userID, year)
 			if err != nil {
 				log.Printf("[GetVacationLimit] Failed to retrieve the newly created default limit for UserID: %d, Year: %d. Error: %v", userID, year, err)
 				// Возвращаем ошибку получения после создания
@@ -113,64 +364,283 @@ func (s *VacationService) GetVacationLimit(userID int, year int) (*models.Vacati
 	return limit, nil
 }
 
-// SetVacationLimit устанавливает (создает или обновляет) лимит отпуска для пользователя
-func (s *VacationService) SetVacationLimit(userID int, year int, totalDays int) error {
+// defaultEntitlement считает начисление по умолчанию на год по политике отпуска и дате приема сотрудника
+func (s *VacationService) defaultEntitlement(userID int, year int) (int, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка получения данных пользователя %d для начисления: %w", userID, err)
+	}
+	if user == nil {
+		return annualEntitlement(nil, year, defaultVacationPolicy().AnnualDays), nil
+	}
+	return s.AnnualEntitlement(user, year)
+}
+
+// SetVacationLimit устанавливает (создает или обновляет) лимит отпуска для пользователя.
+// Изменение фиксируется в журнале как начисление или корректировка от имени actorID.
+func (s *VacationService) SetVacationLimit(userID int, year int, totalDays int, actorID int) error {
 	if totalDays < 0 {
 		return errors.New("количество дней отпуска не может быть отрицательным")
 	}
-	return s.vacationRepo.CreateOrUpdateVacationLimit(userID, year, totalDays)
+	return s.vacationRepo.CreateOrUpdateVacationLimit(userID, year, totalDays, &actorID, "Установка лимита администратором")
+}
+
+// AdjustVacationBalance добавляет ручную корректировку баланса (положительную или отрицательную)
+func (s *VacationService) AdjustVacationBalance(userID int, year int, days int, actorID int, comment string) error {
+	if days == 0 {
+		return errors.New("корректировка должна быть ненулевой")
+	}
+	if strings.TrimSpace(comment) == "" {
+		return errors.New("необходимо указать основание корректировки")
+	}
+	limit, err := s.GetVacationLimit(userID, year)
+	if err != nil {
+		return fmt.Errorf("ошибка получения баланса пользователя %d на %d год: %w", userID, year, err)
+	}
+	if limit.TotalDays+days < 0 {
+		return fmt.Errorf("корректировка %d дней приведет к отрицательному начислению (начислено %d)", days, limit.TotalDays)
+	}
+	entry := &models.LeaveLedgerEntry{
+		UserID: userID, Year: year, EntryType: models.LedgerEntryAdjustment, Days: days,
+		ActorID: &actorID, Comment: strings.TrimSpace(comment),
+	}
+	return s.vacationRepo.AddLedgerEntry(entry)
+}
+
+// GetLeaveLedger возвращает журнал движения дней отпуска пользователя за год вместе с текущим балансом.
+// Доступно самому сотруднику, администратору и руководителю сотрудника.
+func (s *VacationService) GetLeaveLedger(requestingUserID int, targetUserID int, year int) (*models.LeaveLedger, error) {
+	if requestingUserID != targetUserID {
+		requestingUser, err := s.userRepo.FindByID(requestingUserID)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения данных запрашивающего пользователя: %w", err)
+		}
+		if requestingUser == nil {
+			return nil, errors.New("запрашивающий пользователь не найден")
+		}
+		targetUser, err := s.userRepo.FindByID(targetUserID)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения данных сотрудника %d: %w", targetUserID, err)
+		}
+		if targetUser == nil {
+			return nil, fmt.Errorf("сотрудник %d не найден", targetUserID)
+		}
+		accessGranted, err := s.checkUserUnitAccess(requestingUser, targetUser)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка проверки доступа к журналу: %w", err)
+		}
+		if !accessGranted {
+			return nil, errors.New("нет прав на просмотр журнала отпусков этого сотрудника")
+		}
+	}
+
+	balance, err := s.GetVacationLimit(targetUserID, year)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.vacationRepo.GetLedgerEntries(targetUserID, year)
+	if err != nil {
+		return nil, err
+	}
+	return &models.LeaveLedger{UserID: targetUserID, Year: year, Balance: balance, Entries: entries}, nil
+}
+
+// lockVacationLimit блокирует лимит пользователя на год внутри транзакции,
+// предварительно создавая лимит по умолчанию, если его еще нет
+func (s *VacationService) lockVacationLimit(tx repositories.VacationRepositoryInterface, userID int, year int) error {
+	if _, err := s.getVacationLimit(tx, userID, year); err != nil {
+		return fmt.Errorf("ошибка получения лимита отпуска: %w", err)
+	}
+	if err := tx.LockVacationLimit(userID, year); err != nil {
+		return fmt.Errorf("ошибка блокировки лимита отпуска: %w", err)
+	}
+	return nil
+}
+
+// addRequestLedgerEntry добавляет в журнал запись, связанную с заявкой
+func (s *VacationService) addRequestLedgerEntry(repo repositories.VacationRepositoryInterface, req *models.VacationRequest, entryType string, days int, actorID int, comment string) error {
+	requestID := req.ID
+	entry := &models.LeaveLedgerEntry{
+		UserID: req.UserID, Year: req.Year, EntryType: entryType, Days: days,
+		RequestID: &requestID, ActorID: &actorID, Comment: comment,
+	}
+	if err := repo.AddLedgerEntry(entry); err != nil {
+		log.Printf("[Service Ledger] Failed to add %s entry (%+d days) for request %d (user %d, year %d): %v", entryType, days, req.ID, req.UserID, req.Year, err)
+		return fmt.Errorf("ошибка записи в журнал отпусков по заявке %d: %w", req.ID, err)
+	}
+	return nil
+}
+
+// releaseRequestReservation снимает резерв дней по заявке, если он есть
+func (s *VacationService) releaseRequestReservation(repo repositories.VacationRepositoryInterface, req *models.VacationRequest, actorID int, comment string) error {
+	totals, err := repo.GetRequestLedgerTotals(req.ID)
+	if err != nil {
+		return err
+	}
+	if totals.ReservedDays == 0 {
+		log.Printf("[Service Ledger] No reservation to release for request %d", req.ID)
+		return nil
+	}
+	return s.addRequestLedgerEntry(repo, req, models.LedgerEntryReservation, totals.ReservedDays, actorID, comment)
 }
 
 // ValidateVacationRequest проверяет условия отпуска
 func (s *VacationService) ValidateVacationRequest(request *models.VacationRequest) error {
-	hasLongPeriod := false
-	totalDays := 0
+	return s.validateVacationRequest(s.vacationRepo, request)
+}
+
+// validateVacationRequest проверяет заявку по правилам политики отпуска сотрудника, читая баланс через переданный репозиторий.
+// Правила основного отпуска (минимальная часть, количество частей, использование всех доступных дней) применяются
+// к периодам видов, списываемых с основного баланса; для остальных видов проверяется ограничение дней в году.
+// Периоды сверяются с периодами запрета отпусков юнита; предупреждения записываются в request.Warnings.
+// При кампании планирования на год заявка на отпуска графика принимается только на этапе сбора.
+// Все найденные нарушения возвращаются одной ошибкой *PolicyViolationError.
+func (s *VacationService) validateVacationRequest(repo repositories.VacationRepositoryInterface, request *models.VacationRequest) error {
+	return s.validateVacationRequestWith(repo, request, validationOptions{})
+}
+
+// validationOptions - исключения из проверок validateVacationRequest для отдельных сценариев
+type validationOptions struct {
+	// renegotiation - согласование дат заявки на рассмотрении с согласующим (предложение и его принятие):
+	// этап кампании планирования не проверяется, так как даты согласуются на этапах рассмотрения
+	renegotiation bool
+}
+
+// validateVacationRequestWith проверяет заявку как validateVacationRequest с учетом исключений opts
+func (s *VacationService) validateVacationRequestWith(repo repositories.VacationRepositoryInterface, request *models.VacationRequest, opts validationOptions) error {
 	if len(request.Periods) == 0 {
-		return errors.New("необходимо указать хотя бы один период отпуска")
+		return &PolicyViolationError{Violations: []string{"необходимо указать хотя бы один период отпуска"}}
+	}
+	employee, err := s.findActor(request.UserID)
+	if err != nil {
+		return err
+	}
+	policy, err := s.resolvePolicy(employee)
+	if err != nil {
+		return fmt.Errorf("ошибка получения политики отпуска: %w", err)
+	}
+	leaveTypes, err := s.leaveTypesByID(repo)
+	if err != nil {
+		return err
+	}
+	// Дни считаются на сервере по производственному календарю
+	if err := s.recalculateDays(leaveTypes, request); err != nil {
+		return &PolicyViolationError{Violations: []string{err.Error()}}
 	}
 
+	violations := []string{}
+	hasLongPeriod := false
+	mainParts := 0
+	totalDays := 0
 	for i, period := range request.Periods {
 		if period.StartDate.IsZero() || period.EndDate.IsZero() || period.EndDate.Time.Before(period.StartDate.Time) {
-			return fmt.Errorf("некорректные даты в периоде %d: дата начала %s, дата окончания %s",
-				i+1, period.StartDate.Format("2006-01-02"), period.EndDate.Format("2006-01-02"))
+			violations = append(violations, fmt.Sprintf("некорректные даты в периоде %d: дата начала %s, дата окончания %s",
+				i+1, period.StartDate.Format("2006-01-02"), period.EndDate.Format("2006-01-02")))
+			continue
+		}
+		if period.DaysCount == 0 {
+			violations = append(violations, fmt.Sprintf("период %d не содержит дней отпуска (только нерабочие дни)", i+1))
 		}
-		for j := i + 1; j < len(request.Periods); j++ {
-			if doPeriodIntersect(period, request.Periods[j]) {
-				return fmt.Errorf("периоды %d и %d в заявке пересекаются", i+1, j+1)
+		if policy.ForbidOverlap {
+			for j := i + 1; j < len(request.Periods); j++ {
+				if doPeriodIntersect(period, request.Periods[j]) {
+					violations = append(violations, fmt.Sprintf("периоды %d и %d в заявке пересекаются", i+1, j+1))
+				}
 			}
 		}
+		if !leaveTypes[period.LeaveTypeID].CountsTowardMain {
+			continue
+		}
+		mainParts++
 		totalDays += period.DaysCount
-		if period.DaysCount >= 14 {
+		if period.DaysCount >= policy.MinLongPartDays {
 			hasLongPeriod = true
 		}
 	}
-	if !hasLongPeriod {
-		return errors.New("Одна из частей отпуска должна быть не менее 14 календарных дней")
+
+	typeViolations, err := s.validateLeaveTypeLimits(repo, request, leaveTypes)
+	if err != nil {
+		return err
 	}
+	violations = append(violations, typeViolations...)
 
-	limit, err := s.GetVacationLimit(request.UserID, request.Year) // Эта функция теперь пытается создать лимит, если его нет
+	// Периоды запрета отпусков юнита: строгие отклоняют заявку, нестрогие дают предупреждение
+	blackoutViolations, warnings, err := s.checkBlackouts(repo, employee, request)
 	if err != nil {
-		// Если ошибка именно в том, что лимит не найден (и не удалось создать), даем понятное сообщение
-		if errors.Is(err, repositories.ErrLimitNotFound) {
-			log.Printf("[Validation Error] UserID: %d, Year: %d - Limit not found and could not be created: %v", request.UserID, request.Year, err)
-			return fmt.Errorf("лимит отпуска для пользователя %d на %d год не найден и не может быть создан", request.UserID, request.Year)
+		return fmt.Errorf("ошибка проверки периодов запрета отпусков: %w", err)
+	}
+	violations = append(violations, blackoutViolations...)
+	request.Warnings = warnings
+
+	// Кампания планирования: вне этапа сбора заявки на отпуска графика не принимаются
+	if !opts.renegotiation {
+		campaignViolation, err := s.checkPlanningCampaign(repo, employee, request, leaveTypes)
+		if err != nil {
+			return fmt.Errorf("ошибка проверки кампании планирования: %w", err)
+		}
+		if campaignViolation != "" {
+			violations = append(violations, campaignViolation)
+		}
+	}
+
+	// Правила основного отпуска не применяются к заявке только на другие виды отпуска
+	if mainParts > 0 {
+		if policy.MinLongPartDays > 0 && !hasLongPeriod {
+			violations = append(violations, fmt.Sprintf("Одна из частей отпуска должна быть не менее %d календарных дней", policy.MinLongPartDays))
+		}
+		if policy.MaxParts > 0 && mainParts > policy.MaxParts {
+			violations = append(violations, fmt.Sprintf("отпуск можно разделить не более чем на %d части, указано %d", policy.MaxParts, mainParts))
+		}
+
+		limit, err := s.getVacationLimit(repo, request.UserID, request.Year) // Эта функция теперь пытается создать лимит, если его нет
+		if err != nil {
+			// Если ошибка именно в том, что лимит не найден (и не удалось создать), даем понятное сообщение
+			if errors.Is(err, repositories.ErrLimitNotFound) {
+				log.Printf("[Validation Error] UserID: %d, Year: %d - Limit not found and could not be created: %v", request.UserID, request.Year, err)
+				return fmt.Errorf("лимит отпуска для пользователя %d на %d год не найден и не может быть создан", request.UserID, request.Year)
+			}
+			// Для других ошибок получения/создания лимита
+			log.Printf("[Validation Error] UserID: %d, Year: %d - Failed to get/create vacation limit: %v", request.UserID, request.Year, err)
+			return fmt.Errorf("ошибка при получении/создании лимита отпуска: %w", err)
+		}
+		availableDays := limit.AvailableDays
+		log.Printf("[Validation Check] UserID: %d, Year: %d, Limit: %d, Used: %d, Available: %d, Requested: %d", request.UserID, request.Year, limit.TotalDays, limit.UsedDays, availableDays, totalDays)
+		switch {
+		case policy.RequireExactBalance && totalDays != availableDays:
+			violations = append(violations, fmt.Sprintf("необходимо использовать все доступные дни отпуска: доступно %d, запрошено %d", availableDays, totalDays))
+		case totalDays > availableDays:
+			violations = append(violations, fmt.Sprintf("недостаточно дней отпуска: доступно %d, запрошено %d", availableDays, totalDays))
 		}
-		// Для других ошибок получения/создания лимита
-		log.Printf("[Validation Error] UserID: %d, Year: %d - Failed to get/create vacation limit: %v", request.UserID, request.Year, err)
-		return fmt.Errorf("ошибка при получении/создании лимита отпуска: %w", err)
 	}
-	availableDays := limit.TotalDays - limit.UsedDays
-	log.Printf("[Validation Check] UserID: %d, Year: %d, Limit: %d, Used: %d, Available: %d, Requested: %d", request.UserID, request.Year, limit.TotalDays, limit.UsedDays, availableDays, totalDays)
-	if totalDays != availableDays {
-		log.Printf("[Validation Failed] UserID: %d, Year: %d - Days mismatch: available %d, requested %d", request.UserID, request.Year, availableDays, totalDays)
-		return fmt.Errorf("необходимо использовать все доступные дни отпуска: доступно %d, запрошено %d", availableDays, totalDays)
+
+	if len(violations) > 0 {
+		log.Printf("[Validation Failed] UserID: %d, Year: %d - %d violation(s): %s", request.UserID, request.Year, len(violations), strings.Join(violations, "; "))
+		return &PolicyViolationError{Violations: violations}
 	}
-	log.Printf("[Validation OK] UserID: %d, Year: %d - Exact days match passed: available %d, requested %d", request.UserID, request.Year, availableDays, totalDays)
+	log.Printf("[Validation OK] UserID: %d, Year: %d - Request passed policy %v", request.UserID, request.Year, policy.PolicyIDs)
 	return nil
 }
 
-// checkUserUnitAccess проверяет иерархический доступ менеджера к сотруднику
+// checkUserUnitAccess проверяет доступ руководителя к сотруднику: по иерархии юнитов
+// или по действующему делегированию прав от руководителя сотрудника
 func (s *VacationService) checkUserUnitAccess(accessor *models.User, targetUser *models.User) (bool, error) {
+	granted, err := s.directUnitAccess(accessor, targetUser)
+	if err != nil || granted {
+		return granted, err
+	}
+	delegation, err := s.delegationCovering(accessor, targetUser)
+	if err != nil {
+		return false, fmt.Errorf("ошибка проверки делегирования: %w", err)
+	}
+	if delegation != nil {
+		log.Printf("[Access Check] Granted: user %d acts for manager %d on user %d (delegation %d)", accessor.ID, delegation.DelegatorID, targetUser.ID, delegation.ID)
+		return true, nil
+	}
+	return false, nil
+}
+
+// directUnitAccess проверяет иерархический доступ менеджера к сотруднику без учета делегирований
+func (s *VacationService) directUnitAccess(accessor *models.User, targetUser *models.User) (bool, error) {
 	if accessor.IsAdmin {
 		return true, nil
 	}
@@ -195,78 +665,252 @@ func (s *VacationService) checkUserUnitAccess(accessor *models.User, targetUser
 	return false, nil
 }
 
-// SaveVacationRequest сохраняет заявку на отпуск
+// SaveVacationRequest сохраняет заявку на отпуск.
+// Заявка и резерв дней (для заявки на рассмотрении) сохраняются в одной транзакции под блокировкой лимита.
 func (s *VacationService) SaveVacationRequest(request *models.VacationRequest) error {
+	return s.vacationRepo.RunInTx(func(tx repositories.VacationRepositoryInterface) error {
+		return s.createVacationRequest(tx, request)
+	})
+}
+
+// createVacationRequest создает заявку внутри переданной транзакции: пересчитывает дни, сохраняет заявку
+// и переход создания, для заявки на рассмотрении запускает цепочку согласования и резервирует дни под блокировкой лимита.
+func (s *VacationService) createVacationRequest(tx repositories.VacationRepositoryInterface, request *models.VacationRequest) error {
 	// Устанавливаем статус "На рассмотрении" по умолчанию, если он не указан
 	if request.StatusID == 0 {
-		request.StatusID = models.StatusPending // Изменено с StatusDraft на StatusPending
+		request.StatusID = models.StatusPending
 	}
-	request.DaysRequested = 0
-	for _, p := range request.Periods {
-		request.DaysRequested += p.DaysCount
+	if !initialStatuses[request.StatusID] {
+		return fmt.Errorf("%w: заявка может быть создана только как черновик или на рассмотрении", ErrTransitionNotAllowed)
+	}
+	leaveTypes, err := s.leaveTypesByID(tx)
+	if err != nil {
+		return err
+	}
+	if err := s.recalculateDays(leaveTypes, request); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRequest, err)
 	}
 	log.Printf("[Service SaveVacationRequest] Calculated DaysRequested: %d for UserID: %d", request.DaysRequested, request.UserID)
-	return s.vacationRepo.SaveVacationRequest(request)
-}
 
-// SubmitVacationRequest отправляет заявку руководителю
-func (s *VacationService) SubmitVacationRequest(requestID int, userID int) error {
-	req, err := s.vacationRepo.GetVacationRequestByID(requestID)
-	if err != nil {
-		return fmt.Errorf("ошибка получения заявки для отправки: %w", err)
+	reserve := request.StatusID == models.StatusPending && request.DaysRequested > 0
+	if reserve {
+		if err := s.lockVacationLimit(tx, request.UserID, request.Year); err != nil {
+			return err
+		}
+		// Повторная проверка остатка под блокировкой: параллельная заявка могла занять дни
+		limit, err := s.getVacationLimit(tx, request.UserID, request.Year)
+		if err != nil {
+			return fmt.Errorf("ошибка получения лимита отпуска: %w", err)
+		}
+		if request.DaysRequested > limit.AvailableDays {
+			return fmt.Errorf("недостаточно дней отпуска: доступно %d, запрошено %d", limit.AvailableDays, request.DaysRequested)
+		}
 	}
-	if req == nil {
-		return errors.New("заявка не найдена")
+	if err := tx.SaveVacationRequest(request); err != nil {
+		return err
+	}
+	actorID := request.UserID
+	transition := &models.VacationRequestTransition{
+		RequestID: request.ID, ToStatusID: request.StatusID, Action: models.ActionCreate, ActorID: &actorID,
+	}
+	if err := tx.AddRequestTransition(transition); err != nil {
+		return err
+	}
+	if request.StatusID == models.StatusPending {
+		if err := s.startApprovalChain(tx, request, nil); err != nil {
+			return err
+		}
 	}
-	if req.UserID != userID {
-		return errors.New("нет прав на отправку этой заявки")
+	// Заявка, созданная сразу на рассмотрении, резервирует дни
+	if reserve {
+		return s.addRequestLedgerEntry(tx, request, models.LedgerEntryReservation, -request.DaysRequested, request.UserID, "Резерв по заявке")
 	}
-	// Удалена проверка на StatusDraft, так как заявки теперь сразу Pending или другой статус
-	if err = s.ValidateVacationRequest(req); err != nil {
-		return fmt.Errorf("ошибка валидации заявки перед отправкой: %w", err)
+	return nil
+}
+
+// SubmitVacationRequest отправляет черновик заявки руководителю.
+// Проверка, смена статуса и резерв дней выполняются атомарно под блокировкой заявки и лимита.
+func (s *VacationService) SubmitVacationRequest(requestID int, userID int) error {
+	actor, err := s.findActor(userID)
+	if err != nil {
+		return err
 	}
 
-	if req.DaysRequested > 0 {
-		limit, errLimit := s.vacationRepo.GetVacationLimit(req.UserID, req.Year)
-		// Используем errors.Is для сравнения с экспортированной ошибкой
-		// Переменная limitNotFoundErrorMsg больше не нужна
-		if errLimit != nil && !errors.Is(errLimit, repositories.ErrLimitNotFound) {
-			return fmt.Errorf("ошибка получения лимита пользователя %d (год %d) перед отправкой заявки %d: %w", req.UserID, req.Year, requestID, errLimit)
+	var submitted *models.VacationRequest
+	err = s.vacationRepo.RunInTx(func(tx repositories.VacationRepositoryInterface) error {
+		req, err := tx.LockVacationRequest(requestID)
+		if err != nil {
+			return fmt.Errorf("ошибка получения заявки для отправки: %w", err)
 		}
-		// Проверяем на любую ошибку (включая ErrLimitNotFound после попытки создания)
-		if errLimit != nil {
-			log.Printf("[Submit Error] UserID: %d, Year: %d, RequestID: %d - Limit not found: %v", req.UserID, req.Year, requestID, errLimit)
-			return fmt.Errorf("невозможно отправить заявку: лимит отпуска для пользователя %d на %d год не установлен", req.UserID, req.Year)
+		if req == nil {
+			return errors.New("заявка не найдена")
 		}
-		availableDays := limit.TotalDays - limit.UsedDays
-		if req.DaysRequested > availableDays {
-			return fmt.Errorf("недостаточно дней отпуска у пользователя %d (год %d): доступно %d, запрошено %d", req.UserID, req.Year, availableDays, req.DaysRequested)
+		if _, err := s.authorizeTransition(actor, req, models.ActionSubmit); err != nil {
+			return err
 		}
-		errSpend := s.vacationRepo.UpdateVacationLimitUsedDays(req.UserID, req.Year, req.DaysRequested)
-		if errSpend != nil {
-			log.Printf("[Service SubmitVacationRequest] Failed to spend days. UserID: %d, Year: %d, RequestID: %d, Days: %d, Error: %v", req.UserID, req.Year, requestID, req.DaysRequested, errSpend)
-			return fmt.Errorf("ошибка списания %d дней из лимита пользователя %d (год %d) при отправке заявки %d: %w", req.DaysRequested, req.UserID, req.Year, requestID, errSpend)
+		if err := s.lockVacationLimit(tx, req.UserID, req.Year); err != nil {
+			return err
+		}
+		if err = s.validateVacationRequest(tx, req); err != nil {
+			return fmt.Errorf("ошибка валидации заявки перед отправкой: %w", err)
 		}
-		log.Printf("[Service SubmitVacationRequest] Successfully spent days. UserID: %d, Year: %d, RequestID: %d, Days: %d", req.UserID, req.Year, requestID, req.DaysRequested)
-	}
 
-	err = s.vacationRepo.UpdateRequestStatusByID(requestID, models.StatusPending)
-	if err != nil {
+		if err := s.applyTransition(tx, actor, req, models.ActionSubmit, ""); err != nil {
+			return err
+		}
+		if err := s.startApprovalChain(tx, req, actor); err != nil {
+			return err
+		}
 		if req.DaysRequested > 0 {
-			log.Printf("CRITICAL ERROR: Days (%d) for request %d (user %d, year %d) were spent, but failed to set status to Pending. Attempting to revert days...", req.DaysRequested, requestID, req.UserID, req.Year)
-			revertErr := s.vacationRepo.UpdateVacationLimitUsedDays(req.UserID, req.Year, -req.DaysRequested)
-			if revertErr != nil {
-				log.Printf("CRITICAL ERROR: Failed to revert spent days (%d) for request %d (user %d, year %d) after failed submission: %v", req.DaysRequested, requestID, req.UserID, req.Year, revertErr)
-			} else {
-				log.Printf("Successfully reverted days (%d) for request %d (user %d, year %d) after failed submission.", req.DaysRequested, requestID, req.UserID, req.Year)
+			if err := s.addRequestLedgerEntry(tx, req, models.LedgerEntryReservation, -req.DaysRequested, userID, "Резерв по заявке"); err != nil {
+				return err
 			}
+			log.Printf("[Service SubmitVacationRequest] Reserved days. UserID: %d, Year: %d, RequestID: %d, Days: %d", req.UserID, req.Year, requestID, req.DaysRequested)
 		}
-		return fmt.Errorf("ошибка установки статуса 'На рассмотрении' для заявки %d: %w", requestID, err)
+		submitted = req
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-	// TODO: Notify manager
+
+	s.notifyStepApprover(submitted, "Новая заявка на отпуск",
+		fmt.Sprintf("Сотрудник %s отправил заявку на отпуск №%d (%d дн.) на согласование.", actor.FullName, submitted.ID, submitted.DaysRequested))
 	return nil
 }
 
+// UpdateVacationRequest заменяет периоды и комментарий черновика или заявки на рассмотрении.
+// Для заявки на рассмотрении резерв дней пересчитывается, а руководитель получает уведомление об изменении.
+func (s *VacationService) UpdateVacationRequest(requestID int, userID int, update *models.VacationRequestUpdateDTO) (*models.VacationRequest, error) {
+	actor, err := s.findActor(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated *models.VacationRequest
+	err = s.vacationRepo.RunInTx(func(tx repositories.VacationRepositoryInterface) error {
+		req, err := tx.LockVacationRequest(requestID)
+		if err != nil {
+			return fmt.Errorf("ошибка получения заявки для изменения: %w", err)
+		}
+		if req == nil {
+			return errors.New("заявка не найдена")
+		}
+		if _, err := s.authorizeTransition(actor, req, models.ActionEdit); err != nil {
+			return err
+		}
+		if err := s.lockVacationLimit(tx, req.UserID, req.Year); err != nil {
+			return err
+		}
+
+		// Резерв по заявке снимается до проверки, иначе ее же дни не будут считаться доступными
+		isPending := req.StatusID == models.StatusPending
+		if isPending {
+			if err := s.releaseRequestReservation(tx, req, userID, "Снятие резерва при изменении заявки"); err != nil {
+				return err
+			}
+		}
+
+		req.Periods = update.Periods
+		req.Comment = update.Comment
+		if err := s.validateVacationRequest(tx, req); err != nil {
+			return err
+		}
+		if err := tx.UpdateVacationRequest(req); err != nil {
+			return err
+		}
+		if err := s.applyTransition(tx, actor, req, models.ActionEdit, ""); err != nil {
+			return err
+		}
+		// Измененная заявка проходит согласование заново
+		if isPending {
+			if err := s.startApprovalChain(tx, req, actor); err != nil {
+				return err
+			}
+		}
+		if isPending && req.DaysRequested > 0 {
+			if err := s.addRequestLedgerEntry(tx, req, models.LedgerEntryReservation, -req.DaysRequested, userID, "Резерв по измененной заявке"); err != nil {
+				return err
+			}
+		}
+		updated = req
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[Service UpdateVacationRequest] Request %d updated by user %d, days: %d", requestID, userID, updated.DaysRequested)
+
+	if updated.StatusID == models.StatusPending {
+		s.notifyApprover(updated, actor, "Заявка на отпуск изменена",
+			fmt.Sprintf("Сотрудник %s изменил заявку на отпуск №%d (%d дн.). Требуется повторное рассмотрение.", actor.FullName, updated.ID, updated.DaysRequested))
+	}
+	return updated, nil
+}
+
+// findApproverID определяет руководителя, рассматривающего заявки сотрудника:
+// ближайший руководитель юнита вверх по иерархии, не являющийся самим сотрудником.
+// Возвращает nil, если руководитель не найден.
+func (s *VacationService) findApproverID(employee *models.User) (*int, error) {
+	if employee.OrganizationalUnitID == nil {
+		return nil, nil
+	}
+	unitID := employee.OrganizationalUnitID
+	for unitID != nil {
+		unit, err := s.unitRepo.GetByID(*unitID)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения юнита %d: %w", *unitID, err)
+		}
+		if unit == nil {
+			return nil, nil
+		}
+		if unit.ManagerID != nil && *unit.ManagerID != employee.ID {
+			return unit.ManagerID, nil
+		}
+		unitID = unit.ParentID
+	}
+	return nil, nil
+}
+
+// notifyApprover отправляет уведомление руководителю, рассматривающему заявку:
+// согласующему текущего этапа цепочки, а для заявок без цепочки - ближайшему руководителю.
+// Ошибки уведомления не прерывают основную операцию и только логируются.
+func (s *VacationService) notifyApprover(req *models.VacationRequest, employee *models.User, title string, message string) {
+	if employee == nil || employee.ID != req.UserID {
+		found, err := s.userRepo.FindByID(req.UserID)
+		if err != nil || found == nil {
+			log.Printf("[Service Notify] Cannot load employee %d for request %d: %v", req.UserID, req.ID, err)
+			return
+		}
+		employee = found
+	}
+	if models.CurrentApprovalStep(req.ApprovalSteps) != nil {
+		s.notifyStepApprover(req, title, message)
+		return
+	}
+	approverID, err := s.findApproverID(employee)
+	if err != nil {
+		log.Printf("[Service Notify] Failed to find approver for request %d: %v", req.ID, err)
+		return
+	}
+	if approverID == nil {
+		log.Printf("[Service Notify] No approver found for request %d (user %d)", req.ID, req.UserID)
+		return
+	}
+	notification := &models.Notification{UserID: *approverID, Title: title, Message: message}
+	if err := s.vacationRepo.CreateNotification(notification); err != nil {
+		log.Printf("[Service Notify] Failed to notify approver %d about request %d: %v", *approverID, req.ID, err)
+	}
+}
+
+// notifyUser отправляет уведомление пользователю. Ошибки только логируются.
+func (s *VacationService) notifyUser(userID int, title string, message string) {
+	notification := &models.Notification{UserID: userID, Title: title, Message: message}
+	if err := s.vacationRepo.CreateNotification(notification); err != nil {
+		log.Printf("[Service Notify] Failed to notify user %d: %v", userID, err)
+	}
+}
+
 // CheckIntersections проверяет пересечения отпусков (с учетом правила: только внутри отдела/сектора)
 func (s *VacationService) CheckIntersections(unitID int, year int) ([]models.Intersection, error) {
 	targetUnit, err := s.unitRepo.GetByID(unitID)
@@ -365,7 +1009,7 @@ func (s *VacationService) CheckIntersections(unitID int, year int) ([]models.Int
 						if doPeriodIntersect(period1, period2) {
 							start := max(period1.StartDate.Time, period2.StartDate.Time)
 							end := min(period1.EndDate.Time, period2.EndDate.Time)
-							daysCount := int(end.Sub(start).Hours()/24) + 1
+							daysCount := s.calendar.CountVacationDays(start, end)
 							intersection := models.Intersection{
 								UserID1: req1.UserID, UserName1: userMap[req1.UserID].FullName,
 								UserID2: req2.UserID, UserName2: userMap[req2.UserID].FullName,
@@ -406,7 +1050,8 @@ func (s *VacationService) GetOrganizationalUnitVacations(unitID int, year int, s
 	return s.vacationRepo.GetVacationRequestsByOrganizationalUnit(unitID, year, statusFilter)
 }
 
-// GetAllUserVacations получает все заявки (для админов) или заявки своего поддерева (для менеджеров)
+// GetAllUserVacations получает все заявки (для админов) или заявки своего поддерева
+// и поддеревьев, переданных по делегированию (для менеджеров и заместителей)
 func (s *VacationService) GetAllUserVacations(requestingUserID int, yearFilter *int, statusFilter *int, userIDFilter *int, unitIDFilter *int) ([]models.VacationRequestAdminView, error) {
 	requestingUser, err := s.userRepo.FindByID(requestingUserID)
 	if err != nil {
@@ -418,32 +1063,32 @@ func (s *VacationService) GetAllUserVacations(requestingUserID int, yearFilter *
 
 	var unitIDsFilterForRepo []int
 	if !requestingUser.IsAdmin {
-		if requestingUser.IsManager {
-			if requestingUser.OrganizationalUnitID == nil {
+		// Руководителю доступно свое поддерево и поддеревья руководителей, передавших ему права
+		subtreeIDs, err := s.managedUnitIDs(requestingUser)
+		if err != nil {
+			log.Printf("[GetAllUserVacations] Error getting managed units for user %d: %v", requestingUser.ID, err)
+			return nil, fmt.Errorf("ошибка получения подчиненных юнитов: %w", err)
+		}
+		if len(subtreeIDs) == 0 {
+			if requestingUser.IsManager {
 				return []models.VacationRequestAdminView{}, nil
 			}
-			subtreeIDs, err := s.unitRepo.GetSubtreeIDs(*requestingUser.OrganizationalUnitID)
-			if err != nil {
-				log.Printf("[GetAllUserVacations] Error getting subtree for manager %d (unit %d): %v", requestingUser.ID, *requestingUser.OrganizationalUnitID, err)
-				return nil, fmt.Errorf("ошибка получения подчиненных юнитов: %w", err)
-			}
-			unitIDsFilterForRepo = subtreeIDs
-			if unitIDFilter != nil { // Если менеджер дополнительно фильтрует по юниту
-				requestedUnitID := *unitIDFilter
-				found := false
-				for _, allowedID := range subtreeIDs {
-					if allowedID == requestedUnitID {
-						found = true
-						break
-					}
-				}
-				if !found {
-					return nil, errors.New("менеджер может фильтровать заявки только по своему юниту или подчиненным")
+			return nil, errors.New("недостаточно прав для просмотра всех заявок")
+		}
+		unitIDsFilterForRepo = subtreeIDs
+		if unitIDFilter != nil { // Если менеджер дополнительно фильтрует по юниту
+			requestedUnitID := *unitIDFilter
+			found := false
+			for _, allowedID := range subtreeIDs {
+				if allowedID == requestedUnitID {
+					found = true
+					break
 				}
-				unitIDsFilterForRepo = []int{requestedUnitID} // Используем только запрошенный ID
 			}
-		} else {
-			return nil, errors.New("недостаточно прав для просмотра всех заявок")
+			if !found {
+				return nil, errors.New("менеджер может фильтровать заявки только по своему юниту или подчиненным")
+			}
+			unitIDsFilterForRepo = []int{requestedUnitID} // Используем только запрошенный ID
 		}
 	} else { // Админ
 		if unitIDFilter != nil {
@@ -455,223 +1100,363 @@ func (s *VacationService) GetAllUserVacations(requestingUserID int, yearFilter *
 	return s.vacationRepo.GetAllVacationRequests(yearFilter, statusFilter, userIDFilter, unitIDsFilterForRepo)
 }
 
-// CancelVacationRequest отменяет заявку
-func (s *VacationService) CancelVacationRequest(requestID int, cancellingUserID int) error {
-	req, err := s.vacationRepo.GetVacationRequestByID(requestID)
-	if err != nil {
-		return fmt.Errorf("ошибка получения заявки для отмены: %w", err)
+// CancelVacationRequest отменяет заявку.
+// Смена статуса и возврат дней выполняются в одной транзакции под блокировкой заявки и лимита.
+func (s *VacationService) CancelVacationRequest(requestID int, cancellingUserID int, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if s.reasonPolicy.CancellationRequired && reason == "" {
+		return fmt.Errorf("%w: необходимо указать причину отмены", ErrTransitionNotAllowed)
 	}
-	if req == nil {
-		return errors.New("заявка не найдена")
+	actor, err := s.findActor(cancellingUserID)
+	if err != nil {
+		return err
 	}
 
-	canCancel := false
-	// Пользователь может отменить свою заявку только в статусе "На рассмотрении"
-	if req.UserID == cancellingUserID && req.StatusID == models.StatusPending {
-		canCancel = true
-	} else {
-		cancellingUser, err := s.userRepo.FindByID(cancellingUserID)
-		if err != nil || cancellingUser == nil {
-			return errors.New("не удалось проверить права пользователя на отмену")
+	return s.vacationRepo.RunInTx(func(tx repositories.VacationRepositoryInterface) error {
+		req, err := tx.LockVacationRequest(requestID)
+		if err != nil {
+			return fmt.Errorf("ошибка получения заявки для отмены: %w", err)
 		}
-		if cancellingUser.IsAdmin {
-			canCancel = true
-		} else if cancellingUser.IsManager {
-			employee, err := s.userRepo.FindByID(req.UserID)
-			accessGranted, accessErr := s.checkUserUnitAccess(cancellingUser, employee)
-			if accessErr != nil {
-				return fmt.Errorf("ошибка проверки доступа для отмены: %w", accessErr)
-			}
-			if err == nil && employee != nil && accessGranted {
-				canCancel = true
-			}
+		if req == nil {
+			return errors.New("заявка не найдена")
+		}
+		if err := s.ensureScheduleNotLocked(tx, actor, req); err != nil {
+			return err
+		}
+		if err := s.lockVacationLimit(tx, req.UserID, req.Year); err != nil {
+			return err
 		}
-	}
-	if !canCancel {
-		return errors.New("нет прав на отмену этой заявки")
-	}
-	if req.StatusID == models.StatusRejected || req.StatusID == models.StatusCancelled {
-		return fmt.Errorf("нельзя отменить заявку ID %d в статусе '%d'", requestID, req.StatusID)
-	}
 
-	originalStatus := req.StatusID
-	err = s.vacationRepo.UpdateRequestStatusByID(requestID, models.StatusCancelled)
-	if err != nil {
-		return fmt.Errorf("ошибка установки статуса 'Отменена' для заявки %d: %w", requestID, err)
-	}
+		originalStatus := req.StatusID
+		if err := s.applyTransition(tx, actor, req, models.ActionCancel, reason); err != nil {
+			return err
+		}
+		if reason != "" {
+			if err := tx.SetRequestCancellationReason(requestID, reason); err != nil {
+				return err
+			}
+		}
 
-	if (originalStatus == models.StatusPending || originalStatus == models.StatusApproved) && req.DaysRequested > 0 {
-		log.Printf("[Service CancelVacationRequest] Attempting to return days. UserID: %d, Year: %d, RequestID: %d, Days: %d", req.UserID, req.Year, requestID, req.DaysRequested)
-		errReturn := s.vacationRepo.UpdateVacationLimitUsedDays(req.UserID, req.Year, -req.DaysRequested)
-		if errReturn != nil {
-			log.Printf("[Service CancelVacationRequest] CRITICAL ERROR: Failed to return days! UserID: %d, Year: %d, RequestID: %d, Days: %d, Error: %v", req.UserID, req.Year, requestID, req.DaysRequested, errReturn)
-		} else {
-			log.Printf("[Service CancelVacationRequest] Successfully returned days. UserID: %d, Year: %d, RequestID: %d, Days: %d", req.UserID, req.Year, requestID, req.DaysRequested)
+		switch originalStatus {
+		case models.StatusPending, models.StatusChangesProposed:
+			if err := s.releaseRequestReservation(tx, req, cancellingUserID, "Отмена заявки"); err != nil {
+				return fmt.Errorf("ошибка снятия резерва при отмене заявки: %w", err)
+			}
+			if originalStatus == models.StatusChangesProposed {
+				if err := s.closeOpenProposal(tx, req.ID, models.ProposalStatusCancelled, &cancellingUserID, reason); err != nil {
+					return err
+				}
+			}
+		case models.StatusApproved:
+			totals, err := tx.GetRequestLedgerTotals(requestID)
+			if err != nil {
+				return fmt.Errorf("ошибка возврата дней при отмене заявки: %w", err)
+			}
+			if totals.ConsumedDays > 0 {
+				if err := s.addRequestLedgerEntry(tx, req, models.LedgerEntryRefund, totals.ConsumedDays, cancellingUserID, "Отмена утвержденной заявки"); err != nil {
+					return fmt.Errorf("ошибка возврата дней при отмене заявки: %w", err)
+				}
+			}
+			if err := s.syncRequestDelegations(tx, requestID); err != nil {
+				return err
+			}
 		}
-	}
-	// TODO: Notify user
-	return nil
+		log.Printf("[Service CancelVacationRequest] Ledger updated for cancelled request %d (user %d, year %d, original status %d)", requestID, req.UserID, req.Year, originalStatus)
+		// TODO: Notify user
+		return nil
+	})
 }
 
 // ApproveVacationRequest утверждает заявку.
-// Если найдены конфликты и force=false, возвращает конфликты без утверждения.
-// Если конфликтов нет или force=true, утверждает заявку и возвращает конфликты (если были).
-func (s *VacationService) ApproveVacationRequest(requestID int, approverID int, force bool) ([]models.ConflictingPeriod, error) {
-	req, err := s.vacationRepo.GetVacationRequestByID(requestID)
+// Если найдены конфликты (в том числе пересечения вне очереди на пиковые даты) или нарушения правил
+// укомплектованности и force=false, возвращает их без утверждения. Если их нет или force=true, согласует текущий этап цепочки и возвращает найденное (если было).
+// Заявка утверждается на последнем этапе; иначе возвращается следующий этап, ожидающий решения.
+// Проверка конфликтов, смена статуса и списание дней выполняются в одной транзакции под блокировкой
+// заявки, лимита, должности сотрудника и правил укомплектованности, поэтому параллельные утверждения
+// не обходят проверку конфликтов.
+func (s *VacationService) ApproveVacationRequest(requestID int, approverID int, force bool) ([]models.ConflictingPeriod, []models.CoverageViolation, *models.ApprovalStep, error) {
+	// --- Пользователь, выполняющий утверждение ---
+	approver, err := s.findActor(approverID)
 	if err != nil {
-		return nil, fmt.Errorf("ошибка получения заявки ID %d для утверждения: %w", requestID, err)
-	}
-	if req == nil {
-		return nil, fmt.Errorf("заявка ID %d не найдена", requestID)
+		return nil, nil, nil, err
 	}
 
-	// --- Проверка прав на утверждение (как и раньше) ---
-	approver, err := s.userRepo.FindByID(approverID)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка проверки прав пользователя ID %d: %w", approverID, err)
-	}
-	if approver == nil {
-		return nil, fmt.Errorf("утверждающий пользователь ID %d не найден", approverID)
-	}
-
-	canApprove := false
-	if approver.IsAdmin {
-		canApprove = true
-	} else if approver.IsManager {
-		employee, errUser := s.userRepo.FindByID(req.UserID) // Переименована переменная ошибки
-		if errUser != nil {
-			log.Printf("[ApproveVacationRequest] Warning: could not get employee %d data to check unit access: %v", req.UserID, errUser)
-			return nil, fmt.Errorf("ошибка получения данных сотрудника %d для проверки доступа: %w", req.UserID, errUser)
+	var conflicts []models.ConflictingPeriod
+	var coverage []models.CoverageViolation
+	var pendingReq *models.VacationRequest   // Заявка, ожидающая следующего этапа согласования
+	var outOfTurn []models.ConflictingPeriod // Пересечения с заявками, у авторов которых очередь на пиковые даты раньше
+	err = s.vacationRepo.RunInTx(func(tx repositories.VacationRepositoryInterface) error {
+		req, err := tx.LockVacationRequest(requestID)
+		if err != nil {
+			return fmt.Errorf("ошибка получения заявки ID %d для утверждения: %w", requestID, err)
 		}
-		if employee == nil {
-			return nil, fmt.Errorf("сотрудник %d, подавший заявку, не найден", req.UserID)
+		if req == nil {
+			return fmt.Errorf("%w: ID %d", ErrRequestNotFound, requestID)
 		}
-		accessGranted, accessErr := s.checkUserUnitAccess(approver, employee)
-		if accessErr != nil {
-			return nil, fmt.Errorf("ошибка проверки доступа для утверждения: %w", accessErr)
+
+		toStatus, err := s.authorizeTransition(approver, req, models.ActionApprove)
+		if err != nil {
+			return err
 		}
-		if accessGranted {
-			canApprove = true
+		if err := s.lockVacationLimit(tx, req.UserID, req.Year); err != nil {
+			return err
 		}
-	}
-	if !canApprove {
-		log.Printf("[ApproveVacationRequest] Access denied: User %d (admin: %t, manager: %t, unit: %v) cannot approve request %d for user %d", approver.ID, approver.IsAdmin, approver.IsManager, approver.OrganizationalUnitID, requestID, req.UserID)
-		return nil, fmt.Errorf("пользователь ID %d не имеет прав для утверждения заявки ID %d", approverID, requestID)
-	}
-	if req.StatusID != models.StatusPending {
-		return nil, fmt.Errorf("можно утвердить только заявку ID %d в статусе 'На рассмотрении' (текущий статус: %d)", requestID, req.StatusID)
-	}
 
-	// --- Проверка конфликтов ПЕРЕД утверждением ---
-	var conflicts []models.ConflictingPeriod
-	positionID, errPos := s.vacationRepo.GetUserPositionByID(req.UserID)
-	if errPos != nil {
-		// Логируем ошибку, но не прерываем утверждение, если должность не удалось получить
-		log.Printf("[ApproveVacationRequest] Warning: could not get position for user %d while checking conflicts for request %d: %v", req.UserID, requestID, errPos)
-	} else if positionID != nil {
-		// Если должность есть, проверяем конфликты
-		log.Printf("[ApproveVacationRequest] Checking conflicts for request %d (user %d, position %d)", requestID, req.UserID, *positionID)
-		conflicts, err = s.vacationRepo.GetApprovedVacationConflictsByPosition(*positionID, req.UserID, req.Periods)
+		// --- Проверка конфликтов ПЕРЕД утверждением ---
+		positionID, errPos := tx.GetUserPositionByID(req.UserID)
+		if errPos != nil {
+			// Логируем ошибку, но не прерываем утверждение, если должность не удалось получить
+			log.Printf("[ApproveVacationRequest] Warning: could not get position for user %d while checking conflicts for request %d: %v", req.UserID, requestID, errPos)
+		} else if positionID != nil {
+			// Блокируем должность, чтобы параллельное утверждение по той же должности дождалось этой транзакции
+			if err := tx.LockPosition(*positionID); err != nil {
+				return err
+			}
+			log.Printf("[ApproveVacationRequest] Checking conflicts for request %d (user %d, position %d)", requestID, req.UserID, *positionID)
+			conflicts, err = tx.GetApprovedVacationConflictsByPosition(*positionID, req.UserID, req.Periods)
+			if err != nil {
+				return fmt.Errorf("ошибка проверки конфликтов отпусков: %w", err)
+			}
+			if len(conflicts) > 0 {
+				log.Printf("[ApproveVacationRequest] Found %d conflicts for request %d (user %d, position %d)", len(conflicts), requestID, req.UserID, *positionID)
+			}
+			if err := s.annotateConflictPriority(conflicts); err != nil {
+				return fmt.Errorf("ошибка проверки преимущественного права: %w", err)
+			}
+			if err := s.annotateConflictFairness(tx, conflicts); err != nil {
+				return fmt.Errorf("ошибка расчета очередности пиковых периодов: %w", err)
+			}
+			// Заявку сотрудника с преимущественным правом нельзя вытеснить заявкой сотрудника без него
+			if err := s.checkPriorityDisplacement(tx, req, *positionID); err != nil {
+				return err
+			}
+			// Пиковые даты достаются по очереди: пересечения вне очереди возвращаются вместе с конфликтами
+			// и, как конфликты, утверждаются только с подтверждением
+			outOfTurn, err = s.checkFairRotation(tx, req, *positionID)
+			if err != nil {
+				return fmt.Errorf("ошибка проверки очередности пиковых периодов: %w", err)
+			}
+			if len(outOfTurn) > 0 {
+				log.Printf("[ApproveVacationRequest] Request %d is out of turn for peak dates in %d overlap(s)", requestID, len(outOfTurn))
+			}
+			conflicts = append(conflicts, outOfTurn...)
+		} else {
+			log.Printf("[ApproveVacationRequest] Skipping conflict check for request %d as user %d has no position assigned.", requestID, req.UserID)
+		}
+
+		// --- Проверка правил укомплектованности по дням (правила блокируются до конца транзакции) ---
+		coverage, err = s.checkCoverage(tx, req, true)
 		if err != nil {
-			// Логируем ошибку проверки конфликтов, но не прерываем утверждение
-			log.Printf("[ApproveVacationRequest] Error checking conflicts for request %d: %v", requestID, err)
-			// Очищаем конфликты на всякий случай, чтобы не вернуть ошибочные данные
-			conflicts = []models.ConflictingPeriod{}
-			// Можно вернуть ошибку, если считаем это критичным:
-			// return nil, fmt.Errorf("ошибка проверки конфликтов отпусков: %w", err)
-		} else if len(conflicts) > 0 {
-			log.Printf("[ApproveVacationRequest] Found %d conflicts for request %d (user %d, position %d)", len(conflicts), requestID, req.UserID, *positionID)
+			return fmt.Errorf("ошибка проверки правил укомплектованности: %w", err)
+		}
+		if len(coverage) > 0 {
+			log.Printf("[ApproveVacationRequest] Found %d coverage violation(s) for request %d", len(coverage), requestID)
 		}
-	} else {
-		log.Printf("[ApproveVacationRequest] Skipping conflict check for request %d as user %d has no position assigned.", requestID, req.UserID)
-	}
 
-	// --- Проверка, нужно ли прервать из-за конфликтов ---
-	if len(conflicts) > 0 && !force {
-		log.Printf("[ApproveVacationRequest] Conflicts found for request %d and force=false. Returning conflicts without approving.", requestID)
-		// Возвращаем конфликты, но НЕ ошибку. Сигнализируем, что утверждение не выполнено.
-		// Обработчик должен интерпретировать непустой список conflicts при nil ошибке как необходимость подтверждения.
-		return conflicts, nil
-	}
+		// --- Проверка, нужно ли прервать из-за конфликтов или нарушений укомплектованности ---
+		if (len(conflicts) > 0 || len(coverage) > 0) && !force {
+			log.Printf("[ApproveVacationRequest] Conflicts found for request %d and force=false. Returning conflicts without approving.", requestID)
+			// Возвращаем конфликты, но НЕ ошибку. Сигнализируем, что утверждение не выполнено.
+			// Обработчик должен интерпретировать непустой список conflicts при nil ошибке как необходимость подтверждения.
+			return nil
+		}
 
-	// --- Утверждение заявки (установка статуса) ---
-	// Выполняется если конфликтов нет ИЛИ force=true
-	log.Printf("[ApproveVacationRequest] Proceeding with approval for request %d (force=%t, conflicts=%d)", requestID, force, len(conflicts))
-	err = s.vacationRepo.UpdateRequestStatusByID(requestID, models.StatusApproved)
+		// --- Утверждение заявки (установка статуса) ---
+		// Выполняется если конфликтов нет ИЛИ force=true
+		log.Printf("[ApproveVacationRequest] Proceeding with approval for request %d (force=%t, conflicts=%d)", requestID, force, len(conflicts))
+		reasons := []string{}
+		if approvedConflicts := len(conflicts) - len(outOfTurn); approvedConflicts > 0 {
+			reasons = append(reasons, fmt.Sprintf("Утверждено несмотря на конфликты (%d)", approvedConflicts))
+		}
+		if len(coverage) > 0 {
+			reasons = append(reasons, fmt.Sprintf("Утверждено несмотря на нарушение правил укомплектованности (%d дн.)", len(coverage)))
+		}
+		if len(outOfTurn) > 0 {
+			reasons = append(reasons, "Утверждено вне очереди на пиковый период: "+outOfTurnText(outOfTurn))
+		}
+		reason := strings.Join(reasons, ". ")
+
+		// --- Согласование этапа цепочки ---
+		// Заявка утверждается только после последнего этапа; до этого статус не меняется.
+		// Переход записывается до решения по этапу, пока текущим остается этап согласующего.
+		if step := models.CurrentApprovalStep(req.ApprovalSteps); step != nil && step.StepOrder < len(req.ApprovalSteps) {
+			stepReason := fmt.Sprintf("Согласован этап %d из %d", step.StepOrder, len(req.ApprovalSteps))
+			if reason != "" {
+				stepReason += ". " + reason
+			}
+			if err := s.recordTransition(tx, approver, req, models.ActionApproveStep, models.StatusPending, stepReason); err != nil {
+				return err
+			}
+			if _, err := s.decideCurrentStep(tx, req, models.ApprovalStepApproved, approverID, reason); err != nil {
+				return err
+			}
+			pendingReq = req
+			return nil
+		}
+		if err := s.recordTransition(tx, approver, req, models.ActionApprove, toStatus, reason); err != nil {
+			return err
+		}
+		if _, err := s.decideCurrentStep(tx, req, models.ApprovalStepApproved, approverID, reason); err != nil {
+			return err
+		}
+
+		// Резерв по заявке превращается в списание
+		if err := s.releaseRequestReservation(tx, req, approverID, "Утверждение заявки"); err != nil {
+			return fmt.Errorf("ошибка снятия резерва при утверждении заявки: %w", err)
+		}
+		if req.DaysRequested > 0 {
+			if err := s.addRequestLedgerEntry(tx, req, models.LedgerEntryConsumption, -req.DaysRequested, approverID, "Утверждение заявки"); err != nil {
+				return fmt.Errorf("ошибка списания дней при утверждении заявки: %w", err)
+			}
+		}
+		return s.syncRequestDelegations(tx, req.ID)
+	})
 	if err != nil {
-		log.Printf("ERROR: Failed to set status 'Approved' for request %d: %v", requestID, err)
+		log.Printf("[ApproveVacationRequest] Approval of request %d rolled back: %v", requestID, err)
 		// В случае ошибки утверждения, не возвращаем конфликты, а только ошибку
-		return nil, fmt.Errorf("ошибка установки статуса 'Утверждена' для заявки %d: %w", requestID, err)
+		return nil, nil, nil, err
 	}
 
-	log.Printf("[ApproveVacationRequest] Successfully approved request %d. Returning %d conflicts as warnings.", requestID, len(conflicts))
+	log.Printf("[ApproveVacationRequest] Request %d processed. Returning %d conflicts and %d coverage violations.", requestID, len(conflicts), len(coverage))
+
+	if pendingReq != nil {
+		s.notifyStepApprover(pendingReq, "Заявка на отпуск ожидает согласования",
+			fmt.Sprintf("Заявка на отпуск №%d (%d дн.) согласована на предыдущем этапе и ожидает вашего решения.", pendingReq.ID, pendingReq.DaysRequested))
+		return conflicts, coverage, models.CurrentApprovalStep(pendingReq.ApprovalSteps), nil
+	}
 
 	// TODO: Notify user об утверждении
 
-	// Возвращаем найденные конфликты (если есть) и nil в качестве ошибки, т.к. утверждение прошло успешно
-	return conflicts, nil
+	// Возвращаем найденные конфликты и нарушения укомплектованности (если есть) и nil в качестве ошибки
+	return conflicts, coverage, nil, nil
 }
 
-// RejectVacationRequest отклоняет заявку
+// RejectVacationRequest отклоняет заявку.
+// Смена статуса и снятие резерва выполняются в одной транзакции под блокировкой заявки и лимита.
 func (s *VacationService) RejectVacationRequest(requestID int, rejecterID int, reason string) error {
-	req, err := s.vacationRepo.GetVacationRequestByID(requestID)
-	if err != nil {
-		return fmt.Errorf("ошибка получения заявки ID %d для отклонения: %w", requestID, err)
+	reason = strings.TrimSpace(reason)
+	if s.reasonPolicy.RejectionRequired && reason == "" {
+		return fmt.Errorf("%w: необходимо указать причину отклонения", ErrTransitionNotAllowed)
 	}
-	if req == nil {
-		return fmt.Errorf("заявка ID %d не найдена", requestID)
-	}
-
-	rejecter, err := s.userRepo.FindByID(rejecterID)
+	rejecter, err := s.findActor(rejecterID)
 	if err != nil {
-		return fmt.Errorf("ошибка проверки прав пользователя ID %d: %w", rejecterID, err)
-	}
-	if rejecter == nil {
-		return fmt.Errorf("отклоняющий пользователь ID %d не найден", rejecterID)
+		return err
 	}
 
-	canReject := false
-	if rejecter.IsAdmin {
-		canReject = true
-	} else if rejecter.IsManager {
-		employee, err := s.userRepo.FindByID(req.UserID)
-		accessGranted, accessErr := s.checkUserUnitAccess(rejecter, employee)
-		if accessErr != nil {
-			return fmt.Errorf("ошибка проверки доступа для отклонения: %w", accessErr)
+	return s.vacationRepo.RunInTx(func(tx repositories.VacationRepositoryInterface) error {
+		req, err := tx.LockVacationRequest(requestID)
+		if err != nil {
+			return fmt.Errorf("ошибка получения заявки ID %d для отклонения: %w", requestID, err)
 		}
-		if err == nil && employee != nil && accessGranted {
-			canReject = true
-		} else if err != nil {
-			log.Printf("[RejectVacationRequest] Warning: could not get employee %d data to check unit access: %v", req.UserID, err)
+		if req == nil {
+			return fmt.Errorf("%w: ID %d", ErrRequestNotFound, requestID)
 		}
+
+		if err := s.lockVacationLimit(tx, req.UserID, req.Year); err != nil {
+			return err
+		}
+		if err := s.applyTransition(tx, rejecter, req, models.ActionReject, reason); err != nil {
+			return err
+		}
+		if _, err := s.decideCurrentStep(tx, req, models.ApprovalStepRejected, rejecterID, reason); err != nil {
+			return err
+		}
+		if reason != "" {
+			if err := tx.SetRequestRejectionReason(requestID, reason); err != nil {
+				return err
+			}
+		}
+		if err := s.releaseRequestReservation(tx, req, rejecterID, "Отклонение заявки"); err != nil {
+			return fmt.Errorf("ошибка возврата дней в лимит при отклонении заявки: %w", err)
+		}
+
+		// TODO: Notify user
+		return nil
+	})
+}
+
+// findActor получает пользователя, выполняющего действие с заявкой
+func (s *VacationService) findActor(userID int) (*models.User, error) {
+	actor, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка проверки прав пользователя ID %d: %w", userID, err)
 	}
-	if !canReject {
-		return fmt.Errorf("пользователь ID %d не имеет прав для отклонения заявки ID %d", rejecterID, requestID)
-	}
-	if req.StatusID != models.StatusPending {
-		return fmt.Errorf("можно отклонить только заявку ID %d в статусе 'На рассмотрении' (текущий статус: %d)", requestID, req.StatusID)
+	if actor == nil {
+		return nil, fmt.Errorf("пользователь ID %d не найден", userID)
 	}
+	return actor, nil
+}
 
-	err = s.vacationRepo.UpdateRequestStatusByID(requestID, models.StatusRejected)
+// GetRequestHistory возвращает историю переходов заявки.
+// Доступно автору заявки, администратору и руководителю сотрудника.
+func (s *VacationService) GetRequestHistory(requestingUserID int, requestID int) ([]models.VacationRequestTransition, error) {
+	req, err := s.vacationRepo.GetVacationRequestByID(requestID)
 	if err != nil {
-		return fmt.Errorf("ошибка установки статуса 'Отклонена' для заявки %d: %w", requestID, err)
+		return nil, fmt.Errorf("ошибка получения заявки ID %d: %w", requestID, err)
 	}
-
-	if req.DaysRequested > 0 {
-		log.Printf("[Service RejectVacationRequest] Attempting to return days. UserID: %d, Year: %d, RequestID: %d, Days: %d", req.UserID, req.Year, requestID, req.DaysRequested)
-		errReturn := s.vacationRepo.UpdateVacationLimitUsedDays(req.UserID, req.Year, -req.DaysRequested)
-		if errReturn != nil {
-			log.Printf("[Service RejectVacationRequest] CRITICAL ERROR: Failed to return days! UserID: %d, Year: %d, RequestID: %d, Days: %d, Error: %v", req.UserID, req.Year, requestID, req.DaysRequested, errReturn)
-			return fmt.Errorf("заявка отклонена, но произошла ошибка при возврате дней в лимит: %w", errReturn)
-		} else {
-			log.Printf("[Service RejectVacationRequest] Successfully returned days. UserID: %d, Year: %d, RequestID: %d, Days: %d", req.UserID, req.Year, requestID, req.DaysRequested)
+	if req == nil {
+		return nil, fmt.Errorf("%w: ID %d", ErrRequestNotFound, requestID)
+	}
+	if req.UserID != requestingUserID {
+		requestingUser, err := s.findActor(requestingUserID)
+		if err != nil {
+			return nil, err
+		}
+		roles, err := s.actorRoles(requestingUser, req)
+		if err != nil {
+			return nil, err
+		}
+		if !roles[roleAdmin] && !roles[roleManager] {
+			return nil, fmt.Errorf("%w: просмотр истории заявки ID %d", ErrTransitionForbidden, requestID)
 		}
-	} else {
-		log.Printf("[Service RejectVacationRequest] No days to return for RequestID: %d (DaysRequested: %d)", requestID, req.DaysRequested)
 	}
+	return s.vacationRepo.GetRequestTransitions(requestID)
+}
 
-	// TODO: Save rejection reason
-	// TODO: Notify user
+// AdvanceVacationStatuses выполняет системные переходы по датам отпуска:
+// утвержденные заявки, отпуск по которым начался, переводятся в статус "В отпуске",
+// а заявки, все периоды которых закончились, - в статус "Завершена".
+func (s *VacationService) AdvanceVacationStatuses(today time.Time) error {
+	today = truncateToDate(today)
+	steps := []struct {
+		action  string
+		findIDs func(date time.Time) ([]int, error)
+	}{
+		{models.ActionStart, s.vacationRepo.GetRequestIDsDueForStart},
+		{models.ActionComplete, s.vacationRepo.GetRequestIDsDueForCompletion},
+	}
+
+	var failed int
+	for _, step := range steps {
+		ids, err := step.findIDs(today)
+		if err != nil {
+			return fmt.Errorf("ошибка поиска заявок для действия %s: %w", step.action, err)
+		}
+		for _, id := range ids {
+			requestID := id
+			err := s.vacationRepo.RunInTx(func(tx repositories.VacationRepositoryInterface) error {
+				req, err := tx.LockVacationRequest(requestID)
+				if err != nil {
+					return err
+				}
+				if req == nil {
+					return nil
+				}
+				if _, ok := findTransition(step.action, req.StatusID); !ok {
+					return nil // Статус успел измениться параллельно
+				}
+				return s.applyTransition(tx, nil, req, step.action, "")
+			})
+			if err != nil {
+				failed++
+				log.Printf("[AdvanceVacationStatuses] Failed to apply %s to request %d: %v", step.action, requestID, err)
+			}
+		}
+		log.Printf("[AdvanceVacationStatuses] %s: %d requests processed", step.action, len(ids))
+	}
+	if failed > 0 {
+		return fmt.Errorf("не удалось обновить статус %d заявок", failed)
+	}
 	return nil
 }
 
@@ -704,8 +1489,8 @@ func (s *VacationService) GetManagerDashboardData(managerID int) (*models.Manage
 	var errorsOccurred []string      // Срез для сбора некритичных ошибок
 	currentYear := time.Now().Year() // Используем текущий год для подсчета дней
 
-	// 3. Получить количество ожидающих заявок
-	pendingCount, err := s.vacationRepo.CountPendingRequestsByUnitIDs(subtreeIDs)
+	// 3. Получить количество заявок, ожидающих решения руководителя (по цепочке согласования, без собственных)
+	pendingCount, err := s.vacationRepo.CountPendingRequestsForApprover(managerID, subtreeIDs)
 	if err != nil {
 		log.Printf("[GetManagerDashboardData] Error counting pending requests for manager %d (units %v): %v", managerID, subtreeIDs, err)
 		errorsOccurred = append(errorsOccurred, fmt.Sprintf("Ошибка подсчета ожидающих заявок: %v", err))
@@ -734,7 +1519,7 @@ func (s *VacationService) GetManagerDashboardData(managerID int) (*models.Manage
 	}
 
 	// 5. Получить сумму дней по статусам за текущий год
-	approvedDays, err := s.vacationRepo.SumRequestedDaysByStatusAndUnitIDs(subtreeIDs, []int{models.StatusApproved}, currentYear)
+	approvedDays, err := s.vacationRepo.SumRequestedDaysByStatusAndUnitIDs(subtreeIDs, models.ApprovedStatuses, currentYear)
 	if err != nil {
 		log.Printf("[GetManagerDashboardData] Error summing approved days for manager %d (units %v, year %d): %v", managerID, subtreeIDs, currentYear, err)
 		errorsOccurred = append(errorsOccurred, fmt.Sprintf("Ошибка подсчета утвержденных дней: %v", err))
@@ -761,7 +1546,22 @@ func (s *VacationService) GetManagerDashboardData(managerID int) (*models.Manage
 		dashboardData.PendingDaysCountYear = pendingDays
 	}
 
-	// 6. Получить ближайшие конфликты (например, на следующие 30 дней)
+	// 6. Кто рассматривает собственные заявки руководителя: руководитель вышестоящего юнита или администратор
+	ownChain, err := s.buildApprovalChain(manager)
+	if err != nil {
+		log.Printf("[GetManagerDashboardData] Error building approval route for manager %d: %v", managerID, err)
+		errorsOccurred = append(errorsOccurred, fmt.Sprintf("Ошибка определения согласующего: %v", err))
+	} else if len(ownChain) > 0 {
+		dashboardData.OwnApprovalStepType = ownChain[0].StepType
+		if ownChain[0].ApproverID != nil {
+			dashboardData.OwnApproverID = ownChain[0].ApproverID
+			if approver, err := s.userRepo.FindByID(*ownChain[0].ApproverID); err == nil && approver != nil {
+				dashboardData.OwnApproverName = approver.FullName
+			}
+		}
+	}
+
+	// 7. Получить ближайшие конфликты (например, на следующие 30 дней)
 	startDate := time.Now()
 	endDate := startDate.AddDate(0, 1, 0) // +1 месяц
 	conflicts, err := s.vacationRepo.GetUpcomingApprovedConflictsByUnitIDs(subtreeIDs, startDate, endDate)
@@ -844,14 +1644,21 @@ func (s *VacationService) GetVacationConflicts(requestingUserID int, startDate t
 
 	// TODO: Подумать над удалением дубликатов (A+B и B+A) здесь или в репозитории, если требуется.
 	// Пока возвращаем как есть.
+	if err := s.annotateConflictPriority(conflicts); err != nil {
+		return nil, fmt.Errorf("ошибка проверки преимущественного права: %w", err)
+	}
+	if err := s.annotateConflictFairness(s.vacationRepo, conflicts); err != nil {
+		return nil, fmt.Errorf("ошибка расчета очередности пиковых периодов: %w", err)
+	}
 
 	log.Printf("[GetVacationConflicts] Found %d conflicts for user %d (units %v) between %s and %s.", len(conflicts), requestingUserID, unitIDsToCheck, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
 	return conflicts, nil
 }
 
 // --- Вспомогательные функции ---
+// doPeriodIntersect проверяет пересечение периодов (даты начала и окончания включаются в период)
 func doPeriodIntersect(p1, p2 models.VacationPeriod) bool {
-	return p1.StartDate.Time.Before(p2.EndDate.Time) && p2.StartDate.Time.Before(p1.EndDate.Time)
+	return !p1.StartDate.Time.After(p2.EndDate.Time) && !p2.StartDate.Time.After(p1.EndDate.Time)
 }
 func max(t1, t2 time.Time) time.Time {
 	if t1.After(t2) {
@@ -866,18 +1673,22 @@ func (s *VacationService) GetVacationDataForExport(unitIDs []int, year int) ([]m
 
 	// 1. Получить утвержденные заявки для указанных юнитов и года.
 	//    Для формы Т-7 обычно нужны утвержденные отпуска.
-	approvedStatus := models.StatusApproved
-	statusFilter := &approvedStatus // Фильтруем только по утвержденным
+	//    Утвержденными считаются также заявки "В отпуске", "Завершена" и "Отозван из отпуска", поэтому фильтруем по статусу здесь.
 	// Используем GetAllVacationRequests, так как он возвращает больше данных (имена, статусы)
 	// Передаем nil для userIDFilter, так как нам нужны все пользователи в этих юнитах
 	// Передаем yearFilter
 	yearFilter := &year
-	// Исправлено: передаем statusFilter (*int)
-	requests, err := s.vacationRepo.GetAllVacationRequests(yearFilter, statusFilter, nil, unitIDs) // Передаем unitIDs как фильтр
+	allRequests, err := s.vacationRepo.GetAllVacationRequests(yearFilter, nil, nil, unitIDs) // Передаем unitIDs как фильтр
 	if err != nil {
 		log.Printf("[Service GetVacationDataForExport] Error fetching vacation requests for units %v, year %d: %v", unitIDs, year, err)
 		return nil, fmt.Errorf("ошибка получения заявок для экспорта: %w", err)
 	}
+	requests := make([]models.VacationRequestAdminView, 0, len(allRequests))
+	for _, req := range allRequests {
+		if models.IsApprovedStatus(req.StatusID) {
+			requests = append(requests, req)
+		}
+	}
 
 	log.Printf("[Service GetVacationDataForExport] Fetched %d requests for units %v, year %d", len(requests), unitIDs, year)
 
@@ -942,7 +1753,38 @@ func (s *VacationService) GetVacationDataForExport(unitIDs []int, year int) ([]m
 	}
 	log.Printf("[Service GetVacationDataForExport] Fetched details for %d units.", len(unitsMap))
 
-	// 5. Сформировать строки для экспорта (VacationExportRow)
+	// 5. Получить утвержденные переносы (столбцы 11 и 12 формы Т-7) и отзывы из отпуска (столбец 13)
+	periodIDs := []int{}
+	for _, req := range requests {
+		for _, period := range req.Periods {
+			periodIDs = append(periodIDs, period.ID)
+		}
+	}
+	recalls, err := s.vacationRepo.GetVacationRecallsByPeriodIDs(periodIDs)
+	if err != nil {
+		log.Printf("[Service GetVacationDataForExport] Error fetching recalls: %v", err)
+		return nil, fmt.Errorf("ошибка получения отзывов из отпуска для экспорта: %w", err)
+	}
+	recallsByPeriod := make(map[int][]models.VacationRecall)
+	for _, recall := range recalls {
+		recallsByPeriod[recall.PeriodID] = append(recallsByPeriod[recall.PeriodID], recall)
+	}
+	transfersByPeriod := make(map[int][]models.VacationTransfer)
+	transfers, err := s.vacationRepo.GetApprovedTransfersByPeriodIDs(periodIDs)
+	if err != nil {
+		log.Printf("[Service GetVacationDataForExport] Error fetching approved transfers: %v", err)
+		return nil, fmt.Errorf("ошибка получения переносов для экспорта: %w", err)
+	}
+	for _, transfer := range transfers {
+		transfersByPeriod[transfer.PeriodID] = append(transfersByPeriod[transfer.PeriodID], transfer)
+	}
+	// Вид отпуска определяет столбец формы: основной (6) или дополнительный (7)
+	leaveTypes, err := s.leaveTypesByID(s.vacationRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	// 6. Сформировать строки для экспорта (VacationExportRow)
 	exportRows := []models.VacationExportRow{}
 	sequence := 1
 	for _, req := range requests {
@@ -971,32 +1813,43 @@ func (s *VacationService) GetVacationDataForExport(unitIDs []int, year int) ([]m
 			log.Printf("[Service GetVacationDataForExport] Warning: User %d has no assigned position name.", user.ID)
 		}
 
-		// Для каждого периода отпуска создаем отдельную строку в экспорте
+		// Для каждого периода отпуска, включаемого в график, создаем отдельную строку в экспорте
 		for _, period := range req.Periods {
+			leaveType, typeOk := leaveTypes[period.LeaveTypeID]
+			if !typeOk || !leaveType.InSchedule {
+				continue // Учебный отпуск, отпуск без сохранения зарплаты и т.п. в график Т-7 не входят
+			}
+			// Количество дней пересчитывается по производственному календарю и правилу подсчета вида отпуска
+			daysCount := s.countLeaveDays(leaveType, period.StartDate.Time, period.EndDate.Time)
+			mainDays, additionalDays := daysCount, 0
+			if !leaveType.CountsTowardMain {
+				mainDays, additionalDays = 0, daysCount
+			}
 			row := models.VacationExportRow{
 				SequenceNumber:        sequence,
 				UnitName:              unitName,
 				PositionName:          positionName,
 				FullName:              user.FullName,
 				EmployeeNumber:        fmt.Sprintf("%d", user.ID), // Используем ID как табельный номер
-				PlannedDaysMain:       period.DaysCount,           // Пока все дни считаем основными
-				PlannedDaysAdditional: 0,                          // Дополнительные пока не учитываем
-				PlannedDaysTotal:      period.DaysCount,
+				PlannedDaysMain:       mainDays,
+				PlannedDaysAdditional: additionalDays,
+				PlannedDaysTotal:      daysCount,
 				PlannedDate:           period.StartDate,
 				ActualDate:            nil, // Заполняется, если статус Approved?
-				TransferReason:        "",  // Пока пусто
-				TransferDate:          nil, // Пока пусто
-				Note:                  "",  // Пока пусто
+				TransferReason:        "",  // Заполняется по утвержденным переносам
+				TransferDate:          nil, // Заполняется по утвержденным переносам
+				Note:                  "",  // Заполняется при отзыве из отпуска
 			}
 
 			// Заполняем фактическую дату, если заявка утверждена
-			if req.StatusID == models.StatusApproved {
+			if models.IsApprovedStatus(req.StatusID) {
 				// Копируем PlannedDate в ActualDate
 				actualDateCopy := period.StartDate
 				row.ActualDate = &actualDateCopy
 			}
 
-			// TODO: Добавить логику для переносов (TransferReason, TransferDate) и примечаний (Note), если она есть
+			applyTransfersToExportRow(&row, transfersByPeriod[period.ID])
+			applyRecallsToExportRow(&row, recallsByPeriod[period.ID])
 
 			exportRows = append(exportRows, row)
 			sequence++
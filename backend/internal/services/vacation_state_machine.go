@@ -0,0 +1,238 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"vacation-scheduler/internal/models"
+	"vacation-scheduler/internal/repositories"
+)
+
+// ErrTransitionNotAllowed - действие недопустимо для текущего статуса заявки
+var ErrTransitionNotAllowed = errors.New("действие недопустимо для текущего статуса заявки")
+
+// notAllowedError - частный случай ErrTransitionNotAllowed со своим текстом:
+// errors.Is различает частные случаи между собой и сопоставляет каждый с ErrTransitionNotAllowed
+type notAllowedError struct{ msg string }
+
+func (e *notAllowedError) Error() string { return e.msg }
+
+func (e *notAllowedError) Unwrap() error { return ErrTransitionNotAllowed }
+
+// ErrWrongStatus - действие не предусмотрено для текущего статуса заявки (частный случай ErrTransitionNotAllowed)
+var ErrWrongStatus error = &notAllowedError{"действие не предусмотрено для текущего статуса заявки"}
+
+// ErrTransitionForbidden - у пользователя нет прав на выполнение действия с заявкой
+var ErrTransitionForbidden = errors.New("нет прав на выполнение действия с заявкой")
+
+// ErrRequestNotFound - заявка не найдена
+var ErrRequestNotFound = errors.New("заявка не найдена")
+
+// ErrPriorityDisplacement - утверждение вытеснило бы заявку сотрудника с преимущественным правом
+// выбора времени отпуска (частный случай ErrTransitionNotAllowed)
+var ErrPriorityDisplacement error = &notAllowedError{"утверждение нарушает преимущественное право выбора времени отпуска"}
+
+// transitionRole - роль участника по отношению к заявке
+type transitionRole int
+
+const (
+	roleOwner    transitionRole = iota // Автор заявки
+	roleManager                        // Руководитель сотрудника (по иерархии юнитов или по делегированию)
+	roleApprover                       // Согласующий текущего этапа цепочки согласования (или его заместитель)
+	roleAdmin                          // Администратор
+	roleSystem                         // Системный переход (планировщик)
+)
+
+// transitionRule - допустимый переход и роли, которым он разрешен
+type transitionRule struct {
+	From  int
+	To    int
+	Roles []transitionRole
+}
+
+// vacationStateMachine - все допустимые переходы заявки между статусами по действиям
+var vacationStateMachine = map[string][]transitionRule{
+	models.ActionSubmit: {
+		{From: models.StatusDraft, To: models.StatusPending, Roles: []transitionRole{roleOwner}},
+	},
+	models.ActionApproveStep: {
+		{From: models.StatusPending, To: models.StatusPending, Roles: []transitionRole{roleApprover, roleAdmin}},
+	},
+	models.ActionApprove: {
+		{From: models.StatusPending, To: models.StatusApproved, Roles: []transitionRole{roleApprover, roleAdmin}},
+	},
+	models.ActionReject: {
+		{From: models.StatusPending, To: models.StatusRejected, Roles: []transitionRole{roleApprover, roleAdmin}},
+	},
+	models.ActionCancel: {
+		{From: models.StatusDraft, To: models.StatusCancelled, Roles: []transitionRole{roleOwner, roleAdmin}},
+		{From: models.StatusPending, To: models.StatusCancelled, Roles: []transitionRole{roleOwner, roleManager, roleAdmin}},
+		{From: models.StatusApproved, To: models.StatusCancelled, Roles: []transitionRole{roleManager, roleAdmin}},
+		{From: models.StatusChangesProposed, To: models.StatusCancelled, Roles: []transitionRole{roleOwner, roleManager, roleAdmin}},
+	},
+	models.ActionStart: {
+		{From: models.StatusApproved, To: models.StatusInProgress, Roles: []transitionRole{roleSystem}},
+	},
+	models.ActionComplete: {
+		{From: models.StatusInProgress, To: models.StatusCompleted, Roles: []transitionRole{roleSystem}},
+	},
+	models.ActionRecall: {
+		{From: models.StatusInProgress, To: models.StatusRecalled, Roles: []transitionRole{roleManager, roleAdmin}},
+	},
+	models.ActionEdit: {
+		{From: models.StatusDraft, To: models.StatusDraft, Roles: []transitionRole{roleOwner}},
+		{From: models.StatusPending, To: models.StatusPending, Roles: []transitionRole{roleOwner}},
+	},
+	models.ActionTransfer: {
+		{From: models.StatusApproved, To: models.StatusApproved, Roles: []transitionRole{roleManager, roleAdmin}},
+	},
+	models.ActionProposeChanges: {
+		{From: models.StatusPending, To: models.StatusChangesProposed, Roles: []transitionRole{roleApprover, roleAdmin}},
+	},
+	models.ActionAcceptProposal: {
+		{From: models.StatusChangesProposed, To: models.StatusPending, Roles: []transitionRole{roleOwner}},
+	},
+	models.ActionDeclineProposal: {
+		{From: models.StatusChangesProposed, To: models.StatusPending, Roles: []transitionRole{roleOwner}},
+	},
+}
+
+// selfDecisionForbidden - действия, которые нельзя выполнять со своей заявкой даже администратору:
+// решение по заявке руководителя принимает руководитель вышестоящего юнита или другой администратор
+var selfDecisionForbidden = map[string]bool{
+	models.ActionApproveStep:    true,
+	models.ActionApprove:        true,
+	models.ActionReject:         true,
+	models.ActionTransfer:       true,
+	models.ActionProposeChanges: true,
+}
+
+// initialStatuses - статусы, с которыми может быть создана заявка
+var initialStatuses = map[int]bool{
+	models.StatusDraft:   true,
+	models.StatusPending: true,
+}
+
+// findTransition ищет правило перехода для действия из текущего статуса
+func findTransition(action string, fromStatus int) (*transitionRule, bool) {
+	for i, rule := range vacationStateMachine[action] {
+		if rule.From == fromStatus {
+			return &vacationStateMachine[action][i], true
+		}
+	}
+	return nil, false
+}
+
+// actorRoles определяет роли пользователя по отношению к заявке.
+// actor == nil означает системное действие.
+func (s *VacationService) actorRoles(actor *models.User, req *models.VacationRequest) (map[transitionRole]bool, error) {
+	roles := make(map[transitionRole]bool)
+	if actor == nil {
+		roles[roleSystem] = true
+		return roles, nil
+	}
+	if actor.ID == req.UserID {
+		roles[roleOwner] = true
+	}
+	if actor.IsAdmin {
+		roles[roleAdmin] = true
+	}
+	// Права руководителя может получить и не руководитель - по делегированию.
+	// По собственной заявке пользователь остается только автором: руководитель не рассматривает свои заявки.
+	if !roles[roleOwner] {
+		employee, err := s.userRepo.FindByID(req.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения данных сотрудника %d для проверки доступа: %w", req.UserID, err)
+		}
+		if employee == nil {
+			return nil, fmt.Errorf("сотрудник %d, подавший заявку, не найден", req.UserID)
+		}
+		accessGranted, err := s.checkUserUnitAccess(actor, employee)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка проверки доступа: %w", err)
+		}
+		if accessGranted {
+			roles[roleManager] = true
+		}
+	}
+	// Согласующий определяется цепочкой; у заявок без цепочки (отправленных до ее появления) - любой руководитель сотрудника
+	if len(req.ApprovalSteps) > 0 {
+		isApprover, err := s.isStepApprover(actor, req)
+		if err != nil {
+			return nil, err
+		}
+		roles[roleApprover] = isApprover
+	} else {
+		roles[roleApprover] = roles[roleManager]
+	}
+	return roles, nil
+}
+
+// authorizeTransition проверяет, что действие допустимо из текущего статуса заявки и разрешено пользователю.
+// Возвращает целевой статус.
+func (s *VacationService) authorizeTransition(actor *models.User, req *models.VacationRequest, action string) (int, error) {
+	rule, ok := findTransition(action, req.StatusID)
+	if !ok {
+		return 0, fmt.Errorf("%w: действие %s для заявки ID %d в статусе %d", ErrWrongStatus, action, req.ID, req.StatusID)
+	}
+	if actor != nil && actor.ID == req.UserID && selfDecisionForbidden[action] {
+		log.Printf("[StateMachine] Self-decision denied: user %d cannot perform %s on own request %d", actor.ID, action, req.ID)
+		return 0, fmt.Errorf("%w: нельзя принимать решение по собственной заявке (заявка ID %d)", ErrTransitionForbidden, req.ID)
+	}
+	roles, err := s.actorRoles(actor, req)
+	if err != nil {
+		return 0, err
+	}
+	for _, role := range rule.Roles {
+		if roles[role] {
+			return rule.To, nil
+		}
+	}
+	actorID := 0
+	if actor != nil {
+		actorID = actor.ID
+	}
+	log.Printf("[StateMachine] Access denied: user %d cannot perform %s on request %d (status %d)", actorID, action, req.ID, req.StatusID)
+	return 0, fmt.Errorf("%w: пользователь ID %d, действие %s, заявка ID %d", ErrTransitionForbidden, actorID, action, req.ID)
+}
+
+// applyTransition проверяет и выполняет переход заявки внутри транзакции: меняет статус и записывает историю.
+// Заявка должна быть заблокирована (LockVacationRequest). После успешного перехода req.StatusID обновляется.
+func (s *VacationService) applyTransition(tx repositories.VacationRepositoryInterface, actor *models.User, req *models.VacationRequest, action string, reason string) error {
+	toStatus, err := s.authorizeTransition(actor, req, action)
+	if err != nil {
+		return err
+	}
+	return s.recordTransition(tx, actor, req, action, toStatus, reason)
+}
+
+// recordTransition устанавливает статус заявки и записывает переход в историю без проверки прав.
+// Используется после authorizeTransition, когда итоговый статус зависит от данных заявки.
+func (s *VacationService) recordTransition(tx repositories.VacationRepositoryInterface, actor *models.User, req *models.VacationRequest, action string, toStatus int, reason string) error {
+	if err := tx.UpdateRequestStatusByID(req.ID, toStatus); err != nil {
+		return fmt.Errorf("ошибка установки статуса %d для заявки %d: %w", toStatus, req.ID, err)
+	}
+
+	fromStatus := req.StatusID
+	transition := &models.VacationRequestTransition{
+		RequestID: req.ID, FromStatusID: &fromStatus, ToStatusID: toStatus,
+		Action: action, Reason: reason,
+	}
+	if actor != nil {
+		actorID := actor.ID
+		transition.ActorID = &actorID
+		// Действие заместителя записывается вместе с руководителем, передавшим права
+		onBehalfOf, err := s.actingDelegation(actor, req)
+		if err != nil {
+			return err
+		}
+		transition.OnBehalfOfID = onBehalfOf
+	}
+	if err := tx.AddRequestTransition(transition); err != nil {
+		return err
+	}
+	log.Printf("[StateMachine] Request %d: %d -> %d (%s)", req.ID, fromStatus, toStatus, action)
+	req.StatusID = toStatus
+	return nil
+}
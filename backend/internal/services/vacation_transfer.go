@@ -0,0 +1,377 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"vacation-scheduler/internal/models"
+	"vacation-scheduler/internal/repositories"
+)
+
+// CreateVacationTransfer подает заявку на перенос периода утвержденного отпуска на новые даты.
+// Перенос не меняет количество дней: новый период должен содержать столько же дней отпуска, сколько исходный.
+// Новые даты сверяются с периодами запрета отпусков: строгий запрет отклоняет перенос, нестрогий дает предупреждение.
+func (s *VacationService) CreateVacationTransfer(userID int, input *models.VacationTransferCreateDTO) (*models.VacationTransfer, error) {
+	reason := strings.TrimSpace(input.Reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: необходимо указать основание переноса", ErrInvalidRequest)
+	}
+	if input.StartDate.IsZero() || input.EndDate.IsZero() || input.EndDate.Time.Before(input.StartDate.Time) {
+		return nil, fmt.Errorf("%w: некорректные даты переноса", ErrInvalidRequest)
+	}
+	today := truncateToDate(time.Now())
+	if input.StartDate.Time.Before(today) {
+		return nil, fmt.Errorf("%w: отпуск нельзя перенести на прошедшую дату", ErrInvalidRequest)
+	}
+	actor, err := s.findActor(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var transfer *models.VacationTransfer
+	var request *models.VacationRequest
+	err = s.vacationRepo.RunInTx(func(tx repositories.VacationRepositoryInterface) error {
+		period, err := tx.GetVacationPeriodByID(input.PeriodID)
+		if err != nil {
+			return err
+		}
+		if period == nil {
+			return fmt.Errorf("период отпуска %d не найден", input.PeriodID)
+		}
+		req, err := tx.LockVacationRequest(period.RequestID)
+		if err != nil {
+			return fmt.Errorf("ошибка получения заявки для переноса: %w", err)
+		}
+		if req == nil {
+			return errors.New("заявка не найдена")
+		}
+		if req.UserID != userID {
+			return fmt.Errorf("%w: перенести можно только собственный отпуск", ErrTransitionForbidden)
+		}
+		if req.StatusID != models.StatusApproved {
+			return fmt.Errorf("%w: перенести можно только утвержденный отпуск, который еще не начался", ErrTransitionNotAllowed)
+		}
+		if input.StartDate.Year() != req.Year || input.EndDate.Year() != req.Year {
+			return fmt.Errorf("%w: новые даты должны относиться к %d году", ErrInvalidRequest, req.Year)
+		}
+
+		leaveType, err := tx.GetLeaveTypeByID(period.LeaveTypeID)
+		if err != nil {
+			return err
+		}
+		if leaveType == nil {
+			return fmt.Errorf("вид отпуска %d не найден", period.LeaveTypeID)
+		}
+		newPeriod := models.VacationPeriod{ID: period.ID, LeaveTypeID: period.LeaveTypeID, StartDate: input.StartDate, EndDate: input.EndDate}
+		newPeriod.DaysCount = s.countLeaveDays(*leaveType, newPeriod.StartDate.Time, newPeriod.EndDate.Time)
+		if newPeriod.DaysCount != period.DaysCount {
+			return fmt.Errorf("%w: новый период должен содержать %d дн. отпуска, указано %d", ErrInvalidRequest, period.DaysCount, newPeriod.DaysCount)
+		}
+		for _, other := range req.Periods {
+			if other.ID != period.ID && doPeriodIntersect(newPeriod, other) {
+				return fmt.Errorf("%w: новые даты пересекаются с другим периодом этой заявки", ErrInvalidRequest)
+			}
+		}
+		// Количество дней и частей не меняется, поэтому по политике проверяются только периоды запрета на новые даты
+		violations, warnings, err := s.checkBlackouts(tx, actor, transferredRequest(req, newPeriod))
+		if err != nil {
+			return fmt.Errorf("ошибка проверки периодов запрета отпусков: %w", err)
+		}
+		if len(violations) > 0 {
+			return &PolicyViolationError{Violations: violations}
+		}
+
+		pending, err := tx.HasPendingVacationTransfer(period.ID)
+		if err != nil {
+			return err
+		}
+		if pending {
+			return fmt.Errorf("%w: по этому периоду уже есть перенос на рассмотрении", ErrTransitionNotAllowed)
+		}
+
+		transfer = &models.VacationTransfer{
+			RequestID: req.ID, PeriodID: period.ID, UserID: userID, UserFullName: actor.FullName,
+			OriginalStartDate: period.StartDate, OriginalEndDate: period.EndDate,
+			NewStartDate: newPeriod.StartDate, NewEndDate: newPeriod.EndDate, DaysCount: newPeriod.DaysCount,
+			Reason: reason, StatusID: models.StatusPending, Warnings: warnings,
+		}
+		request = req
+		return tx.CreateVacationTransfer(transfer)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[Service CreateVacationTransfer] Transfer %d created for period %d (request %d) by user %d", transfer.ID, transfer.PeriodID, transfer.RequestID, userID)
+
+	s.notifyApprover(request, actor, "Заявка на перенос отпуска",
+		fmt.Sprintf("Сотрудник %s просит перенести отпуск с %s на %s. Основание: %s", actor.FullName,
+			transfer.OriginalStartDate.Format("02.01.2006"), transfer.NewStartDate.Format("02.01.2006"), transfer.Reason))
+	return transfer, nil
+}
+
+// ApproveVacationTransfer утверждает перенос: период заявки получает новые даты,
+// а перенос фиксируется в истории исходной заявки.
+// Новые даты проверяются так же, как при утверждении заявки: периоды запрета, преимущественное право,
+// конфликты по должности, очередность пиковых периодов и правила укомплектованности (под блокировкой должности и правил).
+// Если найдены конфликты или нарушения укомплектованности и force=false, возвращает их без утверждения.
+func (s *VacationService) ApproveVacationTransfer(transferID int, approverID int, force bool) ([]models.ConflictingPeriod, []models.CoverageViolation, error) {
+	approver, err := s.findActor(approverID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var transfer *models.VacationTransfer
+	var conflicts []models.ConflictingPeriod
+	var coverage []models.CoverageViolation
+	approved := false
+	err = s.vacationRepo.RunInTx(func(tx repositories.VacationRepositoryInterface) error {
+		var err error
+		transfer, err = s.lockPendingTransfer(tx, transferID)
+		if err != nil {
+			return err
+		}
+		req, err := tx.LockVacationRequest(transfer.RequestID)
+		if err != nil {
+			return fmt.Errorf("ошибка получения заявки для переноса: %w", err)
+		}
+		if req == nil {
+			return errors.New("заявка не найдена")
+		}
+
+		newPeriod := models.VacationPeriod{ID: transfer.PeriodID, StartDate: transfer.NewStartDate, EndDate: transfer.NewEndDate, DaysCount: transfer.DaysCount}
+		for _, period := range req.Periods {
+			if period.ID == transfer.PeriodID {
+				newPeriod.RequestID, newPeriod.LeaveTypeID = period.RequestID, period.LeaveTypeID
+			}
+		}
+		conflicts, coverage, err = s.checkTransferStaffing(tx, req, newPeriod)
+		if err != nil {
+			return err
+		}
+		if (len(conflicts) > 0 || len(coverage) > 0) && !force {
+			log.Printf("[Service ApproveVacationTransfer] Transfer %d has %d conflicts and %d coverage violations, force=false", transferID, len(conflicts), len(coverage))
+			return nil
+		}
+
+		reason := fmt.Sprintf("Перенос периода %s - %s на %s - %s. Основание: %s",
+			transfer.OriginalStartDate.Format("02.01.2006"), transfer.OriginalEndDate.Format("02.01.2006"),
+			transfer.NewStartDate.Format("02.01.2006"), transfer.NewEndDate.Format("02.01.2006"), transfer.Reason)
+		outOfTurn := []models.ConflictingPeriod{}
+		for _, c := range conflicts {
+			if c.OutOfTurn {
+				outOfTurn = append(outOfTurn, c)
+			}
+		}
+		if approvedConflicts := len(conflicts) - len(outOfTurn); approvedConflicts > 0 {
+			reason += fmt.Sprintf(". Утверждено несмотря на конфликты (%d)", approvedConflicts)
+		}
+		if len(outOfTurn) > 0 {
+			reason += ". Утверждено вне очереди на пиковый период: " + outOfTurnText(outOfTurn)
+		}
+		if len(coverage) > 0 {
+			reason += fmt.Sprintf(". Утверждено несмотря на нарушение правил укомплектованности (%d дн.)", len(coverage))
+		}
+		if err := s.applyTransition(tx, approver, req, models.ActionTransfer, reason); err != nil {
+			return err
+		}
+		if err := tx.UpdateVacationPeriodDates(transfer.PeriodID, transfer.NewStartDate, transfer.NewEndDate, transfer.DaysCount); err != nil {
+			return err
+		}
+		if err := s.syncRequestDelegations(tx, req.ID); err != nil {
+			return err
+		}
+		approved = true
+		return tx.SetVacationTransferDecision(transfer.ID, models.StatusApproved, approverID, "")
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if !approved {
+		return conflicts, coverage, nil
+	}
+	log.Printf("[Service ApproveVacationTransfer] Transfer %d approved by user %d (force=%t)", transferID, approverID, force)
+
+	s.notifyUser(transfer.UserID, "Перенос отпуска утвержден",
+		fmt.Sprintf("Отпуск перенесен на %s - %s.", transfer.NewStartDate.Format("02.01.2006"), transfer.NewEndDate.Format("02.01.2006")))
+	return conflicts, coverage, nil
+}
+
+// checkTransferStaffing проверяет новые даты переноса по правилам, действующим при утверждении заявки.
+// Строгие периоды запрета и вытеснение сотрудника с преимущественным правом прерывают утверждение ошибкой,
+// конфликты по должности, пересечения вне очереди на пиковые даты и нарушения укомплектованности
+// возвращаются для подтверждения.
+// Должность сотрудника и правила укомплектованности блокируются до конца транзакции.
+func (s *VacationService) checkTransferStaffing(tx repositories.VacationRepositoryInterface, req *models.VacationRequest, newPeriod models.VacationPeriod) ([]models.ConflictingPeriod, []models.CoverageViolation, error) {
+	employee, err := s.findActor(req.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	moved := transferredRequest(req, newPeriod)
+	violations, _, err := s.checkBlackouts(tx, employee, moved)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка проверки периодов запрета отпусков: %w", err)
+	}
+	if len(violations) > 0 {
+		return nil, nil, &PolicyViolationError{Violations: violations}
+	}
+
+	// Проверяются только новые даты: остальные периоды заявки уже утверждены
+	checked := *req
+	checked.Periods = []models.VacationPeriod{newPeriod}
+	var conflicts []models.ConflictingPeriod
+	positionID, err := tx.GetUserPositionByID(req.UserID)
+	if err != nil {
+		log.Printf("[checkTransferStaffing] Warning: could not get position for user %d while checking transfer of request %d: %v", req.UserID, req.ID, err)
+	} else if positionID != nil {
+		if err := tx.LockPosition(*positionID); err != nil {
+			return nil, nil, err
+		}
+		conflicts, err = tx.GetApprovedVacationConflictsByPosition(*positionID, req.UserID, checked.Periods)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ошибка проверки конфликтов отпусков: %w", err)
+		}
+		if err := s.annotateConflictPriority(conflicts); err != nil {
+			return nil, nil, fmt.Errorf("ошибка проверки преимущественного права: %w", err)
+		}
+		if err := s.annotateConflictFairness(tx, conflicts); err != nil {
+			return nil, nil, fmt.Errorf("ошибка расчета очередности пиковых периодов: %w", err)
+		}
+		if err := s.checkPriorityDisplacement(tx, &checked, *positionID); err != nil {
+			return nil, nil, err
+		}
+		// Перенос в пиковый период проходит очередь так же, как утверждение заявки
+		outOfTurn, err := s.checkFairRotation(tx, &checked, *positionID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ошибка проверки очередности пиковых периодов: %w", err)
+		}
+		conflicts = append(conflicts, outOfTurn...)
+	}
+	coverage, err := s.checkCoverage(tx, &checked, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка проверки правил укомплектованности: %w", err)
+	}
+	return conflicts, coverage, nil
+}
+
+// transferredRequest возвращает копию заявки, в которой переносимый период заменен новыми датами
+func transferredRequest(req *models.VacationRequest, newPeriod models.VacationPeriod) *models.VacationRequest {
+	moved := *req
+	moved.Periods = make([]models.VacationPeriod, len(req.Periods))
+	for i, period := range req.Periods {
+		if period.ID == newPeriod.ID {
+			period = newPeriod
+		}
+		moved.Periods[i] = period
+	}
+	return &moved
+}
+
+// RejectVacationTransfer отклоняет перенос; даты периода не меняются
+func (s *VacationService) RejectVacationTransfer(transferID int, rejecterID int, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if s.reasonPolicy.RejectionRequired && reason == "" {
+		return fmt.Errorf("%w: необходимо указать причину отклонения", ErrTransitionNotAllowed)
+	}
+	rejecter, err := s.findActor(rejecterID)
+	if err != nil {
+		return err
+	}
+
+	var transfer *models.VacationTransfer
+	err = s.vacationRepo.RunInTx(func(tx repositories.VacationRepositoryInterface) error {
+		var err error
+		transfer, err = s.lockPendingTransfer(tx, transferID)
+		if err != nil {
+			return err
+		}
+		roles, err := s.actorRoles(rejecter, &models.VacationRequest{ID: transfer.RequestID, UserID: transfer.UserID})
+		if err != nil {
+			return err
+		}
+		if !roles[roleManager] && !roles[roleAdmin] {
+			return fmt.Errorf("%w: пользователь ID %d не может отклонить перенос %d", ErrTransitionForbidden, rejecterID, transferID)
+		}
+		return tx.SetVacationTransferDecision(transfer.ID, models.StatusRejected, rejecterID, reason)
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("[Service RejectVacationTransfer] Transfer %d rejected by user %d", transferID, rejecterID)
+
+	message := fmt.Sprintf("Перенос отпуска с %s на %s отклонен.", transfer.OriginalStartDate.Format("02.01.2006"), transfer.NewStartDate.Format("02.01.2006"))
+	if reason != "" {
+		message += " Причина: " + reason
+	}
+	s.notifyUser(transfer.UserID, "Перенос отпуска отклонен", message)
+	return nil
+}
+
+// CancelVacationTransfer отменяет перенос на рассмотрении (только автор)
+func (s *VacationService) CancelVacationTransfer(transferID int, userID int) error {
+	return s.vacationRepo.RunInTx(func(tx repositories.VacationRepositoryInterface) error {
+		transfer, err := s.lockPendingTransfer(tx, transferID)
+		if err != nil {
+			return err
+		}
+		if transfer.UserID != userID {
+			return fmt.Errorf("%w: отменить перенос может только его автор", ErrTransitionForbidden)
+		}
+		return tx.SetVacationTransferDecision(transfer.ID, models.StatusCancelled, userID, "")
+	})
+}
+
+// GetMyVacationTransfers возвращает все переносы пользователя
+func (s *VacationService) GetMyVacationTransfers(userID int) ([]models.VacationTransfer, error) {
+	return s.vacationRepo.GetVacationTransfers(&userID, nil, nil)
+}
+
+// GetVacationTransfersForApproval возвращает переносы сотрудников, доступных руководителю
+// (в том числе по делегированию), администратору - все
+func (s *VacationService) GetVacationTransfersForApproval(requestingUserID int, statusFilter *int) ([]models.VacationTransfer, error) {
+	requestingUser, err := s.findActor(requestingUserID)
+	if err != nil {
+		return nil, err
+	}
+	if requestingUser.IsAdmin {
+		return s.vacationRepo.GetVacationTransfers(nil, statusFilter, nil)
+	}
+	unitIDs, err := s.managedUnitIDs(requestingUser)
+	if err != nil {
+		return nil, err
+	}
+	if len(unitIDs) == 0 {
+		return nil, fmt.Errorf("%w: просмотр переносов доступен руководителям и администраторам", ErrTransitionForbidden)
+	}
+	return s.vacationRepo.GetVacationTransfers(nil, statusFilter, unitIDs)
+}
+
+// lockPendingTransfer блокирует перенос и проверяет, что он еще на рассмотрении
+func (s *VacationService) lockPendingTransfer(tx repositories.VacationRepositoryInterface, transferID int) (*models.VacationTransfer, error) {
+	transfer, err := tx.LockVacationTransfer(transferID)
+	if err != nil {
+		return nil, err
+	}
+	if transfer == nil {
+		return nil, fmt.Errorf("перенос %d не найден", transferID)
+	}
+	if transfer.StatusID != models.StatusPending {
+		return nil, fmt.Errorf("%w: перенос уже рассмотрен (статус %s)", ErrTransitionNotAllowed, transfer.StatusName)
+	}
+	return transfer, nil
+}
+
+// applyTransfersToExportRow заполняет столбцы 9, 11 и 12 формы Т-7 по утвержденным переносам периода.
+// Запланированной остается дата до первого переноса, основание и новая дата берутся из последнего.
+func applyTransfersToExportRow(row *models.VacationExportRow, transfers []models.VacationTransfer) {
+	if len(transfers) == 0 {
+		return
+	}
+	first, last := transfers[0], transfers[len(transfers)-1]
+	row.PlannedDate = first.OriginalStartDate
+	row.TransferReason = last.Reason
+	newDate := last.NewStartDate
+	row.TransferDate = &newDate
+}
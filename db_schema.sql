@@ -18,12 +18,28 @@ CREATE TABLE users (
     position_id INT,
     is_admin BOOLEAN DEFAULT FALSE,
     is_manager BOOLEAN DEFAULT FALSE, -- Роль менеджера может определяться должностью или привязкой к юниту
+    hire_date DATE NULL, -- Дата приема на работу (для пропорционального начисления отпуска)
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     FOREIGN KEY (position_id) REFERENCES positions(id) ON DELETE SET NULL
     -- Внешний ключ для organizational_unit_id будет добавлен после создания таблицы organizational_units
 );
 
+-- Категории преимущественного права выбора времени отпуска (ТК РФ и специальные законы) с периодом действия
+CREATE TABLE user_priority_categories (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    category VARCHAR(30) NOT NULL, -- MINOR, SPOUSE_MATERNITY, YOUNG_CHILD, LARGE_FAMILY, DISABLED_CHILD, MILITARY_SPOUSE, VETERAN, HONORARY_DONOR, OTHER
+    start_date DATE NOT NULL,
+    end_date DATE NULL, -- NULL - бессрочно
+    comment TEXT, -- Основание (документ)
+    created_by INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL,
+    INDEX idx_priority_categories_user (user_id, start_date)
+);
+
 -- Новая таблица для иерархии организационной структуры
 CREATE TABLE organizational_units (
     id INT AUTO_INCREMENT PRIMARY KEY,
@@ -42,13 +58,12 @@ CREATE TABLE organizational_units (
 ALTER TABLE users
 ADD FOREIGN KEY (organizational_unit_id) REFERENCES organizational_units(id) ON DELETE SET NULL;
 
--- Таблица лимитов отпусков
+-- Таблица лимитов отпусков (счет сотрудника на год).
+-- Количество дней не хранится: баланс вычисляется по журналу leave_ledger_entries (см. представление vacation_balances)
 CREATE TABLE vacation_limits (
     id INT AUTO_INCREMENT PRIMARY KEY,
     user_id INT NOT NULL,
     year INT NOT NULL,
-    total_days INT NOT NULL DEFAULT 28,
-    used_days INT NOT NULL DEFAULT 0,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
@@ -63,21 +78,191 @@ CREATE TABLE vacation_requests (
     status_id INT NOT NULL,
     days_requested INT NOT NULL DEFAULT 0, -- Добавлено поле для хранения запрошенных дней
     comment TEXT,
+    rejection_reason TEXT, -- Причина отклонения
+    cancellation_reason TEXT, -- Причина отмены
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 );
 
+-- Политики отпуска: правила для всей организации, юнита (с наследованием вниз по дереву), должности
+-- или должности внутри юнита. NULL в правиле - значение наследуется от более общей политики.
+CREATE TABLE vacation_policies (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    unit_id INT NULL, -- NULL - политика не привязана к юниту
+    position_id INT NULL, -- NULL - политика не привязана к должности
+    annual_days INT NULL, -- Дней основного отпуска за полный год работы
+    min_long_part_days INT NULL, -- Минимальная продолжительность одной из частей (0 - без требования)
+    max_parts INT NULL, -- Максимальное количество частей (0 - без ограничения)
+    require_exact_balance BOOLEAN NULL, -- Заявка должна использовать все доступные дни
+    forbid_overlap BOOLEAN NULL, -- Периоды заявки не должны пересекаться
+    comment TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (unit_id) REFERENCES organizational_units(id) ON DELETE CASCADE,
+    FOREIGN KEY (position_id) REFERENCES positions(id) ON DELETE CASCADE
+);
+
+-- Правила укомплектованности: сколько сотрудников юнита (или группы должностей в юните)
+-- могут отсутствовать одновременно или сколько должно оставаться на месте в каждый день
+CREATE TABLE coverage_rules (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    unit_id INT NOT NULL,
+    include_subunits BOOLEAN NOT NULL DEFAULT TRUE, -- Правило действует на все поддерево юнита
+    max_absent INT NULL, -- Максимум одновременно отсутствующих (NULL - без ограничения)
+    min_present INT NULL, -- Минимум сотрудников на месте (NULL - без ограничения)
+    comment TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (unit_id) REFERENCES organizational_units(id) ON DELETE CASCADE
+);
+
+-- Группа должностей правила укомплектованности (нет строк - правило действует на все должности)
+CREATE TABLE coverage_rule_positions (
+    rule_id INT NOT NULL,
+    position_id INT NOT NULL,
+    PRIMARY KEY (rule_id, position_id),
+    FOREIGN KEY (rule_id) REFERENCES coverage_rules(id) ON DELETE CASCADE,
+    FOREIGN KEY (position_id) REFERENCES positions(id) ON DELETE CASCADE
+);
+
+-- Периоды запрета отпусков юнита (закрытие квартала, отчетный период); действуют и на все поддерево юнита
+CREATE TABLE unit_blackouts (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    unit_id INT NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    severity ENUM('BLOCK', 'WARN') NOT NULL DEFAULT 'BLOCK', -- BLOCK - заявка отклоняется при проверке, WARN - только предупреждение
+    reason VARCHAR(255) NOT NULL,
+    created_by INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (unit_id) REFERENCES organizational_units(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL,
+    INDEX idx_unit_blackouts_unit_dates (unit_id, start_date, end_date)
+);
+
+-- Должности, на которые запрет отпусков не распространяется
+CREATE TABLE unit_blackout_exempt_positions (
+    blackout_id INT NOT NULL,
+    position_id INT NOT NULL,
+    PRIMARY KEY (blackout_id, position_id),
+    FOREIGN KEY (blackout_id) REFERENCES unit_blackouts(id) ON DELETE CASCADE,
+    FOREIGN KEY (position_id) REFERENCES positions(id) ON DELETE CASCADE
+);
+
+-- Пиковые периоды отпусков (лето, новогодние праздники), повторяющиеся каждый год.
+-- По отпускам сотрудника в пиковые периоды прошлых лет считается очередность выбора пиковых дат.
+CREATE TABLE peak_windows (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    unit_id INT NULL, -- NULL - для всей организации; иначе действует на поддерево юнита
+    name VARCHAR(100) NOT NULL,
+    start_month TINYINT NOT NULL,
+    start_day TINYINT NOT NULL,
+    end_month TINYINT NOT NULL, -- Окончание раньше начала - период переходит на следующий год
+    end_day TINYINT NOT NULL,
+    comment TEXT,
+    created_by INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (unit_id) REFERENCES organizational_units(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- Кампании планирования графика отпусков на год (для всей организации или поддерева юнита).
+-- Вне этапа сбора заявки на отпуска графика не принимаются; утвержденный график меняется только переносом.
+CREATE TABLE planning_campaigns (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    year INT NOT NULL,
+    unit_id INT NULL, -- NULL - кампания для всей организации
+    phase ENUM('COLLECTING', 'MANAGER_REVIEW', 'HR_REVIEW', 'LOCKED') NOT NULL DEFAULT 'COLLECTING',
+    collecting_deadline DATE NOT NULL,
+    manager_review_deadline DATE NOT NULL,
+    hr_review_deadline DATE NOT NULL, -- График утверждается не позднее чем за две недели до начала года
+    locked_at TIMESTAMP NULL,
+    created_by INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (unit_id) REFERENCES organizational_units(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL,
+    INDEX idx_planning_campaigns_year (year, unit_id)
+);
+
+-- Справочник видов отпуска
+CREATE TABLE leave_types (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    code VARCHAR(30) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    counting_rule ENUM('CALENDAR', 'WORKING') NOT NULL DEFAULT 'CALENDAR', -- Дни отпуска считаются в календарных или рабочих днях
+    counts_toward_main BOOLEAN NOT NULL DEFAULT FALSE, -- Дни списываются с баланса основного отпуска
+    in_schedule BOOLEAN NOT NULL DEFAULT FALSE, -- Включается в график отпусков (форма Т-7)
+    is_paid BOOLEAN NOT NULL DEFAULT TRUE,
+    max_days_per_year INT NULL, -- Ограничение дней в году (NULL - без ограничения)
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+-- Пожелания сотрудника к графику отпусков на год: варианты наборов периодов по приоритету и ограничения
+CREATE TABLE vacation_preferences (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    year INT NOT NULL,
+    comment TEXT,
+    chosen_alternative_id INT NULL, -- Вариант, по которому создана заявка
+    converted_request_id INT NULL, -- Заявка, созданная по выбранному варианту
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_vacation_preference (user_id, year),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (converted_request_id) REFERENCES vacation_requests(id) ON DELETE SET NULL
+);
+
+-- Варианты пожелания (rank 1 - наиболее желательный)
+CREATE TABLE vacation_preference_alternatives (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    preference_id INT NOT NULL,
+    `rank` INT NOT NULL,
+    FOREIGN KEY (preference_id) REFERENCES vacation_preferences(id) ON DELETE CASCADE,
+    UNIQUE KEY uq_preference_alternative_rank (preference_id, `rank`)
+);
+
+-- Периоды варианта пожелания
+CREATE TABLE vacation_preference_periods (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    alternative_id INT NOT NULL,
+    leave_type_id INT NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    FOREIGN KEY (alternative_id) REFERENCES vacation_preference_alternatives(id) ON DELETE CASCADE,
+    FOREIGN KEY (leave_type_id) REFERENCES leave_types(id)
+);
+
+-- Ограничения пожелания: отпуск должен включать даты (школьные каникулы) или не затрагивать их.
+-- HARD - обязательно для каждого варианта, SOFT - желательно
+CREATE TABLE vacation_preference_constraints (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    preference_id INT NOT NULL,
+    kind ENUM('INCLUDE', 'EXCLUDE') NOT NULL,
+    strength ENUM('HARD', 'SOFT') NOT NULL DEFAULT 'SOFT',
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    FOREIGN KEY (preference_id) REFERENCES vacation_preferences(id) ON DELETE CASCADE
+);
+
 -- Таблица периодов отпуска
 CREATE TABLE vacation_periods (
     id INT AUTO_INCREMENT PRIMARY KEY,
     request_id INT NOT NULL,
+    leave_type_id INT NOT NULL DEFAULT 1, -- Вид отпуска (по умолчанию - основной)
     start_date DATE NOT NULL,
     end_date DATE NOT NULL,
     days_count INT NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-    FOREIGN KEY (request_id) REFERENCES vacation_requests(id) ON DELETE CASCADE
+    FOREIGN KEY (request_id) REFERENCES vacation_requests(id) ON DELETE CASCADE,
+    FOREIGN KEY (leave_type_id) REFERENCES leave_types(id)
 );
 
 -- Таблица статусов заявок
@@ -91,6 +276,199 @@ CREATE TABLE vacation_status (
 ALTER TABLE vacation_requests
 ADD FOREIGN KEY (status_id) REFERENCES vacation_status(id);
 
+-- История переходов заявок между статусами
+CREATE TABLE vacation_request_transitions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    request_id INT NOT NULL,
+    from_status_id INT NULL, -- NULL при создании заявки
+    to_status_id INT NOT NULL,
+    action VARCHAR(30) NOT NULL COMMENT 'CREATE, SUBMIT, APPROVE_STEP, APPROVE, REJECT, CANCEL, START, COMPLETE, RECALL, EDIT, TRANSFER, PROPOSE_CHANGES, ACCEPT_PROPOSAL, DECLINE_PROPOSAL',
+    actor_id INT NULL, -- NULL для системных переходов
+    on_behalf_of_id INT NULL, -- Руководитель, передавший права по делегированию (если actor_id действовал как заместитель)
+    reason TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (request_id) REFERENCES vacation_requests(id) ON DELETE CASCADE,
+    FOREIGN KEY (from_status_id) REFERENCES vacation_status(id),
+    FOREIGN KEY (to_status_id) REFERENCES vacation_status(id),
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (on_behalf_of_id) REFERENCES users(id) ON DELETE SET NULL,
+    INDEX idx_transitions_request (request_id)
+);
+
+-- Предложения руководителя изменить даты заявки на рассмотрении (переписка по заявке).
+-- Сотрудник принимает предложение (периоды заявки заменяются предложенными) или отклоняет его с пояснением.
+CREATE TABLE vacation_request_proposals (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    request_id INT NOT NULL,
+    proposed_by INT NULL,
+    comment TEXT,
+    status ENUM('OPEN', 'ACCEPTED', 'DECLINED', 'CANCELLED') NOT NULL DEFAULT 'OPEN', -- CANCELLED - заявка отменена до ответа
+    response_comment TEXT,
+    responded_by INT NULL,
+    responded_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (request_id) REFERENCES vacation_requests(id) ON DELETE CASCADE,
+    FOREIGN KEY (proposed_by) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (responded_by) REFERENCES users(id) ON DELETE SET NULL,
+    INDEX idx_proposals_request (request_id)
+);
+
+-- Предложенные периоды
+CREATE TABLE vacation_request_proposal_periods (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    proposal_id INT NOT NULL,
+    leave_type_id INT NOT NULL DEFAULT 1,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    days_count INT NOT NULL,
+    FOREIGN KEY (proposal_id) REFERENCES vacation_request_proposals(id) ON DELETE CASCADE,
+    FOREIGN KEY (leave_type_id) REFERENCES leave_types(id)
+);
+
+-- Этапы цепочки согласования заявки. Цепочка строится по иерархии юнитов при отправке заявки
+-- на рассмотрение: руководители юнитов снизу вверх, затем (по настройке) этап отдела кадров.
+-- Заявка утверждается, когда согласованы все этапы по порядку.
+CREATE TABLE vacation_approval_steps (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    request_id INT NOT NULL,
+    step_order INT NOT NULL, -- Порядок этапа, начиная с 1
+    step_type ENUM('MANAGER', 'HR') NOT NULL, -- Руководитель юнита или отдел кадров (любой администратор)
+    unit_id INT NULL, -- Юнит, руководитель которого согласует этап
+    approver_id INT NULL, -- Согласующий; NULL для этапа отдела кадров
+    status ENUM('PENDING', 'APPROVED', 'REJECTED') NOT NULL DEFAULT 'PENDING',
+    decided_by INT NULL, -- Кто фактически принял решение по этапу
+    decided_at TIMESTAMP NULL,
+    comment TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (request_id) REFERENCES vacation_requests(id) ON DELETE CASCADE,
+    FOREIGN KEY (unit_id) REFERENCES organizational_units(id) ON DELETE SET NULL,
+    FOREIGN KEY (approver_id) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (decided_by) REFERENCES users(id) ON DELETE SET NULL,
+    UNIQUE KEY uq_approval_step (request_id, step_order)
+);
+
+-- Делегирование прав руководителя на рассмотрение заявок его поддерева на время отсутствия.
+-- Записи с source_request_id создаются автоматически по утвержденному отпуску руководителя.
+CREATE TABLE approval_delegations (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    delegator_id INT NOT NULL, -- Руководитель, передающий права
+    delegate_id INT NOT NULL, -- Заместитель
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    comment TEXT,
+    source_request_id INT NULL, -- Заявка на отпуск руководителя, по которой делегирование создано автоматически
+    created_by INT NULL,
+    revoked_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (delegator_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (delegate_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (source_request_id) REFERENCES vacation_requests(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL,
+    INDEX idx_delegations_delegate (delegate_id, start_date, end_date)
+);
+
+-- Отзывы сотрудников из отпуска (период усекается, неиспользованные дни возвращаются на баланс)
+CREATE TABLE vacation_recalls (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    request_id INT NOT NULL,
+    period_id INT NOT NULL,
+    recall_date DATE NOT NULL, -- Первый рабочий день после отзыва
+    original_end_date DATE NOT NULL,
+    unused_days INT NOT NULL,
+    employee_consent BOOLEAN NOT NULL,
+    reason TEXT,
+    recalled_by INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (request_id) REFERENCES vacation_requests(id) ON DELETE CASCADE,
+    FOREIGN KEY (period_id) REFERENCES vacation_periods(id) ON DELETE CASCADE,
+    FOREIGN KEY (recalled_by) REFERENCES users(id) ON DELETE SET NULL,
+    INDEX idx_recalls_period (period_id)
+);
+
+-- Заявки на перенос периодов утвержденного отпуска
+CREATE TABLE vacation_transfers (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    request_id INT NOT NULL,
+    period_id INT NOT NULL,
+    user_id INT NOT NULL,
+    original_start_date DATE NOT NULL, -- Даты периода на момент подачи переноса
+    original_end_date DATE NOT NULL,
+    new_start_date DATE NOT NULL,
+    new_end_date DATE NOT NULL,
+    days_count INT NOT NULL,
+    reason TEXT NOT NULL, -- Основание переноса
+    status_id INT NOT NULL, -- На рассмотрении / Утверждена / Отклонена / Отменена
+    decided_by INT NULL,
+    decision_comment TEXT,
+    decided_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (request_id) REFERENCES vacation_requests(id) ON DELETE CASCADE,
+    FOREIGN KEY (period_id) REFERENCES vacation_periods(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (status_id) REFERENCES vacation_status(id),
+    FOREIGN KEY (decided_by) REFERENCES users(id) ON DELETE SET NULL,
+    INDEX idx_transfers_period (period_id, status_id)
+);
+
+-- Журнал движения дней отпуска (только добавление записей, без изменения и удаления)
+-- days: положительное значение увеличивает доступный остаток, отрицательное - уменьшает
+CREATE TABLE leave_ledger_entries (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    year INT NOT NULL,
+    entry_type VARCHAR(20) NOT NULL COMMENT 'ACCRUAL, RESERVATION, CONSUMPTION, REFUND, ADJUSTMENT, CARRY_OVER, CARRY_OUT, EXPIRY',
+    days INT NOT NULL,
+    request_id INT NULL, -- Заявка, с которой связано движение (если есть)
+    origin_year INT NULL, -- Год, за который начислены перенесенные дни (для CARRY_OVER)
+    actor_id INT NULL, -- Пользователь, выполнивший действие (NULL - система)
+    comment VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (request_id) REFERENCES vacation_requests(id) ON DELETE SET NULL,
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL,
+    INDEX idx_ledger_user_year (user_id, year),
+    INDEX idx_ledger_request (request_id)
+);
+
+-- Итоги перехода года: остаток закрываемого года переносится или сгорает один раз для каждого сотрудника
+CREATE TABLE vacation_year_rollovers (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    year INT NOT NULL, -- Закрываемый год
+    carried_days INT NOT NULL,
+    expired_days INT NOT NULL,
+    actor_id INT NULL, -- NULL - запуск из командной строки
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_user_rollover_year (user_id, year),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- Баланс отпуска, вычисляемый по журналу
+-- total_days - начислено за год (начисления и корректировки), carried_over_days - перенесено с прошлых лет,
+-- carried_out_days - перенесено на следующий год, expired_days - сгорело при переходе года,
+-- reserved_days - зарезервировано заявками на рассмотрении,
+-- consumed_days - использовано по утвержденным заявкам (за вычетом возвратов), used_days = reserved_days + consumed_days
+CREATE VIEW vacation_balances AS
+SELECT
+    vl.id AS limit_id,
+    vl.user_id,
+    vl.year,
+    COALESCE(SUM(CASE WHEN le.entry_type IN ('ACCRUAL', 'ADJUSTMENT') THEN le.days END), 0) AS total_days,
+    COALESCE(SUM(CASE WHEN le.entry_type = 'CARRY_OVER' THEN le.days END), 0) AS carried_over_days,
+    COALESCE(-SUM(CASE WHEN le.entry_type = 'CARRY_OUT' THEN le.days END), 0) AS carried_out_days,
+    COALESCE(-SUM(CASE WHEN le.entry_type = 'EXPIRY' THEN le.days END), 0) AS expired_days,
+    COALESCE(-SUM(CASE WHEN le.entry_type = 'RESERVATION' THEN le.days END), 0) AS reserved_days,
+    COALESCE(-SUM(CASE WHEN le.entry_type IN ('CONSUMPTION', 'REFUND') THEN le.days END), 0) AS consumed_days,
+    COALESCE(-SUM(CASE WHEN le.entry_type IN ('RESERVATION', 'CONSUMPTION', 'REFUND') THEN le.days END), 0) AS used_days,
+    COALESCE(SUM(le.days), 0) AS available_days,
+    vl.created_at,
+    vl.updated_at
+FROM vacation_limits vl
+LEFT JOIN leave_ledger_entries le ON le.user_id = vl.user_id AND le.year = vl.year
+GROUP BY vl.id, vl.user_id, vl.year, vl.created_at, vl.updated_at;
+
 -- Таблица уведомлений
 CREATE TABLE notifications (
     id INT AUTO_INCREMENT PRIMARY KEY,
@@ -103,13 +481,28 @@ CREATE TABLE notifications (
 );
 
 -- Заполнение таблицы статусов
--- Статус 'Черновик' (ID=1) удален. Остальные ID НЕ СДВИГАЮТСЯ.
 INSERT INTO vacation_status (id, name, description) VALUES
--- (1, 'Черновик', 'Заявка создана, но не отправлена'), -- УДАЛЕНО
+(1, 'Черновик', 'Заявка создана, но не отправлена'),
 (2, 'На рассмотрении', 'Заявка отправлена руководителю'),
 (3, 'Утверждена', 'Заявка утверждена руководителем'),
 (4, 'Отклонена', 'Заявка отклонена руководителем'),
-('Отменена', 'Заявка отменена сотрудником');
+(5, 'Отменена', 'Заявка отменена'),
+(6, 'В отпуске', 'Отпуск начался'),
+(7, 'Завершена', 'Отпуск завершен'),
+(8, 'Отозван', 'Сотрудник отозван из отпуска'),
+(9, 'Предложены изменения', 'Руководитель предложил другие даты, ожидается ответ сотрудника');
+
+-- Политика отпуска по умолчанию для всей организации (ст. 115 и 125 ТК РФ)
+INSERT INTO vacation_policies (unit_id, position_id, annual_days, min_long_part_days, max_parts, require_exact_balance, forbid_overlap, comment) VALUES
+(NULL, NULL, 28, 14, 0, TRUE, TRUE, 'Политика по умолчанию');
+
+-- Заполнение справочника видов отпуска
+INSERT INTO leave_types (id, code, name, counting_rule, counts_toward_main, in_schedule, is_paid, max_days_per_year) VALUES
+(1, 'MAIN', 'Ежегодный основной оплачиваемый отпуск', 'CALENDAR', TRUE, TRUE, TRUE, NULL),
+(2, 'ADDITIONAL', 'Ежегодный дополнительный оплачиваемый отпуск', 'CALENDAR', FALSE, TRUE, TRUE, NULL),
+(3, 'IRREGULAR', 'Дополнительный отпуск за ненормированный рабочий день', 'CALENDAR', FALSE, TRUE, TRUE, 3),
+(4, 'STUDY', 'Учебный отпуск', 'CALENDAR', FALSE, FALSE, TRUE, 40),
+(5, 'UNPAID', 'Отпуск без сохранения заработной платы', 'CALENDAR', FALSE, FALSE, FALSE, NULL);
 
 -- Заполнение таблицы organizational_units (Иерархия подразделений)
 -- Корневые элементы